@@ -0,0 +1,121 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"Video-Translation-Simulator/pkg/analyze"
+)
+
+/*
+	analyze is a developer tool for tuning the client's backoff parameters from
+	real log data. It reads structured JSON client logs from the file given as
+	its argument (or from stdin when none is given), as written by a client
+	with SetSlogLogger and a slog.JSONHandler, and prints summary statistics,
+	a histogram of the next_delay values and a plot of delay over attempt number.
+
+	Usage: go run cmd/analyze/main.go [--buckets 10] [--height 10] [client.log]
+*/
+
+func main() {
+	buckets := flag.Int("buckets", 10, "Number of histogram buckets")
+	height := flag.Int("height", 10, "Height in rows of the delay over attempt plot")
+	width := flag.Int("width", 60, "Maximum width in columns of the plots")
+	flag.Parse()
+	if *buckets <= 0 || *height <= 0 || *width <= 0 {
+		log.Fatalf("--buckets, --height and --width must be positive")
+	}
+
+	var input io.Reader = os.Stdin
+	if path := flag.Arg(0); path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			log.Fatalf("Failed to open log file: %v", err)
+		}
+		defer f.Close()
+		input = f
+	}
+
+	result, err := analyze.Parse(input)
+	if err != nil {
+		log.Fatalf("Failed to parse log: %v", err)
+	}
+	if len(result.Samples) == 0 {
+		log.Fatalf("No next_delay values found (%d lines skipped)", result.Skipped)
+	}
+
+	renderSummary(os.Stdout, analyze.Summarize(result.Samples), result.Skipped)
+	renderHistogram(os.Stdout, analyze.Histogram(result.Samples, *buckets), *width)
+	renderTimeSeries(os.Stdout, result.Samples, *width, *height)
+}
+
+func renderSummary(w io.Writer, s analyze.Summary, skipped int) {
+	fmt.Fprintf(w, "Samples: %d (%d lines skipped)\n\n", s.Count, skipped)
+	fmt.Fprintf(w, "  min     %v\n", s.Min)
+	fmt.Fprintf(w, "  max     %v\n", s.Max)
+	fmt.Fprintf(w, "  mean    %v\n", s.Mean)
+	fmt.Fprintf(w, "  stddev  %v\n", s.StdDev)
+	fmt.Fprintf(w, "  p50     %v\n", s.P50)
+	fmt.Fprintf(w, "  p95     %v\n", s.P95)
+	fmt.Fprintf(w, "  p99     %v\n\n", s.P99)
+}
+
+func renderHistogram(w io.Writer, buckets []analyze.Bucket, width int) {
+	fmt.Fprintln(w, "Delay histogram:")
+	most := 0
+	for _, b := range buckets {
+		if b.Count > most {
+			most = b.Count
+		}
+	}
+	for _, b := range buckets {
+		bar := 0
+		if most > 0 {
+			bar = b.Count * width / most
+		}
+		fmt.Fprintf(w, "  %10v - %-10v |%s %d\n", b.Lower.Round(time.Millisecond), b.Upper.Round(time.Millisecond), strings.Repeat("#", bar), b.Count)
+	}
+	fmt.Fprintln(w)
+}
+
+// renderTimeSeries plots delay (y) over attempt order (x). When there are more
+// samples than columns, each column shows the largest delay it covers.
+func renderTimeSeries(w io.Writer, samples []analyze.Sample, width, height int) {
+	columns := len(samples)
+	if columns > width {
+		columns = width
+	}
+	values := make([]time.Duration, columns)
+	for i, s := range samples {
+		col := i * columns / len(samples)
+		if s.NextDelay > values[col] {
+			values[col] = s.NextDelay
+		}
+	}
+	var top time.Duration
+	for _, v := range values {
+		if v > top {
+			top = v
+		}
+	}
+
+	fmt.Fprintf(w, "Delay over attempts (%d..%d):\n", samples[0].Attempt, samples[len(samples)-1].Attempt)
+	for row := height; row >= 1; row-- {
+		threshold := top * time.Duration(row) / time.Duration(height)
+		var line strings.Builder
+		for _, v := range values {
+			if v >= threshold && v > 0 {
+				line.WriteByte('*')
+			} else {
+				line.WriteByte(' ')
+			}
+		}
+		fmt.Fprintf(w, "  %10v |%s\n", threshold.Round(time.Millisecond), line.String())
+	}
+	fmt.Fprintf(w, "  %10s +%s\n", "", strings.Repeat("-", columns))
+}
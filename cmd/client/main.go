@@ -1,23 +1,139 @@
 package main
 
 import (
+    "context"
+    "encoding/json"
+    "errors"
+    "flag"
+    "fmt"
+    "io"
     "log"
+    "net"
     "net/http"
     "os"
+    "os/signal"
+    "sync"
+    "syscall"
+    "time"
 
     "Video-Translation-Simulator/pkg/client"
+    "Video-Translation-Simulator/pkg/notify"
 )
 
 func main() {
+    shutdownTimeout := flag.Duration("shutdown-timeout", 15*time.Second, "Time allowed for in-flight requests to finish on shutdown")
+    verbose := flag.Bool("verbose", false, "Print a timing breakdown for every request sent to the server")
+    output := flag.String("output", "table", "Format of the --verbose timings: table or json")
+    notifyDone := flag.Bool("notify", false, "Send a desktop notification when a job reaches a final status")
+    flag.Parse()
+
     logger := log.New(os.Stdout, "INFO: ", log.LstdFlags)
-    c := client.NewClient("http://localhost:8080", logger)
+
+    var opts []client.ClientOption
+    if *verbose {
+        report, err := timingReporter(*output)
+        if err != nil {
+            logger.Fatalf("Invalid flags: %v", err)
+        }
+        opts = append(opts, client.WithTransport(client.NewTracingTransport(nil, report)))
+    }
+    if *notifyDone {
+        opts = append(opts, client.WithOnFinal(notifyFinal(notify.New(logger), logger)))
+    }
+
+    if err := probeServer(serverURL, opts...); err != nil {
+        logger.Fatalf("%v. Is the server running? Start it with `make server`.", err)
+    }
+    ln, err := net.Listen("tcp", ":9090")
+    if err != nil {
+        logger.Fatalf("Client server failed: %v", err)
+    }
+    if err := run(ln, *shutdownTimeout, logger, opts...); err != nil {
+        logger.Fatalf("Client server failed: %v", err)
+    }
+}
+
+// serverURL is the translation server the client polls.
+const serverURL = "http://localhost:8080"
+
+// probeServer checks the server is reachable, so a missing server is reported
+// at startup rather than on the first status request.
+func probeServer(url string, opts ...client.ClientOption) error {
+    c := client.NewClient(url, log.New(io.Discard, "", 0), opts...)
+    return c.Probe(context.Background())
+}
+
+// timingReporter returns a function printing request timings to stdout in the
+// given format.
+func timingReporter(format string) (func(client.RequestTiming), error) {
+    var mu sync.Mutex
+    switch format {
+    case "json":
+        enc := json.NewEncoder(os.Stdout)
+        return func(t client.RequestTiming) {
+            mu.Lock()
+            defer mu.Unlock()
+            enc.Encode(t)
+        }, nil
+    case "table":
+        fmt.Printf("%-40s %10s %10s %10s %10s %10s\n", "url", "dns_ms", "connect_ms", "tls_ms", "ttfb_ms", "total_ms")
+        return func(t client.RequestTiming) {
+            mu.Lock()
+            defer mu.Unlock()
+            fmt.Printf("%-40s %10.2f %10.2f %10.2f %10.2f %10.2f\n", t.URL, t.DNSMs, t.ConnectMs, t.TLSMs, t.TTFBMs, t.TotalMs)
+        }, nil
+    default:
+        return nil, fmt.Errorf("unknown output format %q, expected table or json", format)
+    }
+}
+
+// notifyFinal returns a hook sending a notification for each finished job.
+func notifyFinal(n notify.Notifier, logger *log.Logger) func(string) {
+    return func(status string) {
+        if err := n.Notify("Video translation", "Job finished with status: "+status); err != nil {
+            logger.Printf("Sending notification failed: %v", err)
+        }
+    }
+}
+
+// run serves the client library on ln until SIGTERM or SIGINT is received,
+// then stops accepting new requests and gives in-flight polling up to
+// shutdownTimeout to finish before returning.
+func run(ln net.Listener, shutdownTimeout time.Duration, logger *log.Logger, opts ...client.ClientOption) error {
+    ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+    defer stop()
+
+    c := client.NewClient(serverURL, logger, opts...)
 
     // Set up the HTTP server.
-    http.HandleFunc("/status", c.HandleStatusRequest)
+    mux := http.NewServeMux()
+    mux.HandleFunc("/status", c.HandleStatusRequest)
+    mux.HandleFunc("/debug/snapshot", c.HandleSnapshotRequest)
+    mux.HandleFunc("/debug/stats", c.HandleStatsRequest)
+    srv := &http.Server{Handler: mux}
+
+    errCh := make(chan error, 1)
+    go func() {
+        logger.Printf("Client server is starting on %s", ln.Addr())
+        errCh <- srv.Serve(ln)
+    }()
+
+    select {
+    case err := <-errCh:
+        return err
+    case <-ctx.Done():
+    }
+
+    logger.Printf("Shutdown signal received, waiting up to %v for in-flight requests", shutdownTimeout)
+    shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+    defer cancel()
+    err := srv.Shutdown(shutdownCtx)
+
+    status, pending := c.Status()
+    logger.Printf("Shutdown summary: last status %q, polling still pending: %v", status, pending)
 
-    serverAddress := ":9090"
-    logger.Printf("Client server is starting on %s", serverAddress)
-    if err := http.ListenAndServe(serverAddress, nil); err != nil {
-        logger.Fatalf("Client server failed to start: %v", err)
+    if errors.Is(err, context.DeadlineExceeded) {
+        return errors.New("shutdown timed out before in-flight requests finished")
     }
+    return err
 }
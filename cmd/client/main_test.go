@@ -0,0 +1,55 @@
+//go:build unix
+
+package main
+
+import (
+    "io/ioutil"
+    "log"
+    "net"
+    "net/http"
+    "syscall"
+    "testing"
+    "time"
+)
+
+func TestRunShutsDownOnSIGTERM(t *testing.T) {
+    ln, err := net.Listen("tcp", "127.0.0.1:0")
+    if err != nil {
+        t.Fatalf("listening: %v", err)
+    }
+    logger := log.New(ioutil.Discard, "", 0)
+
+    done := make(chan error, 1)
+    go func() {
+        done <- run(ln, time.Second, logger)
+    }()
+
+    // Wait until the server answers requests, the signal handler is
+    // registered by then. Connecting alone isn't enough, the listener
+    // accepts connections before run starts serving.
+    deadline := time.Now().Add(2 * time.Second)
+    for {
+        resp, err := http.Get("http://" + ln.Addr().String() + "/debug/stats")
+        if err == nil {
+            resp.Body.Close()
+            break
+        }
+        if time.Now().After(deadline) {
+            t.Fatalf("client server never started: %v", err)
+        }
+        time.Sleep(10 * time.Millisecond)
+    }
+
+    if err := syscall.Kill(syscall.Getpid(), syscall.SIGTERM); err != nil {
+        t.Fatalf("sending SIGTERM: %v", err)
+    }
+
+    select {
+    case err := <-done:
+        if err != nil {
+            t.Fatalf("expected clean shutdown, got %v", err)
+        }
+    case <-time.After(3 * time.Second):
+        t.Fatal("client server did not shut down after SIGTERM")
+    }
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+    "context"
+    "io/ioutil"
+    "log"
+    "net/http"
+    "net/http/httptest"
+    "testing"
+    "time"
+
+    "Video-Translation-Simulator/pkg/client"
+    "Video-Translation-Simulator/pkg/testutil"
+)
+
+// recordingNotifier records the notifications it is asked to send.
+type recordingNotifier struct {
+    messages []string
+}
+
+func (n *recordingNotifier) Notify(title, message string) error {
+    n.messages = append(n.messages, title+": "+message)
+    return nil
+}
+
+// noBackoff polls again straight away.
+type noBackoff struct{}
+
+func (noBackoff) NextDelay(context.Context, time.Duration) time.Duration { return 0 }
+
+func TestNotifyOnFinalStatus(t *testing.T) {
+    srv := testutil.NewDryRunServer()
+    defer srv.Close()
+    srv.QueueStatus("", "pending", "completed")
+
+    logger := log.New(ioutil.Discard, "", 0)
+    n := &recordingNotifier{}
+    c := client.NewClient(srv.URL, logger,
+        client.WithOnFinal(notifyFinal(n, logger)),
+        client.WithBackoff(noBackoff{}))
+
+    poll := func() {
+        c.HandleStatusRequest(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/status", nil))
+    }
+    poll()
+    if len(n.messages) != 0 {
+        t.Fatalf("notified before the job finished: %q", n.messages)
+    }
+    poll()
+    if len(n.messages) != 1 || n.messages[0] != "Video translation: Job finished with status: completed" {
+        t.Fatalf("expected one completion notification, got %q", n.messages)
+    }
+}
@@ -1,32 +1,130 @@
 package main
 
 import (
+    "context"
 		"flag"
     "log"
+    "log/slog"
+    "net"
+    "os"
+    "strconv"
+    "strings"
+    "time"
+    "Video-Translation-Simulator/pkg/config"
     "Video-Translation-Simulator/pkg/server"
 )
 
 /*
 	Main Function is used as the entry point to start the server
 	it is kept minimal since we are assuming a simple server with a configurable response time
-	You can configure the response time using the file config.json at the root directory of this project
+	You can configure the response time with flags, or with a JSON file passed via --config
+	(see pkg/config/configs/default.json for the defaults). Flags take precedence over the file.
 
 	Please make sure port 8080 is not already bound to another process. 
 */
 
 func main() {
+	configPath := flag.String("config", "", "JSON config file, merged over the built-in defaults")
 	delay := flag.Int("delay", 10, "Delay before returning final status (in seconds)")
 	errorRate := flag.Int("error", 20, "Probability of returning 'error' instead of 'completed' (0-100)")
+	enablePPROF := flag.Bool("pprof", false, "Serve net/http/pprof endpoints on the debug address")
+	slowLorisDefense := flag.Duration("slow-loris-defense", 0, "Close connections that take longer than this to send request headers (0 disables)")
+	apiKey := flag.String("api-key", os.Getenv("SERVER_API_KEY"), "Key required by the /admin/ endpoints, which are disabled when empty (defaults to $SERVER_API_KEY)")
+	blocklist := flag.String("blocklist", "", "File of CIDR ranges to reject with 403, one per line, reloaded every minute and on SIGHUP")
+	debugAddr := flag.String("debug-addr", server.DefaultDebugAddr, "Address for the pprof endpoints (keep on 127.0.0.1 unless the network is trusted)")
+	grpcAddr := flag.String("grpc-addr", "", "Address to also serve the gRPC translation service on (disabled when empty)")
+	trustedProxies := flag.String("trusted-proxies", "", "Comma separated IPs of proxies whose X-Forwarded-For and X-Real-IP headers are believed")
 
 	// Parse the flags
 	flag.Parse()
 
+	// Flags given on the command line win over the config file, also when it is reloaded.
+	applyFlags := func(cfg *config.Config) {
+			flag.Visit(func(f *flag.Flag) {
+					switch f.Name {
+					case "delay":
+							cfg.DelaySeconds = *delay
+					case "error":
+							cfg.ErrorRate = *errorRate
+					case "debug-addr":
+							cfg.DebugAddr = *debugAddr
+					}
+			})
+	}
+
+	cfg, err := config.LoadFile(*configPath)
+	if err != nil {
+			log.Fatalf("Failed to load config: %v", err)
+	}
+	applyFlags(cfg)
+
+	// Check the port is free up front, so a clash is reported before anything else starts.
+	var preFlights []server.PreFlightCheck
+	if _, port, err := net.SplitHostPort(cfg.Addr); err == nil {
+			if n, err := strconv.Atoi(port); err == nil && n > 0 {
+					preFlights = append(preFlights, server.PortAvailableCheck{Port: n})
+			}
+	}
+
+	// The log level can be changed by reloading the config file.
+	logLevel := new(slog.LevelVar)
+	logLevel.Set(cfg.Level())
+
+	opts := []server.ServerOption{
+			server.WithLogger(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: logLevel}))),
+			server.WithConfig(&server.Config{DelaySeconds: cfg.DelaySeconds, ErrorRate: cfg.ErrorRate}),
+			server.WithPPROF(*enablePPROF),
+			server.WithDebugAddr(cfg.DebugAddr),
+			server.WithReadHeaderTimeout(*slowLorisDefense),
+			server.WithAPIKey(*apiKey),
+			server.WithPreFlightChecks(preFlights...),
+	}
+	if *blocklist != "" {
+			opts = append(opts, server.WithBlocklist(*blocklist))
+	}
+	if *trustedProxies != "" {
+			var proxies []net.IP
+			for _, p := range strings.Split(*trustedProxies, ",") {
+					ip := net.ParseIP(strings.TrimSpace(p))
+					if ip == nil {
+							log.Fatalf("Invalid trusted proxy %q", p)
+					}
+					proxies = append(proxies, ip)
+			}
+			opts = append(opts, server.WithTrustedProxies(proxies...))
+	}
+
 	// Initialize and start the server with the parsed values
-	srv, err := server.NewServer(*delay, *errorRate)
+	srv, err := server.NewServer(opts...)
 	if err != nil {
 			log.Fatalf("Failed to initialize server: %v", err)
 	}
-	if err := srv.Start(":8080"); err != nil {
+
+	// Pick up changes to the delay and error rate without a restart.
+	if *configPath != "" {
+			watcher, err := config.NewConfigWatcher(*configPath, 2*time.Second, func(c *config.Config) error {
+					applyFlags(c)
+					if err := srv.ApplyConfig(c.DelaySeconds, c.ErrorRate); err != nil {
+							return err
+					}
+					logLevel.Set(c.Level())
+					return nil
+			})
+			if err != nil {
+					log.Fatalf("Failed to watch config: %v", err)
+			}
+			watcher.Start(context.Background())
+	}
+
+	if *grpcAddr != "" {
+			go func() {
+					if err := srv.StartGRPC(*grpcAddr); err != nil {
+							log.Fatalf("gRPC server failed to start: %v", err)
+					}
+			}()
+	}
+
+	if err := srv.Start(cfg.Addr); err != nil {
 			log.Fatalf("Server failed to start: %v", err)
 	}
 }
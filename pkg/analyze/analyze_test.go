@@ -0,0 +1,65 @@
+package analyze
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseSkipsUnrelatedLines(t *testing.T) {
+	log := strings.Join([]string{
+		`{"attempt":1,"next_delay":"500ms"}`,
+		`INFO: plain text line`,
+		`{"attempt":2,"status":"pending"}`,
+		`{"attempt":3,"next_delay":1500}`,
+		`{"next_delay":"2s"}`,
+	}, "\n")
+
+	result, err := Parse(strings.NewReader(log))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	want := []Sample{
+		{Attempt: 1, NextDelay: 500 * time.Millisecond},
+		{Attempt: 3, NextDelay: 1500 * time.Millisecond},
+		{Attempt: 4, NextDelay: 2 * time.Second},
+	}
+	if len(result.Samples) != len(want) || result.Skipped != 2 {
+		t.Fatalf("expected %d samples and 2 skipped, got %+v", len(want), result)
+	}
+	for i := range want {
+		if result.Samples[i] != want[i] {
+			t.Fatalf("sample %d: expected %+v, got %+v", i, want[i], result.Samples[i])
+		}
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	var samples []Sample
+	for i := 1; i <= 100; i++ {
+		samples = append(samples, Sample{Attempt: i, NextDelay: time.Duration(i) * time.Millisecond})
+	}
+	s := Summarize(samples)
+
+	if s.Count != 100 || s.Min != time.Millisecond || s.Max != 100*time.Millisecond {
+		t.Fatalf("unexpected count/min/max: %+v", s)
+	}
+	if s.Mean != 50500*time.Microsecond {
+		t.Fatalf("expected mean 50.5ms, got %v", s.Mean)
+	}
+	if s.P50 != 50*time.Millisecond || s.P95 != 95*time.Millisecond || s.P99 != 99*time.Millisecond {
+		t.Fatalf("unexpected percentiles: %+v", s)
+	}
+	// Standard deviation of 1..100 is sqrt(833.25) ~= 28.866ms.
+	if s.StdDev.Round(time.Microsecond) != 28866*time.Microsecond {
+		t.Fatalf("unexpected stddev %v", s.StdDev)
+	}
+}
+
+func TestHistogramCountsEverySample(t *testing.T) {
+	samples := []Sample{{NextDelay: 0}, {NextDelay: 5}, {NextDelay: 9}, {NextDelay: 10}}
+	buckets := Histogram(samples, 2)
+	if len(buckets) != 2 || buckets[0].Count != 1 || buckets[1].Count != 3 {
+		t.Fatalf("unexpected buckets %+v", buckets)
+	}
+}
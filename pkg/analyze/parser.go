@@ -0,0 +1,85 @@
+package analyze
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+/*
+	The analyze package reads structured client logs and summarises the backoff
+	delays found in them. Parsing and statistics live here, rendering is left to
+	the caller (see cmd/analyze) so the numbers can be reused elsewhere.
+
+	Each log line is expected to be a JSON object, such as those written by a
+	client given a slog.JSONHandler with SetSlogLogger. Lines carrying a
+	"next_delay" field are turned into a Sample, everything else is skipped so
+	mixed logs can be fed in as they are.
+*/
+
+// Sample is a single backoff delay logged by the client.
+type Sample struct {
+	Attempt   int
+	NextDelay time.Duration
+}
+
+// ParseResult holds the samples found in a log along with how many lines were skipped.
+type ParseResult struct {
+	Samples []Sample
+	Skipped int
+}
+
+// logLine is the subset of a client log line the parser cares about.
+type logLine struct {
+	Attempt   int             `json:"attempt"`
+	NextDelay json.RawMessage `json:"next_delay"`
+}
+
+// Parse reads JSON log lines from r and returns every next_delay sample in the
+// order they appear. A next_delay may be a duration string ("1.5s") or a number
+// of milliseconds. Samples without an attempt number follow on from the one
+// before.
+func Parse(r io.Reader) (*ParseResult, error) {
+	result := &ParseResult{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		var line logLine
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil || len(line.NextDelay) == 0 {
+			result.Skipped++
+			continue
+		}
+		delay, err := parseDelay(line.NextDelay)
+		if err != nil {
+			result.Skipped++
+			continue
+		}
+		if line.Attempt == 0 {
+			line.Attempt = 1
+			if n := len(result.Samples); n > 0 {
+				line.Attempt = result.Samples[n-1].Attempt + 1
+			}
+		}
+		result.Samples = append(result.Samples, Sample{Attempt: line.Attempt, NextDelay: delay})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading log: %w", err)
+	}
+	return result, nil
+}
+
+// parseDelay accepts either a Go duration string or a number of milliseconds.
+func parseDelay(raw json.RawMessage) (time.Duration, error) {
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return time.ParseDuration(text)
+	}
+	var ms float64
+	if err := json.Unmarshal(raw, &ms); err != nil {
+		return 0, fmt.Errorf("next_delay %s is neither a duration nor a number", raw)
+	}
+	return time.Duration(ms * float64(time.Millisecond)), nil
+}
@@ -0,0 +1,123 @@
+package analyze
+
+import (
+	"math/big"
+	"sort"
+	"time"
+)
+
+// Summary holds descriptive statistics of a set of delays.
+type Summary struct {
+	Count  int
+	Min    time.Duration
+	Max    time.Duration
+	Mean   time.Duration
+	StdDev time.Duration
+	P50    time.Duration
+	P95    time.Duration
+	P99    time.Duration
+}
+
+// Bucket is one bar of a delay histogram, covering [Lower, Upper).
+type Bucket struct {
+	Lower time.Duration
+	Upper time.Duration
+	Count int
+}
+
+// statsPrecision is the mantissa size used for the big.Float maths.
+const statsPrecision = 256
+
+// Summarize computes the statistics of the given samples. Sums are kept in
+// math/big so long logs do not lose precision or overflow.
+func Summarize(samples []Sample) Summary {
+	if len(samples) == 0 {
+		return Summary{}
+	}
+	sorted := sortedDelays(samples)
+	n := int64(len(sorted))
+
+	sum := new(big.Int)
+	for _, d := range sorted {
+		sum.Add(sum, big.NewInt(int64(d)))
+	}
+	mean := new(big.Rat).SetFrac(sum, big.NewInt(n))
+
+	// Population variance: sum((x - mean)^2) / n, kept exact as a rational.
+	variance := new(big.Rat)
+	for _, d := range sorted {
+		diff := new(big.Rat).Sub(new(big.Rat).SetInt64(int64(d)), mean)
+		variance.Add(variance, diff.Mul(diff, diff))
+	}
+	variance.Quo(variance, new(big.Rat).SetInt64(n))
+	stddev := new(big.Float).SetPrec(statsPrecision).SetRat(variance)
+	stddev.Sqrt(stddev)
+
+	return Summary{
+		Count:  len(sorted),
+		Min:    sorted[0],
+		Max:    sorted[len(sorted)-1],
+		Mean:   ratToDuration(mean),
+		StdDev: floatToDuration(stddev),
+		P50:    percentile(sorted, 50),
+		P95:    percentile(sorted, 95),
+		P99:    percentile(sorted, 99),
+	}
+}
+
+// Histogram splits the range of delays into n equally sized buckets.
+func Histogram(samples []Sample, n int) []Bucket {
+	if len(samples) == 0 || n <= 0 {
+		return nil
+	}
+	sorted := sortedDelays(samples)
+	lo, hi := sorted[0], sorted[len(sorted)-1]
+	width := (hi - lo) / time.Duration(n)
+	if width <= 0 {
+		return []Bucket{{Lower: lo, Upper: hi + 1, Count: len(sorted)}}
+	}
+
+	buckets := make([]Bucket, n)
+	for i := range buckets {
+		buckets[i].Lower = lo + time.Duration(i)*width
+		buckets[i].Upper = buckets[i].Lower + width
+	}
+	// The last bucket also takes the maximum and any rounding leftovers.
+	buckets[n-1].Upper = hi + 1
+	for _, d := range sorted {
+		i := int((d - lo) / width)
+		if i >= n {
+			i = n - 1
+		}
+		buckets[i].Count++
+	}
+	return buckets
+}
+
+func sortedDelays(samples []Sample) []time.Duration {
+	delays := make([]time.Duration, len(samples))
+	for i, s := range samples {
+		delays[i] = s.NextDelay
+	}
+	sort.Slice(delays, func(i, j int) bool { return delays[i] < delays[j] })
+	return delays
+}
+
+// percentile returns the nearest-rank p-th percentile of sorted delays.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+func ratToDuration(r *big.Rat) time.Duration {
+	f, _ := r.Float64()
+	return time.Duration(f)
+}
+
+func floatToDuration(f *big.Float) time.Duration {
+	v, _ := f.Float64()
+	return time.Duration(v)
+}
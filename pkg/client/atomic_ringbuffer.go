@@ -0,0 +1,67 @@
+package client
+
+import (
+    "sort"
+    "sync/atomic"
+)
+
+// AtomicRingBuffer is a lock-free alternative to RingBuffer. Writers claim a
+// slot by atomically incrementing the head index and then publish their item
+// into it, so a stalled writer never blocks the others. Each push allocates,
+// so compare both with the benchmarks before switching.
+//
+// Snapshot is best effort under concurrent pushes: an item whose slot has been
+// claimed but not yet written is left out, and an item overwritten while the
+// snapshot is taken is replaced by its newer value. Items are always returned
+// in push order.
+type AtomicRingBuffer[T any] struct {
+    head  atomic.Uint64
+    slots []atomic.Pointer[ringEntry[T]]
+}
+
+// ringEntry pairs an item with its position in the overall push order, so a
+// reader can tell which lap of the buffer a slot belongs to.
+type ringEntry[T any] struct {
+    seq  uint64
+    item T
+}
+
+// NewAtomicRingBuffer returns an empty AtomicRingBuffer holding up to capacity items.
+func NewAtomicRingBuffer[T any](capacity int) *AtomicRingBuffer[T] {
+    if capacity <= 0 {
+        capacity = 1
+    }
+    return &AtomicRingBuffer[T]{slots: make([]atomic.Pointer[ringEntry[T]], capacity)}
+}
+
+// Push adds an item, overwriting the oldest one if the buffer is full.
+func (b *AtomicRingBuffer[T]) Push(item T) {
+    seq := b.head.Add(1) - 1
+    b.slots[seq%uint64(len(b.slots))].Store(&ringEntry[T]{seq: seq, item: item})
+}
+
+// Snapshot returns a copy of the buffered items, oldest first.
+func (b *AtomicRingBuffer[T]) Snapshot() []T {
+    head := b.head.Load()
+    size := uint64(len(b.slots))
+    var oldest uint64
+    if head > size {
+        oldest = head - size
+    }
+
+    entries := make([]*ringEntry[T], 0, size)
+    for i := range b.slots {
+        e := b.slots[i].Load()
+        // Skip empty slots and ones from before the window we are reading.
+        if e != nil && e.seq >= oldest {
+            entries = append(entries, e)
+        }
+    }
+    sort.Slice(entries, func(i, j int) bool { return entries[i].seq < entries[j].seq })
+
+    items := make([]T, len(entries))
+    for i, e := range entries {
+        items[i] = e.item
+    }
+    return items
+}
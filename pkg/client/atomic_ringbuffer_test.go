@@ -0,0 +1,91 @@
+package client
+
+import (
+    "reflect"
+    "sync"
+    "testing"
+)
+
+func TestAtomicRingBufferWraparound(t *testing.T) {
+    b := NewAtomicRingBuffer[int](3)
+    b.Push(1)
+    b.Push(2)
+    if got := b.Snapshot(); !reflect.DeepEqual(got, []int{1, 2}) {
+        t.Fatalf("expected [1 2], got %v", got)
+    }
+    for i := 3; i <= 10; i++ {
+        b.Push(i)
+    }
+    if got := b.Snapshot(); !reflect.DeepEqual(got, []int{8, 9, 10}) {
+        t.Fatalf("expected [8 9 10], got %v", got)
+    }
+}
+
+// Run with -race to check the buffer is safe under concurrent writers and a reader.
+func TestAtomicRingBufferConcurrentPush(t *testing.T) {
+    const writers, perWriter, capacity = 10, 1000, 64
+    b := NewAtomicRingBuffer[int](capacity)
+
+    stop := make(chan struct{})
+    readerDone := make(chan struct{})
+    go func() {
+        defer close(readerDone)
+        for {
+            select {
+            case <-stop:
+                return
+            default:
+            }
+            if snap := b.Snapshot(); len(snap) > capacity {
+                t.Errorf("snapshot holds %d items, capacity is %d", len(snap), capacity)
+                return
+            }
+        }
+    }()
+
+    var wg sync.WaitGroup
+    for w := 0; w < writers; w++ {
+        wg.Add(1)
+        go func(w int) {
+            defer wg.Done()
+            for i := 0; i < perWriter; i++ {
+                b.Push(w*perWriter + i)
+            }
+        }(w)
+    }
+    wg.Wait()
+    close(stop)
+    <-readerDone
+
+    snap := b.Snapshot()
+    if len(snap) != capacity {
+        t.Fatalf("expected a full buffer of %d, got %d", capacity, len(snap))
+    }
+    // Each writer's items must still appear in the order it pushed them.
+    last := make(map[int]int)
+    for _, v := range snap {
+        w := v / perWriter
+        if prev, ok := last[w]; ok && v <= prev {
+            t.Fatalf("writer %d items out of order: %d after %d", w, v, prev)
+        }
+        last[w] = v
+    }
+}
+
+func BenchmarkRingBufferConcurrentPush(b *testing.B) {
+    buf := NewRingBuffer[RequestRecord](historySize)
+    b.RunParallel(func(pb *testing.PB) {
+        for pb.Next() {
+            buf.Push(RequestRecord{Attempt: 1})
+        }
+    })
+}
+
+func BenchmarkAtomicRingBufferConcurrentPush(b *testing.B) {
+    buf := NewAtomicRingBuffer[RequestRecord](historySize)
+    b.RunParallel(func(pb *testing.PB) {
+        for pb.Next() {
+            buf.Push(RequestRecord{Attempt: 1})
+        }
+    })
+}
@@ -0,0 +1,57 @@
+package client
+
+import (
+    "context"
+    "time"
+)
+
+// BackoffStrategy decides how long to wait before the next poll, given the
+// previous delay (zero before the first backoff).
+type BackoffStrategy interface {
+    NextDelay(ctx context.Context, current time.Duration) time.Duration
+}
+
+// exponentialBackoff is the client's own exponential backoff with jitter.
+type exponentialBackoff struct {
+    c *Client
+}
+
+func (b exponentialBackoff) NextDelay(_ context.Context, current time.Duration) time.Duration {
+    return b.c.nextDelay(current)
+}
+
+// DefaultDeadlineBuffer is the safety margin DeadlineAwareBackoff keeps before a deadline.
+const DefaultDeadlineBuffer = 500 * time.Millisecond
+
+// DeadlineAwareBackoff wraps another strategy and shrinks its delay so the next
+// poll still happens before the context's deadline. Without it, a context with
+// 10s left and a computed backoff of 8s would likely miss the deadline on the
+// following request. Delays are capped to deadline - now - Buffer. Once the
+// deadline is within Buffer the delay is 0, polling once more straight away;
+// the context then ends the caller's wait when the deadline passes.
+type DeadlineAwareBackoff struct {
+    Base   BackoffStrategy
+    Buffer time.Duration
+}
+
+// NewDeadlineAwareBackoff wraps base with the default safety margin.
+func NewDeadlineAwareBackoff(base BackoffStrategy) *DeadlineAwareBackoff {
+    return &DeadlineAwareBackoff{Base: base, Buffer: DefaultDeadlineBuffer}
+}
+
+// NextDelay implements BackoffStrategy.
+func (b *DeadlineAwareBackoff) NextDelay(ctx context.Context, current time.Duration) time.Duration {
+    delay := b.Base.NextDelay(ctx, current)
+    deadline, ok := ctx.Deadline()
+    if !ok {
+        return delay
+    }
+    remaining := time.Until(deadline) - b.Buffer
+    if remaining <= 0 {
+        return 0
+    }
+    if delay > remaining {
+        return remaining
+    }
+    return delay
+}
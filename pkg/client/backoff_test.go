@@ -0,0 +1,70 @@
+package client
+
+import (
+    "context"
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "sync"
+    "testing"
+    "time"
+)
+
+// constantBackoff always waits the same amount.
+type constantBackoff time.Duration
+
+func (b constantBackoff) NextDelay(context.Context, time.Duration) time.Duration {
+    return time.Duration(b)
+}
+
+func TestDeadlineAwareBackoffCapsDelay(t *testing.T) {
+    b := NewDeadlineAwareBackoff(constantBackoff(5 * time.Second))
+
+    if d := b.NextDelay(context.Background(), 0); d != 5*time.Second {
+        t.Fatalf("expected the base delay without a deadline, got %v", d)
+    }
+
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+    if d := b.NextDelay(ctx, 0); d != 5*time.Second {
+        t.Fatalf("expected the base delay well before the deadline, got %v", d)
+    }
+
+    ctx, cancel = context.WithTimeout(context.Background(), 3*time.Second)
+    defer cancel()
+    if d := b.NextDelay(ctx, 0); d > 2500*time.Millisecond || d < 2400*time.Millisecond {
+        t.Fatalf("expected ~2.5s before a 3s deadline, got %v", d)
+    }
+
+    ctx, cancel = context.WithTimeout(context.Background(), 400*time.Millisecond)
+    defer cancel()
+    if d := b.NextDelay(ctx, 0); d != 0 {
+        t.Fatalf("expected no delay within the buffer, got %v", d)
+    }
+}
+
+func TestWaitForCompletionSleepIsCappedByDeadline(t *testing.T) {
+    var mu sync.Mutex
+    var requests []time.Time
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        mu.Lock()
+        requests = append(requests, time.Now())
+        mu.Unlock()
+        json.NewEncoder(w).Encode(map[string]string{"result": "pending"})
+    }))
+    defer srv.Close()
+
+    c := newTestClient(srv.URL, WithBackoff(NewDeadlineAwareBackoff(constantBackoff(5*time.Second))))
+    ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+    defer cancel()
+    c.WaitForCompletion(ctx)
+
+    mu.Lock()
+    defer mu.Unlock()
+    if len(requests) < 2 {
+        t.Fatalf("expected a second poll before the deadline, got %d requests", len(requests))
+    }
+    if slept := requests[1].Sub(requests[0]); slept < 2300*time.Millisecond || slept > 2700*time.Millisecond {
+        t.Fatalf("expected to sleep ~2.5s, slept %v", slept)
+    }
+}
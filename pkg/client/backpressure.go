@@ -0,0 +1,105 @@
+package client
+
+import (
+    "context"
+    "errors"
+    "time"
+
+    "Video-Translation-Simulator/pkg/types"
+)
+
+// DefaultBackpressureCheckInterval is how often a blocked caller checks for
+// capacity when BackpressureClient.CheckInterval is not positive.
+const DefaultBackpressureCheckInterval = time.Second
+
+// BackpressureClient waits for jobs like Client.WaitForCompletion, but treats
+// an overloaded server as a reason to hold the caller rather than to fail.
+// While the client's circuit is open, or the server's rate limit is used up,
+// it blocks until capacity is back or ctx is done, and such waits don't count
+// towards the retry policy's MaxAttempts. It suits batch pipelines which must
+// not drop jobs.
+type BackpressureClient struct {
+    Client        *Client
+    CheckInterval time.Duration // How often to look again while the circuit is open.
+}
+
+// NewBackpressureClient returns a BackpressureClient polling through c.
+func NewBackpressureClient(c *Client) *BackpressureClient {
+    return &BackpressureClient{Client: c, CheckInterval: DefaultBackpressureCheckInterval}
+}
+
+// WaitWithBackpressure polls the job created with jobID on POST /jobs, or the
+// implicit /status job when jobID is empty, until it reaches a final status.
+// Other failures are retried with the client's backoff and retry policy.
+func (b *BackpressureClient) WaitWithBackpressure(ctx context.Context, jobID string) (string, error) {
+    c := b.Client
+    path := jobStatusPath(jobID)
+    transitions := c.newStatusTracker(jobID)
+    var delay time.Duration
+    failures := 0
+    for {
+        if err := b.waitForCapacity(ctx); err != nil {
+            return "", err
+        }
+        status, err := c.retrieveStatusAt(ctx, c.serverURL(), path, transitions)
+        var wait time.Duration
+        switch {
+        case errors.Is(err, ErrCircuitOpen):
+            // Opened since the check, so wait for it again.
+            continue
+        case errors.Is(err, ErrRateLimited):
+            // Servers sending X-RateLimit headers are waited for above.
+            c.logf("Backpressure: Rate limited by the server, waiting")
+            wait = b.checkInterval()
+        case errors.Is(err, types.ErrInvalidTransition) || errors.Is(err, ErrResponseTooLarge):
+            return "", err
+        case err != nil:
+            failures++
+            c.logf("Backpressure: Error fetching status: %v", err)
+            if failures >= c.retry.MaxAttempts {
+                return "", errors.New("max retries reached")
+            }
+            delay = c.backoff.NextDelay(ctx, delay)
+            wait = delay
+        case types.Status(status).IsTerminal():
+            return status, nil
+        default:
+            delay = c.backoff.NextDelay(ctx, delay)
+            wait = delay
+        }
+
+        select {
+        case <-time.After(wait):
+        case <-ctx.Done():
+            return "", ctx.Err()
+        }
+    }
+}
+
+// waitForCapacity blocks while the client's circuit is open or the server's
+// rate limit is used up, returning ctx.Err() if ctx is done first.
+func (b *BackpressureClient) waitForCapacity(ctx context.Context) error {
+    for {
+        var wait time.Duration
+        if h := b.Client.circuit.Load(); h != nil && !h.IsHealthy() {
+            wait = b.checkInterval()
+        } else if w, limited := b.Client.rateLimitWait(); limited {
+            wait = w
+        } else {
+            return nil
+        }
+        select {
+        case <-time.After(wait):
+        case <-ctx.Done():
+            return ctx.Err()
+        }
+    }
+}
+
+// checkInterval returns CheckInterval, or the default if it is not positive.
+func (b *BackpressureClient) checkInterval() time.Duration {
+    if b.CheckInterval <= 0 {
+        return DefaultBackpressureCheckInterval
+    }
+    return b.CheckInterval
+}
@@ -0,0 +1,88 @@
+package client
+
+import (
+    "context"
+    "errors"
+    "net/http"
+    "net/http/httptest"
+    "strconv"
+    "sync/atomic"
+    "testing"
+    "time"
+
+    "Video-Translation-Simulator/pkg/testutil"
+)
+
+// openCircuit makes c's circuit report the server as unhealthy until the
+// returned function is called.
+func openCircuit(c *Client) (closeCircuit func()) {
+    h := NewHealthChecker(c, time.Hour)
+    h.healthy = false
+    c.circuit.Store(h)
+    return func() {
+        h.mu.Lock()
+        h.healthy = true
+        h.mu.Unlock()
+    }
+}
+
+func TestBackpressureCancelledWhileCircuitOpen(t *testing.T) {
+    srv := testutil.NewDryRunServer()
+    defer srv.Close()
+    c := newTestClient(srv.URL)
+    openCircuit(c)
+
+    b := NewBackpressureClient(c)
+    b.CheckInterval = 10 * time.Millisecond
+    ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+    defer cancel()
+    start := time.Now()
+    if _, err := b.WaitWithBackpressure(ctx, "abc"); !errors.Is(err, context.DeadlineExceeded) {
+        t.Fatalf("expected the wait to end with the context, got %v", err)
+    }
+    if elapsed := time.Since(start); elapsed > time.Second {
+        t.Fatalf("expected the wait to stop with the context, took %v", elapsed)
+    }
+    if n := srv.Requests("abc"); n != 0 {
+        t.Fatalf("expected no requests while the circuit is open, got %d", n)
+    }
+}
+
+func TestBackpressureResumesWhenCircuitCloses(t *testing.T) {
+    srv := testutil.NewDryRunServer()
+    defer srv.Close()
+    srv.QueueStatus("abc", "pending", "completed")
+    c := newTestClient(srv.URL, WithBackoff(constantBackoff(0)))
+    closeCircuit := openCircuit(c)
+    time.AfterFunc(50*time.Millisecond, closeCircuit)
+
+    b := NewBackpressureClient(c)
+    b.CheckInterval = 10 * time.Millisecond
+    status, err := b.WaitWithBackpressure(context.Background(), "abc")
+    if err != nil || status != "completed" {
+        t.Fatalf("expected completed once the circuit closed, got %q, %v", status, err)
+    }
+}
+
+func TestBackpressureCancelledWhileRateLimited(t *testing.T) {
+    var requests atomic.Int32
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        requests.Add(1)
+        w.Header().Set("X-RateLimit-Limit", "1")
+        w.Header().Set("X-RateLimit-Remaining", "0")
+        w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+        http.Error(w, "too many requests", http.StatusTooManyRequests)
+    }))
+    defer srv.Close()
+
+    // Many attempts would otherwise be spent retrying the 429.
+    c := newTestClient(srv.URL, WithRetryPolicy(NewRetryPolicy().WithMaxAttempts(1)))
+    ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+    defer cancel()
+    if _, err := NewBackpressureClient(c).WaitWithBackpressure(ctx, ""); !errors.Is(err, context.DeadlineExceeded) {
+        t.Fatalf("expected the wait to end with the context, got %v", err)
+    }
+    if n := requests.Load(); n != 1 {
+        t.Fatalf("expected a single request before waiting for the reset, got %d", n)
+    }
+}
@@ -0,0 +1,50 @@
+package client
+
+import (
+    "errors"
+    "time"
+)
+
+// ErrBudgetExhausted is returned by WaitForCompletion when the time budget set
+// with WithTimeBudget runs out before a final status is received.
+var ErrBudgetExhausted = errors.New("time budget exhausted")
+
+// TimeBudget is a total time allowance which is spent explicitly, by both the
+// requests made and the backoff sleeps between them. Unlike comparing against
+// a start time, this only counts time the caller chose to spend. It is not
+// safe for concurrent use.
+type TimeBudget struct {
+    remaining time.Duration
+}
+
+// NewTimeBudget returns a budget with total left to spend.
+func NewTimeBudget(total time.Duration) *TimeBudget {
+    return &TimeBudget{remaining: total}
+}
+
+// Spend deducts d from the budget.
+func (b *TimeBudget) Spend(d time.Duration) {
+    b.remaining -= d
+}
+
+// Remaining returns how much of the budget is left, never less than zero.
+func (b *TimeBudget) Remaining() time.Duration {
+    if b.remaining < 0 {
+        return 0
+    }
+    return b.remaining
+}
+
+// Allows reports whether d can be spent without going over the budget.
+func (b *TimeBudget) Allows(d time.Duration) bool {
+    return d <= b.remaining
+}
+
+// WithTimeBudget limits the total time WaitForCompletion spends on requests
+// and backoff sleeps. It gives up with ErrBudgetExhausted rather than start a
+// sleep the budget can't cover. Zero, the default, means no budget.
+func WithTimeBudget(total time.Duration) ClientOption {
+    return func(c *Client) {
+        c.timeBudget = total
+    }
+}
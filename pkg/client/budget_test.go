@@ -0,0 +1,42 @@
+package client
+
+import (
+    "context"
+    "errors"
+    "testing"
+    "time"
+
+    "Video-Translation-Simulator/pkg/testutil"
+)
+
+func TestTimeBudgetCountsBackoffSleeps(t *testing.T) {
+    srv := testutil.NewDryRunServer()
+    defer srv.Close()
+    srv.QueueStatus("", "pending")
+
+    // Requests are near instant, so only the sleeps use up the budget.
+    c := newTestClient(srv.URL, WithBackoff(constantBackoff(time.Second)), WithTimeBudget(3*time.Second))
+    start := time.Now()
+    _, err := c.WaitForCompletion(context.Background())
+    if !errors.Is(err, ErrBudgetExhausted) {
+        t.Fatalf("expected ErrBudgetExhausted, got %v", err)
+    }
+    if n := srv.Requests(""); n > 3 {
+        t.Fatalf("expected at most 3 attempts within a 3s budget of 1s sleeps, got %d", n)
+    }
+    if elapsed := time.Since(start); elapsed > 3*time.Second {
+        t.Fatalf("kept going for %v on a 3s budget", elapsed)
+    }
+}
+
+func TestTimeBudget(t *testing.T) {
+    b := NewTimeBudget(time.Second)
+    b.Spend(400 * time.Millisecond)
+    if b.Remaining() != 600*time.Millisecond || !b.Allows(600*time.Millisecond) || b.Allows(601*time.Millisecond) {
+        t.Fatalf("unexpected budget after spending 400ms: %v left", b.Remaining())
+    }
+    b.Spend(time.Second)
+    if b.Remaining() != 0 || b.Allows(time.Nanosecond) {
+        t.Fatalf("expected an overspent budget to have nothing left, got %v", b.Remaining())
+    }
+}
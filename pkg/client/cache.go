@@ -0,0 +1,120 @@
+package client
+
+import (
+    "sync"
+    "time"
+
+    "Video-Translation-Simulator/pkg/types"
+)
+
+// DefaultResultTTL is how long a TTLCache keeps results by default.
+const DefaultResultTTL = 5 * time.Minute
+
+// ResultCache stores final job statuses, which never change once reached, so
+// the client can answer from it instead of asking the server again. A job
+// retried with RetryJob starts over, so caches which also have a
+// Delete(jobID string) method, like TTLCache, forget it then.
+type ResultCache interface {
+    Get(jobID string) (types.Status, bool)
+    Set(jobID string, s types.Status)
+}
+
+// TTLCache is a ResultCache whose entries expire after a fixed time. It is
+// safe for concurrent use.
+type TTLCache struct {
+    ttl time.Duration
+
+    mu      sync.Mutex
+    entries map[string]ttlEntry
+}
+
+type ttlEntry struct {
+    status  types.Status
+    expires time.Time
+}
+
+// NewTTLCache returns a TTLCache keeping entries for ttl, or for
+// DefaultResultTTL if ttl is not positive.
+func NewTTLCache(ttl time.Duration) *TTLCache {
+    if ttl <= 0 {
+        ttl = DefaultResultTTL
+    }
+    return &TTLCache{ttl: ttl, entries: make(map[string]ttlEntry)}
+}
+
+// Get returns the status stored for jobID, unless it has expired.
+func (c *TTLCache) Get(jobID string) (types.Status, bool) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    e, ok := c.entries[jobID]
+    if !ok {
+        return types.StatusNone, false
+    }
+    if time.Now().After(e.expires) {
+        delete(c.entries, jobID)
+        return types.StatusNone, false
+    }
+    return e.status, true
+}
+
+// Set stores s for jobID, replacing any earlier entry.
+func (c *TTLCache) Set(jobID string, s types.Status) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    c.entries[jobID] = ttlEntry{status: s, expires: time.Now().Add(c.ttl)}
+}
+
+// Delete forgets the status stored for jobID, if any.
+func (c *TTLCache) Delete(jobID string) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    delete(c.entries, jobID)
+}
+
+// WithResultCache makes the client remember final statuses in cache and
+// answer from it without contacting the server. Results are keyed by job, so
+// the cache is only used for a job polled with WithJobID: polling the implicit
+// /status job after a final status starts a new job, which is never cached.
+func WithResultCache(cache ResultCache) ClientOption {
+    return func(c *Client) {
+        c.results = cache
+    }
+}
+
+// resultKey is the key of the client's job in the result cache, or "" if
+// the job's result can't be cached.
+func (c *Client) resultKey() string {
+    if c.results == nil || c.ownJobID() == "" {
+        return ""
+    }
+    if job := c.submitted.Load(); job != nil {
+        return job.baseURL + job.path
+    }
+    return c.BaseURL + jobStatusPath(c.jobID)
+}
+
+// cachedResult returns the cached final status of the client's job, if any.
+func (c *Client) cachedResult() (string, bool) {
+    key := c.resultKey()
+    if key == "" {
+        return "", false
+    }
+    s, ok := c.results.Get(key)
+    return string(s), ok
+}
+
+// cacheResult stores status for the client's job if it is final.
+func (c *Client) cacheResult(status string) {
+    if key := c.resultKey(); key != "" && types.Status(status).IsTerminal() {
+        c.results.Set(key, types.Status(status))
+    }
+}
+
+// forgetResult drops the cached final status of the client's job, if the
+// cache can delete entries.
+func (c *Client) forgetResult() {
+    cache, ok := c.results.(interface{ Delete(jobID string) })
+    if key := c.resultKey(); ok && key != "" {
+        cache.Delete(key)
+    }
+}
@@ -0,0 +1,73 @@
+package client
+
+import (
+    "context"
+    "testing"
+    "time"
+
+    "Video-Translation-Simulator/pkg/testutil"
+    "Video-Translation-Simulator/pkg/types"
+)
+
+func TestTTLCacheExpiry(t *testing.T) {
+    c := NewTTLCache(50 * time.Millisecond)
+    if _, ok := c.Get("job"); ok {
+        t.Fatal("expected an empty cache")
+    }
+    c.Set("job", types.StatusCompleted)
+    if s, ok := c.Get("job"); !ok || s != types.StatusCompleted {
+        t.Fatalf("expected completed, got %q, %v", s, ok)
+    }
+    time.Sleep(60 * time.Millisecond)
+    if _, ok := c.Get("job"); ok {
+        t.Fatal("expected the entry to expire")
+    }
+}
+
+func TestResultCacheSkipsServer(t *testing.T) {
+    srv := testutil.NewDryRunServer()
+    defer srv.Close()
+    srv.QueueStatus("abc", "pending", "completed")
+
+    c := newTestClient(srv.URL, WithJobID("abc"), WithBackoff(constantBackoff(0)), WithResultCache(NewTTLCache(0)))
+    if status := pollStatus(t, c); status != "pending" {
+        t.Fatalf("expected pending, got %q", status)
+    }
+    for i := 0; i < 10; i++ {
+        if status := pollStatus(t, c); status != "completed" {
+            t.Fatalf("request %d: expected completed, got %q", i+1, status)
+        }
+    }
+    // Only the request that saw the job complete reached the server.
+    if n := srv.Requests("abc"); n != 2 {
+        t.Fatalf("expected 2 requests to the server, got %d", n)
+    }
+
+    status, err := c.WaitForCompletion(context.Background())
+    if err != nil || status != "completed" {
+        t.Fatalf("expected the cached completed, got %q, %v", status, err)
+    }
+    if n := srv.Requests("abc"); n != 2 {
+        t.Fatalf("expected WaitForCompletion to use the cache, got %d requests", n)
+    }
+}
+
+func TestResultCacheSkipsImplicitJob(t *testing.T) {
+    srv := testutil.NewDryRunServer()
+    defer srv.Close()
+    srv.QueueStatus("", "pending", "completed", "pending")
+
+    // Each sequence on /status is a new job, so the first result is not reused.
+    c := newTestClient(srv.URL, WithBackoff(constantBackoff(0)), WithResultCache(NewTTLCache(0)))
+    for _, want := range []string{"pending", "completed"} {
+        if status := pollStatus(t, c); status != want {
+            t.Fatalf("expected %s, got %q", want, status)
+        }
+    }
+    if status := pollStatus(t, c); status != "pending" {
+        t.Fatalf("expected the next job to start, got %q", status)
+    }
+    if n := srv.Requests(""); n != 3 {
+        t.Fatalf("expected both requests to reach the server, got %d", n)
+    }
+}
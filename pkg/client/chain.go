@@ -0,0 +1,75 @@
+package client
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "time"
+
+    "Video-Translation-Simulator/pkg/types"
+)
+
+// ErrChainFailed is returned by WaitForChain when a job in the chain ends in
+// error, so the jobs after it are never started.
+var ErrChainFailed = errors.New("job in chain failed")
+
+// WaitForChain waits for the job created with firstJobID on POST /jobs, and
+// for each job chained after it with next_job, which the server starts as the
+// job before it completes. It returns the IDs of the jobs in order once the
+// last one completes. If a job ends in error the chain stops there, and the
+// IDs up to the failed job are returned with ErrChainFailed. Each job is
+// polled with the client's backoff and retry policy.
+func (c *Client) WaitForChain(ctx context.Context, firstJobID string) ([]string, error) {
+    if firstJobID == "" {
+        return nil, errors.New("waiting for chain: no job ID")
+    }
+    // Chained jobs are started by the server holding the first.
+    baseURL := c.serverURL()
+    if job := c.submitted.Load(); job != nil && job.id == firstJobID {
+        baseURL = job.baseURL
+    }
+    var ids []string
+    for id := firstJobID; id != ""; {
+        ids = append(ids, id)
+        transitions := c.newStatusTracker(id)
+        status, err := c.waitForJobAt(ctx, baseURL, jobStatusPath(id), transitions)
+        if err != nil {
+            return ids, err
+        }
+        if status != string(types.StatusCompleted) {
+            return ids, fmt.Errorf("%w: job %s ended in %s", ErrChainFailed, id, status)
+        }
+        c.logf("Chain: Job %s completed, next job %q", id, transitions.nextJobID)
+        id = transitions.nextJobID
+    }
+    return ids, nil
+}
+
+// waitForJobAt polls the status endpoint at path on the server at baseURL
+// until the job reaches a final status, backing off between polls and giving
+// up after the retry policy's MaxAttempts failed requests.
+func (c *Client) waitForJobAt(ctx context.Context, baseURL, path string, transitions *statusTracker) (string, error) {
+    var delay time.Duration
+    failures := 0
+    for {
+        status, err := c.retrieveStatusAt(ctx, baseURL, path, transitions)
+        switch {
+        case errors.Is(err, types.ErrInvalidTransition) || errors.Is(err, ErrResponseTooLarge):
+            return "", err
+        case err != nil:
+            c.logf("Chain: Error fetching status from %s: %v", path, err)
+            if err := ctx.Err(); err != nil {
+                return "", err
+            }
+            if failures++; failures >= c.retry.MaxAttempts {
+                return "", errors.New("max retries reached")
+            }
+        case types.Status(status).IsTerminal():
+            return status, nil
+        }
+        delay = c.backoff.NextDelay(ctx, delay)
+        if err := c.sleep(ctx, delay); err != nil {
+            return "", err
+        }
+    }
+}
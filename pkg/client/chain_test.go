@@ -0,0 +1,73 @@
+package client
+
+import (
+    "context"
+    "errors"
+    "net/http"
+    "reflect"
+    "strings"
+    "testing"
+    "time"
+
+    "Video-Translation-Simulator/pkg/testutil/fixtures"
+)
+
+// submitRaw creates a job on the server at baseURL with body.
+func submitRaw(t *testing.T, baseURL, body string) {
+    t.Helper()
+    resp, err := http.Post(baseURL+"/jobs", "application/json", strings.NewReader(body))
+    if err != nil {
+        t.Fatalf("POST /jobs: %v", err)
+    }
+    resp.Body.Close()
+    if resp.StatusCode != http.StatusAccepted {
+        t.Fatalf("POST /jobs: expected 202, got %d", resp.StatusCode)
+    }
+}
+
+func TestWaitForChain(t *testing.T) {
+    _, ts := fixtures.NewFixtureServer(fixtures.FixtureFastServer)
+    defer ts.Close()
+    submitRaw(t, ts.URL, `{"id":"extract","next_job":{"id":"translate","next_job":{"id":"dub"}}}`)
+
+    c := newTestClient(ts.URL, WithBackoff(constantBackoff(100*time.Millisecond)))
+    ids, err := c.WaitForChain(context.Background(), "extract")
+    if err != nil {
+        t.Fatalf("WaitForChain: %v", err)
+    }
+    if want := []string{"extract", "translate", "dub"}; !reflect.DeepEqual(ids, want) {
+        t.Fatalf("expected %v, got %v", want, ids)
+    }
+
+    // Each job was only created once the one before completed, a delay of 1s later.
+    jobs, err := c.ListJobs(context.Background(), JobFilter{})
+    if err != nil {
+        t.Fatalf("ListJobs: %v", err)
+    }
+    if len(jobs) != 3 {
+        t.Fatalf("expected 3 jobs, got %d", len(jobs))
+    }
+    for i, job := range jobs {
+        if job.JobID != ids[i] || job.Status != "completed" {
+            t.Fatalf("expected job %s completed, got %+v", ids[i], job)
+        }
+        if i == 0 {
+            continue
+        }
+        if prev := jobs[i-1]; prev.NextJobID != job.JobID || job.CreatedAt.Sub(prev.CreatedAt) < time.Second {
+            t.Fatalf("expected %s to start a second after %s, got %+v after %+v", job.JobID, prev.JobID, job, prev)
+        }
+    }
+}
+
+func TestWaitForChainStopsAtFailure(t *testing.T) {
+    _, ts := fixtures.NewFixtureServer(fixtures.FixtureAlwaysError)
+    defer ts.Close()
+    submitRaw(t, ts.URL, `{"id":"doomed","next_job":{"id":"never"}}`)
+
+    c := newTestClient(ts.URL, WithBackoff(constantBackoff(100*time.Millisecond)))
+    ids, err := c.WaitForChain(context.Background(), "doomed")
+    if !errors.Is(err, ErrChainFailed) || !reflect.DeepEqual(ids, []string{"doomed"}) {
+        t.Fatalf("expected ErrChainFailed after doomed, got %v, %v", ids, err)
+    }
+}
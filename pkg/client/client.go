@@ -4,11 +4,19 @@ import (
     "context"
     "encoding/json"
     "errors"
+    "fmt"
+    "io"
     "log"
-    "math/rand"
+    "log/slog"
     "net/http"
+    "net/url"
     "sync"
+    "sync/atomic"
     "time"
+
+    "github.com/gorilla/websocket"
+
+    "Video-Translation-Simulator/pkg/types"
 )
 
 /*
@@ -54,24 +62,216 @@ type Client struct {
     status        string
     attempt       int
     delay         time.Duration
-    maxDelay      time.Duration
-    maxRetries    int
+    retry         RetryPolicy
     lastRequest   time.Time
     nextRequest   time.Time
-    initialDelay  time.Duration
+    sequenceStart time.Time
     pending       bool
     timeout       time.Duration
+    history       *RingBuffer[RequestRecord]
+
+    interruptibleSleep    bool
+    afterFuncCancellation bool
+    backoff               BackoffStrategy
+
+    // logMu guards Logger and slogger separately from mu, which is held for
+    // the whole of HandleStatusRequest.
+    logMu         sync.RWMutex
+    slogger       *slog.Logger
+
+    rateLimit     atomic.Pointer[RateLimitState]
+    stats         pollStats
+    onFinal       func(status string)
+    interceptor   ResponseInterceptor
+    limiter       *BurstLimiter
+    timeBudget    time.Duration
+
+    // Every response is checked against statusMachine. transitions tracks the
+    // sequence run by HandleStatusRequest, under mu; other sequences track their own.
+    // finals holds the final status of each job ID seen, so that sequences
+    // started after a job finished still refuse to see it change.
+    statusMachine *types.StatusMachine
+    transitions   *statusTracker
+    finals        sync.Map
+
+    ndjson        bool
+    pool          *ServerPool
+
+    maxResponseBytes int64
+    results          ResultCache
+
+    // wsMu guards ws, the idle connection opened by ConnectWebSocket or left
+    // by the last subscription. A subscription takes it while in use.
+    wsMu          sync.Mutex
+    ws            *websocket.Conn
+    apiKey        string // Set by WithAPIKey for the WebSocket handshake.
+
+    sseReconnectDelay time.Duration // Set by WithSSEReconnect.
+    sseMaxReconnects  int
+
+    longPollWait  time.Duration
+    metrics       *pollMetrics
+    jobID         string // Set by WithJobID, polled instead of the implicit /status job.
+    submitted     atomic.Pointer[submittedJob] // Set by SubmitJob, polled instead of jobID.
+    retryOnError  bool // Set by WithRetryOnError.
+    jobHeaders    map[string]string // Set by WithJobHeaders, sent with SubmitJob.
+    jobTimeout    time.Duration // Set by WithJobTimeout, sent with SubmitJob.
+    jobMetadata   map[string]string // Set by WithJobMetadata, sent with SubmitJob.
+    jobLabels     map[string]string // Set by WithJobLabels, sent with SubmitJob.
+    circuit       atomic.Pointer[HealthChecker] // Set while a HealthChecker runs.
+}
+
+// ErrRateLimited is returned for status requests the server turned away with
+// 429 Too Many Requests. They are retried like any other failure.
+var ErrRateLimited = errors.New("rate limited by the server")
+
+// DefaultMaxResponseSize is the largest response body the client accepts by default.
+const DefaultMaxResponseSize = 1 << 20
+
+// ErrResponseTooLarge is returned when the server's response is bigger than
+// the client's maximum response size. It is not retried.
+var ErrResponseTooLarge = errors.New("response from server is too large")
+
+// WithMaxResponseSize sets the largest response body, in bytes, that the
+// client reads from the server. Larger responses fail with ErrResponseTooLarge.
+// Non-positive values are ignored.
+func WithMaxResponseSize(bytes int64) ClientOption {
+    return func(c *Client) {
+        if bytes > 0 {
+            c.maxResponseBytes = bytes
+        }
+    }
+}
+
+// WithTimeout sets how long each request to the server may take, 5s by
+// default. Non-positive values are ignored.
+func WithTimeout(d time.Duration) ClientOption {
+    return func(c *Client) {
+        if d > 0 {
+            c.timeout = d
+        }
+    }
+}
+
+// WithLongPoll asks the server to hold each status request for up to wait
+// while the job is pending, answering as soon as it finishes, which saves
+// most of the round trips of polling. The per-request timeout is extended by
+// wait. Servers without long polling ignore the hint and answer at once.
+// Non-positive values are ignored.
+func WithLongPoll(wait time.Duration) ClientOption {
+    return func(c *Client) {
+        if wait > 0 {
+            c.longPollWait = wait
+        }
+    }
+}
+
+// WithJobID makes the client poll the job created with id on POST /jobs, at
+// /jobs/{id}/status, rather than the server's implicit /status job.
+func WithJobID(id string) ClientOption {
+    return func(c *Client) {
+        c.jobID = id
+    }
+}
+
+// ownJobID is the ID of the client's job: the one last submitted with
+// SubmitJob, else the one set by WithJobID, or "" for the implicit /status job.
+func (c *Client) ownJobID() string {
+    if job := c.submitted.Load(); job != nil {
+        return job.id
+    }
+    return c.jobID
+}
+
+// statusTarget is the server to ask for the status of the client's job and
+// the path of its status endpoint. A submitted job is only known to the
+// server it was submitted to, so a server pool isn't consulted for it.
+func (c *Client) statusTarget() (baseURL, path string) {
+    if job := c.submitted.Load(); job != nil {
+        return job.baseURL, job.path
+    }
+    return c.serverURL(), jobStatusPath(c.jobID)
+}
+
+// jobStatusPath is the path of the status endpoint of the job created with
+// jobID on POST /jobs, or of the implicit /status job when jobID is empty.
+func jobStatusPath(jobID string) string {
+    if jobID != "" {
+        return "/jobs/" + url.PathEscape(jobID) + "/status"
+    }
+    return "/status"
+}
+
+// historySize is the number of recent attempts kept for diagnostics.
+const historySize = 50
+
+// ClientOption configures optional settings on a Client created by NewClient.
+type ClientOption func(*Client)
+
+// WithTransport sets the RoundTripper used for requests to the server, e.g. a
+// TracingTransport.
+func WithTransport(rt http.RoundTripper) ClientOption {
+    return func(c *Client) {
+        c.httpClient.Transport = rt
+    }
+}
+
+// WithInterruptibleSleep controls whether a backoff sleep in WaitForCompletion
+// is cut short when the context is cancelled. It is enabled by default; disable
+// it to keep the old behaviour of always sleeping out the full delay.
+func WithInterruptibleSleep(enabled bool) ClientOption {
+    return func(c *Client) {
+        c.interruptibleSleep = enabled
+    }
+}
+
+// WithAfterFuncCancellation makes interruptible backoff sleeps stop through
+// context.AfterFunc rather than a select on ctx.Done(). A context that is
+// already cancelled then always skips the sleep, even a zero length one.
+func WithAfterFuncCancellation(enabled bool) ClientOption {
+    return func(c *Client) {
+        c.afterFuncCancellation = enabled
+    }
+}
+
+// WithBackoff replaces the client's backoff strategy. The default is the
+// client's exponential backoff with jitter wrapped in a DeadlineAwareBackoff;
+// wrap b in NewDeadlineAwareBackoff to keep honouring context deadlines.
+func WithBackoff(b BackoffStrategy) ClientOption {
+    return func(c *Client) {
+        c.backoff = b
+    }
+}
+
+// WithOnFinal registers fn to be called whenever a job reaches a final
+// status. It is called after the client has released its lock, so fn may
+// use the Client.
+func WithOnFinal(fn func(status string)) ClientOption {
+    return func(c *Client) {
+        c.onFinal = fn
+    }
+}
+
+// ResponseInterceptor inspects or rewrites a response from the server before
+// the client acts on it. body is the full response body.
+type ResponseInterceptor func(resp *http.Response, body []byte) (*http.Response, []byte, error)
+
+// WithResponseInterceptor calls f on every response received in RetrieveStatus,
+// before its headers and body are read. It is meant for tests which need to
+// corrupt responses without changing the server.
+func WithResponseInterceptor(f ResponseInterceptor) ClientOption {
+    return func(c *Client) {
+        c.interceptor = f
+    }
 }
 
-// NewClient initializes a new Client with default settings.
-func NewClient(baseURL string, logger *log.Logger) *Client {
-    return &Client{
+// NewClient initializes a new Client with default settings, then applies opts.
+func NewClient(baseURL string, logger *log.Logger, opts ...ClientOption) *Client {
+    c := &Client{
         BaseURL:      baseURL,
         Logger:       logger,
         httpClient:   &http.Client{},
-        initialDelay: 500 * time.Millisecond,
-        maxDelay:     10 * time.Second,
-        maxRetries:   20,
+        retry:        *NewRetryPolicy(),
         status:       "",
         attempt:      0,
         delay:        0,
@@ -79,31 +279,74 @@ func NewClient(baseURL string, logger *log.Logger) *Client {
         nextRequest:  time.Time{},
         pending:      false,
         timeout:      5 * time.Second,
+        history:      NewRingBuffer[RequestRecord](historySize),
+
+        interruptibleSleep: true,
+        maxResponseBytes:   DefaultMaxResponseSize,
+        statusMachine:      types.NewStatusMachine(),
+    }
+    c.backoff = NewDeadlineAwareBackoff(exponentialBackoff{c})
+    for _, opt := range opts {
+        opt(c)
     }
+    return c
 }
 
 // HandleStatusRequest handles incoming /status HTTP requests.
 func (c *Client) HandleStatusRequest(w http.ResponseWriter, r *http.Request) {
     ctx := r.Context()
 
+    if acceptsNDJSON(r) {
+        c.streamStatus(w, r)
+        return
+    }
+    if websocket.IsWebSocketUpgrade(r) {
+        c.relayWebSocket(w, r)
+        return
+    }
+
+    // Deferred before the unlock so that it runs once the lock is released.
+    var final string
+    defer func() {
+        if final != "" && c.onFinal != nil {
+            c.onFinal(final)
+        }
+    }()
+
     c.mu.Lock()
     defer c.mu.Unlock()
 
+    // The caller may have given up while we were waiting on the lock.
+    if err := ctx.Err(); err != nil {
+        c.logf("Request abandoned before polling: %v", err)
+        c.respondWithError(w, err.Error())
+        return
+    }
+
+    // A job whose final status is cached is not polled again.
+    if status, ok := c.cachedResult(); ok && !c.pending {
+        c.logf("Answering with cached final status %s", status)
+        c.respondWithStatus(w, status)
+        return
+    }
+
     // Check if we need to initialize a new polling sequence.
     if !c.pending {
-        c.Logger.Println("Starting new polling sequence")
+        c.logf("Starting new polling sequence")
         c.pending = true
         c.status = "pending"
         c.attempt = 0
-        c.delay = c.initialDelay
+        c.delay = c.retry.InitialInterval
         c.lastRequest = time.Time{}
         c.nextRequest = time.Now()
+        c.sequenceStart = time.Now()
+        c.transitions = c.newStatusTracker(c.ownJobID())
     }
 
     now := time.Now()
     if now.Before(c.nextRequest) {
         // Not yet time to make the next request.
-        c.Logger.Printf("Next request to server in %v", c.nextRequest.Sub(now))
+        c.logf("Next request to server in %v", c.nextRequest.Sub(now))
         // Return last known status.
         c.respondWithStatus(w, c.status)
         return
@@ -111,33 +354,317 @@ func (c *Client) HandleStatusRequest(w http.ResponseWriter, r *http.Request) {
 
     // Make request to  server.
     c.attempt++
-    status, err := c.RetrieveStatus(ctx)
+    status, err := c.retrieveStatus(ctx, c.transitions)
+    c.stats.recordAttempt(c.attempt, status, err)
+    record := RequestRecord{Attempt: c.attempt, Status: status, Timestamp: time.Now()}
     if err != nil {
-        c.Logger.Printf("Attempt %d: Error fetching status: %v", c.attempt, err)
-        if c.attempt >= c.maxRetries {
-            c.Logger.Printf("Max retries reached")
-            c.respondWithError(w, "Max retries reached")
+        c.logAttrs(errorAttrs(c.attempt, err), "Attempt %d: Error fetching status: %v", c.attempt, err)
+        record.Error = err.Error()
+        c.history.Push(record)
+        if errors.Is(err, types.ErrInvalidTransition) || errors.Is(err, ErrResponseTooLarge) {
+            // The server contradicted itself or is misbehaving, asking again won't help.
+            c.respondWithError(w, err.Error())
+            c.pending = false
+            return
+        }
+        if c.attempt >= c.retry.MaxAttempts {
+            c.logf("Max retries reached")
+            c.respondWithError(w, "Max retries reached. Recent attempts:\n"+formatHistory(c.history.Snapshot(), 10))
+            c.pending = false
+            return
+        }
+        if c.retry.elapsedExceeded(c.sequenceStart) {
+            c.logf("Max elapsed time reached")
+            c.respondWithError(w, "Max elapsed time reached. Recent attempts:\n"+formatHistory(c.history.Snapshot(), 10))
             c.pending = false
             return
         }
     } else {
-        c.Logger.Printf("Attempt %d: Received status: %s", c.attempt, status)
+        c.logAttrs(statusAttrs(c.attempt, status), "Attempt %d: Received status: %s", c.attempt, status)
         c.status = status
-        if status == "pending" {
+        done := types.Status(status).IsTerminal()
+        if wait, limited := c.rateLimitWait(); !done && limited {
+            // Out of requests: hold off until the rate limit resets rather than backing off.
+            c.nextRequest = time.Now().Add(wait)
+            c.logAttrs(delayAttrs(c.attempt, wait), "Rate limited, next attempt when the limit resets in %v", wait)
+            c.stats.recordDelay(wait)
+            c.metrics.recordDelay(wait)
+            record.Delay = wait
+        } else if !done && c.retry.elapsedExceeded(c.sequenceStart) {
+            c.logf("Max elapsed time reached while the job is still pending")
+            c.history.Push(record)
+            c.respondWithError(w, ErrMaxElapsedTime.Error())
+            c.pending = false
+            return
+        } else if !done {
             // Update delay and next request time.
-            c.delay = c.nextDelay(c.delay)
+            c.delay = c.backoff.NextDelay(ctx, c.delay)
             c.nextRequest = time.Now().Add(c.delay)
-            c.Logger.Printf("Next attempt in %v", c.delay)
+            c.logAttrs(delayAttrs(c.attempt, c.delay), "Next attempt in %v", c.delay)
+            c.stats.recordDelay(c.delay)
+            c.metrics.recordDelay(c.delay)
+            record.Delay = c.delay
         } else {
             // Final status received.
             c.pending = false
+            final = status
+            c.metrics.recordFinal(c.sequenceStart)
         }
+        c.history.Push(record)
     }
 
     c.lastRequest = time.Now()
     c.respondWithStatus(w, c.status)
 }
 
+// History returns the most recent attempts made by the client, oldest first.
+func (c *Client) History() []RequestRecord {
+    return c.history.Snapshot()
+}
+
+// SetLogger replaces the logger used by the client, e.g. after log rotation.
+// Subsequent log lines go to logger, even if a slog logger was set before.
+func (c *Client) SetLogger(logger *log.Logger) {
+    c.logMu.Lock()
+    defer c.logMu.Unlock()
+    c.Logger = logger
+    c.slogger = nil
+}
+
+// SetSlogLogger switches the client to structured logging. Log lines are
+// written to logger at info level until SetLogger is called again. Lines about
+// an attempt carry it as the attempt attribute, along with the status
+// received, the error or the next_delay before the following one.
+func (c *Client) SetSlogLogger(logger *slog.Logger) {
+    c.logMu.Lock()
+    defer c.logMu.Unlock()
+    c.slogger = logger
+}
+
+// logf writes a log line to whichever logger is currently active.
+func (c *Client) logf(format string, args ...interface{}) {
+    c.logAttrs(nil, format, args...)
+}
+
+// logAttrs is logf with attrs added to the line when logging with slog, e.g.
+// so cmd/analyze can read the backoff delays from a JSON log. The standard
+// logger only gets the formatted message.
+func (c *Client) logAttrs(attrs []slog.Attr, format string, args ...interface{}) {
+    c.logMu.RLock()
+    logger, slogger := c.Logger, c.slogger
+    c.logMu.RUnlock()
+
+    if slogger != nil {
+        slogger.LogAttrs(context.Background(), slog.LevelInfo, fmt.Sprintf(format, args...), attrs...)
+        return
+    }
+    logger.Printf(format, args...)
+}
+
+// delayAttrs describes a backoff before the next attempt, with next_delay as
+// a duration string as slog would otherwise write nanoseconds.
+func delayAttrs(attempt int, delay time.Duration) []slog.Attr {
+    return []slog.Attr{slog.Int("attempt", attempt), slog.String("next_delay", delay.String())}
+}
+
+// statusAttrs describes the status an attempt received.
+func statusAttrs(attempt int, status string) []slog.Attr {
+    return []slog.Attr{slog.Int("attempt", attempt), slog.String("status", status)}
+}
+
+// errorAttrs describes an attempt which failed.
+func errorAttrs(attempt int, err error) []slog.Attr {
+    return []slog.Attr{slog.Int("attempt", attempt), slog.String("error", err.Error())}
+}
+
+// Status returns the last known job status and whether a polling sequence is
+// still in progress.
+func (c *Client) Status() (string, bool) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    return c.status, c.pending
+}
+
+// ClientSnapshot is a point-in-time copy of the client's polling state.
+type ClientSnapshot struct {
+    Status      string        `json:"status"`
+    Attempt     int           `json:"attempt"`
+    Pending     bool          `json:"pending"`
+    NextRequest time.Time     `json:"next_request"`
+    LastRequest time.Time     `json:"last_request"`
+    Delay       time.Duration `json:"delay"` // In nanoseconds when encoded to JSON.
+}
+
+// Snapshot returns a copy of the client's current polling state, taken under the mutex.
+func (c *Client) Snapshot() ClientSnapshot {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    return ClientSnapshot{
+        Status:      c.status,
+        Attempt:     c.attempt,
+        Pending:     c.pending,
+        NextRequest: c.nextRequest,
+        LastRequest: c.lastRequest,
+        Delay:       c.delay,
+    }
+}
+
+// HandleSnapshotRequest serves the client's current state as JSON, for debugging
+// long running clients without restarting them.
+func (c *Client) HandleSnapshotRequest(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Content-Type", "application/json")
+    if err := json.NewEncoder(w).Encode(c.Snapshot()); err != nil {
+        c.logf("Error encoding snapshot: %v", err)
+    }
+}
+
+// WaitForCompletion polls the server until a final status is received, the
+// retries are exhausted or ctx is done. The per-attempt timeout in RetrieveStatus
+// only bounds a single request, so the parent context is checked again before
+// every backoff sleep to make sure we never sleep past its deadline.
+//
+// With WithNDJSON it first asks for a stream of status changes instead, and
+// only polls if the server doesn't offer one. A polled job which the server
+// failed for running past its timeout returns ErrJobTimeout.
+func (c *Client) WaitForCompletion(ctx context.Context) (string, error) {
+    // Polling after a failed stream carries on from what the stream saw.
+    transitions := c.newStatusTracker(c.ownJobID())
+    if c.ndjson {
+        status, err := c.waitForStream(ctx, transitions)
+        if !errors.Is(err, errNotStreaming) {
+            if err == nil && c.onFinal != nil {
+                c.onFinal(status)
+            }
+            return status, err
+        }
+        c.logf("Server does not stream status changes, polling instead")
+    }
+    status, err := c.poll(ctx, transitions, nil)
+    if err == nil && status == "error" && transitions.reason == ReasonJobTimeout {
+        return status, ErrJobTimeout
+    }
+    return status, err
+}
+
+// poll is the polling loop behind WaitForCompletion, checking statuses with
+// transitions. onStatus, if not nil, is called with every status received.
+func (c *Client) poll(ctx context.Context, transitions *statusTracker, onStatus func(string)) (string, error) {
+    if status, ok := c.cachedResult(); ok {
+        c.logf("Answering with cached final status %s", status)
+        return status, nil
+    }
+    var delay time.Duration
+    var budget *TimeBudget
+    start := time.Now()
+    if c.timeBudget > 0 {
+        budget = NewTimeBudget(c.timeBudget)
+    }
+    for attempt := 1; ; attempt++ {
+        requestStart := time.Now()
+        status, err := c.retrieveStatus(ctx, transitions)
+        if budget != nil {
+            budget.Spend(time.Since(requestStart))
+        }
+        c.stats.recordAttempt(attempt, status, err)
+        if err != nil {
+            c.logAttrs(errorAttrs(attempt, err), "Attempt %d: Error fetching status: %v", attempt, err)
+            if errors.Is(err, types.ErrInvalidTransition) || errors.Is(err, ErrResponseTooLarge) {
+                return "", err
+            }
+            // The request most likely failed because the caller gave up.
+            if err := ctx.Err(); err != nil {
+                return "", err
+            }
+            if attempt >= c.retry.MaxAttempts {
+                return "", errors.New("max retries reached")
+            }
+        } else {
+            c.logAttrs(statusAttrs(attempt, status), "Attempt %d: Received status: %s", attempt, status)
+            if onStatus != nil {
+                onStatus(status)
+            }
+            if types.Status(status).IsTerminal() && !(status == "error" && c.retryFailedJob(ctx, transitions)) {
+                c.metrics.recordFinal(start)
+                if c.onFinal != nil {
+                    c.onFinal(status)
+                }
+                return status, nil
+            }
+        }
+
+        // Don't bother sleeping if the caller has already given up.
+        if err := ctx.Err(); err != nil {
+            return "", err
+        }
+        if c.retry.elapsedExceeded(start) {
+            return "", ErrMaxElapsedTime
+        }
+        // Out of requests: wait for the rate limit to reset rather than backing off.
+        wait, limited := c.rateLimitWait()
+        if limited {
+            c.logAttrs(delayAttrs(attempt, wait), "Rate limited, next attempt when the limit resets in %v", wait)
+        } else {
+            delay = c.backoff.NextDelay(ctx, delay)
+            wait = delay
+            c.logAttrs(delayAttrs(attempt, wait), "Next attempt in %v", wait)
+        }
+        if budget != nil && !budget.Allows(wait) {
+            c.logf("Time budget exhausted, %v left is not enough for the next backoff", budget.Remaining())
+            return "", ErrBudgetExhausted
+        }
+        c.stats.recordDelay(wait)
+        c.metrics.recordDelay(wait)
+        sleepStart := time.Now()
+        if err := c.sleep(ctx, wait); err != nil {
+            return "", err
+        }
+        if budget != nil {
+            budget.Spend(time.Since(sleepStart))
+        }
+    }
+}
+
+// sleep waits for d, returning early with ctx.Err() if ctx is done first and
+// interruptible sleep is enabled.
+func (c *Client) sleep(ctx context.Context, d time.Duration) error {
+    if !c.interruptibleSleep {
+        time.Sleep(d)
+        return nil
+    }
+    if c.afterFuncCancellation {
+        return sleepAfterFunc(ctx, d)
+    }
+    select {
+    case <-time.After(d):
+        return nil
+    case <-ctx.Done():
+        return ctx.Err()
+    }
+}
+
+// sleepAfterFunc is sleep's interruptible wait using context.AfterFunc to stop
+// it. A context that is already done stops the sleep before it starts, rather
+// than racing the timer as a select on a zero delay would.
+func sleepAfterFunc(ctx context.Context, d time.Duration) error {
+    // AfterFunc calls back from its own goroutine even for a done context, so check first.
+    if err := ctx.Err(); err != nil {
+        return err
+    }
+    wake, cancel := context.WithCancel(context.Background())
+    defer cancel()
+    stop := context.AfterFunc(ctx, cancel)
+    defer stop()
+
+    timer := time.NewTimer(d)
+    defer timer.Stop()
+    select {
+    case <-timer.C:
+        return nil
+    case <-wake.Done():
+        return ctx.Err()
+    }
+}
+
 func (c *Client) respondWithStatus(w http.ResponseWriter, status string) {
     w.Header().Set("Content-Type", "application/json")
     w.WriteHeader(http.StatusOK)
@@ -148,54 +675,168 @@ func (c *Client) respondWithError(w http.ResponseWriter, message string) {
     http.Error(w, message, http.StatusInternalServerError)
 }
 
-// nextDelay calculates the next delay with exponential backoff and jitter.
+// nextDelay calculates the next delay with exponential backoff and jitter,
+// as set by the client's retry policy.
 func (c *Client) nextDelay(currentDelay time.Duration) time.Duration {
-    if currentDelay == 0 {
-        currentDelay = c.initialDelay
-    } else {
-        currentDelay *= 2
-    }
-    if currentDelay > c.maxDelay {
-        currentDelay = c.maxDelay
-    }
-    // Add jitter.
-    jitter := time.Duration(rand.Int63n(int64(currentDelay / 2)))
-    totalDelay := currentDelay/2 + jitter
-    c.Logger.Printf("Exponential backoff: current delay %v, jitter added %v, total delay %v", currentDelay/2, jitter, totalDelay)
+    base, jitter := c.retry.nextInterval(currentDelay)
+    totalDelay := base + jitter
+    c.logf("Exponential backoff: current delay %v, jitter added %v, total delay %v", base, jitter, totalDelay)
     return totalDelay
 }
 
 // RetrieveStatus makes an HTTP GET request to the /status endpoint.
+// A single request doesn't see the job start, so its status is only checked
+// against the final status the job already reached, if any.
 func (c *Client) RetrieveStatus(ctx context.Context) (string, error) {
-    req, err := http.NewRequest("GET", c.BaseURL+"/status", nil)
+    transitions := c.newStatusTracker(c.ownJobID())
+    if transitions.last == types.StatusNone {
+        // Anything a scheduled job may move on to, i.e. any status.
+        transitions.last = types.StatusScheduled
+    }
+    return c.retrieveStatus(ctx, transitions)
+}
+
+// retrieveStatus is RetrieveStatus as part of a polling sequence, checking the
+// status against the sequence's previous one.
+func (c *Client) retrieveStatus(ctx context.Context, transitions *statusTracker) (string, error) {
+    baseURL, path := c.statusTarget()
+    return c.retrieveStatusAt(ctx, baseURL, path, transitions)
+}
+
+// retrieveStatusAt is retrieveStatus for the status endpoint at path on the
+// server at baseURL.
+func (c *Client) retrieveStatusAt(ctx context.Context, baseURL, path string, transitions *statusTracker) (status string, err error) {
+    if c.limiter != nil {
+        if err := c.limiter.Wait(ctx); err != nil {
+            return "", err
+        }
+    }
+
+    if h := c.circuit.Load(); h != nil && !h.IsHealthy() {
+        return "", ErrCircuitOpen
+    }
+
+    if c.pool != nil {
+        start := time.Now()
+        defer func() {
+            c.pool.RecordResult(baseURL, err == nil, float64(time.Since(start))/float64(time.Millisecond))
+        }()
+    }
+
+    target := baseURL + path
+    if c.longPollWait > 0 {
+        target += "?wait=" + url.QueryEscape(c.longPollWait.String())
+    }
+    req, err := http.NewRequest("GET", target, nil)
     if err != nil {
         return "", err
     }
 
-    ctx, cancel := context.WithTimeout(ctx, c.timeout)
+    ctx, cancel := context.WithTimeout(ctx, c.timeout+c.longPollWait)
     defer cancel()
     req = req.WithContext(ctx)
 
+    c.metrics.recordAttempt()
     resp, err := c.httpClient.Do(req)
     if err != nil {
         return "", err
     }
     defer resp.Body.Close()
 
-    if resp.StatusCode != http.StatusOK {
+    // Read one byte past the limit to tell a body that fits exactly from one that doesn't.
+    raw, err := io.ReadAll(io.LimitReader(resp.Body, c.maxResponseBytes+1))
+    if err != nil {
+        return "", err
+    }
+    if int64(len(raw)) > c.maxResponseBytes {
+        return "", ErrResponseTooLarge
+    }
+    if c.interceptor != nil {
+        if resp, raw, err = c.interceptor(resp, raw); err != nil {
+            return "", err
+        }
+    }
+
+    // Rate limit headers matter on rejected responses too, so read them first.
+    if state, ok := parseRateLimit(resp.Header); ok {
+        c.rateLimit.Store(&state)
+        c.logf("Rate limit: %d of %d requests remaining, resets at %v", state.Remaining, state.Limit, state.Reset)
+    }
+
+    if resp.StatusCode == http.StatusTooManyRequests {
+        return "", ErrRateLimited
+    }
+    // A long poll that ran out of time is answered with 202 and the pending status.
+    if resp.StatusCode != http.StatusOK && !(c.longPollWait > 0 && resp.StatusCode == http.StatusAccepted) {
         return "", errors.New("received non-200 response from server")
     }
 
+    // The server estimates how long the job has left, which bounds how long it is worth retrying for.
+    if remaining := resp.Header.Get("X-Retry-Budget-Remaining"); remaining != "" {
+        c.logf("Server reports %ss of retry budget remaining", remaining)
+    }
+
     var response struct {
-        Result string `json:"result"`
+        Result    string `json:"result"`
+        Reason    string `json:"reason"`
+        NextJobID string `json:"next_job_id"`
     }
-    if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+    if err := json.Unmarshal(raw, &response); err != nil {
         return "", err
     }
+    if err := transitions.check(types.Status(response.Result)); err != nil {
+        return "", err
+    }
+    transitions.reason, transitions.nextJobID = response.Reason, response.NextJobID
+    // Only the client's own job is cached, not others polled on its behalf.
+    if transitions.jobID == c.ownJobID() {
+        c.cacheResult(response.Result)
+    }
 
     return response.Result, nil
 }
 
+// statusTracker checks the statuses seen by one polling sequence against a
+// StatusMachine. A job created with an ID starts from the final status it
+// last reached in any sequence, so it can't finish twice. The implicit /status
+// job is replaced by the server once it finishes, so each sequence starts it
+// from scratch.
+type statusTracker struct {
+    machine   *types.StatusMachine
+    last      types.Status
+    reason    string // Given by the server with last, if any.
+    nextJobID string // Given by the server with a completed status, if any.
+    jobID     string
+    finals    *sync.Map
+}
+
+// newStatusTracker returns a tracker for the job created with jobID, or the
+// implicit /status job when jobID is empty.
+func (c *Client) newStatusTracker(jobID string) *statusTracker {
+    t := &statusTracker{machine: c.statusMachine, jobID: jobID, finals: &c.finals}
+    if final, ok := c.finals.Load(jobID); ok && jobID != "" {
+        t.last = final.(types.Status)
+    } else if c.longPollWait > 0 {
+        // The server holds long polls until the job finishes, so the first
+        // status seen may already be final. A scheduled job may move on to
+        // any status.
+        t.last = types.StatusScheduled
+    }
+    return t
+}
+
+// check returns ErrInvalidTransition if the job can't have moved from the last
+// status seen to status.
+func (t *statusTracker) check(status types.Status) error {
+    if !t.machine.IsValid(t.last, status) {
+        return fmt.Errorf("%w from %q to %q", types.ErrInvalidTransition, t.last, status)
+    }
+    if status.IsTerminal() && t.jobID != "" {
+        t.finals.Store(t.jobID, status)
+    }
+    t.last = status
+    return nil
+}
 
 // TODO : We can add an adaptiveRetry and request queue as well. But since our server load isnt variying 
 // and only 1 user is making requests in this simulation, we wouldnt need it.
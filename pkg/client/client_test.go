@@ -0,0 +1,258 @@
+package client
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "errors"
+    "io/ioutil"
+    "log"
+    "log/slog"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "sync/atomic"
+    "testing"
+    "time"
+)
+
+// newDelayedServer returns a stub server which reports "pending" until delay has
+// passed and "completed" afterwards, mimicking the translation server.
+func newDelayedServer(delay time.Duration) *httptest.Server {
+    start := time.Now()
+    return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        result := "pending"
+        if time.Since(start) >= delay {
+            result = "completed"
+        }
+        w.Header().Set("Content-Type", "application/json")
+        json.NewEncoder(w).Encode(map[string]string{"result": result})
+    }))
+}
+
+func newTestClient(baseURL string, opts ...ClientOption) *Client {
+    return NewClient(baseURL, log.New(ioutil.Discard, "", 0), opts...)
+}
+
+func TestWaitForCompletionHonoursParentDeadline(t *testing.T) {
+    srv := newDelayedServer(2 * time.Second)
+    defer srv.Close()
+
+    c := newTestClient(srv.URL, WithRetryPolicy(NewRetryPolicy().WithInitialInterval(20*time.Millisecond).WithMaxInterval(50*time.Millisecond)))
+
+    ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(200*time.Millisecond))
+    defer cancel()
+
+    start := time.Now()
+    _, err := c.WaitForCompletion(ctx)
+    elapsed := time.Since(start)
+
+    if !errors.Is(err, context.DeadlineExceeded) {
+        t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+    }
+    if elapsed > 300*time.Millisecond {
+        t.Fatalf("client kept polling for %v after a 200ms deadline", elapsed)
+    }
+}
+
+func TestWaitForCompletionReturnsFinalStatus(t *testing.T) {
+    srv := newDelayedServer(100 * time.Millisecond)
+    defer srv.Close()
+
+    c := newTestClient(srv.URL, WithRetryPolicy(NewRetryPolicy().WithInitialInterval(20*time.Millisecond).WithMaxInterval(40*time.Millisecond)))
+
+    status, err := c.WaitForCompletion(context.Background())
+    if err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+    if status != "completed" {
+        t.Fatalf("expected completed, got %q", status)
+    }
+}
+
+func TestWaitForCompletionCancelDuringBackoff(t *testing.T) {
+    srv := newDelayedServer(time.Hour)
+    defer srv.Close()
+
+    // The first backoff sleep is between 2 and 4 seconds.
+    c := newTestClient(srv.URL, WithRetryPolicy(NewRetryPolicy().WithInitialInterval(4*time.Second).WithMaxInterval(4*time.Second)))
+
+    ctx, cancel := context.WithCancel(context.Background())
+    done := make(chan error, 1)
+    go func() {
+        _, err := c.WaitForCompletion(ctx)
+        done <- err
+    }()
+
+    time.Sleep(200 * time.Millisecond)
+    cancel()
+    cancelled := time.Now()
+
+    select {
+    case err := <-done:
+        if !errors.Is(err, context.Canceled) {
+            t.Fatalf("expected context.Canceled, got %v", err)
+        }
+        if waited := time.Since(cancelled); waited > 100*time.Millisecond {
+            t.Fatalf("took %v to notice cancellation", waited)
+        }
+    case <-time.After(5 * time.Second):
+        t.Fatal("WaitForCompletion did not return after cancellation")
+    }
+}
+
+func TestWaitForCompletionUninterruptibleSleep(t *testing.T) {
+    srv := newDelayedServer(time.Hour)
+    defer srv.Close()
+
+    // Without a DeadlineAwareBackoff, which wouldn't sleep past the deadline.
+    c := newTestClient(srv.URL, WithInterruptibleSleep(false), WithBackoff(constantBackoff(300*time.Millisecond)))
+
+    ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+    defer cancel()
+
+    start := time.Now()
+    if _, err := c.WaitForCompletion(ctx); !errors.Is(err, context.DeadlineExceeded) {
+        t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+    }
+    if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+        t.Fatalf("sleep was interrupted after %v", elapsed)
+    }
+}
+
+func TestSnapshotEndpointMidPolling(t *testing.T) {
+    srv := newDelayedServer(time.Hour)
+    defer srv.Close()
+    c := newTestClient(srv.URL)
+
+    // Kick off a polling sequence.
+    rec := httptest.NewRecorder()
+    c.HandleStatusRequest(rec, httptest.NewRequest(http.MethodGet, "/status", nil))
+    if rec.Code != http.StatusOK {
+        t.Fatalf("status request failed with %d", rec.Code)
+    }
+
+    rec = httptest.NewRecorder()
+    c.HandleSnapshotRequest(rec, httptest.NewRequest(http.MethodGet, "/debug/snapshot", nil))
+    var snap ClientSnapshot
+    if err := json.NewDecoder(rec.Body).Decode(&snap); err != nil {
+        t.Fatalf("decoding snapshot: %v", err)
+    }
+
+    if snap.Status != "pending" || !snap.Pending || snap.Attempt != 1 {
+        t.Fatalf("unexpected polling state: %+v", snap)
+    }
+    if snap.NextRequest.IsZero() || snap.LastRequest.IsZero() || snap.Delay <= 0 {
+        t.Fatalf("expected timing fields to be set: %+v", snap)
+    }
+}
+
+func TestSetLoggerMidPolling(t *testing.T) {
+    srv := newDelayedServer(time.Hour)
+    defer srv.Close()
+
+    var first, second bytes.Buffer
+    c := NewClient(srv.URL, log.New(&first, "", 0))
+    poll := func() {
+        c.mu.Lock()
+        c.nextRequest = time.Time{} // Skip the backoff so every poll hits the server.
+        c.mu.Unlock()
+        c.HandleStatusRequest(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/status", nil))
+    }
+
+    poll()
+    if !strings.Contains(first.String(), "Attempt 1:") {
+        t.Fatalf("expected first attempt in the original logger, got %q", first.String())
+    }
+
+    c.SetLogger(log.New(&second, "", 0))
+    before := first.Len()
+    poll()
+    if first.Len() != before {
+        t.Fatalf("original logger kept receiving lines: %q", first.String()[before:])
+    }
+    if !strings.Contains(second.String(), "Attempt 2:") {
+        t.Fatalf("expected second attempt in the new logger, got %q", second.String())
+    }
+
+    var structured bytes.Buffer
+    c.SetSlogLogger(slog.New(slog.NewJSONHandler(&structured, nil)))
+    before = second.Len()
+    poll()
+    if second.Len() != before {
+        t.Fatalf("log.Logger kept receiving lines after switching to slog: %q", second.String()[before:])
+    }
+    if !strings.Contains(structured.String(), `"msg":"Attempt 3: Received status: pending"`) {
+        t.Fatalf("expected structured log line for the third attempt, got %q", structured.String())
+    }
+    if !strings.Contains(structured.String(), `"attempt":3,"status":"pending"`) {
+        t.Fatalf("expected the attempt and status as attributes, got %q", structured.String())
+    }
+}
+
+func TestSleepCancellationLatency(t *testing.T) {
+    for _, afterFunc := range []bool{false, true} {
+        c := newTestClient("", WithAfterFuncCancellation(afterFunc))
+
+        ctx, cancel := context.WithCancel(context.Background())
+        time.AfterFunc(50*time.Millisecond, cancel)
+        start := time.Now()
+        if err := c.sleep(ctx, 5*time.Second); !errors.Is(err, context.Canceled) {
+            t.Fatalf("afterFunc=%v: expected context.Canceled, got %v", afterFunc, err)
+        }
+        latency := time.Since(start) - 50*time.Millisecond
+        t.Logf("afterFunc=%v: sleep returned %v after cancellation", afterFunc, latency)
+        if latency > 50*time.Millisecond {
+            t.Fatalf("afterFunc=%v: took %v to notice cancellation", afterFunc, latency)
+        }
+    }
+
+    // Only the AfterFunc version reliably skips a zero length sleep on a cancelled context.
+    c := newTestClient("", WithAfterFuncCancellation(true))
+    ctx, cancel := context.WithCancel(context.Background())
+    cancel()
+    for i := 0; i < 100; i++ {
+        if err := c.sleep(ctx, 0); !errors.Is(err, context.Canceled) {
+            t.Fatalf("expected context.Canceled for an already cancelled context, got %v", err)
+        }
+    }
+}
+
+func TestMaxResponseSize(t *testing.T) {
+    // A valid response padded out to 2 MB.
+    var requests atomic.Int32
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        requests.Add(1)
+        w.Header().Set("Content-Type", "application/json")
+        w.Write([]byte(`{"result": "pending"}`))
+        w.Write(bytes.Repeat([]byte(" "), 2<<20))
+    }))
+    defer srv.Close()
+
+    c := newTestClient(srv.URL, WithMaxResponseSize(1<<20), WithBackoff(constantBackoff(0)))
+    if _, err := c.RetrieveStatus(context.Background()); !errors.Is(err, ErrResponseTooLarge) {
+        t.Fatalf("expected ErrResponseTooLarge, got %v", err)
+    }
+
+    // It is not retried.
+    requests.Store(0)
+    if _, err := c.WaitForCompletion(context.Background()); !errors.Is(err, ErrResponseTooLarge) {
+        t.Fatalf("expected ErrResponseTooLarge, got %v", err)
+    }
+    if n := requests.Load(); n != 1 {
+        t.Fatalf("expected a single attempt, got %d", n)
+    }
+
+    // The default limit of 1 MB applies without the option.
+    if _, err := newTestClient(srv.URL).RetrieveStatus(context.Background()); !errors.Is(err, ErrResponseTooLarge) {
+        t.Fatalf("expected ErrResponseTooLarge with the default limit, got %v", err)
+    }
+    if status, err := newTestClient(srv.URL, WithMaxResponseSize(3<<20)).RetrieveStatus(context.Background()); err != nil || status != "pending" {
+        t.Fatalf("expected pending under a 3 MB limit, got %q, %v", status, err)
+    }
+    for _, size := range []int64{0, -1} {
+        if c := newTestClient(srv.URL, WithMaxResponseSize(size)); c.maxResponseBytes != DefaultMaxResponseSize {
+            t.Fatalf("expected WithMaxResponseSize(%d) to be ignored, got %d", size, c.maxResponseBytes)
+        }
+    }
+}
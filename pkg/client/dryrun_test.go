@@ -0,0 +1,208 @@
+package client
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "errors"
+    "log/slog"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+    "time"
+
+    "Video-Translation-Simulator/pkg/analyze"
+    "Video-Translation-Simulator/pkg/testutil"
+    "Video-Translation-Simulator/pkg/types"
+)
+
+// These tests drive the client against a DryRunServer, so polling sequences
+// that take seconds against the real server run instantly.
+
+// pollStatus sends one /status request to the client and returns its result.
+func pollStatus(t *testing.T, c *Client) string {
+    t.Helper()
+    rec := httptest.NewRecorder()
+    c.HandleStatusRequest(rec, httptest.NewRequest(http.MethodGet, "/status", nil))
+    if rec.Code != http.StatusOK {
+        t.Fatalf("status request failed with %d: %s", rec.Code, rec.Body.String())
+    }
+    var body map[string]string
+    if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+        t.Fatalf("decoding response: %v", err)
+    }
+    return body["result"]
+}
+
+func TestDryRunWaitForCompletion(t *testing.T) {
+    srv := testutil.NewDryRunServer()
+    defer srv.Close()
+    srv.QueueStatus("", "pending", "pending", "pending", "completed")
+
+    c := newTestClient(srv.URL, WithBackoff(constantBackoff(0)))
+    status, err := c.WaitForCompletion(context.Background())
+    if err != nil || status != "completed" {
+        t.Fatalf("expected completed, got %q, %v", status, err)
+    }
+    if n := srv.Requests(""); n != 4 {
+        t.Fatalf("expected 4 polls, got %d", n)
+    }
+}
+
+func TestDryRunWaitForCompletionError(t *testing.T) {
+    srv := testutil.NewDryRunServer()
+    defer srv.Close()
+    srv.QueueStatus("", "pending", "error")
+
+    c := newTestClient(srv.URL, WithBackoff(constantBackoff(0)))
+    status, err := c.WaitForCompletion(context.Background())
+    if err != nil || status != "error" {
+        t.Fatalf("expected error status, got %q, %v", status, err)
+    }
+}
+
+func TestDryRunHandleStatusRequestSequence(t *testing.T) {
+    srv := testutil.NewDryRunServer()
+    defer srv.Close()
+    srv.QueueStatus("", "pending", "pending", "completed")
+
+    c := newTestClient(srv.URL, WithBackoff(constantBackoff(0)))
+    for i, want := range []string{"pending", "pending", "completed"} {
+        if got := pollStatus(t, c); got != want {
+            t.Fatalf("poll %d: expected %q, got %q", i+1, want, got)
+        }
+    }
+    if _, pending := c.Status(); pending {
+        t.Fatal("expected polling to stop at the final status")
+    }
+}
+
+func TestDryRunNewSequenceAfterError(t *testing.T) {
+    srv := testutil.NewDryRunServer()
+    defer srv.Close()
+    srv.QueueStatus("", "pending", "error", "pending", "completed")
+
+    c := newTestClient(srv.URL, WithBackoff(constantBackoff(0)))
+    var got []string
+    for i := 0; i < 4; i++ {
+        got = append(got, pollStatus(t, c))
+    }
+    want := []string{"pending", "error", "pending", "completed"}
+    for i := range want {
+        if got[i] != want[i] {
+            t.Fatalf("expected %v, got %v", want, got)
+        }
+    }
+    if attempt := c.Snapshot().Attempt; attempt != 2 {
+        t.Fatalf("expected the retry after the error to start a new sequence, attempt is %d", attempt)
+    }
+}
+
+func TestDryRunCachedStatusDuringBackoff(t *testing.T) {
+    srv := testutil.NewDryRunServer()
+    defer srv.Close()
+    srv.QueueStatus("", "pending", "completed")
+
+    c := newTestClient(srv.URL, WithBackoff(constantBackoff(time.Hour)))
+    for i := 0; i < 10; i++ {
+        if got := pollStatus(t, c); got != "pending" {
+            t.Fatalf("poll %d: expected the cached pending status, got %q", i+1, got)
+        }
+    }
+    if n := srv.Requests(""); n != 1 {
+        t.Fatalf("expected a single request to the server during the backoff, got %d", n)
+    }
+}
+
+func TestDryRunInvalidTransitionIsNotRetried(t *testing.T) {
+    srv := testutil.NewDryRunServer()
+    defer srv.Close()
+    srv.QueueStatus("", "pending", "paused")
+
+    // "paused" is not a status a pending job can move to.
+    c := newTestClient(srv.URL, WithBackoff(constantBackoff(0)))
+    _, err := c.WaitForCompletion(context.Background())
+    if !errors.Is(err, types.ErrInvalidTransition) {
+        t.Fatalf("expected ErrInvalidTransition, got %v", err)
+    }
+    if n := srv.Requests(""); n != 2 {
+        t.Fatalf("expected the invalid transition not to be retried, got %d requests", n)
+    }
+
+    // Polling picks up again once the server reports a new job.
+    srv.QueueStatus("", "pending")
+    rec := httptest.NewRecorder()
+    c.HandleStatusRequest(rec, httptest.NewRequest(http.MethodGet, "/status", nil))
+    if rec.Code != http.StatusOK {
+        t.Fatalf("expected a new sequence to start cleanly, got %d: %s", rec.Code, rec.Body.String())
+    }
+}
+
+func TestDryRunJobCannotFinishTwice(t *testing.T) {
+    srv := testutil.NewDryRunServer()
+    defer srv.Close()
+    srv.QueueStatus("abc", "pending", "error", "completed")
+
+    // The second sequence starts from the error the first one ended on.
+    c := newTestClient(srv.URL, WithJobID("abc"), WithBackoff(constantBackoff(0)))
+    for _, want := range []string{"pending", "error"} {
+        if status := pollStatus(t, c); status != want {
+            t.Fatalf("expected %s, got %q", want, status)
+        }
+    }
+    rec := httptest.NewRecorder()
+    c.HandleStatusRequest(rec, httptest.NewRequest(http.MethodGet, "/status", nil))
+    if rec.Code != http.StatusInternalServerError || !strings.Contains(rec.Body.String(), `from "error" to "completed"`) {
+        t.Fatalf("expected completed after error to be rejected, got %d: %s", rec.Code, rec.Body.String())
+    }
+}
+
+func TestDryRunSequencesTrackTransitionsSeparately(t *testing.T) {
+    srv := testutil.NewDryRunServer()
+    defer srv.Close()
+
+    // A job is pending before it reaches a final status.
+    srv.QueueStatus("", "completed")
+    c := newTestClient(srv.URL, WithBackoff(constantBackoff(0)))
+    if _, err := c.WaitForCompletion(context.Background()); !errors.Is(err, types.ErrInvalidTransition) {
+        t.Fatalf("expected ErrInvalidTransition for completed on the first poll, got %v", err)
+    }
+
+    // A WaitForCompletion run in the middle of the /status sequence doesn't
+    // disturb what that sequence has seen.
+    srv.QueueStatus("", "pending", "pending", "error", "paused")
+    if status := pollStatus(t, c); status != "pending" {
+        t.Fatalf("expected pending, got %q", status)
+    }
+    if status, err := c.WaitForCompletion(context.Background()); err != nil || status != "error" {
+        t.Fatalf("expected error, got %q, %v", status, err)
+    }
+    rec := httptest.NewRecorder()
+    c.HandleStatusRequest(rec, httptest.NewRequest(http.MethodGet, "/status", nil))
+    if rec.Code != http.StatusInternalServerError || !strings.Contains(rec.Body.String(), `from "pending" to "paused"`) {
+        t.Fatalf("expected the /status sequence to check against its own pending, got %d: %s", rec.Code, rec.Body.String())
+    }
+}
+
+func TestDryRunSlogLogFeedsAnalyze(t *testing.T) {
+    srv := testutil.NewDryRunServer()
+    defer srv.Close()
+    srv.QueueStatus("", "pending", "pending", "completed")
+
+    var buf bytes.Buffer
+    c := newTestClient(srv.URL, WithBackoff(constantBackoff(time.Millisecond)))
+    c.SetSlogLogger(slog.New(slog.NewJSONHandler(&buf, nil)))
+    if _, err := c.WaitForCompletion(context.Background()); err != nil {
+        t.Fatalf("WaitForCompletion: %v", err)
+    }
+
+    result, err := analyze.Parse(&buf)
+    if err != nil {
+        t.Fatalf("Parse: %v", err)
+    }
+    want := []analyze.Sample{{Attempt: 1, NextDelay: time.Millisecond}, {Attempt: 2, NextDelay: time.Millisecond}}
+    if len(result.Samples) != len(want) || result.Samples[0] != want[0] || result.Samples[1] != want[1] {
+        t.Fatalf("expected samples %+v, got %+v", want, result.Samples)
+    }
+}
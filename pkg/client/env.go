@@ -0,0 +1,85 @@
+package client
+
+import (
+    "errors"
+    "fmt"
+    "log"
+    "net/url"
+    "os"
+    "strconv"
+    "time"
+)
+
+// Environment variables read by NewClientFromEnv. Only the server URL is
+// required; the others fall back to the NewClient defaults when unset.
+const (
+    EnvServerURL      = "TRANSLATION_SERVER_URL"
+    EnvMaxRetries     = "TRANSLATION_MAX_RETRIES"
+    EnvInitialDelayMs = "TRANSLATION_INITIAL_DELAY_MS"
+    EnvMaxDelayMs     = "TRANSLATION_MAX_DELAY_MS"
+    EnvTimeoutMs      = "TRANSLATION_TIMEOUT_MS"
+    EnvAPIKey         = "TRANSLATION_API_KEY"
+)
+
+// NewClientFromEnv creates a Client configured entirely from environment
+// variables, for deployments that keep their settings there. It returns an
+// error naming the variable if one is missing or invalid.
+func NewClientFromEnv(logger *log.Logger) (*Client, error) {
+    serverURL := os.Getenv(EnvServerURL)
+    if serverURL == "" {
+        return nil, fmt.Errorf("%s must be set to the translation server's URL", EnvServerURL)
+    }
+    if u, err := url.Parse(serverURL); err != nil || u.Scheme == "" || u.Host == "" {
+        return nil, fmt.Errorf("%s=%q is not an absolute URL", EnvServerURL, serverURL)
+    }
+
+    policy := NewRetryPolicy()
+    var opts []ClientOption
+    var errs []error
+
+    if n, ok, err := envPositiveInt(EnvMaxRetries); err != nil {
+        errs = append(errs, err)
+    } else if ok {
+        policy.WithMaxAttempts(n)
+    }
+    if n, ok, err := envPositiveInt(EnvInitialDelayMs); err != nil {
+        errs = append(errs, err)
+    } else if ok {
+        policy.WithInitialInterval(time.Duration(n) * time.Millisecond)
+    }
+    if n, ok, err := envPositiveInt(EnvMaxDelayMs); err != nil {
+        errs = append(errs, err)
+    } else if ok {
+        policy.WithMaxInterval(time.Duration(n) * time.Millisecond)
+    }
+    if policy.MaxInterval < policy.InitialInterval {
+        errs = append(errs, fmt.Errorf("%s (%v) must not be less than %s (%v)", EnvMaxDelayMs, policy.MaxInterval, EnvInitialDelayMs, policy.InitialInterval))
+    }
+    if n, ok, err := envPositiveInt(EnvTimeoutMs); err != nil {
+        errs = append(errs, err)
+    } else if ok {
+        opts = append(opts, WithTimeout(time.Duration(n)*time.Millisecond))
+    }
+    if err := errors.Join(errs...); err != nil {
+        return nil, err
+    }
+
+    opts = append(opts, WithRetryPolicy(policy))
+    if key := os.Getenv(EnvAPIKey); key != "" {
+        opts = append(opts, WithAPIKey(key))
+    }
+    return NewClient(serverURL, logger, opts...), nil
+}
+
+// envPositiveInt reads name as a positive integer, reporting whether it was set.
+func envPositiveInt(name string) (int, bool, error) {
+    raw, ok := os.LookupEnv(name)
+    if !ok || raw == "" {
+        return 0, false, nil
+    }
+    n, err := strconv.Atoi(raw)
+    if err != nil || n <= 0 {
+        return 0, false, fmt.Errorf("%s=%q must be a positive integer", name, raw)
+    }
+    return n, true, nil
+}
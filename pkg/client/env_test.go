@@ -0,0 +1,143 @@
+package client
+
+import (
+    "context"
+    "io/ioutil"
+    "log"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+    "time"
+)
+
+func TestNewClientFromEnv(t *testing.T) {
+    tests := []struct {
+        name    string
+        env     map[string]string
+        wantErr string // Empty when the client should be created.
+        check   func(t *testing.T, c *Client)
+    }{
+        {
+            name:    "server URL missing",
+            env:     map[string]string{},
+            wantErr: EnvServerURL,
+        },
+        {
+            name:    "server URL not absolute",
+            env:     map[string]string{EnvServerURL: "localhost"},
+            wantErr: EnvServerURL,
+        },
+        {
+            name: "defaults",
+            env:  map[string]string{EnvServerURL: "http://localhost:8080"},
+            check: func(t *testing.T, c *Client) {
+                if c.BaseURL != "http://localhost:8080" || c.retry != *NewRetryPolicy() || c.timeout != 5*time.Second {
+                    t.Fatalf("expected the NewClient defaults, got %+v, timeout %v", c.retry, c.timeout)
+                }
+            },
+        },
+        {
+            name: "max retries",
+            env:  map[string]string{EnvServerURL: "http://server", EnvMaxRetries: "7"},
+            check: func(t *testing.T, c *Client) {
+                if c.retry.MaxAttempts != 7 {
+                    t.Fatalf("expected 7 attempts, got %d", c.retry.MaxAttempts)
+                }
+            },
+        },
+        {
+            name:    "max retries invalid",
+            env:     map[string]string{EnvServerURL: "http://server", EnvMaxRetries: "many"},
+            wantErr: EnvMaxRetries,
+        },
+        {
+            name: "initial delay",
+            env:  map[string]string{EnvServerURL: "http://server", EnvInitialDelayMs: "250"},
+            check: func(t *testing.T, c *Client) {
+                if c.retry.InitialInterval != 250*time.Millisecond {
+                    t.Fatalf("expected 250ms, got %v", c.retry.InitialInterval)
+                }
+            },
+        },
+        {
+            name:    "initial delay negative",
+            env:     map[string]string{EnvServerURL: "http://server", EnvInitialDelayMs: "-1"},
+            wantErr: EnvInitialDelayMs,
+        },
+        {
+            name: "max delay",
+            env:  map[string]string{EnvServerURL: "http://server", EnvMaxDelayMs: "3000"},
+            check: func(t *testing.T, c *Client) {
+                if c.retry.MaxInterval != 3*time.Second {
+                    t.Fatalf("expected 3s, got %v", c.retry.MaxInterval)
+                }
+            },
+        },
+        {
+            name:    "max delay below initial delay",
+            env:     map[string]string{EnvServerURL: "http://server", EnvInitialDelayMs: "2000", EnvMaxDelayMs: "1000"},
+            wantErr: EnvMaxDelayMs,
+        },
+        {
+            name: "timeout",
+            env:  map[string]string{EnvServerURL: "http://server", EnvTimeoutMs: "1500"},
+            check: func(t *testing.T, c *Client) {
+                if c.timeout != 1500*time.Millisecond {
+                    t.Fatalf("expected 1.5s, got %v", c.timeout)
+                }
+            },
+        },
+        {
+            name:    "timeout zero",
+            env:     map[string]string{EnvServerURL: "http://server", EnvTimeoutMs: "0"},
+            wantErr: EnvTimeoutMs,
+        },
+        {
+            name: "api key",
+            env:  map[string]string{EnvServerURL: "http://server", EnvAPIKey: "secret"},
+            check: func(t *testing.T, c *Client) {
+                if tr, ok := c.httpClient.Transport.(*apiKeyTransport); !ok || tr.key != "secret" {
+                    t.Fatalf("expected the API key transport, got %T", c.httpClient.Transport)
+                }
+            },
+        },
+    }
+
+    vars := []string{EnvServerURL, EnvMaxRetries, EnvInitialDelayMs, EnvMaxDelayMs, EnvTimeoutMs, EnvAPIKey}
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            for _, name := range vars {
+                t.Setenv(name, tt.env[name])
+            }
+            c, err := NewClientFromEnv(log.New(ioutil.Discard, "", 0))
+            if tt.wantErr != "" {
+                if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+                    t.Fatalf("expected an error naming %s, got %v", tt.wantErr, err)
+                }
+                return
+            }
+            if err != nil {
+                t.Fatalf("NewClientFromEnv: %v", err)
+            }
+            tt.check(t, c)
+        })
+    }
+}
+
+func TestWithAPIKey(t *testing.T) {
+    keys := make(chan string, 1)
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        keys <- r.Header.Get(APIKeyHeader)
+        w.Write([]byte(`{"result": "pending"}`))
+    }))
+    defer srv.Close()
+
+    c := newTestClient(srv.URL, WithAPIKey("secret"))
+    if _, err := c.RetrieveStatus(context.Background()); err != nil {
+        t.Fatalf("RetrieveStatus: %v", err)
+    }
+    if got := <-keys; got != "secret" {
+        t.Fatalf("expected the key in %s, got %q", APIKeyHeader, got)
+    }
+}
@@ -0,0 +1,150 @@
+package client
+
+import (
+    "context"
+    "sync"
+
+    "Video-Translation-Simulator/pkg/types"
+)
+
+// StatusUpdate is a status sent to a ClientGroup caller. Final is set on the
+// last update before the channel is closed, which carries the job's final
+// status or the error polling gave up with.
+type StatusUpdate struct {
+    Status string
+    Err    error
+    Final  bool
+}
+
+// ClientGroup lets many callers wait on the same job without each polling the
+// server. One goroutine polls using the client's backoff and every caller
+// gets the results on its own channel. Polling starts with the first caller
+// and stops once the job finishes or all callers have gone; a caller added
+// after that starts a new job, once the previous poll has wound down.
+//
+// A slow caller only ever misses intermediate updates, never the final one:
+// each channel holds just the latest update.
+type ClientGroup struct {
+    client *Client
+
+    mu      sync.Mutex
+    members map[<-chan StatusUpdate]*groupMember
+    current *groupRun // The poll in progress, nil when idle.
+    last    *groupRun // The most recent poll, possibly still winding down.
+}
+
+type groupMember struct {
+    ch   chan StatusUpdate
+    stop func() bool // Stops watching the caller's context.
+}
+
+type groupRun struct {
+    cancel context.CancelFunc
+    done   chan struct{} // Closed once run returns.
+}
+
+// NewClientGroup returns a ClientGroup polling with c.
+func NewClientGroup(c *Client) *ClientGroup {
+    return &ClientGroup{
+        client:  c,
+        members: make(map[<-chan StatusUpdate]*groupMember),
+    }
+}
+
+// Add registers a caller and returns the channel its updates arrive on. The
+// caller is removed, and the channel closed, when ctx is done.
+func (g *ClientGroup) Add(ctx context.Context) <-chan StatusUpdate {
+    ch := make(chan StatusUpdate, 1)
+    m := &groupMember{ch: ch}
+
+    g.mu.Lock()
+    defer g.mu.Unlock()
+    g.members[ch] = m
+    // Set under mu; the callback runs in its own goroutine, so it waits for Add to finish.
+    m.stop = context.AfterFunc(ctx, func() { g.Remove(ch) })
+    if g.current == nil {
+        pollCtx, cancel := context.WithCancel(context.Background())
+        prev := g.last
+        g.current = &groupRun{cancel: cancel, done: make(chan struct{})}
+        g.last = g.current
+        go g.run(pollCtx, g.current, prev)
+    }
+    return ch
+}
+
+// Remove deregisters the caller owning ch and closes ch. Removing the last
+// caller stops polling.
+func (g *ClientGroup) Remove(ch <-chan StatusUpdate) {
+    g.mu.Lock()
+    defer g.mu.Unlock()
+    m, ok := g.members[ch]
+    if !ok {
+        return
+    }
+    g.remove(m)
+    if len(g.members) == 0 && g.current != nil {
+        g.current.cancel()
+        g.current = nil
+    }
+}
+
+// remove deletes m and closes its channel. Called with mu held.
+func (g *ClientGroup) remove(m *groupMember) {
+    delete(g.members, m.ch)
+    m.stop()
+    close(m.ch)
+}
+
+// run polls until the job finishes, then sends the result to every caller
+// and closes their channels. It first waits for prev, a cancelled poll, to
+// return, so two polls are never in flight at once.
+func (g *ClientGroup) run(ctx context.Context, r *groupRun, prev *groupRun) {
+    defer close(r.done)
+    if prev != nil {
+        select {
+        case <-prev.done:
+        case <-ctx.Done():
+            return
+        }
+    }
+    status, err := g.client.poll(ctx, g.client.newStatusTracker(g.client.ownJobID()), func(status string) {
+        // The final status is sent below, marked as final.
+        if !types.Status(status).IsTerminal() {
+            g.broadcast(r, StatusUpdate{Status: status})
+        }
+    })
+
+    g.mu.Lock()
+    defer g.mu.Unlock()
+    defer r.cancel()
+    if g.current != r {
+        // Everyone left and the poll was cancelled.
+        return
+    }
+    g.current = nil
+    g.sendLocked(StatusUpdate{Status: status, Err: err, Final: true})
+    for _, m := range g.members {
+        g.remove(m)
+    }
+}
+
+// broadcast sends u to every caller, as long as r is still the current poll.
+func (g *ClientGroup) broadcast(r *groupRun, u StatusUpdate) {
+    g.mu.Lock()
+    defer g.mu.Unlock()
+    if g.current == r {
+        g.sendLocked(u)
+    }
+}
+
+// sendLocked replaces whatever update each caller hasn't read yet with u.
+// Called with mu held, so nothing else sends on the channels meanwhile.
+func (g *ClientGroup) sendLocked(u StatusUpdate) {
+    for _, m := range g.members {
+        select {
+        case <-m.ch:
+        default:
+        }
+        m.ch <- u
+    }
+}
@@ -0,0 +1,145 @@
+package client
+
+import (
+    "context"
+    "io"
+    "net/http"
+    "strings"
+    "sync"
+    "sync/atomic"
+    "testing"
+    "time"
+
+    "Video-Translation-Simulator/pkg/testutil"
+)
+
+// lastUpdate reads ch until it is closed and returns the last update.
+func lastUpdate(t *testing.T, ch <-chan StatusUpdate) StatusUpdate {
+    t.Helper()
+    var last StatusUpdate
+    timeout := time.After(5 * time.Second)
+    for {
+        select {
+        case u, ok := <-ch:
+            if !ok {
+                return last
+            }
+            last = u
+        case <-timeout:
+            t.Fatal("timed out waiting for the channel to close")
+        }
+    }
+}
+
+func TestClientGroupSharesOnePoller(t *testing.T) {
+    srv := testutil.NewDryRunServer()
+    defer srv.Close()
+    srv.QueueStatus("", "pending", "pending", "pending", "completed")
+
+    g := NewClientGroup(newTestClient(srv.URL, WithBackoff(constantBackoff(50*time.Millisecond))))
+
+    var wg sync.WaitGroup
+    results := make(chan StatusUpdate, 10)
+    for i := 0; i < 10; i++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            results <- lastUpdate(t, g.Add(context.Background()))
+        }()
+    }
+    wg.Wait()
+    close(results)
+
+    for u := range results {
+        if !u.Final || u.Status != "completed" || u.Err != nil {
+            t.Fatalf("expected a final completed update, got %+v", u)
+        }
+    }
+    if n := srv.Requests(""); n != 4 {
+        t.Fatalf("expected the callers to share 4 polls, got %d", n)
+    }
+}
+
+func TestClientGroupRemove(t *testing.T) {
+    srv := testutil.NewDryRunServer()
+    defer srv.Close()
+    srv.QueueStatus("", "pending", "pending", "completed")
+
+    g := NewClientGroup(newTestClient(srv.URL, WithBackoff(constantBackoff(50*time.Millisecond))))
+    ctx, cancel := context.WithCancel(context.Background())
+    cancelled := g.Add(ctx)
+    removed := g.Add(context.Background())
+    stays := g.Add(context.Background())
+
+    cancel()
+    g.Remove(removed)
+    if u := lastUpdate(t, cancelled); u.Final {
+        t.Fatalf("expected no final update for a cancelled caller, got %+v", u)
+    }
+    if u := lastUpdate(t, removed); u.Final {
+        t.Fatalf("expected no final update for a removed caller, got %+v", u)
+    }
+    if u := lastUpdate(t, stays); !u.Final || u.Status != "completed" {
+        t.Fatalf("expected the remaining caller to get completed, got %+v", u)
+    }
+}
+
+func TestClientGroupStopsWithoutCallers(t *testing.T) {
+    srv := testutil.NewDryRunServer()
+    defer srv.Close()
+    srv.QueueStatus("", "pending")
+
+    g := NewClientGroup(newTestClient(srv.URL, WithBackoff(constantBackoff(20*time.Millisecond))))
+    ch := g.Add(context.Background())
+    time.Sleep(50 * time.Millisecond)
+    g.Remove(ch)
+
+    time.Sleep(50 * time.Millisecond)
+    before := srv.Requests("")
+    time.Sleep(100 * time.Millisecond)
+    if after := srv.Requests(""); after != before {
+        t.Fatalf("expected polling to stop, requests went from %d to %d", before, after)
+    }
+}
+
+// slowTransport answers "pending" after a delay, even once the request is
+// cancelled, and records the most requests it had in flight at once.
+type slowTransport struct {
+    delay    time.Duration
+    inFlight atomic.Int32
+    peak     atomic.Int32
+}
+
+func (t *slowTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+    n := t.inFlight.Add(1)
+    defer t.inFlight.Add(-1)
+    for {
+        peak := t.peak.Load()
+        if n <= peak || t.peak.CompareAndSwap(peak, n) {
+            break
+        }
+    }
+    time.Sleep(t.delay)
+    return &http.Response{
+        StatusCode: http.StatusOK,
+        Header:     http.Header{"Content-Type": []string{"application/json"}},
+        Body:       io.NopCloser(strings.NewReader(`{"result":"pending"}`)),
+        Request:    req,
+    }, nil
+}
+
+func TestClientGroupWaitsForPreviousPoll(t *testing.T) {
+    rt := &slowTransport{delay: 100 * time.Millisecond}
+    g := NewClientGroup(newTestClient("http://unused", WithTransport(rt), WithBackoff(constantBackoff(0))))
+
+    first := g.Add(context.Background())
+    time.Sleep(20 * time.Millisecond)
+    g.Remove(first)
+    second := g.Add(context.Background())
+    time.Sleep(150 * time.Millisecond)
+    g.Remove(second)
+
+    if peak := rt.peak.Load(); peak != 1 {
+        t.Fatalf("expected one poll in flight at a time, got %d", peak)
+    }
+}
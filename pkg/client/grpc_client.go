@@ -0,0 +1,156 @@
+package client
+
+import (
+    "context"
+    "errors"
+    "io"
+    "log"
+    "time"
+
+    "google.golang.org/grpc"
+    "google.golang.org/grpc/codes"
+    "google.golang.org/grpc/status"
+
+    pb "Video-Translation-Simulator/pkg/grpc"
+    "Video-Translation-Simulator/pkg/types"
+)
+
+// GRPCClient polls the translation server over gRPC instead of HTTP, with the
+// same retry policy as Client.
+type GRPCClient struct {
+    conn    *grpc.ClientConn
+    service pb.TranslationServiceClient
+    retry   RetryPolicy
+    timeout time.Duration
+    logger  *log.Logger
+}
+
+// NewGRPCClient returns a GRPCClient for the server at target, e.g.
+// "localhost:9000". opts must set the transport credentials, e.g.
+// grpc.WithTransportCredentials(insecure.NewCredentials()) for a server
+// without TLS. No connection is made until the first call. Close the client
+// when done.
+func NewGRPCClient(target string, opts ...grpc.DialOption) (*GRPCClient, error) {
+    conn, err := grpc.NewClient(target, opts...)
+    if err != nil {
+        return nil, err
+    }
+    return &GRPCClient{
+        conn:    conn,
+        service: pb.NewTranslationServiceClient(conn),
+        retry:   *NewRetryPolicy(),
+        timeout: 5 * time.Second,
+        logger:  log.Default(),
+    }, nil
+}
+
+// SetRetryPolicy replaces the retry policy with a copy of p, fixed up like
+// WithRetryPolicy does. A nil p is ignored.
+func (g *GRPCClient) SetRetryPolicy(p *RetryPolicy) {
+    if p != nil {
+        g.retry = p.sanitized()
+    }
+}
+
+// SetLogger replaces the logger, log.Default() unless set.
+func (g *GRPCClient) SetLogger(logger *log.Logger) {
+    g.logger = logger
+}
+
+// Close closes the connection to the server.
+func (g *GRPCClient) Close() error {
+    return g.conn.Close()
+}
+
+// GetStatus asks the server once for the status of the job created with
+// jobID, or of the implicit /status job when jobID is empty.
+func (g *GRPCClient) GetStatus(ctx context.Context, jobID string) (string, error) {
+    ctx, cancel := context.WithTimeout(ctx, g.timeout)
+    defer cancel()
+    resp, err := g.service.GetStatus(ctx, &pb.StatusRequest{JobId: jobID})
+    if err != nil {
+        return "", err
+    }
+    return resp.GetResult(), nil
+}
+
+// WaitForCompletion polls the job with GetStatus until it reaches a final
+// status, backing off between polls as Client does. Failures the server may
+// recover from, such as it being unavailable, are retried up to the policy's
+// MaxAttempts; others, such as an unknown job, are returned straight away.
+func (g *GRPCClient) WaitForCompletion(ctx context.Context, jobID string) (string, error) {
+    var delay time.Duration
+    start := time.Now()
+    for attempt := 1; ; attempt++ {
+        result, err := g.GetStatus(ctx, jobID)
+        if err != nil {
+            g.logger.Printf("Attempt %d: Error fetching status: %v", attempt, err)
+            if ctxErr := ctx.Err(); ctxErr != nil {
+                return "", ctxErr
+            }
+            if !retryableCode(status.Code(err)) {
+                return "", err
+            }
+            if attempt >= g.retry.MaxAttempts {
+                return "", errors.New("max retries reached")
+            }
+        } else {
+            g.logger.Printf("Attempt %d: Received status: %s", attempt, result)
+            if types.Status(result).IsTerminal() {
+                return result, nil
+            }
+        }
+
+        if g.retry.elapsedExceeded(start) {
+            return "", ErrMaxElapsedTime
+        }
+        base, jitter := g.retry.nextInterval(delay)
+        delay = base + jitter
+        g.logger.Printf("Next attempt in %v", delay)
+        timer := time.NewTimer(delay)
+        select {
+        case <-ctx.Done():
+            timer.Stop()
+            return "", ctx.Err()
+        case <-timer.C:
+        }
+    }
+}
+
+// retryableCode reports whether a call failing with code may succeed later.
+func retryableCode(code codes.Code) bool {
+    switch code {
+    case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Aborted:
+        return true
+    }
+    return false
+}
+
+// Watch streams the job's status from the server, which sends it every
+// second until the job reaches a final status. The channel is closed when the
+// stream ends or ctx is done.
+func (g *GRPCClient) Watch(ctx context.Context, jobID string) (<-chan StatusEvent, error) {
+    stream, err := g.service.WatchStatus(ctx, &pb.StatusRequest{JobId: jobID})
+    if err != nil {
+        return nil, err
+    }
+    events := make(chan StatusEvent)
+    go func() {
+        defer close(events)
+        for {
+            resp, err := stream.Recv()
+            if err != nil {
+                if err != io.EOF && ctx.Err() == nil {
+                    g.logger.Printf("Status stream ended with error: %v", err)
+                }
+                return
+            }
+            select {
+            case events <- StatusEvent{Result: resp.GetResult(), ReceivedAt: time.Now()}:
+            case <-ctx.Done():
+                return
+            }
+        }
+    }()
+    return events, nil
+}
@@ -0,0 +1,61 @@
+package client
+
+import (
+    "context"
+    "io"
+    "log"
+    "net"
+    "testing"
+    "time"
+
+    "google.golang.org/grpc"
+    "google.golang.org/grpc/codes"
+    "google.golang.org/grpc/credentials/insecure"
+    "google.golang.org/grpc/status"
+
+    "Video-Translation-Simulator/pkg/server"
+    "Video-Translation-Simulator/pkg/testutil/fixtures"
+)
+
+func TestGRPCClient(t *testing.T) {
+    srv, err := server.NewServer(server.WithConfig(fixtures.FixtureFastServer))
+    if err != nil {
+        t.Fatalf("NewServer: %v", err)
+    }
+    lis, err := net.Listen("tcp", "127.0.0.1:0")
+    if err != nil {
+        t.Fatalf("listen: %v", err)
+    }
+    gs := grpc.NewServer()
+    srv.RegisterGRPC(gs)
+    go gs.Serve(lis)
+    defer gs.Stop()
+
+    g, err := NewGRPCClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+    if err != nil {
+        t.Fatalf("NewGRPCClient: %v", err)
+    }
+    defer g.Close()
+    g.SetLogger(log.New(io.Discard, "", 0))
+    g.SetRetryPolicy(&RetryPolicy{
+        MaxAttempts:     20,
+        InitialInterval: 100 * time.Millisecond,
+        MaxInterval:     200 * time.Millisecond,
+        Multiplier:      2,
+    })
+
+    ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+    defer cancel()
+    if result, err := g.WaitForCompletion(ctx, ""); err != nil || result != "completed" {
+        t.Fatalf("expected completed, got %q, %v", result, err)
+    }
+
+    // An unknown job is not worth retrying.
+    start := time.Now()
+    if _, err := g.WaitForCompletion(ctx, "nope"); status.Code(err) != codes.NotFound {
+        t.Fatalf("expected NotFound, got %v", err)
+    }
+    if elapsed := time.Since(start); elapsed > time.Second {
+        t.Fatalf("expected NotFound without retries, took %v", elapsed)
+    }
+}
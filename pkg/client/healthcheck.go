@@ -0,0 +1,179 @@
+package client
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "net/http"
+    "sync"
+    "time"
+)
+
+// DefaultHealthCheckInterval is used by Start when Interval is not positive.
+const DefaultHealthCheckInterval = 10 * time.Second
+
+// ErrCircuitOpen is returned for status requests made while a running
+// HealthChecker reports the server as unhealthy. They are retried like any
+// other failure, so polling resumes once the server recovers.
+var ErrCircuitOpen = errors.New("circuit open: server is unhealthy")
+
+// HealthChecker periodically calls GET /healthz on the client's server and
+// tracks whether it is reachable. It reports a server as unhealthy after
+// UnhealthyThreshold consecutive failures, and as recovered after
+// HealthyThreshold consecutive successes following that. While it runs it acts
+// as the client's circuit breaker: the circuit opens when the server turns
+// unhealthy, failing status requests with ErrCircuitOpen without sending
+// them, and closes again on recovery.
+type HealthChecker struct {
+    Interval           time.Duration
+    UnhealthyThreshold int
+    HealthyThreshold   int
+    OnUnhealthy        func(error) // Optional, called when the server turns unhealthy.
+    OnRecovery         func()      // Optional, called when the server recovers.
+
+    client *Client
+
+    mu          sync.Mutex
+    healthy     bool
+    failures    int
+    successes   int
+    lastCheckAt time.Time
+    stop        chan struct{}
+    done        chan struct{}
+}
+
+// NewHealthChecker returns a HealthChecker for c's server checking every
+// interval, which turns unhealthy after 3 failures and recovers after 2 successes.
+func NewHealthChecker(c *Client, interval time.Duration) *HealthChecker {
+    return &HealthChecker{
+        Interval:           interval,
+        UnhealthyThreshold: 3,
+        HealthyThreshold:   2,
+        client:             c,
+        healthy:            true,
+    }
+}
+
+// Start begins checking in the background until ctx is done or Stop is called,
+// every Interval or DefaultHealthCheckInterval if that is not positive.
+// Calling Start on a running checker has no effect.
+func (h *HealthChecker) Start(ctx context.Context) {
+    h.mu.Lock()
+    defer h.mu.Unlock()
+    if h.stop != nil {
+        return
+    }
+    interval := h.Interval
+    if interval <= 0 {
+        interval = DefaultHealthCheckInterval
+    }
+    h.stop, h.done = make(chan struct{}), make(chan struct{})
+    h.client.circuit.Store(h)
+
+    go func(stop, done chan struct{}) {
+        defer close(done)
+        defer h.client.circuit.CompareAndSwap(h, nil)
+        ticker := time.NewTicker(interval)
+        defer ticker.Stop()
+        for {
+            err := h.check(ctx)
+            // A check cut short by ctx says nothing about the server.
+            if ctx.Err() != nil {
+                return
+            }
+            h.record(err)
+            select {
+            case <-ctx.Done():
+                return
+            case <-stop:
+                return
+            case <-ticker.C:
+            }
+        }
+    }(h.stop, h.done)
+}
+
+// Stop stops the background checks, waiting for one in progress to finish,
+// and closes the client's circuit.
+func (h *HealthChecker) Stop() {
+    h.mu.Lock()
+    stop, done := h.stop, h.done
+    h.stop, h.done = nil, nil
+    h.mu.Unlock()
+
+    if stop != nil {
+        close(stop)
+        <-done
+    }
+}
+
+// IsHealthy reports whether the server is currently considered healthy.
+func (h *HealthChecker) IsHealthy() bool {
+    h.mu.Lock()
+    defer h.mu.Unlock()
+    return h.healthy
+}
+
+// LastCheckAt returns when the server was last checked, zero if never.
+func (h *HealthChecker) LastCheckAt() time.Time {
+    h.mu.Lock()
+    defer h.mu.Unlock()
+    return h.lastCheckAt
+}
+
+// check performs a single GET /healthz, bounded by the client's request timeout.
+func (h *HealthChecker) check(ctx context.Context) error {
+    ctx, cancel := context.WithTimeout(ctx, h.client.timeout)
+    defer cancel()
+
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.client.BaseURL+"/healthz", nil)
+    if err != nil {
+        return err
+    }
+    resp, err := h.client.httpClient.Do(req)
+    if err != nil {
+        return err
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+        return fmt.Errorf("health check returned %d", resp.StatusCode)
+    }
+    return nil
+}
+
+// record updates the consecutive counters and fires the hooks on a state change.
+func (h *HealthChecker) record(err error) {
+    h.mu.Lock()
+    h.lastCheckAt = time.Now()
+    var onUnhealthy, onRecovery bool
+    if err != nil {
+        h.failures++
+        h.successes = 0
+        if h.healthy && h.failures >= h.UnhealthyThreshold {
+            h.healthy = false
+            onUnhealthy = true
+        }
+    } else {
+        h.successes++
+        h.failures = 0
+        if !h.healthy && h.successes >= h.HealthyThreshold {
+            h.healthy = true
+            onRecovery = true
+        }
+    }
+    h.mu.Unlock()
+
+    // Hooks run without the lock so they may call back into the checker.
+    if onUnhealthy {
+        h.client.logf("Server marked unhealthy: %v", err)
+        if h.OnUnhealthy != nil {
+            h.OnUnhealthy(err)
+        }
+    }
+    if onRecovery {
+        h.client.logf("Server recovered")
+        if h.OnRecovery != nil {
+            h.OnRecovery()
+        }
+    }
+}
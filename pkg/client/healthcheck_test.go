@@ -0,0 +1,138 @@
+package client
+
+import (
+    "context"
+    "errors"
+    "net/http"
+    "net/http/httptest"
+    "sync/atomic"
+    "testing"
+    "time"
+)
+
+func TestHealthCheckerTransitions(t *testing.T) {
+    var failing atomic.Bool
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if r.URL.Path != "/healthz" || failing.Load() {
+            w.WriteHeader(http.StatusServiceUnavailable)
+            return
+        }
+        w.WriteHeader(http.StatusOK)
+    }))
+    defer srv.Close()
+
+    unhealthy := make(chan error, 1)
+    recovered := make(chan struct{}, 1)
+    h := NewHealthChecker(newTestClient(srv.URL), 10*time.Millisecond)
+    h.OnUnhealthy = func(err error) { unhealthy <- err }
+    h.OnRecovery = func() { recovered <- struct{}{} }
+
+    h.Start(context.Background())
+    defer h.Stop()
+
+    failing.Store(true)
+    select {
+    case err := <-unhealthy:
+        if err == nil {
+            t.Fatal("expected the failure reason to be passed to OnUnhealthy")
+        }
+    case <-time.After(time.Second):
+        t.Fatal("OnUnhealthy was never called")
+    }
+    if h.IsHealthy() {
+        t.Fatal("expected checker to report unhealthy")
+    }
+
+    failing.Store(false)
+    select {
+    case <-recovered:
+    case <-time.After(time.Second):
+        t.Fatal("OnRecovery was never called")
+    }
+    if !h.IsHealthy() {
+        t.Fatal("expected checker to report healthy after recovery")
+    }
+    if time.Since(h.LastCheckAt()) > time.Second {
+        t.Fatalf("LastCheckAt not updated: %v", h.LastCheckAt())
+    }
+}
+
+func TestHealthCheckerStop(t *testing.T) {
+    var checks atomic.Int32
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        checks.Add(1)
+    }))
+    defer srv.Close()
+
+    h := NewHealthChecker(newTestClient(srv.URL), 5*time.Millisecond)
+    h.Start(context.Background())
+    time.Sleep(30 * time.Millisecond)
+    h.Stop()
+
+    after := checks.Load()
+    time.Sleep(30 * time.Millisecond)
+    if checks.Load() != after {
+        t.Fatal("health checks continued after Stop")
+    }
+}
+
+func TestHealthCheckerOpensCircuit(t *testing.T) {
+    var failing atomic.Bool
+    var polls atomic.Int32
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if r.URL.Path == "/status" {
+            polls.Add(1)
+            w.Write([]byte(`{"result":"pending"}`))
+            return
+        }
+        if failing.Load() {
+            w.WriteHeader(http.StatusServiceUnavailable)
+        }
+    }))
+    defer srv.Close()
+
+    c := newTestClient(srv.URL)
+    unhealthy := make(chan error, 1)
+    h := NewHealthChecker(c, 5*time.Millisecond)
+    h.OnUnhealthy = func(err error) { unhealthy <- err }
+    h.Start(context.Background())
+
+    failing.Store(true)
+    select {
+    case <-unhealthy:
+    case <-time.After(time.Second):
+        t.Fatal("OnUnhealthy was never called")
+    }
+    if _, err := c.RetrieveStatus(context.Background()); !errors.Is(err, ErrCircuitOpen) {
+        t.Fatalf("expected ErrCircuitOpen, got %v", err)
+    }
+    if polls.Load() != 0 {
+        t.Fatal("expected no request to reach the server while the circuit is open")
+    }
+
+    // Stopping the checker closes the circuit, and its cancelled check is not
+    // reported as another failure.
+    h.Stop()
+    if _, err := c.RetrieveStatus(context.Background()); err != nil {
+        t.Fatalf("expected the circuit to close after Stop, got %v", err)
+    }
+}
+
+func TestHealthCheckerIgnoresCancelledChecks(t *testing.T) {
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        <-r.Context().Done()
+    }))
+    defer srv.Close()
+
+    h := NewHealthChecker(newTestClient(srv.URL), 0)
+    h.UnhealthyThreshold = 1
+    h.OnUnhealthy = func(err error) { t.Errorf("unexpected OnUnhealthy: %v", err) }
+    ctx, cancel := context.WithCancel(context.Background())
+    h.Start(ctx)
+    time.Sleep(20 * time.Millisecond)
+    cancel()
+    h.Stop()
+    if !h.IsHealthy() {
+        t.Fatal("expected a check cancelled by ctx not to count as a failure")
+    }
+}
@@ -1,81 +1,99 @@
 package client
 
 import (
+    "bytes"
+    "context"
     "encoding/json"
     "io/ioutil"
     "log"
     "net/http"
+    "net/http/httptest"
     "os"
+    "strings"
     "testing"
     "time"
-)
 
-func TestClientHandleStatusRequest(t *testing.T) {
-    logger := log.New(os.Stdout, "TestLog: ", log.LstdFlags)
-    c := NewClient("http://localhost:8080", logger)
+    "github.com/prometheus/client_golang/prometheus"
 
-    // First we initialize a test client server
-    server := http.Server{
-        Addr:    ":9090",
-        Handler: http.HandlerFunc(c.HandleStatusRequest),
-    }
+    "Video-Translation-Simulator/pkg/testutil/fixtures"
+)
 
-    // Start the server in a seperate goroutine to keep it open to requests without blockers
-    go func() {
-        if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-            logger.Fatalf("ListenAndServe(): %v", err)
-        }
-    }()
-    defer server.Close()
+// serverURL is the address of the translation server TestMain runs in-process.
+var serverURL string
+
+// TestMain runs a translation server for the integration tests below, with a
+// one second delay so they finish quickly.
+func TestMain(m *testing.M) {
+    _, ts := fixtures.NewFixtureServer(fixtures.FixtureFastServer)
+    serverURL = ts.URL
 
-    client := &http.Client{}
+    code := m.Run()
+    ts.Close()
+    os.Exit(code)
+}
 
-    // Simulating user requests. User may click repeatedly in the beginning
-    // Initial rapid requests.
-    for i := 0; i < 100; i++ {
-        resp, err := client.Get("http://localhost:9090/status")
+// startJob makes sure the shared server has a job in progress. Its job starts
+// when the server is created and only restarts after a final status has been
+// polled, so by the time a test runs it may well have finished already.
+func startJob(t *testing.T) {
+    t.Helper()
+    for i := 0; i < 3; i++ {
+        resp, err := http.Get(serverURL + "/status")
         if err != nil {
-            t.Fatalf("Request failed: %v", err)
+            t.Fatalf("Polling translation server failed: %v", err)
         }
+        var result map[string]string
+        json.NewDecoder(resp.Body).Decode(&result)
         resp.Body.Close()
-        time.Sleep(10 * time.Millisecond)
+        if result["result"] == "pending" {
+            return
+        }
     }
+    t.Fatal("translation server did not start a new job")
+}
+
+func TestClientHandleStatusRequest(t *testing.T) {
+    logger := log.New(os.Stdout, "TestLog: ", log.LstdFlags)
+    startJob(t)
+    c := NewClient(serverURL, logger)
 
-    // Slowing down requests. They slow down later on
+    // First we initialize a test client server
+    clientServer := httptest.NewServer(http.HandlerFunc(c.HandleStatusRequest))
+    defer clientServer.Close()
+
+    client := clientServer.Client()
+
+    // Simulating user requests. User may click repeatedly in the beginning;
+    // all but the first should be answered from the client's cached status.
     for i := 0; i < 10; i++ {
-        resp, err := client.Get("http://localhost:9090/status")
+        resp, err := client.Get(clientServer.URL + "/status")
         if err != nil {
             t.Fatalf("Request failed: %v", err)
         }
         resp.Body.Close()
-        time.Sleep(2 * time.Second)
+        if resp.StatusCode != http.StatusOK {
+            t.Fatalf("Request %d: expected 200, got %s", i+1, resp.Status)
+        }
     }
+
+    // The slower requests later on are covered by the DryRunServer tests in dryrun_test.go.
 }
 
 func TestClientHandleErrors(t *testing.T) {
     logger := log.New(os.Stdout, "TestLog: ", log.LstdFlags)
-    c := NewClient("http://localhost:8080", logger)
+    startJob(t)
+    c := NewClient(serverURL, logger)
 
     // First we initialize a test client server
-    server := http.Server{
-        Addr:    ":9090",
-        Handler: http.HandlerFunc(c.HandleStatusRequest),
-    }
-
-    // Start the server in a seperate goroutine to keep it open to requests without blockers.
-    go func() {
-        if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-            logger.Fatalf("ListenAndServe(): %v", err)
-        }
-    }()
-    defer server.Close()
+    clientServer := httptest.NewServer(http.HandlerFunc(c.HandleStatusRequest))
+    defer clientServer.Close()
 
     // Simulate user requests with potential errors.
     // Set timeout higher than server delay. Hardcoded for simplicity and simulation.
-    client := &http.Client{Timeout: 25 * time.Second} 
+    client := &http.Client{Timeout: 5 * time.Second}
 
     for i := 0; i < 5; i++ {
-        resp, err := client.Get("http://localhost:9090/status")
+        resp, err := client.Get(clientServer.URL + "/status")
         if err != nil {
             t.Fatalf("Request failed: %v", err)
         }
@@ -96,7 +114,113 @@ func TestClientHandleErrors(t *testing.T) {
             break
         }
 
-        time.Sleep(5 * time.Second)
+        time.Sleep(time.Second)
+    }
+
+}
+
+func TestLongPollIntegration(t *testing.T) {
+    startJob(t)
+    c := newTestClient(serverURL, WithLongPoll(5*time.Second))
+
+    // The job takes a second, well within the wait, so one request is enough.
+    start := time.Now()
+    status, err := c.RetrieveStatus(context.Background())
+    if err != nil {
+        t.Fatalf("RetrieveStatus: %v", err)
+    }
+    if status != "completed" && status != "error" {
+        t.Fatalf("expected a final status from a single long poll, got %q", status)
+    }
+    if elapsed := time.Since(start); elapsed > 3*time.Second {
+        t.Fatalf("expected the response once the job finished, took %v", elapsed)
     }
 
+    // A wait shorter than the job answers 202 with the pending status.
+    startJob(t)
+    c = newTestClient(serverURL, WithLongPoll(100*time.Millisecond))
+    if status, err := c.RetrieveStatus(context.Background()); err != nil || status != "pending" {
+        t.Fatalf("expected pending once the wait ran out, got %q, %v", status, err)
+    }
+}
+
+func TestMetricsIntegration(t *testing.T) {
+    startJob(t)
+    reg := prometheus.NewRegistry()
+    c := newTestClient(serverURL, WithMetricsRegistry(reg), WithRetryPolicy(NewRetryPolicy().WithInitialInterval(100*time.Millisecond).WithMaxInterval(200*time.Millisecond)))
+    clientServer := httptest.NewServer(http.HandlerFunc(c.HandleStatusRequest))
+    defer clientServer.Close()
+
+    deadline := time.Now().Add(10 * time.Second)
+    for {
+        resp, err := http.Get(clientServer.URL + "/status")
+        if err != nil {
+            t.Fatalf("Request failed: %v", err)
+        }
+        var result map[string]string
+        json.NewDecoder(resp.Body).Decode(&result)
+        resp.Body.Close()
+        if result["result"] == "completed" || result["result"] == "error" {
+            break
+        }
+        if time.Now().After(deadline) {
+            t.Fatal("job did not finish in time")
+        }
+        time.Sleep(100 * time.Millisecond)
+    }
+
+    families, err := reg.Gather()
+    if err != nil {
+        t.Fatalf("Gather: %v", err)
+    }
+    got := make(map[string]float64)
+    for _, f := range families {
+        m := f.GetMetric()[0]
+        switch {
+        case m.GetCounter() != nil:
+            got[f.GetName()] = m.GetCounter().GetValue()
+        case m.GetHistogram() != nil:
+            got[f.GetName()] = float64(m.GetHistogram().GetSampleCount())
+        }
+    }
+    if got["client_poll_attempts_total"] == 0 {
+        t.Errorf("expected client_poll_attempts_total to count the polls, got %v", got)
+    }
+    if got["client_poll_duration_seconds"] != 1 {
+        t.Errorf("expected one client_poll_duration_seconds observation, got %v", got)
+    }
+    if got["client_backoff_delay_seconds"] == 0 {
+        t.Errorf("expected client_backoff_delay_seconds observations, got %v", got)
+    }
+}
+
+func TestMetricsRegistryConflicts(t *testing.T) {
+    if c := newTestClient(serverURL, WithMetricsRegistry(nil)); c.metrics != nil {
+        t.Fatal("expected a nil registry to be ignored")
+    }
+
+    reg := prometheus.NewRegistry()
+    reg.MustRegister(prometheus.NewGauge(prometheus.GaugeOpts{Name: "client_poll_attempts_total", Help: "Something else."}))
+    var logs bytes.Buffer
+    c := NewClient(serverURL, log.New(&logs, "", 0), WithMetricsRegistry(reg))
+    if c.metrics == nil {
+        t.Fatal("expected the metrics to be usable despite the conflict")
+    }
+    c.metrics.recordAttempt()
+    c.metrics.recordDelay(time.Second)
+    if !strings.Contains(logs.String(), "client_poll_attempts_total") {
+        t.Fatalf("expected the conflict to be logged, got %q", logs.String())
+    }
+
+    families, err := reg.Gather()
+    if err != nil {
+        t.Fatalf("Gather: %v", err)
+    }
+    names := map[string]bool{}
+    for _, f := range families {
+        names[f.GetName()] = true
+    }
+    if !names["client_backoff_delay_seconds"] || !names["client_poll_duration_seconds"] {
+        t.Fatalf("expected the other metrics to be registered, got %v", names)
+    }
 }
@@ -0,0 +1,64 @@
+package client
+
+import (
+    "bytes"
+    "context"
+    "net/http"
+    "strconv"
+    "testing"
+    "time"
+)
+
+func TestInterceptorMalformedJSON(t *testing.T) {
+    srv := newDelayedServer(time.Hour)
+    defer srv.Close()
+
+    c := newTestClient(srv.URL, WithResponseInterceptor(func(resp *http.Response, body []byte) (*http.Response, []byte, error) {
+        return resp, body[:len(body)/2], nil
+    }))
+    if status, err := c.RetrieveStatus(context.Background()); err == nil {
+        t.Fatalf("expected an error decoding truncated JSON, got status %q", status)
+    }
+}
+
+func TestInterceptorRewritesStatus(t *testing.T) {
+    srv := newDelayedServer(time.Hour)
+    defer srv.Close()
+
+    // The job is left pending on the first poll, as it can't fail before it starts.
+    responses := 0
+    c := newTestClient(srv.URL, WithBackoff(constantBackoff(0)), WithResponseInterceptor(func(resp *http.Response, body []byte) (*http.Response, []byte, error) {
+        if responses++; responses == 1 {
+            return resp, body, nil
+        }
+        return resp, bytes.Replace(body, []byte("pending"), []byte("error"), 1), nil
+    }))
+    status, err := c.WaitForCompletion(context.Background())
+    if err != nil || status != "error" {
+        t.Fatalf("expected the rewritten error status, got %q, %v", status, err)
+    }
+}
+
+func TestInterceptorAddsMissingHeaders(t *testing.T) {
+    srv := newDelayedServer(time.Hour)
+    defer srv.Close()
+
+    reset := time.Now().Add(time.Minute).Truncate(time.Second)
+    c := newTestClient(srv.URL, WithResponseInterceptor(func(resp *http.Response, body []byte) (*http.Response, []byte, error) {
+        resp.Header.Set("X-RateLimit-Limit", "10")
+        resp.Header.Set("X-RateLimit-Remaining", "0")
+        resp.Header.Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+        return resp, body, nil
+    }))
+    if _, err := c.RetrieveStatus(context.Background()); err != nil {
+        t.Fatalf("RetrieveStatus: %v", err)
+    }
+
+    state, ok := c.RateLimit()
+    if !ok || state.Limit != 10 || state.Remaining != 0 || !state.Reset.Equal(reset) {
+        t.Fatalf("expected the injected rate limit, got %+v (ok=%v)", state, ok)
+    }
+    if _, limited := c.rateLimitWait(); !limited {
+        t.Fatal("expected the client to wait for the injected rate limit to reset")
+    }
+}
@@ -0,0 +1,138 @@
+package client
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "fmt"
+    "io"
+    "net/http"
+    "net/url"
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// JobSummary describes a job listed by ListJobs or updated by UpdateJob.
+type JobSummary struct {
+    JobID     string            `json:"job_id"`
+    Status    string            `json:"status"`
+    Metadata  map[string]string `json:"metadata,omitempty"`
+    Labels    map[string]string `json:"labels,omitempty"`
+    Priority  int               `json:"priority"`
+    WorkerID  int               `json:"worker_id"` // Of the server worker owning the job, for debugging.
+    FileSizeBytes int64         `json:"file_size_bytes,omitempty"` // Of the file sent with MultipartUploadClient.
+    CreatedAt time.Time         `json:"created_at"`
+    NextJobID string            `json:"next_job_id,omitempty"` // Set once a job with a next job completes.
+}
+
+// JobFilter picks the jobs ListJobs returns. The zero JobFilter lists every
+// job the server holds.
+type JobFilter struct {
+    // Tags must all be in a job's metadata: "key:value" for key set to value,
+    // or a bare "key" for key set at all.
+    Tags []string
+    // Selector picks jobs with all of these labels, set with WithJobLabels.
+    Selector map[string]string
+    // Limit and Offset page through the jobs, oldest first. A zero Limit
+    // lists them all.
+    Limit  int
+    Offset int
+}
+
+// query encodes the filter as GET /jobs parameters.
+func (f JobFilter) query() url.Values {
+    q := url.Values{}
+    for _, tag := range f.Tags {
+        q.Add("tag", tag)
+    }
+    if len(f.Selector) > 0 {
+        terms := make([]string, 0, len(f.Selector))
+        for key, value := range f.Selector {
+            terms = append(terms, key+"="+value)
+        }
+        sort.Strings(terms)
+        q.Set("selector", strings.Join(terms, ","))
+    }
+    if f.Limit > 0 {
+        q.Set("limit", strconv.Itoa(f.Limit))
+    }
+    if f.Offset > 0 {
+        q.Set("offset", strconv.Itoa(f.Offset))
+    }
+    return q
+}
+
+// ListJobs lists the jobs created with POST /jobs which match filter, oldest
+// first, with GET /jobs.
+func (c *Client) ListJobs(ctx context.Context, filter JobFilter) ([]*JobSummary, error) {
+    ctx, cancel := context.WithTimeout(ctx, c.timeout)
+    defer cancel()
+    target := c.serverURL() + "/jobs"
+    if q := filter.query(); len(q) > 0 {
+        target += "?" + q.Encode()
+    }
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+    if err != nil {
+        return nil, err
+    }
+    resp, err := c.httpClient.Do(req)
+    if err != nil {
+        return nil, err
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+        msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+        return nil, fmt.Errorf("listing jobs: server responded with %d: %s", resp.StatusCode, bytes.TrimSpace(msg))
+    }
+
+    var list struct {
+        Jobs []*JobSummary `json:"jobs"`
+    }
+    if err := json.NewDecoder(io.LimitReader(resp.Body, c.maxResponseBytes)).Decode(&list); err != nil {
+        return nil, fmt.Errorf("decoding job list: %w", err)
+    }
+    return list.Jobs, nil
+}
+
+// JobPatch is the change UpdateJob makes to a job, sent as a JSON merge patch:
+// fields left nil are unchanged.
+type JobPatch struct {
+    // Metadata entries are set to their values, and removed when nil.
+    // Entries not listed are kept.
+    Metadata map[string]*string `json:"metadata,omitempty"`
+    Priority *int               `json:"priority,omitempty"`
+}
+
+// UpdateJob changes the metadata and priority of the job created with jobID
+// on POST /jobs, with PATCH /jobs/{id}, returning the job as updated. The
+// server refuses to change a finished job with 409.
+func (c *Client) UpdateJob(ctx context.Context, jobID string, patch JobPatch) (*JobSummary, error) {
+    body, err := json.Marshal(patch)
+    if err != nil {
+        return nil, err
+    }
+    ctx, cancel := context.WithTimeout(ctx, c.timeout)
+    defer cancel()
+    req, err := http.NewRequestWithContext(ctx, http.MethodPatch, c.serverURL()+"/jobs/"+url.PathEscape(jobID), bytes.NewReader(body))
+    if err != nil {
+        return nil, err
+    }
+    req.Header.Set("Content-Type", "application/merge-patch+json")
+    resp, err := c.httpClient.Do(req)
+    if err != nil {
+        return nil, err
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+        msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+        return nil, fmt.Errorf("updating job: server responded with %d: %s", resp.StatusCode, bytes.TrimSpace(msg))
+    }
+
+    var summary JobSummary
+    if err := json.NewDecoder(io.LimitReader(resp.Body, c.maxResponseBytes)).Decode(&summary); err != nil {
+        return nil, fmt.Errorf("decoding updated job: %w", err)
+    }
+    return &summary, nil
+}
@@ -0,0 +1,123 @@
+package client
+
+import (
+    "context"
+    "reflect"
+    "strings"
+    "testing"
+
+    "Video-Translation-Simulator/pkg/testutil/fixtures"
+)
+
+func TestListJobs(t *testing.T) {
+    _, ts := fixtures.NewFixtureServer(fixtures.FixtureSlowServer)
+    defer ts.Close()
+
+    for id, lang := range map[string]string{"en-1": "en", "de-1": "de", "en-2": "en"} {
+        c := newTestClient(ts.URL, WithJobMetadata(map[string]string{"source_lang": lang}))
+        if _, err := c.SubmitJob(context.Background(), id); err != nil {
+            t.Fatalf("SubmitJob: %v", err)
+        }
+    }
+
+    c := newTestClient(ts.URL)
+    ids := func(filter JobFilter) []string {
+        t.Helper()
+        jobs, err := c.ListJobs(context.Background(), filter)
+        if err != nil {
+            t.Fatalf("ListJobs: %v", err)
+        }
+        var ids []string
+        for _, job := range jobs {
+            if job.Status != "pending" {
+                t.Fatalf("expected %s to be pending, got %q", job.JobID, job.Status)
+            }
+            ids = append(ids, job.JobID)
+        }
+        return ids
+    }
+
+    all := ids(JobFilter{})
+    if len(all) != 3 {
+        t.Fatalf("expected 3 jobs, got %v", all)
+    }
+    english := ids(JobFilter{Tags: []string{"source_lang:en"}})
+    if len(english) != 2 || english[0] == "de-1" || english[1] == "de-1" {
+        t.Fatalf("expected the two English jobs, got %v", english)
+    }
+    if page := ids(JobFilter{Tags: []string{"source_lang:en"}, Limit: 1, Offset: 1}); !reflect.DeepEqual(page, english[1:]) {
+        t.Fatalf("expected the second English job, got %v", page)
+    }
+    if none := ids(JobFilter{Tags: []string{"source_lang:fr"}}); len(none) != 0 {
+        t.Fatalf("expected no French jobs, got %v", none)
+    }
+}
+
+func TestListJobsBySelector(t *testing.T) {
+    _, ts := fixtures.NewFixtureServer(fixtures.FixtureSlowServer)
+    defer ts.Close()
+
+    for id, labels := range map[string]map[string]string{
+        "prod-high": {"env": "prod", "priority": "high"},
+        "prod-low":  {"env": "prod", "priority": "low"},
+        "dev-high":  {"env": "dev", "priority": "high"},
+    } {
+        if _, err := newTestClient(ts.URL, WithJobLabels(labels)).SubmitJob(context.Background(), id); err != nil {
+            t.Fatalf("SubmitJob: %v", err)
+        }
+    }
+
+    c := newTestClient(ts.URL)
+    jobs, err := c.ListJobs(context.Background(), JobFilter{Selector: map[string]string{"env": "prod", "priority": "high"}})
+    if err != nil {
+        t.Fatalf("ListJobs: %v", err)
+    }
+    if len(jobs) != 1 || jobs[0].JobID != "prod-high" || jobs[0].Labels["env"] != "prod" {
+        t.Fatalf("expected only prod-high, got %+v", jobs)
+    }
+    if jobs, err = c.ListJobs(context.Background(), JobFilter{Selector: map[string]string{}}); err != nil || len(jobs) != 3 {
+        t.Fatalf("expected the empty selector to list all 3 jobs, got %d, %v", len(jobs), err)
+    }
+}
+
+func TestUpdateJob(t *testing.T) {
+    _, ts := fixtures.NewFixtureServer(fixtures.FixtureSlowServer)
+    defer ts.Close()
+    c := newTestClient(ts.URL, WithJobMetadata(map[string]string{"team": "nlp", "source_lang": "en"}))
+    if _, err := c.SubmitJob(context.Background(), "editable"); err != nil {
+        t.Fatalf("SubmitJob: %v", err)
+    }
+
+    lang, priority := "de", 3
+    job, err := c.UpdateJob(context.Background(), "editable", JobPatch{
+        Metadata: map[string]*string{"team": nil, "target_lang": &lang},
+        Priority: &priority,
+    })
+    if err != nil {
+        t.Fatalf("UpdateJob: %v", err)
+    }
+    if want := map[string]string{"source_lang": "en", "target_lang": "de"}; job.Priority != 3 || !reflect.DeepEqual(job.Metadata, want) {
+        t.Fatalf("expected priority 3 and %v, got %+v", want, job)
+    }
+
+    if _, err := c.UpdateJob(context.Background(), "missing", JobPatch{Priority: &priority}); err == nil || !strings.Contains(err.Error(), "404") {
+        t.Fatalf("expected a 404 for a missing job, got %v", err)
+    }
+}
+
+func TestUpdateFinishedJob(t *testing.T) {
+    _, ts := fixtures.NewFixtureServer(fixtures.FixtureInstantComplete)
+    defer ts.Close()
+    c := newTestClient(ts.URL)
+    if _, err := c.SubmitJob(context.Background(), "finished"); err != nil {
+        t.Fatalf("SubmitJob: %v", err)
+    }
+    if status, err := c.RetrieveStatus(context.Background()); err != nil || status != "completed" {
+        t.Fatalf("expected completed, got %q, %v", status, err)
+    }
+
+    priority := 1
+    if _, err := c.UpdateJob(context.Background(), "finished", JobPatch{Priority: &priority}); err == nil || !strings.Contains(err.Error(), "409") {
+        t.Fatalf("expected a 409 for a finished job, got %v", err)
+    }
+}
@@ -0,0 +1,100 @@
+package client
+
+import (
+    "errors"
+    "time"
+
+    "github.com/prometheus/client_golang/prometheus"
+)
+
+// pollMetrics exports the client's polling behaviour to Prometheus. A nil
+// *pollMetrics, the default, records nothing.
+type pollMetrics struct {
+    attempts     prometheus.Counter
+    pollDuration prometheus.Histogram
+    backoffDelay prometheus.Histogram
+}
+
+// WithMetricsRegistry registers the client's polling metrics with reg:
+//   - client_poll_attempts_total, requests made to the server
+//   - client_poll_duration_seconds, from the first request of a job to its final status
+//   - client_backoff_delay_seconds, the wait scheduled before each further attempt
+//
+// Clients sharing a registry share its metrics. A nil reg is ignored. A metric
+// which can't be registered, e.g. because reg holds a different collector
+// under its name, is logged and left unexported.
+func WithMetricsRegistry(reg prometheus.Registerer) ClientOption {
+    return func(c *Client) {
+        if reg == nil {
+            return
+        }
+        var err error
+        c.metrics, err = newPollMetrics(reg)
+        if err != nil {
+            c.logf("Not exporting all poll metrics: %v", err)
+        }
+    }
+}
+
+// newPollMetrics registers the polling metrics with reg. The metrics it
+// returns are always usable; the error lists those left unregistered.
+func newPollMetrics(reg prometheus.Registerer) (*pollMetrics, error) {
+    var errs []error
+    m := &pollMetrics{
+        attempts: register(reg, prometheus.NewCounter(prometheus.CounterOpts{
+            Name: "client_poll_attempts_total",
+            Help: "Status requests made to the translation server.",
+        }), &errs),
+        pollDuration: register(reg, prometheus.NewHistogram(prometheus.HistogramOpts{
+            Name:    "client_poll_duration_seconds",
+            Help:    "Time from the first status request of a job to its final status.",
+            Buckets: prometheus.ExponentialBuckets(0.5, 2, 10),
+        }), &errs),
+        backoffDelay: register(reg, prometheus.NewHistogram(prometheus.HistogramOpts{
+            Name:    "client_backoff_delay_seconds",
+            Help:    "Delay scheduled before the next status request.",
+            Buckets: prometheus.ExponentialBuckets(0.1, 2, 10),
+        }), &errs),
+    }
+    return m, errors.Join(errs...)
+}
+
+// register registers c with reg, returning the collector already registered
+// under the same name if there is one. If c can't be registered it is
+// returned unregistered, with the error added to errs.
+func register[C prometheus.Collector](reg prometheus.Registerer, c C, errs *[]error) C {
+    if err := reg.Register(c); err != nil {
+        var are prometheus.AlreadyRegisteredError
+        if errors.As(err, &are) {
+            if existing, ok := are.ExistingCollector.(C); ok {
+                return existing
+            }
+        }
+        *errs = append(*errs, err)
+    }
+    return c
+}
+
+// recordAttempt counts a request to the server.
+func (m *pollMetrics) recordAttempt() {
+    if m == nil {
+        return
+    }
+    m.attempts.Inc()
+}
+
+// recordDelay records a backoff scheduled before the next attempt.
+func (m *pollMetrics) recordDelay(d time.Duration) {
+    if m == nil {
+        return
+    }
+    m.backoffDelay.Observe(d.Seconds())
+}
+
+// recordFinal records how long a job took to reach a final status since start.
+func (m *pollMetrics) recordFinal(start time.Time) {
+    if m == nil {
+        return
+    }
+    m.pollDuration.Observe(time.Since(start).Seconds())
+}
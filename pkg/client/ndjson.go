@@ -0,0 +1,145 @@
+package client
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "mime"
+    "net/http"
+    "strings"
+
+    "Video-Translation-Simulator/pkg/types"
+)
+
+// NDJSONContentType is the media type of a newline-delimited JSON stream of
+// status changes, one {"result": ...} object per line.
+const NDJSONContentType = "application/x-ndjson"
+
+// errNotStreaming is returned by waitForStream when the server answers with a
+// single pending status instead of a stream, or the request fails.
+var errNotStreaming = errors.New("server did not respond with a status stream")
+
+// WithNDJSON makes WaitForCompletion ask the server for a stream of status
+// changes with Accept: application/x-ndjson, falling back to polling when the
+// server doesn't support it. The stream is a single long request, so the
+// per-request timeout, rate limiting and response interceptor don't apply.
+func WithNDJSON(enabled bool) ClientOption {
+    return func(c *Client) {
+        c.ndjson = enabled
+    }
+}
+
+// acceptsNDJSON reports whether the request asks for a status stream.
+func acceptsNDJSON(r *http.Request) bool {
+    for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+        if mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accept)); err == nil && mediaType == NDJSONContentType {
+            return true
+        }
+    }
+    return false
+}
+
+// streamStatus serves a /status request asking for NDJSON: it polls the server
+// for a job of its own and writes a line for every change in status, closing
+// the response once the job reaches a final status. It keeps its own attempt
+// count, backoff and transition checks rather than joining the polling
+// sequence of plain /status requests. Like WaitForCompletion it still adds to
+// the client's stats, metrics, rate limit state and result cache, which are
+// safe for concurrent use.
+func (c *Client) streamStatus(w http.ResponseWriter, r *http.Request) {
+    flusher, ok := w.(http.Flusher)
+    if !ok {
+        c.respondWithError(w, "streaming is not supported")
+        return
+    }
+    w.Header().Set("Content-Type", NDJSONContentType)
+    w.WriteHeader(http.StatusOK)
+    flusher.Flush()
+
+    enc := json.NewEncoder(w)
+    last := ""
+    // poll calls onFinal itself once the job reaches a final status.
+    _, err := c.poll(r.Context(), c.newStatusTracker(c.ownJobID()), func(status string) {
+        if status == last {
+            return
+        }
+        last = status
+        if err := enc.Encode(map[string]string{"result": status}); err != nil {
+            c.logf("Error writing status line: %v", err)
+        }
+        flusher.Flush()
+    })
+    if err != nil {
+        // Headers are long gone, so report the failure in the stream itself.
+        c.logf("Status stream ended early: %v", err)
+        enc.Encode(map[string]string{"error": err.Error()})
+    }
+}
+
+// waitForStream requests a status stream and reads it until a final status,
+// checking statuses with transitions.
+func (c *Client) waitForStream(ctx context.Context, transitions *statusTracker) (string, error) {
+    baseURL, path := c.statusTarget()
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+path, nil)
+    if err != nil {
+        return "", err
+    }
+    req.Header.Set("Accept", NDJSONContentType)
+
+    // Failures are left to the polling loop to retry.
+    resp, err := c.httpClient.Do(req)
+    if err != nil {
+        return "", fmt.Errorf("%w: %v", errNotStreaming, err)
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+        return "", fmt.Errorf("%w: status %d", errNotStreaming, resp.StatusCode)
+    }
+    dec := json.NewDecoder(resp.Body)
+
+    // A server without streaming answers as if polled; use that as the first poll.
+    mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+    if mediaType != NDJSONContentType {
+        var response struct {
+            Result string `json:"result"`
+        }
+        if err := dec.Decode(&response); err != nil {
+            return "", fmt.Errorf("%w: %v", errNotStreaming, err)
+        }
+        if err := transitions.check(types.Status(response.Result)); err != nil {
+            return "", err
+        }
+        if types.Status(response.Result).IsTerminal() {
+            return response.Result, nil
+        }
+        return "", errNotStreaming
+    }
+
+    for {
+        var line struct {
+            Result string `json:"result"`
+            Error  string `json:"error"`
+        }
+        if err := dec.Decode(&line); err != nil {
+            if ctxErr := ctx.Err(); ctxErr != nil {
+                return "", ctxErr
+            }
+            if err == io.EOF {
+                return "", errors.New("status stream ended before a final status")
+            }
+            return "", err
+        }
+        if line.Error != "" {
+            return "", fmt.Errorf("server reported: %s", line.Error)
+        }
+        if err := transitions.check(types.Status(line.Result)); err != nil {
+            return "", err
+        }
+        c.logf("Stream: Received status: %s", line.Result)
+        if types.Status(line.Result).IsTerminal() {
+            return line.Result, nil
+        }
+    }
+}
@@ -0,0 +1,144 @@
+package client
+
+import (
+    "bufio"
+    "context"
+    "encoding/json"
+    "fmt"
+    "io"
+    "net/http"
+    "net/http/httptest"
+    "sync/atomic"
+    "testing"
+    "time"
+
+    "Video-Translation-Simulator/pkg/testutil"
+)
+
+func TestHandleStatusRequestStreamsNDJSON(t *testing.T) {
+    srv := testutil.NewDryRunServer()
+    defer srv.Close()
+    srv.QueueStatus("", "pending", "pending", "pending", "completed")
+
+    c := newTestClient(srv.URL, WithBackoff(constantBackoff(0)))
+    front := httptest.NewServer(http.HandlerFunc(c.HandleStatusRequest))
+    defer front.Close()
+
+    req, _ := http.NewRequest(http.MethodGet, front.URL+"/status", nil)
+    req.Header.Set("Accept", NDJSONContentType)
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil {
+        t.Fatalf("GET /status: %v", err)
+    }
+    defer resp.Body.Close()
+    if ct := resp.Header.Get("Content-Type"); ct != NDJSONContentType {
+        t.Fatalf("expected %s, got %q", NDJSONContentType, ct)
+    }
+
+    // One line per change in status, then the stream is closed.
+    var results []string
+    scanner := bufio.NewScanner(resp.Body)
+    for scanner.Scan() {
+        var line map[string]string
+        if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+            t.Fatalf("parsing line %q: %v", scanner.Text(), err)
+        }
+        results = append(results, line["result"])
+    }
+    if err := scanner.Err(); err != nil {
+        t.Fatalf("reading stream: %v", err)
+    }
+    if fmt.Sprint(results) != "[pending completed]" {
+        t.Fatalf("expected [pending completed], got %v", results)
+    }
+}
+
+func TestNDJSONStreamCallsOnFinalOnce(t *testing.T) {
+    srv := testutil.NewDryRunServer()
+    defer srv.Close()
+    srv.QueueStatus("", "pending", "completed")
+
+    var finals atomic.Int32
+    c := newTestClient(srv.URL, WithBackoff(constantBackoff(0)), WithOnFinal(func(string) { finals.Add(1) }))
+    front := httptest.NewServer(http.HandlerFunc(c.HandleStatusRequest))
+    defer front.Close()
+
+    req, _ := http.NewRequest(http.MethodGet, front.URL+"/status", nil)
+    req.Header.Set("Accept", NDJSONContentType)
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil {
+        t.Fatalf("GET /status: %v", err)
+    }
+    io.Copy(io.Discard, resp.Body)
+    resp.Body.Close()
+    if n := finals.Load(); n != 1 {
+        t.Fatalf("expected the final hook to be called once, got %d", n)
+    }
+}
+
+func TestWaitForCompletionReadsNDJSON(t *testing.T) {
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if r.Header.Get("Accept") != NDJSONContentType {
+            t.Errorf("expected the client to ask for NDJSON, got Accept %q", r.Header.Get("Accept"))
+        }
+        w.Header().Set("Content-Type", NDJSONContentType)
+        fmt.Fprintln(w, `{"result":"pending"}`)
+        w.(http.Flusher).Flush()
+        fmt.Fprintln(w, `{"result":"error"}`)
+    }))
+    defer srv.Close()
+
+    c := newTestClient(srv.URL, WithNDJSON(true))
+    if status, err := c.WaitForCompletion(context.Background()); err != nil || status != "error" {
+        t.Fatalf("expected error from the stream, got %q, %v", status, err)
+    }
+}
+
+func TestWaitForCompletionNDJSONFallsBackToPolling(t *testing.T) {
+    srv := testutil.NewDryRunServer()
+    defer srv.Close()
+    srv.QueueStatus("", "pending", "completed")
+
+    c := newTestClient(srv.URL, WithNDJSON(true), WithBackoff(constantBackoff(0)))
+    if status, err := c.WaitForCompletion(context.Background()); err != nil || status != "completed" {
+        t.Fatalf("expected completed by polling, got %q, %v", status, err)
+    }
+}
+
+func TestNDJSONStreamKeepsItsOwnSequence(t *testing.T) {
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        w.Header().Set("Content-Type", "application/json")
+        w.Write([]byte(`{"result":"pending"}`))
+    }))
+    defer srv.Close()
+    c := newTestClient(srv.URL, WithBackoff(constantBackoff(time.Millisecond)))
+
+    ctx, cancel := context.WithCancel(context.Background())
+    done := make(chan struct{})
+    go func() {
+        defer close(done)
+        req := httptest.NewRequest(http.MethodGet, "/status", nil).WithContext(ctx)
+        req.Header.Set("Accept", NDJSONContentType)
+        c.HandleStatusRequest(httptest.NewRecorder(), req)
+    }()
+
+    for i := 0; i < 5; i++ {
+        if got := pollStatus(t, c); got != "pending" {
+            t.Fatalf("expected pending, got %q", got)
+        }
+        // Past the backoff, so every plain poll reaches the server.
+        time.Sleep(5 * time.Millisecond)
+    }
+    cancel()
+    <-done
+
+    c.mu.Lock()
+    attempt := c.attempt
+    c.mu.Unlock()
+    if attempt != 5 {
+        t.Fatalf("expected the stream's polls to stay out of the plain sequence, got attempt %d", attempt)
+    }
+    if n := c.Stats().TotalAttempts; n <= 5 {
+        t.Fatalf("expected the stream's polls in the client's stats, got %d attempts", n)
+    }
+}
@@ -0,0 +1,51 @@
+package client
+
+import (
+    "context"
+    "fmt"
+    "net/http"
+    "time"
+)
+
+// ProbeTimeout bounds each request Probe sends, so an unreachable server is
+// reported quickly rather than after the client's full request timeout.
+const ProbeTimeout = 2 * time.Second
+
+// Probe checks the server is reachable before a long polling sequence. It
+// sends GET /healthz, falling back to GET /status for servers without a health
+// endpoint, and returns nil once either answers with 200. With a server pool
+// it probes one server picked by the pool. Errors name the URL tried and, if
+// the server answered, the status code.
+func (c *Client) Probe(ctx context.Context) error {
+    baseURL := c.serverURL()
+    url := baseURL + "/healthz"
+    code, err := c.probe(ctx, url)
+    if err == nil && code == http.StatusNotFound {
+        url = baseURL + "/status"
+        code, err = c.probe(ctx, url)
+    }
+    if err != nil {
+        return fmt.Errorf("server at %s is unreachable: %w", url, err)
+    }
+    if code != http.StatusOK {
+        return fmt.Errorf("server at %s responded with %d", url, code)
+    }
+    return nil
+}
+
+// probe sends a GET to url and returns the response's status code.
+func (c *Client) probe(ctx context.Context, url string) (int, error) {
+    ctx, cancel := context.WithTimeout(ctx, ProbeTimeout)
+    defer cancel()
+
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+    if err != nil {
+        return 0, err
+    }
+    resp, err := c.httpClient.Do(req)
+    if err != nil {
+        return 0, err
+    }
+    resp.Body.Close()
+    return resp.StatusCode, nil
+}
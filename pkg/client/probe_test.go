@@ -0,0 +1,64 @@
+package client
+
+import (
+    "context"
+    "net"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+)
+
+func TestProbeUnreachableServer(t *testing.T) {
+    // Take a free port and release it, so nothing is listening there.
+    l, err := net.Listen("tcp", "127.0.0.1:0")
+    if err != nil {
+        t.Fatalf("listen: %v", err)
+    }
+    url := "http://" + l.Addr().String()
+    l.Close()
+
+    err = newTestClient(url).Probe(context.Background())
+    if err == nil {
+        t.Fatal("expected an error probing a server that is not listening")
+    }
+    if !strings.Contains(err.Error(), url+"/healthz") {
+        t.Fatalf("expected the error to name %s/healthz, got %v", url, err)
+    }
+}
+
+func TestProbe(t *testing.T) {
+    tests := []struct {
+        name    string
+        healthz int // 0 for no health endpoint.
+        status  int
+        wantErr string
+    }{
+        {"healthy", http.StatusOK, http.StatusOK, ""},
+        {"unhealthy", http.StatusServiceUnavailable, http.StatusOK, "/healthz responded with 503"},
+        {"falls back to status", 0, http.StatusOK, ""},
+        {"status fails", 0, http.StatusInternalServerError, "/status responded with 500"},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            mux := http.NewServeMux()
+            if tt.healthz != 0 {
+                mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(tt.healthz) })
+            }
+            mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(tt.status) })
+            srv := httptest.NewServer(mux)
+            defer srv.Close()
+
+            err := newTestClient(srv.URL).Probe(context.Background())
+            if tt.wantErr == "" {
+                if err != nil {
+                    t.Fatalf("expected no error, got %v", err)
+                }
+                return
+            }
+            if err == nil || !strings.Contains(err.Error(), srv.URL+tt.wantErr) {
+                t.Fatalf("expected an error containing %q, got %v", srv.URL+tt.wantErr, err)
+            }
+        })
+    }
+}
@@ -0,0 +1,49 @@
+package client
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "fmt"
+    "io"
+    "net/http"
+    "net/url"
+    "time"
+)
+
+// ProgressUpdate is a report of a job's progress, as sent by a worker to the
+// server's POST /jobs/{id}/progress.
+type ProgressUpdate struct {
+    Subtask    string    `json:"subtask"`
+    Percent    int       `json:"percent"`
+    Message    string    `json:"message,omitempty"`
+    ReportedAt time.Time `json:"reported_at"`
+}
+
+// GetProgress returns the progress reported for the job created with jobID
+// on POST /jobs, oldest first, with GET /jobs/{id}/progress.
+func (c *Client) GetProgress(ctx context.Context, jobID string) ([]ProgressUpdate, error) {
+    ctx, cancel := context.WithTimeout(ctx, c.timeout)
+    defer cancel()
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.serverURL()+"/jobs/"+url.PathEscape(jobID)+"/progress", nil)
+    if err != nil {
+        return nil, err
+    }
+    resp, err := c.httpClient.Do(req)
+    if err != nil {
+        return nil, err
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+        msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+        return nil, fmt.Errorf("getting progress: server responded with %d: %s", resp.StatusCode, bytes.TrimSpace(msg))
+    }
+
+    var history struct {
+        Updates []ProgressUpdate `json:"updates"`
+    }
+    if err := json.NewDecoder(io.LimitReader(resp.Body, c.maxResponseBytes)).Decode(&history); err != nil {
+        return nil, fmt.Errorf("decoding progress: %w", err)
+    }
+    return history.Updates, nil
+}
@@ -0,0 +1,44 @@
+package client
+
+import (
+    "context"
+    "net/http"
+    "strings"
+    "testing"
+
+    "Video-Translation-Simulator/pkg/testutil/fixtures"
+)
+
+func TestGetProgress(t *testing.T) {
+    _, ts := fixtures.NewFixtureServer(fixtures.FixtureSlowServer)
+    defer ts.Close()
+    c := newTestClient(ts.URL)
+    if _, err := c.SubmitJob(context.Background(), "dubbing"); err != nil {
+        t.Fatalf("SubmitJob: %v", err)
+    }
+    if updates, err := c.GetProgress(context.Background(), "dubbing"); err != nil || len(updates) != 0 {
+        t.Fatalf("expected no progress yet, got %+v, %v", updates, err)
+    }
+
+    for _, body := range []string{`{"subtask":"transcription","percent":30}`, `{"subtask":"voice","percent":60,"message":"speaker 2/3"}`} {
+        resp, err := http.Post(ts.URL+"/jobs/dubbing/progress", "application/json", strings.NewReader(body))
+        if err != nil {
+            t.Fatalf("reporting progress: %v", err)
+        }
+        resp.Body.Close()
+        if resp.StatusCode != http.StatusNoContent {
+            t.Fatalf("reporting progress: expected 204, got %d", resp.StatusCode)
+        }
+    }
+
+    updates, err := c.GetProgress(context.Background(), "dubbing")
+    if err != nil {
+        t.Fatalf("GetProgress: %v", err)
+    }
+    if len(updates) != 2 || updates[0].Subtask != "transcription" || updates[1].Percent != 60 || updates[1].Message != "speaker 2/3" {
+        t.Fatalf("unexpected progress %+v", updates)
+    }
+    if _, err := c.GetProgress(context.Background(), "missing"); err == nil || !strings.Contains(err.Error(), "404") {
+        t.Fatalf("expected a 404 for a missing job, got %v", err)
+    }
+}
@@ -0,0 +1,143 @@
+package client
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "net/http"
+    "strconv"
+    "sync"
+    "time"
+)
+
+// RateLimitState is the server's rate limit as advertised by the
+// X-RateLimit-Limit, X-RateLimit-Remaining and X-RateLimit-Reset headers of
+// its last response.
+type RateLimitState struct {
+    Limit     int
+    Remaining int
+    Reset     time.Time // When requests are let through again if Remaining is 0.
+}
+
+// parseRateLimit reads the rate limit headers from h. X-RateLimit-Reset is a
+// Unix timestamp in seconds. It returns false when the headers are absent or
+// malformed.
+func parseRateLimit(h http.Header) (RateLimitState, bool) {
+    limit, err := strconv.Atoi(h.Get("X-RateLimit-Limit"))
+    if err != nil {
+        return RateLimitState{}, false
+    }
+    remaining, err := strconv.Atoi(h.Get("X-RateLimit-Remaining"))
+    if err != nil {
+        return RateLimitState{}, false
+    }
+    reset, err := strconv.ParseInt(h.Get("X-RateLimit-Reset"), 10, 64)
+    if err != nil {
+        return RateLimitState{}, false
+    }
+    return RateLimitState{Limit: limit, Remaining: remaining, Reset: time.Unix(reset, 0)}, true
+}
+
+// RateLimit returns the rate limit reported by the server's last response, if any.
+func (c *Client) RateLimit() (RateLimitState, bool) {
+    state := c.rateLimit.Load()
+    if state == nil {
+        return RateLimitState{}, false
+    }
+    return *state, true
+}
+
+// rateLimitWait returns how long to wait for the server's rate limit to reset
+// when the last response said no requests are left.
+func (c *Client) rateLimitWait() (time.Duration, bool) {
+    state, ok := c.RateLimit()
+    if !ok || state.Remaining > 0 {
+        return 0, false
+    }
+    wait := time.Until(state.Reset)
+    if wait <= 0 {
+        return 0, false
+    }
+    return wait, true
+}
+
+// BurstLimiter limits the client's own request rate: up to burstSize requests
+// go out straight away, after which they are held to sustainedRPS. It is a
+// token bucket holding burstSize tokens and refilling at sustainedRPS.
+// Waiting callers reserve their token up front, so they are served in order.
+type BurstLimiter struct {
+    mu        sync.Mutex
+    burst     float64
+    rate      float64 // Tokens per second.
+    tokens    float64 // Goes negative while callers are waiting for a reserved token.
+    updatedAt time.Time
+}
+
+// NewBurstLimiter returns a BurstLimiter with a full burst available.
+func NewBurstLimiter(burstSize int, sustainedRPS float64) *BurstLimiter {
+    if burstSize < 1 {
+        burstSize = 1
+    }
+    return &BurstLimiter{
+        burst:     float64(burstSize),
+        rate:      sustainedRPS,
+        tokens:    float64(burstSize),
+        updatedAt: time.Now(),
+    }
+}
+
+// WithBurstLimiter makes the client wait on l before every request to the server.
+func WithBurstLimiter(l *BurstLimiter) ClientOption {
+    return func(c *Client) {
+        c.limiter = l
+    }
+}
+
+// Wait blocks until a request may be sent or ctx is done. If ctx has a
+// deadline before the request could go out, it fails straight away without
+// using up a token.
+func (l *BurstLimiter) Wait(ctx context.Context) error {
+    delay, err := l.reserve(ctx)
+    if err != nil {
+        return err
+    }
+    if delay <= 0 {
+        return nil
+    }
+    timer := time.NewTimer(delay)
+    defer timer.Stop()
+    select {
+    case <-timer.C:
+        return nil
+    case <-ctx.Done():
+        // The reserved token is not handed back, which keeps the accounting simple
+        // at the cost of the next caller waiting slightly longer.
+        return ctx.Err()
+    }
+}
+
+// reserve takes a token and returns how long until it is available.
+func (l *BurstLimiter) reserve(ctx context.Context) (time.Duration, error) {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+
+    now := time.Now()
+    l.tokens += now.Sub(l.updatedAt).Seconds() * l.rate
+    if l.tokens > l.burst {
+        l.tokens = l.burst
+    }
+    l.updatedAt = now
+
+    var delay time.Duration
+    if l.tokens < 1 {
+        if l.rate <= 0 {
+            return 0, errors.New("burst limit exhausted and no sustained rate configured")
+        }
+        delay = time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+    }
+    if deadline, ok := ctx.Deadline(); ok && now.Add(delay).After(deadline) {
+        return 0, fmt.Errorf("rate limited for %v, beyond the context deadline: %w", delay, context.DeadlineExceeded)
+    }
+    l.tokens--
+    return delay, nil
+}
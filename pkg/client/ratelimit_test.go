@@ -0,0 +1,137 @@
+package client
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "net/http"
+    "net/http/httptest"
+    "strconv"
+    "sync"
+    "testing"
+    "time"
+)
+
+func TestParseRateLimit(t *testing.T) {
+    h := http.Header{}
+    if _, ok := parseRateLimit(h); ok {
+        t.Fatal("expected no state without headers")
+    }
+
+    h.Set("X-RateLimit-Limit", "10")
+    h.Set("X-RateLimit-Remaining", "3")
+    h.Set("X-RateLimit-Reset", "1700000000")
+    state, ok := parseRateLimit(h)
+    if !ok {
+        t.Fatal("expected headers to parse")
+    }
+    if state.Limit != 10 || state.Remaining != 3 || !state.Reset.Equal(time.Unix(1700000000, 0)) {
+        t.Fatalf("unexpected state %+v", state)
+    }
+
+    h.Set("X-RateLimit-Remaining", "lots")
+    if _, ok := parseRateLimit(h); ok {
+        t.Fatal("expected malformed headers to be ignored")
+    }
+}
+
+func TestWaitForCompletionSleepsUntilRateLimitReset(t *testing.T) {
+    // Reset on a whole second at least a second away, as the header has second precision.
+    reset := time.Now().Add(time.Second).Truncate(time.Second).Add(time.Second)
+
+    var mu sync.Mutex
+    var requests []time.Time
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        mu.Lock()
+        requests = append(requests, time.Now())
+        first := len(requests) == 1
+        mu.Unlock()
+
+        result := "completed"
+        if first {
+            result = "pending"
+            w.Header().Set("X-RateLimit-Limit", "1")
+            w.Header().Set("X-RateLimit-Remaining", "0")
+            w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+        }
+        json.NewEncoder(w).Encode(map[string]string{"result": result})
+    }))
+    defer srv.Close()
+
+    // The normal backoff would retry within 20ms.
+    c := newTestClient(srv.URL, WithRetryPolicy(NewRetryPolicy().WithInitialInterval(20*time.Millisecond).WithMaxInterval(20*time.Millisecond)))
+
+    if _, err := c.WaitForCompletion(context.Background()); err != nil {
+        t.Fatalf("WaitForCompletion: %v", err)
+    }
+
+    mu.Lock()
+    defer mu.Unlock()
+    if len(requests) != 2 {
+        t.Fatalf("expected 2 requests, got %d", len(requests))
+    }
+    if requests[1].Before(reset) {
+        t.Fatalf("retried %v before the rate limit reset", reset.Sub(requests[1]))
+    }
+    if late := requests[1].Sub(reset); late > 200*time.Millisecond {
+        t.Fatalf("retried %v after the rate limit reset", late)
+    }
+}
+
+func TestBurstLimiterThrottlesAfterBurst(t *testing.T) {
+    var mu sync.Mutex
+    var arrivals []time.Duration
+    start := time.Now()
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        mu.Lock()
+        arrivals = append(arrivals, time.Since(start))
+        mu.Unlock()
+        json.NewEncoder(w).Encode(map[string]string{"result": "pending"})
+    }))
+    defer srv.Close()
+
+    c := newTestClient(srv.URL, WithBurstLimiter(NewBurstLimiter(5, 2)))
+
+    // Fire 15 requests within 100ms. With a burst of 5 and 2/s after that, only
+    // two more fit in before the deadline.
+    ctx, cancel := context.WithTimeout(context.Background(), 1200*time.Millisecond)
+    defer cancel()
+    var wg sync.WaitGroup
+    failures := make(chan error, 15)
+    for i := 0; i < 15; i++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            if _, err := c.RetrieveStatus(ctx); err != nil {
+                failures <- err
+            }
+        }()
+        time.Sleep(5 * time.Millisecond)
+    }
+    wg.Wait()
+    close(failures)
+
+    if len(failures) != 8 {
+        t.Fatalf("expected 8 requests to be refused before the deadline, got %d", len(failures))
+    }
+    for err := range failures {
+        if !errors.Is(err, context.DeadlineExceeded) {
+            t.Fatalf("expected deadline errors, got %v", err)
+        }
+    }
+
+    if len(arrivals) != 7 {
+        t.Fatalf("expected 7 requests to reach the server, got %d: %v", len(arrivals), arrivals)
+    }
+    for i, at := range arrivals[:5] {
+        if at > 150*time.Millisecond {
+            t.Fatalf("burst request %d was held back until %v", i+1, at)
+        }
+    }
+    for i, at := range arrivals[5:] {
+        earliest := time.Duration(i+1) * 450 * time.Millisecond
+        if at < earliest {
+            t.Fatalf("throttled request %d sent at %v, expected no earlier than %v", i+6, at, earliest)
+        }
+    }
+}
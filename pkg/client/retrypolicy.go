@@ -0,0 +1,173 @@
+package client
+
+import (
+    "errors"
+    "math/rand"
+    "time"
+)
+
+// ErrMaxElapsedTime is returned once a polling sequence has run for longer
+// than the retry policy's MaxElapsedTime.
+var ErrMaxElapsedTime = errors.New("max elapsed time reached")
+
+// RetryPolicy controls how often and for how long the client polls. Delays
+// start at InitialInterval and grow by Multiplier after every poll, up to
+// MaxInterval. With a RandomizationFactor of r, each delay is picked at random
+// between (1-r) and 1 times the computed value, so clients polling the same
+// job spread out.
+type RetryPolicy struct {
+    MaxAttempts         int           // Polls that may fail before giving up.
+    MaxElapsedTime      time.Duration // Zero means no limit.
+    InitialInterval     time.Duration
+    MaxInterval         time.Duration
+    Multiplier          float64
+    RandomizationFactor float64
+}
+
+// NewRetryPolicy returns the client's default policy: 20 attempts, delays
+// doubling from 500ms up to 10s, halved at random by up to half.
+func NewRetryPolicy() *RetryPolicy {
+    return &RetryPolicy{
+        MaxAttempts:         20,
+        InitialInterval:     500 * time.Millisecond,
+        MaxInterval:         10 * time.Second,
+        Multiplier:          2,
+        RandomizationFactor: 0.5,
+    }
+}
+
+// WithMaxAttempts sets MaxAttempts and returns p.
+func (p *RetryPolicy) WithMaxAttempts(n int) *RetryPolicy {
+    p.MaxAttempts = n
+    return p
+}
+
+// WithMaxElapsedTime sets MaxElapsedTime and returns p.
+func (p *RetryPolicy) WithMaxElapsedTime(d time.Duration) *RetryPolicy {
+    p.MaxElapsedTime = d
+    return p
+}
+
+// WithInitialInterval sets InitialInterval and returns p.
+func (p *RetryPolicy) WithInitialInterval(d time.Duration) *RetryPolicy {
+    p.InitialInterval = d
+    return p
+}
+
+// WithMaxInterval sets MaxInterval and returns p.
+func (p *RetryPolicy) WithMaxInterval(d time.Duration) *RetryPolicy {
+    p.MaxInterval = d
+    return p
+}
+
+// WithMultiplier sets Multiplier and returns p. Values below 1 are treated as
+// 1, a constant delay.
+func (p *RetryPolicy) WithMultiplier(m float64) *RetryPolicy {
+    if m < 1 {
+        m = 1
+    }
+    p.Multiplier = m
+    return p
+}
+
+// WithJitter sets RandomizationFactor, clamped to [0, 1], and returns p.
+func (p *RetryPolicy) WithJitter(factor float64) *RetryPolicy {
+    if factor < 0 {
+        factor = 0
+    } else if factor > 1 {
+        factor = 1
+    }
+    p.RandomizationFactor = factor
+    return p
+}
+
+// WithRetryPolicy replaces the client's retry policy with a copy of p, so
+// changing p afterwards has no effect on the client. Non-positive attempts and
+// intervals take the defaults of NewRetryPolicy, and Multiplier and
+// RandomizationFactor are clamped as by WithMultiplier and WithJitter, so a
+// zero value policy can't poll in a tight loop.
+func WithRetryPolicy(p *RetryPolicy) ClientOption {
+    return func(c *Client) {
+        if p != nil {
+            c.retry = p.sanitized()
+        }
+    }
+}
+
+// sanitized returns a copy of p with out of range fields fixed up as described
+// at WithRetryPolicy. MaxInterval is also raised to at least InitialInterval.
+func (p *RetryPolicy) sanitized() RetryPolicy {
+    defaults := NewRetryPolicy()
+    cp := *p
+    if cp.MaxAttempts < 1 {
+        cp.MaxAttempts = defaults.MaxAttempts
+    }
+    if cp.MaxElapsedTime < 0 {
+        cp.MaxElapsedTime = 0
+    }
+    if cp.InitialInterval <= 0 {
+        cp.InitialInterval = defaults.InitialInterval
+    }
+    if cp.MaxInterval <= 0 {
+        cp.MaxInterval = defaults.MaxInterval
+    }
+    if cp.MaxInterval < cp.InitialInterval {
+        cp.MaxInterval = cp.InitialInterval
+    }
+    cp.WithMultiplier(cp.Multiplier).WithJitter(cp.RandomizationFactor)
+    return cp
+}
+
+// WithMaxRetries sets how many polls may fail before the client gives up, the
+// retry policy's MaxAttempts. Values below 1 are ignored.
+func WithMaxRetries(n int) ClientOption {
+    return func(c *Client) {
+        if n > 0 {
+            c.retry.MaxAttempts = n
+        }
+    }
+}
+
+// WithInitialDelay sets the delay before the first retry, the retry policy's
+// InitialInterval. Non-positive values are ignored.
+func WithInitialDelay(d time.Duration) ClientOption {
+    return func(c *Client) {
+        if d > 0 {
+            c.retry.InitialInterval = d
+        }
+    }
+}
+
+// WithMaxDelay caps the delay between retries, the retry policy's
+// MaxInterval. Non-positive values are ignored.
+func WithMaxDelay(d time.Duration) ClientOption {
+    return func(c *Client) {
+        if d > 0 {
+            c.retry.MaxInterval = d
+        }
+    }
+}
+
+// elapsedExceeded reports whether a sequence started at start has run past
+// MaxElapsedTime.
+func (p *RetryPolicy) elapsedExceeded(start time.Time) bool {
+    return p.MaxElapsedTime > 0 && time.Since(start) >= p.MaxElapsedTime
+}
+
+// nextInterval returns the delay to wait after one of current, zero for the
+// first, split into the fixed part and the random jitter added to it.
+func (p *RetryPolicy) nextInterval(current time.Duration) (base, jitter time.Duration) {
+    if current == 0 {
+        current = p.InitialInterval
+    } else {
+        current = time.Duration(float64(current) * p.Multiplier)
+    }
+    if current > p.MaxInterval {
+        current = p.MaxInterval
+    }
+    spread := time.Duration(float64(current) * p.RandomizationFactor)
+    if spread > 0 {
+        jitter = time.Duration(rand.Int63n(int64(spread)))
+    }
+    return current - spread, jitter
+}
@@ -0,0 +1,130 @@
+package client
+
+import (
+    "context"
+    "errors"
+    "net/http"
+    "net/http/httptest"
+    "sync/atomic"
+    "testing"
+    "time"
+)
+
+func TestRetryPolicyBuilder(t *testing.T) {
+    p := NewRetryPolicy().WithMaxAttempts(5).WithMultiplier(3).WithJitter(0.25).
+        WithInitialInterval(time.Second).WithMaxInterval(time.Minute).WithMaxElapsedTime(time.Hour)
+    want := RetryPolicy{
+        MaxAttempts:         5,
+        MaxElapsedTime:      time.Hour,
+        InitialInterval:     time.Second,
+        MaxInterval:         time.Minute,
+        Multiplier:          3,
+        RandomizationFactor: 0.25,
+    }
+    if *p != want {
+        t.Fatalf("expected %+v, got %+v", want, *p)
+    }
+
+    if p := NewRetryPolicy().WithMultiplier(0.5).WithJitter(2); p.Multiplier != 1 || p.RandomizationFactor != 1 {
+        t.Fatalf("expected out of range values to be clamped, got %+v", *p)
+    }
+}
+
+func TestRetryPolicyIsCopied(t *testing.T) {
+    p := NewRetryPolicy().WithMaxAttempts(3)
+    c := newTestClient("http://unused", WithRetryPolicy(p))
+    p.WithMaxAttempts(100)
+    if c.retry.MaxAttempts != 3 {
+        t.Fatalf("expected the client to keep 3 attempts, got %d", c.retry.MaxAttempts)
+    }
+}
+
+func TestRetryOptions(t *testing.T) {
+    c := newTestClient("http://unused")
+    if c.retry != *NewRetryPolicy() || c.timeout != 5*time.Second {
+        t.Fatalf("expected the defaults without options, got %+v and a %v timeout", c.retry, c.timeout)
+    }
+
+    c = newTestClient("http://unused", WithMaxRetries(3), WithInitialDelay(time.Second), WithMaxDelay(4*time.Second), WithTimeout(time.Minute))
+    if c.retry.MaxAttempts != 3 || c.retry.InitialInterval != time.Second || c.retry.MaxInterval != 4*time.Second || c.timeout != time.Minute {
+        t.Fatalf("options not applied: %+v with a %v timeout", c.retry, c.timeout)
+    }
+
+    c = newTestClient("http://unused", WithMaxRetries(0), WithInitialDelay(-1), WithMaxDelay(0), WithTimeout(0), WithLongPoll(-time.Second))
+    if c.retry != *NewRetryPolicy() || c.timeout != 5*time.Second || c.longPollWait != 0 {
+        t.Fatalf("expected invalid values to be ignored, got %+v, a %v timeout and a %v long poll", c.retry, c.timeout, c.longPollWait)
+    }
+}
+
+func TestRetryPolicyDelays(t *testing.T) {
+    c := newTestClient("http://unused", WithRetryPolicy(NewRetryPolicy().
+        WithInitialInterval(100*time.Millisecond).WithMaxInterval(time.Second).WithMultiplier(3).WithJitter(0)))
+
+    var delay time.Duration
+    for _, want := range []time.Duration{100 * time.Millisecond, 300 * time.Millisecond, 900 * time.Millisecond, time.Second} {
+        delay = c.nextDelay(delay)
+        if delay != want {
+            t.Fatalf("expected %v, got %v", want, delay)
+        }
+    }
+
+    c = newTestClient("http://unused", WithRetryPolicy(NewRetryPolicy().
+        WithInitialInterval(time.Second).WithJitter(0.2)))
+    for i := 0; i < 100; i++ {
+        if d := c.nextDelay(0); d < 800*time.Millisecond || d >= time.Second {
+            t.Fatalf("expected a delay in [800ms, 1s), got %v", d)
+        }
+    }
+}
+
+func TestRetryPolicyMaxAttempts(t *testing.T) {
+    var requests atomic.Int32
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        requests.Add(1)
+        http.Error(w, "down", http.StatusInternalServerError)
+    }))
+    defer srv.Close()
+
+    c := newTestClient(srv.URL, WithBackoff(constantBackoff(0)), WithRetryPolicy(NewRetryPolicy().WithMaxAttempts(3)))
+    if _, err := c.WaitForCompletion(context.Background()); err == nil {
+        t.Fatal("expected an error")
+    }
+    if n := requests.Load(); n != 3 {
+        t.Fatalf("expected 3 attempts, got %d", n)
+    }
+}
+
+func TestRetryPolicyMaxElapsedTime(t *testing.T) {
+    srv := newDelayedServer(time.Hour)
+    defer srv.Close()
+
+    c := newTestClient(srv.URL, WithBackoff(constantBackoff(20*time.Millisecond)),
+        WithRetryPolicy(NewRetryPolicy().WithMaxElapsedTime(100*time.Millisecond)))
+    start := time.Now()
+    if _, err := c.WaitForCompletion(context.Background()); !errors.Is(err, ErrMaxElapsedTime) {
+        t.Fatalf("expected ErrMaxElapsedTime, got %v", err)
+    }
+    if elapsed := time.Since(start); elapsed > time.Second {
+        t.Fatalf("kept polling for %v", elapsed)
+    }
+}
+
+func TestRetryPolicyIsSanitized(t *testing.T) {
+    bad := &RetryPolicy{MaxElapsedTime: -time.Second, Multiplier: 0, RandomizationFactor: 3}
+    want := *NewRetryPolicy()
+    want.RandomizationFactor = 1
+    want.Multiplier = 1
+
+    c := newTestClient("http://unused", WithRetryPolicy(bad))
+    if c.retry != want {
+        t.Fatalf("expected %+v, got %+v", want, c.retry)
+    }
+    g := &GRPCClient{}
+    g.SetRetryPolicy(bad)
+    if g.retry != want {
+        t.Fatalf("expected the gRPC client to get %+v, got %+v", want, g.retry)
+    }
+    if base, _ := c.retry.nextInterval(0); base < 0 {
+        t.Fatalf("expected a non-negative base delay, got %v", base)
+    }
+}
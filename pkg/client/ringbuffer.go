@@ -0,0 +1,78 @@
+package client
+
+import (
+    "fmt"
+    "strings"
+    "sync"
+    "time"
+)
+
+// RequestRecord describes a single attempt at fetching the status from the server.
+type RequestRecord struct {
+    Attempt   int
+    Status    string
+    Error     string
+    Delay     time.Duration // Backoff scheduled after this attempt, zero if none.
+    Timestamp time.Time
+}
+
+// String formats the record as a single line for error messages and logs.
+func (r RequestRecord) String() string {
+    result := r.Status
+    if r.Error != "" {
+        result = "error: " + r.Error
+    }
+    return fmt.Sprintf("attempt %d at %s: %s (next delay %v)", r.Attempt, r.Timestamp.Format("15:04:05.000"), result, r.Delay)
+}
+
+// RingBuffer keeps the most recent items pushed to it, overwriting the oldest
+// once it reaches capacity. It is safe for concurrent use.
+type RingBuffer[T any] struct {
+    mu    sync.Mutex
+    items []T
+    next  int
+    full  bool
+}
+
+// NewRingBuffer returns an empty RingBuffer holding up to capacity items.
+func NewRingBuffer[T any](capacity int) *RingBuffer[T] {
+    if capacity <= 0 {
+        capacity = 1
+    }
+    return &RingBuffer[T]{items: make([]T, capacity)}
+}
+
+// Push adds an item, dropping the oldest one if the buffer is full.
+func (b *RingBuffer[T]) Push(item T) {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    b.items[b.next] = item
+    b.next = (b.next + 1) % len(b.items)
+    if b.next == 0 {
+        b.full = true
+    }
+}
+
+// Snapshot returns a copy of the buffered items, oldest first.
+func (b *RingBuffer[T]) Snapshot() []T {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    if !b.full {
+        return append([]T(nil), b.items[:b.next]...)
+    }
+    out := make([]T, 0, len(b.items))
+    out = append(out, b.items[b.next:]...)
+    return append(out, b.items[:b.next]...)
+}
+
+// formatHistory renders the last n records, one per line.
+func formatHistory(records []RequestRecord, n int) string {
+    if len(records) > n {
+        records = records[len(records)-n:]
+    }
+    lines := make([]string, len(records))
+    for i, r := range records {
+        lines[i] = r.String()
+    }
+    return strings.Join(lines, "\n")
+}
@@ -0,0 +1,65 @@
+package client
+
+import (
+    "reflect"
+    "strings"
+    "testing"
+)
+
+func TestRingBufferBeforeWraparound(t *testing.T) {
+    b := NewRingBuffer[int](3)
+    if got := b.Snapshot(); len(got) != 0 {
+        t.Fatalf("expected empty snapshot, got %v", got)
+    }
+    b.Push(1)
+    b.Push(2)
+    if got := b.Snapshot(); !reflect.DeepEqual(got, []int{1, 2}) {
+        t.Fatalf("expected [1 2], got %v", got)
+    }
+}
+
+func TestRingBufferWraparound(t *testing.T) {
+    b := NewRingBuffer[int](3)
+    for i := 1; i <= 3; i++ {
+        b.Push(i)
+    }
+    if got := b.Snapshot(); !reflect.DeepEqual(got, []int{1, 2, 3}) {
+        t.Fatalf("expected [1 2 3] when exactly full, got %v", got)
+    }
+
+    b.Push(4)
+    if got := b.Snapshot(); !reflect.DeepEqual(got, []int{2, 3, 4}) {
+        t.Fatalf("expected oldest item overwritten, got %v", got)
+    }
+
+    for i := 5; i <= 10; i++ {
+        b.Push(i)
+    }
+    if got := b.Snapshot(); !reflect.DeepEqual(got, []int{8, 9, 10}) {
+        t.Fatalf("expected [8 9 10] after wrapping several times, got %v", got)
+    }
+}
+
+func TestRingBufferSnapshotIsACopy(t *testing.T) {
+    b := NewRingBuffer[int](2)
+    b.Push(1)
+    snap := b.Snapshot()
+    snap[0] = 99
+    if got := b.Snapshot(); got[0] != 1 {
+        t.Fatalf("modifying a snapshot changed the buffer: %v", got)
+    }
+}
+
+func TestFormatHistoryKeepsLastRecords(t *testing.T) {
+    var records []RequestRecord
+    for i := 1; i <= 15; i++ {
+        records = append(records, RequestRecord{Attempt: i, Status: "pending"})
+    }
+    lines := strings.Split(formatHistory(records, 10), "\n")
+    if len(lines) != 10 {
+        t.Fatalf("expected 10 lines, got %d", len(lines))
+    }
+    if !strings.HasPrefix(lines[0], "attempt 6 ") || !strings.HasPrefix(lines[9], "attempt 15 ") {
+        t.Fatalf("expected attempts 6 to 15, got first %q and last %q", lines[0], lines[9])
+    }
+}
@@ -0,0 +1,145 @@
+package client
+
+import (
+    "math"
+    "math/rand"
+    "sync"
+    "time"
+)
+
+const (
+    // DefaultPoolAlpha is the weight of the newest latency in a server's health score.
+    DefaultPoolAlpha = 0.3
+    // DefaultPoolThresholdMs is the health score above which a server is avoided.
+    DefaultPoolThresholdMs = 1000.0
+    // DefaultPoolHalfLife is how long it takes an idle server's health score
+    // to halve.
+    DefaultPoolHalfLife = 30 * time.Second
+)
+
+// ServerPool spreads requests over several servers, preferring the ones that
+// have been responding quickly. Each server's health score is an
+// exponentially weighted moving average of its latency in milliseconds, so
+// lower is healthier. Failed requests count as twice the threshold. Scores
+// decay towards healthy while a server isn't used, so one that was avoided
+// is tried again once it has had time to recover.
+type ServerPool struct {
+    Alpha       float64       // Weight of the newest latency, between 0 and 1.
+    ThresholdMs float64       // Servers scoring above this are not selected.
+    HalfLife    time.Duration // Zero disables the decay.
+
+    mu      sync.Mutex
+    urls    []string
+    scores  map[string]float64
+    updated map[string]time.Time // When each score was last set.
+    rand    *rand.Rand
+}
+
+// NewServerPool returns a pool over urls, with every server starting out
+// healthy. A pool without servers selects "".
+func NewServerPool(urls []string) *ServerPool {
+    p := &ServerPool{
+        Alpha:       DefaultPoolAlpha,
+        ThresholdMs: DefaultPoolThresholdMs,
+        HalfLife:    DefaultPoolHalfLife,
+        urls:        append([]string(nil), urls...),
+        scores:      make(map[string]float64, len(urls)),
+        updated:     make(map[string]time.Time, len(urls)),
+        rand:        rand.New(rand.NewSource(time.Now().UnixNano())),
+    }
+    for _, u := range urls {
+        p.scores[u] = 0
+    }
+    return p
+}
+
+// WithServerPool makes the client send each request to a server picked from
+// urls by a ServerPool, instead of BaseURL. An empty urls is ignored.
+func WithServerPool(urls []string) ClientOption {
+    return func(c *Client) {
+        if len(urls) > 0 {
+            c.pool = NewServerPool(urls)
+        }
+    }
+}
+
+// serverURL returns the server to send the next request to: one picked by the
+// pool if there is one, otherwise BaseURL.
+func (c *Client) serverURL() string {
+    if c.pool != nil {
+        return c.pool.SelectServer()
+    }
+    return c.BaseURL
+}
+
+// SelectServer picks a server at random, weighted towards lower health
+// scores. Servers over the threshold are skipped unless all of them are, in
+// which case the healthiest is used so the pool can't get stuck.
+func (p *ServerPool) SelectServer() string {
+    p.mu.Lock()
+    defer p.mu.Unlock()
+
+    now := time.Now()
+    var total float64
+    weights := make([]float64, len(p.urls))
+    for i, u := range p.urls {
+        if score := p.score(u, now); score <= p.ThresholdMs {
+            weights[i] = 1 / (score + 1)
+            total += weights[i]
+        }
+    }
+    if total == 0 {
+        return p.healthiest(now)
+    }
+
+    pick := p.rand.Float64() * total
+    for i, w := range weights {
+        if pick < w {
+            return p.urls[i]
+        }
+        pick -= w
+    }
+    return p.healthiest(now)
+}
+
+// healthiest returns the server with the lowest score. Called with mu held.
+func (p *ServerPool) healthiest(now time.Time) string {
+    best := ""
+    for _, u := range p.urls {
+        if best == "" || p.score(u, now) < p.score(best, now) {
+            best = u
+        }
+    }
+    return best
+}
+
+// score returns the health score of url decayed to now. Called with mu held.
+func (p *ServerPool) score(url string, now time.Time) float64 {
+    score := p.scores[url]
+    if p.HalfLife <= 0 || score == 0 {
+        return score
+    }
+    return score * math.Pow(0.5, float64(now.Sub(p.updated[url]))/float64(p.HalfLife))
+}
+
+// RecordResult updates the health score of url after a request to it.
+func (p *ServerPool) RecordResult(url string, success bool, latencyMs float64) {
+    p.mu.Lock()
+    defer p.mu.Unlock()
+    if _, ok := p.scores[url]; !ok {
+        return
+    }
+    if !success && latencyMs < 2*p.ThresholdMs {
+        latencyMs = 2 * p.ThresholdMs
+    }
+    now := time.Now()
+    p.scores[url] = p.Alpha*latencyMs + (1-p.Alpha)*p.score(url, now)
+    p.updated[url] = now
+}
+
+// HealthScore returns the current health score of url.
+func (p *ServerPool) HealthScore(url string) float64 {
+    p.mu.Lock()
+    defer p.mu.Unlock()
+    return p.score(url, time.Now())
+}
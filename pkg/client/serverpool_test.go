@@ -0,0 +1,102 @@
+package client
+
+import (
+    "context"
+    "testing"
+    "time"
+
+    "Video-Translation-Simulator/pkg/testutil"
+    "Video-Translation-Simulator/pkg/types"
+)
+
+func TestServerPoolAvoidsSlowServer(t *testing.T) {
+    pool := NewServerPool([]string{"http://a", "http://b", "http://c"})
+    for i := 0; i < 10; i++ {
+        pool.RecordResult("http://a", true, 20)
+        pool.RecordResult("http://b", true, 5000)
+        pool.RecordResult("http://c", true, 30)
+    }
+    if score := pool.HealthScore("http://b"); score <= pool.ThresholdMs {
+        t.Fatalf("expected the slow server's score over the threshold, got %v", score)
+    }
+
+    seen := map[string]int{}
+    for i := 0; i < 200; i++ {
+        seen[pool.SelectServer()]++
+    }
+    if seen["http://b"] != 0 {
+        t.Fatalf("slow server was still selected %d times", seen["http://b"])
+    }
+    if seen["http://a"] == 0 || seen["http://c"] == 0 {
+        t.Fatalf("expected both healthy servers to be used, got %v", seen)
+    }
+}
+
+func TestServerPoolFallsBackToHealthiest(t *testing.T) {
+    pool := NewServerPool([]string{"http://a", "http://b"})
+    pool.RecordResult("http://a", false, 10)
+    pool.RecordResult("http://b", false, 10)
+    pool.RecordResult("http://b", false, 10)
+
+    if got := pool.SelectServer(); got != "http://a" {
+        t.Fatalf("expected the least unhealthy server, got %s", got)
+    }
+}
+
+func TestClientRecordsServerPoolResults(t *testing.T) {
+    fast := newDelayedServer(time.Hour)
+    defer fast.Close()
+    down := newDelayedServer(time.Hour)
+    down.Close() // Refuses connections.
+
+    c := newTestClient("", WithServerPool([]string{fast.URL, down.URL}))
+    c.pool.HalfLife = 0 // Compare scores exactly.
+    for i := 0; i < 20; i++ {
+        c.RetrieveStatus(context.Background())
+    }
+    // A single failure is enough to make the unreachable server very unlikely to be picked again.
+    if score := c.pool.HealthScore(down.URL); score < c.pool.Alpha*2*c.pool.ThresholdMs {
+        t.Fatalf("expected the unreachable server to be penalised, got %v", score)
+    }
+    if score := c.pool.HealthScore(fast.URL); score > 100 {
+        t.Fatalf("expected the working server to stay healthy, got %v", score)
+    }
+}
+
+func TestServerPoolScoresRecover(t *testing.T) {
+    pool := NewServerPool([]string{"http://a", "http://b"})
+    pool.HalfLife = 10 * time.Millisecond
+    for i := 0; i < 10; i++ {
+        pool.RecordResult("http://a", false, 0)
+    }
+    if score := pool.HealthScore("http://a"); score <= pool.ThresholdMs {
+        t.Fatalf("expected the failing server's score over the threshold, got %v", score)
+    }
+    time.Sleep(100 * time.Millisecond)
+    if score := pool.HealthScore("http://a"); score > pool.ThresholdMs {
+        t.Fatalf("expected the score to decay below the threshold, got %v", score)
+    }
+}
+
+func TestWithServerPoolIgnoresEmptyList(t *testing.T) {
+    if c := newTestClient("http://base", WithServerPool(nil)); c.pool != nil || c.serverURL() != "http://base" {
+        t.Fatalf("expected an empty pool to be ignored, got %v", c.pool)
+    }
+}
+
+func TestProbeAndStreamsUseServerPool(t *testing.T) {
+    srv := testutil.NewDryRunServer()
+    defer srv.Close()
+    srv.QueueStatus("", "completed")
+
+    c := newTestClient("http://unused", WithServerPool([]string{srv.URL}), WithNDJSON(true))
+    if err := c.Probe(context.Background()); err != nil {
+        t.Fatalf("Probe: %v", err)
+    }
+    // Carry on from a pending job, as after a first poll.
+    transitions := c.newStatusTracker("")
+    transitions.last = types.StatusPending
+    if status, err := c.waitForStream(context.Background(), transitions); err != nil || status != "completed" {
+        t.Fatalf("expected completed from the pool's server, got %q, %v", status, err)
+    }
+}
@@ -0,0 +1,161 @@
+package client
+
+import (
+    "bufio"
+    "context"
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "strings"
+    "time"
+
+    "Video-Translation-Simulator/pkg/types"
+)
+
+// StatusEvent is a status pushed by the server on a status stream.
+type StatusEvent struct {
+    Result     string
+    ReceivedAt time.Time
+}
+
+// DefaultSSEReconnectDelay is the first wait before reconnecting a dropped
+// status stream when WithSSEReconnect is given no delay.
+const DefaultSSEReconnectDelay = 500 * time.Millisecond
+
+// WithSSEReconnect makes StreamStatus reconnect streams of jobs created with
+// POST /jobs which drop before a final status, up to maxReconnects times, waiting delay before the first
+// attempt and doubling it for each one after. A non-positive delay uses
+// DefaultSSEReconnectDelay; a non-positive maxReconnects is ignored. Streams of
+// the implicit /status job are never reconnected, as the server starts a new
+// job for a stream opened after the last one finished, which a dropped stream
+// may not have shown.
+func WithSSEReconnect(delay time.Duration, maxReconnects int) ClientOption {
+    return func(c *Client) {
+        if maxReconnects <= 0 {
+            return
+        }
+        if delay <= 0 {
+            delay = DefaultSSEReconnectDelay
+        }
+        c.sseReconnectDelay, c.sseMaxReconnects = delay, maxReconnects
+    }
+}
+
+// StreamStatus subscribes to the server's server-sent events for a job, the
+// one created with jobID on POST /jobs, or the implicit /status job when jobID
+// is empty. It returns once the stream is open; events are then delivered on
+// the channel, which is closed when the stream ends, after the final status,
+// or when ctx is done. With WithSSEReconnect a stream of a job with an ID which
+// drops before the final status is opened again and continues on the same channel, starting
+// from the last status received, which is not sent twice. Like WithNDJSON
+// streams, it bypasses the per-request timeout, rate limiting and the
+// client's own polling state.
+func (c *Client) StreamStatus(ctx context.Context, jobID string) (<-chan StatusEvent, error) {
+    resp, err := c.openStream(ctx, jobID)
+    if err != nil {
+        return nil, err
+    }
+
+    events := make(chan StatusEvent)
+    go func() {
+        defer close(events)
+        last := c.readEvents(ctx, resp, events, "")
+        resp.Body.Close()
+        if jobID == "" {
+            return
+        }
+
+        delay := c.sseReconnectDelay
+        for attempt := 1; attempt <= c.sseMaxReconnects; attempt++ {
+            if ctx.Err() != nil || types.Status(last).IsTerminal() {
+                return
+            }
+            c.logf("Stream: Dropped before a final status, reconnecting in %v (%d of %d)", delay, attempt, c.sseMaxReconnects)
+            select {
+            case <-time.After(delay):
+            case <-ctx.Done():
+                return
+            }
+            delay *= 2
+            resp, err := c.openStream(ctx, jobID)
+            if err != nil {
+                c.logf("Stream: Reconnecting failed: %v", err)
+                continue
+            }
+            last = c.readEvents(ctx, resp, events, last)
+            resp.Body.Close()
+        }
+    }()
+    return events, nil
+}
+
+// openStream requests the event stream of the job with jobID.
+func (c *Client) openStream(ctx context.Context, jobID string) (*http.Response, error) {
+    target := c.serverURL() + jobStatusPath(jobID) + "/stream"
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+    if err != nil {
+        return nil, err
+    }
+    req.Header.Set("Accept", "text/event-stream")
+
+    resp, err := c.httpClient.Do(req)
+    if err != nil {
+        return nil, err
+    }
+    if resp.StatusCode != http.StatusOK {
+        resp.Body.Close()
+        return nil, fmt.Errorf("status stream from %s: unexpected status %d", target, resp.StatusCode)
+    }
+    return resp, nil
+}
+
+// readEvents parses the data lines of an event stream, sending an event for
+// each one carrying a result, until the stream or ctx ends. A first event
+// repeating last, the status a dropped stream ended on, is skipped. It returns
+// the last status received.
+func (c *Client) readEvents(ctx context.Context, resp *http.Response, events chan<- StatusEvent, last string) string {
+    resuming := last != ""
+    scanner := bufio.NewScanner(resp.Body)
+    var data strings.Builder
+    for scanner.Scan() {
+        line := scanner.Text()
+        if field, ok := strings.CutPrefix(line, "data:"); ok {
+            if data.Len() > 0 {
+                data.WriteByte('\n')
+            }
+            data.WriteString(strings.TrimPrefix(field, " "))
+            continue
+        }
+        // Other fields, such as comments and IDs, are ignored. A blank line
+        // ends the event.
+        if line != "" || data.Len() == 0 {
+            continue
+        }
+
+        var response struct {
+            Result string `json:"result"`
+        }
+        payload := data.String()
+        data.Reset()
+        if err := json.Unmarshal([]byte(payload), &response); err != nil || response.Result == "" {
+            c.logf("Stream: Ignoring malformed event: %q", payload)
+            continue
+        }
+        c.logf("Stream: Received status: %s", response.Result)
+        replayed := resuming && response.Result == last
+        resuming = false
+        last = response.Result
+        if replayed {
+            continue
+        }
+        select {
+        case events <- StatusEvent{Result: response.Result, ReceivedAt: time.Now()}:
+        case <-ctx.Done():
+            return last
+        }
+    }
+    if err := scanner.Err(); err != nil && ctx.Err() == nil {
+        c.logf("Stream: Ended with error: %v", err)
+    }
+    return last
+}
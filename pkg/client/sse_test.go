@@ -0,0 +1,159 @@
+package client
+
+import (
+    "context"
+    "fmt"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "sync/atomic"
+    "testing"
+    "time"
+)
+
+func TestStreamStatus(t *testing.T) {
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if r.URL.Path != "/jobs/abc123/status/stream" {
+            http.NotFound(w, r)
+            return
+        }
+        w.Header().Set("Content-Type", "text/event-stream")
+        fmt.Fprint(w, ": a comment\n\n")
+        fmt.Fprint(w, "data: {\"result\":\"pending\"}\n\n")
+        fmt.Fprint(w, "data: not json\n\n")
+        fmt.Fprint(w, "data: {\"result\":\"pending\"}\n\n")
+        fmt.Fprint(w, "data: {\"result\":\"completed\"}\n\n")
+    }))
+    defer srv.Close()
+
+    start := time.Now()
+    events, err := newTestClient(srv.URL).StreamStatus(context.Background(), "abc123")
+    if err != nil {
+        t.Fatalf("StreamStatus: %v", err)
+    }
+    var results []string
+    for e := range events {
+        if e.ReceivedAt.Before(start) {
+            t.Fatalf("event received at %v, before the stream was opened", e.ReceivedAt)
+        }
+        results = append(results, e.Result)
+    }
+    if got := strings.Join(results, ","); got != "pending,pending,completed" {
+        t.Fatalf("expected pending,pending,completed, got %s", got)
+    }
+
+    if _, err := newTestClient(srv.URL).StreamStatus(context.Background(), "missing"); err == nil || !strings.Contains(err.Error(), "404") {
+        t.Fatalf("expected an error with the 404, got %v", err)
+    }
+}
+
+func TestStreamStatusCancel(t *testing.T) {
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        w.Header().Set("Content-Type", "text/event-stream")
+        fmt.Fprint(w, "data: {\"result\":\"pending\"}\n\n")
+        w.(http.Flusher).Flush()
+        <-r.Context().Done()
+    }))
+    defer srv.Close()
+
+    ctx, cancel := context.WithCancel(context.Background())
+    events, err := newTestClient(srv.URL).StreamStatus(ctx, "")
+    if err != nil {
+        t.Fatalf("StreamStatus: %v", err)
+    }
+    if e := <-events; e.Result != "pending" {
+        t.Fatalf("expected pending, got %q", e.Result)
+    }
+    cancel()
+    select {
+    case _, ok := <-events:
+        if ok {
+            t.Fatal("expected no more events after cancelling")
+        }
+    case <-time.After(2 * time.Second):
+        t.Fatal("channel not closed after cancelling")
+    }
+}
+
+func TestStreamStatusReconnect(t *testing.T) {
+    var connects atomic.Int32
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        n := connects.Add(1)
+        w.Header().Set("Content-Type", "text/event-stream")
+        if n == 1 {
+            // Drop the connection after 3 events, before the final status.
+            fmt.Fprint(w, "data: {\"result\":\"pending\"}\n\n")
+            fmt.Fprint(w, "data: {\"result\":\"pending\"}\n\n")
+            fmt.Fprint(w, "data: {\"result\":\"processing\"}\n\n")
+            return
+        }
+        fmt.Fprint(w, "data: {\"result\":\"processing\"}\n\n")
+        fmt.Fprint(w, "data: {\"result\":\"completed\"}\n\n")
+    }))
+    defer srv.Close()
+
+    c := newTestClient(srv.URL, WithSSEReconnect(10*time.Millisecond, 3))
+    events, err := c.StreamStatus(context.Background(), "abc123")
+    if err != nil {
+        t.Fatalf("StreamStatus: %v", err)
+    }
+    var results []string
+    for e := range events {
+        results = append(results, e.Result)
+    }
+    if got := strings.Join(results, ","); got != "pending,pending,processing,completed" {
+        t.Fatalf("expected pending,pending,processing,completed, got %s", got)
+    }
+    if n := connects.Load(); n != 2 {
+        t.Fatalf("expected 2 connections, got %d", n)
+    }
+}
+
+func TestStreamStatusReconnectExhausted(t *testing.T) {
+    var connects atomic.Int32
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        connects.Add(1)
+        w.Header().Set("Content-Type", "text/event-stream")
+        fmt.Fprint(w, "data: {\"result\":\"pending\"}\n\n")
+    }))
+    defer srv.Close()
+
+    c := newTestClient(srv.URL, WithSSEReconnect(time.Millisecond, 2))
+    events, err := c.StreamStatus(context.Background(), "abc123")
+    if err != nil {
+        t.Fatalf("StreamStatus: %v", err)
+    }
+    var results []string
+    for e := range events {
+        results = append(results, e.Result)
+    }
+    if got := strings.Join(results, ","); got != "pending" {
+        t.Fatalf("expected a single pending, got %s", got)
+    }
+    if n := connects.Load(); n != 3 {
+        t.Fatalf("expected 3 connections, got %d", n)
+    }
+}
+
+func TestStreamStatusImplicitJobNotReconnected(t *testing.T) {
+    var connects atomic.Int32
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        connects.Add(1)
+        w.Header().Set("Content-Type", "text/event-stream")
+        fmt.Fprint(w, "data: {\"result\":\"pending\"}\n\n")
+    }))
+    defer srv.Close()
+
+    // The job may have finished after the stream dropped, and a new stream
+    // would start the next one.
+    c := newTestClient(srv.URL, WithSSEReconnect(time.Millisecond, 2))
+    events, err := c.StreamStatus(context.Background(), "")
+    if err != nil {
+        t.Fatalf("StreamStatus: %v", err)
+    }
+    for range events {
+    }
+    if n := connects.Load(); n != 1 {
+        t.Fatalf("expected 1 connection, got %d", n)
+    }
+}
@@ -0,0 +1,75 @@
+package client
+
+import (
+    "encoding/json"
+    "net/http"
+    "sync"
+    "time"
+)
+
+// ClientStats summarises the client's polling activity since it was created.
+type ClientStats struct {
+    TotalAttempts     int       `json:"total_attempts"`
+    TotalSuccesses    int       `json:"total_successes"` // Attempts which got a status back from the server.
+    TotalErrors       int       `json:"total_errors"`    // Attempts which failed, e.g. timeouts or non-200 responses.
+    CurrentJobAttempt int       `json:"current_job_attempt"`
+    AverageDelayMs    float64   `json:"average_delay_ms"` // Mean backoff scheduled between attempts.
+    LastStatus        string    `json:"last_status"`
+    LastRequestAt     time.Time `json:"last_request_at"`
+}
+
+// pollStats accumulates ClientStats. It has its own lock so Stats can be read
+// without waiting for c.mu, which is held for the whole of HandleStatusRequest.
+type pollStats struct {
+    mu         sync.RWMutex
+    stats      ClientStats
+    delays     int
+    totalDelay time.Duration
+}
+
+// recordAttempt counts a request to the server made as attempt of the current job.
+func (p *pollStats) recordAttempt(attempt int, status string, err error) {
+    p.mu.Lock()
+    defer p.mu.Unlock()
+    p.stats.TotalAttempts++
+    if err != nil {
+        p.stats.TotalErrors++
+    } else {
+        p.stats.TotalSuccesses++
+        p.stats.LastStatus = status
+    }
+    p.stats.CurrentJobAttempt = attempt
+    p.stats.LastRequestAt = time.Now()
+}
+
+// recordDelay counts a backoff scheduled before the next attempt.
+func (p *pollStats) recordDelay(d time.Duration) {
+    p.mu.Lock()
+    defer p.mu.Unlock()
+    p.delays++
+    p.totalDelay += d
+}
+
+func (p *pollStats) snapshot() ClientStats {
+    p.mu.RLock()
+    defer p.mu.RUnlock()
+    s := p.stats
+    if p.delays > 0 {
+        s.AverageDelayMs = float64(p.totalDelay) / float64(p.delays) / float64(time.Millisecond)
+    }
+    return s
+}
+
+// Stats returns the client's polling statistics. It is safe to call while
+// polling is in progress.
+func (c *Client) Stats() ClientStats {
+    return c.stats.snapshot()
+}
+
+// HandleStatsRequest serves the client's polling statistics as JSON.
+func (c *Client) HandleStatsRequest(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Content-Type", "application/json")
+    if err := json.NewEncoder(w).Encode(c.Stats()); err != nil {
+        c.logf("Error encoding stats: %v", err)
+    }
+}
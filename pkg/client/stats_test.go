@@ -0,0 +1,59 @@
+package client
+
+import (
+    "context"
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "sync/atomic"
+    "testing"
+    "time"
+)
+
+func TestStatsCountTenAttempts(t *testing.T) {
+    // Three failed requests, six pending polls and then the final status.
+    var calls atomic.Int32
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        n := calls.Add(1)
+        switch {
+        case n <= 3:
+            http.Error(w, "unavailable", http.StatusServiceUnavailable)
+        case n < 10:
+            json.NewEncoder(w).Encode(map[string]string{"result": "pending"})
+        default:
+            json.NewEncoder(w).Encode(map[string]string{"result": "completed"})
+        }
+    }))
+    defer srv.Close()
+
+    c := newTestClient(srv.URL, WithBackoff(constantBackoff(2*time.Millisecond)))
+    start := time.Now()
+    if status, err := c.WaitForCompletion(context.Background()); err != nil || status != "completed" {
+        t.Fatalf("expected completed, got %q, %v", status, err)
+    }
+
+    stats := c.Stats()
+    if stats.TotalAttempts != 10 || stats.TotalSuccesses != 7 || stats.TotalErrors != 3 {
+        t.Fatalf("unexpected counters: %+v", stats)
+    }
+    if stats.CurrentJobAttempt != 10 || stats.LastStatus != "completed" {
+        t.Fatalf("unexpected job state: %+v", stats)
+    }
+    // A backoff is scheduled after each of the first nine attempts.
+    if stats.AverageDelayMs != 2 {
+        t.Fatalf("expected an average delay of 2ms, got %v", stats.AverageDelayMs)
+    }
+    if stats.LastRequestAt.Before(start) {
+        t.Fatalf("expected last request after %v, got %v", start, stats.LastRequestAt)
+    }
+
+    rec := httptest.NewRecorder()
+    c.HandleStatsRequest(rec, httptest.NewRequest(http.MethodGet, "/debug/stats", nil))
+    var served ClientStats
+    if err := json.NewDecoder(rec.Body).Decode(&served); err != nil {
+        t.Fatalf("decoding stats: %v", err)
+    }
+    if served.TotalAttempts != 10 || served.AverageDelayMs != 2 {
+        t.Fatalf("unexpected stats from /debug/stats: %+v", served)
+    }
+}
@@ -0,0 +1,220 @@
+package client
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "net/http"
+    "net/url"
+    "strings"
+    "time"
+
+    "Video-Translation-Simulator/pkg/types"
+)
+
+// SubmitResponse is the server's answer to POST /jobs.
+type SubmitResponse struct {
+    JobID                 string `json:"job_id"`
+    PollURL               string `json:"poll_url"`
+    EstimatedDelaySeconds int    `json:"estimated_delay_seconds"`
+    FileSizeBytes         int64  `json:"file_size_bytes,omitempty"` // Of the file sent with MultipartUploadClient.
+}
+
+// submittedJob is the job last created with SubmitJob.
+type submittedJob struct {
+    id      string
+    baseURL string // The server the job was submitted to, which alone holds it.
+    path    string
+}
+
+// WithJobHeaders sends headers with every job created by SubmitJob. The
+// server keeps those named X-Job-Correlation-*, e.g. X-Job-Correlation-Id,
+// and echoes them back on each of the job's status responses.
+func WithJobHeaders(headers map[string]string) ClientOption {
+    return func(c *Client) {
+        c.jobHeaders = make(map[string]string, len(headers))
+        for name, value := range headers {
+            c.jobHeaders[name] = value
+        }
+    }
+}
+
+// WithJobMetadata sends metadata with every job created by SubmitJob, e.g.
+// {"source_lang": "en"}, which ListJobs can then filter on.
+func WithJobMetadata(metadata map[string]string) ClientOption {
+    return func(c *Client) {
+        c.jobMetadata = make(map[string]string, len(metadata))
+        for key, value := range metadata {
+            c.jobMetadata[key] = value
+        }
+    }
+}
+
+// WithJobLabels sends labels with every job created by SubmitJob, e.g.
+// {"env": "prod"}, which ListJobs can then select jobs by with
+// JobFilter.Selector.
+func WithJobLabels(labels map[string]string) ClientOption {
+    return func(c *Client) {
+        c.jobLabels = make(map[string]string, len(labels))
+        for key, value := range labels {
+            c.jobLabels[key] = value
+        }
+    }
+}
+
+// ReasonJobTimeout is the reason the server gives for failing a job which ran
+// past the timeout set with WithJobTimeout.
+const ReasonJobTimeout = "job_timeout"
+
+// ErrJobTimeout is returned by WaitForCompletion, along with the "error"
+// status, when the server failed the job for running past its timeout.
+var ErrJobTimeout = errors.New("job timed out")
+
+// WithJobTimeout asks the server to fail jobs created by SubmitJob if they are
+// still pending after d, rounded up to whole seconds. WaitForCompletion then
+// returns ErrJobTimeout rather than a plain "error".
+func WithJobTimeout(d time.Duration) ClientOption {
+    return func(c *Client) {
+        if d > 0 {
+            c.jobTimeout = d
+        }
+    }
+}
+
+// SubmitJob creates a job on the server with POST /jobs, under id or an ID
+// chosen by the server when id is empty. The server answers 202 Accepted
+// with a Location header, which the client polls from then on instead of
+// working out the job's status URL itself. Jobs are held in the memory of the
+// server which created them, so with a server pool the job is still polled on
+// the server it was submitted to.
+func (c *Client) SubmitJob(ctx context.Context, id string) (SubmitResponse, error) {
+    return c.submitJob(ctx, id, nil)
+}
+
+// ScheduleJob is SubmitJob for a job which the server keeps "scheduled" until
+// scheduledAt, after which it is pending and its delay starts. Polling treats
+// a scheduled job like a pending one.
+func (c *Client) ScheduleJob(ctx context.Context, id string, scheduledAt time.Time) (SubmitResponse, error) {
+    return c.submitJob(ctx, id, &scheduledAt)
+}
+
+// submitJob is SubmitJob, scheduling the job if scheduledAt is not nil.
+func (c *Client) submitJob(ctx context.Context, id string, scheduledAt *time.Time) (SubmitResponse, error) {
+    body, err := json.Marshal(struct {
+        ID             string     `json:"id,omitempty"`
+        TimeoutSeconds int        `json:"timeout_seconds,omitempty"`
+        ScheduledAt    *time.Time `json:"scheduled_at,omitempty"`
+        Metadata       map[string]string `json:"metadata,omitempty"`
+        Labels         map[string]string `json:"labels,omitempty"`
+    }{id, int((c.jobTimeout + time.Second - 1) / time.Second), scheduledAt, c.jobMetadata, c.jobLabels})
+    if err != nil {
+        return SubmitResponse{}, err
+    }
+    ctx, cancel := context.WithTimeout(ctx, c.timeout)
+    defer cancel()
+    baseURL := c.serverURL()
+    req, err := http.NewRequestWithContext(ctx, http.MethodPost, baseURL+"/jobs", bytes.NewReader(body))
+    if err != nil {
+        return SubmitResponse{}, err
+    }
+    for name, value := range c.jobHeaders {
+        req.Header.Set(name, value)
+    }
+    req.Header.Set("Content-Type", "application/json")
+    resp, err := c.httpClient.Do(req)
+    if err != nil {
+        return SubmitResponse{}, err
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusAccepted {
+        msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+        return SubmitResponse{}, fmt.Errorf("submitting job: server responded with %d: %s", resp.StatusCode, bytes.TrimSpace(msg))
+    }
+
+    var submitted SubmitResponse
+    if err := json.NewDecoder(io.LimitReader(resp.Body, c.maxResponseBytes)).Decode(&submitted); err != nil {
+        return SubmitResponse{}, fmt.Errorf("decoding submitted job: %w", err)
+    }
+    location, err := url.Parse(resp.Header.Get("Location"))
+    if err != nil || location.Path == "" {
+        return SubmitResponse{}, fmt.Errorf("submitting job: invalid Location %q", resp.Header.Get("Location"))
+    }
+    // A relative Location is on the server the job was submitted to.
+    if location.IsAbs() {
+        baseURL = location.Scheme + "://" + location.Host
+    }
+    c.submitted.Store(&submittedJob{id: submitted.JobID, baseURL: baseURL, path: location.EscapedPath()})
+    return submitted, nil
+}
+
+// RetryResponse is the server's answer to POST /jobs/{id}/retry.
+type RetryResponse struct {
+    JobID      string `json:"job_id"`
+    RetryCount int    `json:"retry_count"`
+    Status     string `json:"status"`
+}
+
+// WithRetryOnError makes polling retry the client's job with RetryJob when it
+// ends in "error", and carry on polling it, until the server refuses to retry
+// it again. It only applies to jobs created with SubmitJob or polled with
+// WithJobID, as the implicit /status job can't be retried.
+func WithRetryOnError(enabled bool) ClientOption {
+    return func(c *Client) {
+        c.retryOnError = enabled
+    }
+}
+
+// RetryJob starts the client's job again with POST /jobs/{id}/retry after it
+// ended in "error". The server refuses with 409 once the job has used up its
+// retries, or if it didn't fail. The job's final status is forgotten, both by
+// the client and by a ResultCache with a Delete method.
+func (c *Client) RetryJob(ctx context.Context) (RetryResponse, error) {
+    id := c.ownJobID()
+    if id == "" {
+        return RetryResponse{}, errors.New("retrying job: no job to retry, submit one with SubmitJob or set WithJobID")
+    }
+    ctx, cancel := context.WithTimeout(ctx, c.timeout)
+    defer cancel()
+    baseURL, path := c.statusTarget()
+    req, err := http.NewRequestWithContext(ctx, http.MethodPost, baseURL+strings.TrimSuffix(path, "/status")+"/retry", nil)
+    if err != nil {
+        return RetryResponse{}, err
+    }
+    resp, err := c.httpClient.Do(req)
+    if err != nil {
+        return RetryResponse{}, err
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+        msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+        return RetryResponse{}, fmt.Errorf("retrying job: server responded with %d: %s", resp.StatusCode, bytes.TrimSpace(msg))
+    }
+
+    var retried RetryResponse
+    if err := json.NewDecoder(io.LimitReader(resp.Body, c.maxResponseBytes)).Decode(&retried); err != nil {
+        return RetryResponse{}, fmt.Errorf("decoding retried job: %w", err)
+    }
+    c.finals.Delete(id)
+    c.forgetResult()
+    return retried, nil
+}
+
+// retryFailedJob retries the client's job after polling saw it fail, if
+// WithRetryOnError is set, reporting whether it started again. transitions
+// then expects the job to be pending again.
+func (c *Client) retryFailedJob(ctx context.Context, transitions *statusTracker) bool {
+    if !c.retryOnError || c.ownJobID() == "" {
+        return false
+    }
+    retried, err := c.RetryJob(ctx)
+    if err != nil {
+        c.logf("Not retrying failed job: %v", err)
+        return false
+    }
+    c.logf("Job failed, started retry %d", retried.RetryCount)
+    transitions.last, transitions.reason = types.StatusPending, ""
+    return true
+}
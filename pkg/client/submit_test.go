@@ -0,0 +1,300 @@
+package client
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "sync"
+    "sync/atomic"
+    "testing"
+    "time"
+
+    "Video-Translation-Simulator/pkg/testutil/fixtures"
+)
+
+func TestSubmitJobFollowsLocation(t *testing.T) {
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        switch {
+        case r.Method == http.MethodPost && r.URL.Path == "/jobs":
+            var req struct{ ID string }
+            json.NewDecoder(r.Body).Decode(&req)
+            // Deliberately not the usual /jobs/{id}/status.
+            w.Header().Set("Location", "/elsewhere/"+req.ID)
+            w.WriteHeader(http.StatusAccepted)
+            fmt.Fprintf(w, `{"job_id":%q,"poll_url":"/elsewhere/%s","estimated_delay_seconds":3}`, req.ID, req.ID)
+        case r.URL.Path == "/elsewhere/abc":
+            w.Write([]byte(`{"result":"completed"}`))
+        default:
+            http.NotFound(w, r)
+        }
+    }))
+    defer srv.Close()
+
+    c := newTestClient(srv.URL)
+    job, err := c.SubmitJob(context.Background(), "abc")
+    if err != nil {
+        t.Fatalf("SubmitJob: %v", err)
+    }
+    if job.JobID != "abc" || job.EstimatedDelaySeconds != 3 {
+        t.Fatalf("unexpected response %+v", job)
+    }
+    if status, err := c.RetrieveStatus(context.Background()); err != nil || status != "completed" {
+        t.Fatalf("expected completed from the Location URL, got %q, %v", status, err)
+    }
+}
+
+func TestSubmitJobAgainstServer(t *testing.T) {
+    c := newTestClient(serverURL)
+    id := fmt.Sprintf("submit-%d", time.Now().UnixNano())
+    job, err := c.SubmitJob(context.Background(), id)
+    if err != nil {
+        t.Fatalf("SubmitJob: %v", err)
+    }
+    if job.JobID != id || job.PollURL != "/jobs/"+id+"/status" {
+        t.Fatalf("unexpected response %+v", job)
+    }
+    if status, err := c.RetrieveStatus(context.Background()); err != nil || status != "pending" {
+        t.Fatalf("expected the new job to be pending, got %q, %v", status, err)
+    }
+
+    // A second job with the same ID is refused.
+    if _, err := c.SubmitJob(context.Background(), id); err == nil {
+        t.Fatal("expected a duplicate job ID to be rejected")
+    }
+}
+
+// newJobServer returns a server holding the jobs submitted to it in memory,
+// reporting pending on their first poll and completed after, and 404 for
+// jobs submitted elsewhere.
+func newJobServer(polls *atomic.Int32) *httptest.Server {
+    var mu sync.Mutex
+    jobs := map[string]int{}
+    return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        mu.Lock()
+        defer mu.Unlock()
+        if r.Method == http.MethodPost && r.URL.Path == "/jobs" {
+            var req struct{ ID string }
+            json.NewDecoder(r.Body).Decode(&req)
+            jobs[req.ID] = 0
+            w.Header().Set("Location", "/jobs/"+req.ID+"/status")
+            w.WriteHeader(http.StatusAccepted)
+            fmt.Fprintf(w, `{"job_id":%q}`, req.ID)
+            return
+        }
+        id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/jobs/"), "/status")
+        n, ok := jobs[id]
+        if !ok {
+            http.NotFound(w, r)
+            return
+        }
+        polls.Add(1)
+        jobs[id] = n + 1
+        if n == 0 {
+            w.Write([]byte(`{"result":"pending"}`))
+            return
+        }
+        w.Write([]byte(`{"result":"completed"}`))
+    }))
+}
+
+func TestSubmitJobPollsTheSubmittingServer(t *testing.T) {
+    var polls atomic.Int32
+    a, b := newJobServer(&polls), newJobServer(&polls)
+    defer a.Close()
+    defer b.Close()
+
+    c := newTestClient("http://unused", WithServerPool([]string{a.URL, b.URL}), WithBackoff(constantBackoff(0)))
+    for i := 0; i < 10; i++ {
+        if _, err := c.SubmitJob(context.Background(), fmt.Sprintf("job-%d", i)); err != nil {
+            t.Fatalf("SubmitJob: %v", err)
+        }
+        if status, err := c.WaitForCompletion(context.Background()); err != nil || status != "completed" {
+            t.Fatalf("job %d: expected completed from the submitting server, got %q, %v", i, status, err)
+        }
+    }
+}
+
+func TestSubmitJobUsesResultCache(t *testing.T) {
+    var polls atomic.Int32
+    srv := newJobServer(&polls)
+    defer srv.Close()
+
+    c := newTestClient(srv.URL, WithBackoff(constantBackoff(0)), WithResultCache(NewTTLCache(0)))
+    if _, err := c.SubmitJob(context.Background(), "cached"); err != nil {
+        t.Fatalf("SubmitJob: %v", err)
+    }
+    for i := 0; i < 2; i++ {
+        if status, err := c.WaitForCompletion(context.Background()); err != nil || status != "completed" {
+            t.Fatalf("wait %d: expected completed, got %q, %v", i+1, status, err)
+        }
+    }
+    if n := polls.Load(); n != 2 {
+        t.Fatalf("expected the second wait to use the cache, got %d polls", n)
+    }
+}
+
+func TestSubmitJobTimeout(t *testing.T) {
+    release := make(chan struct{})
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        <-release
+    }))
+    defer srv.Close()
+    defer close(release)
+
+    c := newTestClient(srv.URL, WithTimeout(50*time.Millisecond))
+    start := time.Now()
+    if _, err := c.SubmitJob(context.Background(), "slow"); !errors.Is(err, context.DeadlineExceeded) {
+        t.Fatalf("expected the submission to time out, got %v", err)
+    }
+    if elapsed := time.Since(start); elapsed > time.Second {
+        t.Fatalf("expected the timeout to cut the request short, took %v", elapsed)
+    }
+}
+
+// newFlakyJobServer returns a server holding the job "flaky", which reports
+// pending on the first poll of each run and then fails until it has been
+// retried failures times, after which it completes. It allows up to
+// maxRetries retries.
+func newFlakyJobServer(failures, maxRetries int) *httptest.Server {
+    var mu sync.Mutex
+    retries, polls := 0, 0
+    return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        mu.Lock()
+        defer mu.Unlock()
+        switch {
+        case r.Method == http.MethodPost && r.URL.Path == "/jobs/flaky/retry":
+            if polls < 2 || retries >= failures || retries >= maxRetries {
+                http.Error(w, "not retryable", http.StatusConflict)
+                return
+            }
+            retries++
+            polls = 0
+            fmt.Fprintf(w, `{"job_id":"flaky","retry_count":%d,"status":"pending"}`, retries)
+        case r.URL.Path == "/jobs/flaky/status":
+            polls++
+            w.Header().Set("Content-Type", "application/json")
+            switch {
+            case polls == 1:
+                w.Write([]byte(`{"result":"pending"}`))
+            case retries < failures:
+                w.Write([]byte(`{"result":"error"}`))
+            default:
+                w.Write([]byte(`{"result":"completed"}`))
+            }
+        default:
+            http.NotFound(w, r)
+        }
+    }))
+}
+
+func TestRetryOnErrorRetriesFailedJob(t *testing.T) {
+    srv := newFlakyJobServer(1, 1)
+    defer srv.Close()
+
+    // The failure is cached by both caches until the retry drops it.
+    cache := NewTTLCache(0)
+    c := newTestClient(srv.URL, WithJobID("flaky"), WithBackoff(constantBackoff(0)), WithRetryOnError(true),
+        WithResultCache(cache), WithResponseCaching(time.Minute))
+    if status, err := c.WaitForCompletion(context.Background()); err != nil || status != "completed" {
+        t.Fatalf("expected the retried job to complete, got %q, %v", status, err)
+    }
+    if s, ok := cache.Get(srv.URL + "/jobs/flaky/status"); !ok || s != "completed" {
+        t.Fatalf("expected completed to be cached, got %q, %v", s, ok)
+    }
+}
+
+func TestRetryOnErrorGivesUp(t *testing.T) {
+    srv := newFlakyJobServer(5, 2)
+    defer srv.Close()
+
+    c := newTestClient(srv.URL, WithJobID("flaky"), WithBackoff(constantBackoff(0)), WithRetryOnError(true))
+    if status, err := c.WaitForCompletion(context.Background()); err != nil || status != "error" {
+        t.Fatalf("expected error once the retries are used up, got %q, %v", status, err)
+    }
+    if resp, err := c.RetryJob(context.Background()); err == nil || !strings.Contains(err.Error(), "409") {
+        t.Fatalf("expected the server to refuse another retry, got %+v, %v", resp, err)
+    }
+}
+
+func TestRetryJobWithoutJob(t *testing.T) {
+    c := newTestClient("http://unused")
+    if _, err := c.RetryJob(context.Background()); err == nil {
+        t.Fatal("expected an error retrying the implicit job")
+    }
+}
+
+func TestSubmitJobSendsJobHeaders(t *testing.T) {
+    var mu sync.Mutex
+    var echoed []string
+    c := newTestClient(serverURL, WithBackoff(constantBackoff(100*time.Millisecond)),
+        WithJobHeaders(map[string]string{"X-Job-Correlation-Id": "order-42"}),
+        WithResponseInterceptor(func(resp *http.Response, body []byte) (*http.Response, []byte, error) {
+            mu.Lock()
+            defer mu.Unlock()
+            echoed = append(echoed, resp.Header.Get("X-Job-Correlation-Id"))
+            return resp, body, nil
+        }))
+    if _, err := c.SubmitJob(context.Background(), fmt.Sprintf("correlated-%d", time.Now().UnixNano())); err != nil {
+        t.Fatalf("SubmitJob: %v", err)
+    }
+    if status, err := c.WaitForCompletion(context.Background()); err != nil || status != "completed" {
+        t.Fatalf("expected completed, got %q, %v", status, err)
+    }
+
+    mu.Lock()
+    defer mu.Unlock()
+    if len(echoed) < 2 {
+        t.Fatalf("expected several polls, got %d", len(echoed))
+    }
+    for i, got := range echoed {
+        if got != "order-42" {
+            t.Fatalf("poll %d: expected X-Job-Correlation-Id order-42, got %q", i+1, got)
+        }
+    }
+}
+
+func TestSubmitJobTimesOut(t *testing.T) {
+    _, ts := fixtures.NewFixtureServer(fixtures.FixtureSlowServer)
+    defer ts.Close()
+
+    c := newTestClient(ts.URL, WithBackoff(constantBackoff(100*time.Millisecond)), WithJobTimeout(time.Second))
+    if _, err := c.SubmitJob(context.Background(), "slow"); err != nil {
+        t.Fatalf("SubmitJob: %v", err)
+    }
+    start := time.Now()
+    status, err := c.WaitForCompletion(context.Background())
+    if !errors.Is(err, ErrJobTimeout) || status != "error" {
+        t.Fatalf("expected error with ErrJobTimeout, got %q, %v", status, err)
+    }
+    if elapsed := time.Since(start); elapsed > 2*time.Second {
+        t.Fatalf("expected the job to time out within 2s, took %v", elapsed)
+    }
+}
+
+func TestScheduleJob(t *testing.T) {
+    _, ts := fixtures.NewFixtureServer(fixtures.FixtureFastServer)
+    defer ts.Close()
+
+    var statuses []string
+    c := newTestClient(ts.URL, WithBackoff(constantBackoff(100*time.Millisecond)))
+    start := time.Now()
+    if _, err := c.ScheduleJob(context.Background(), "later", start.Add(200*time.Millisecond)); err != nil {
+        t.Fatalf("ScheduleJob: %v", err)
+    }
+    status, err := c.poll(context.Background(), c.newStatusTracker(c.ownJobID()), func(status string) {
+        statuses = append(statuses, status)
+    })
+    if err != nil || status != "completed" {
+        t.Fatalf("expected the scheduled job to complete, got %q, %v", status, err)
+    }
+    if statuses[0] != "scheduled" || !strings.Contains(strings.Join(statuses, " "), "scheduled pending") {
+        t.Fatalf("expected the job to be scheduled, then pending, got %v", statuses)
+    }
+    if elapsed := time.Since(start); elapsed < 1200*time.Millisecond {
+        t.Fatalf("expected the delay to start at the scheduled time, completed after %v", elapsed)
+    }
+}
@@ -0,0 +1,137 @@
+package client
+
+import (
+    "crypto/tls"
+    "io"
+    "net/http"
+    "net/http/httptrace"
+    "sync"
+    "time"
+)
+
+// RequestTiming is the time spent in each phase of a single request to the server.
+// Phases that did not happen (e.g. DNS on a reused connection, TLS over plain
+// HTTP) are left at zero.
+type RequestTiming struct {
+    URL       string  `json:"url"`
+    DNSMs     float64 `json:"dns_ms"`
+    ConnectMs float64 `json:"connect_ms"`
+    TLSMs     float64 `json:"tls_ms"`
+    TTFBMs    float64 `json:"ttfb_ms"`
+    TotalMs   float64 `json:"total_ms"`
+}
+
+// TracingTransport is a RoundTripper which wraps another one and reports a
+// RequestTiming for every request it sends. It can be layered with any other
+// transport, and is installed on a Client with WithTransport.
+type TracingTransport struct {
+    Base   http.RoundTripper   // Defaults to http.DefaultTransport when nil.
+    Report func(RequestTiming) // Called once per request, after the body is closed.
+}
+
+// NewTracingTransport returns a TracingTransport wrapping base which passes
+// every timing to report.
+func NewTracingTransport(base http.RoundTripper, report func(RequestTiming)) *TracingTransport {
+    return &TracingTransport{Base: base, Report: report}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *TracingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+    rec := &timingRecorder{timing: RequestTiming{URL: req.URL.String()}, start: time.Now()}
+    req = req.WithContext(httptrace.WithClientTrace(req.Context(), rec.clientTrace()))
+
+    base := t.Base
+    if base == nil {
+        base = http.DefaultTransport
+    }
+    resp, err := base.RoundTrip(req)
+    if err != nil {
+        t.report(rec.finish())
+        return nil, err
+    }
+
+    // Total time includes reading the body, so only report once the caller is done with it.
+    resp.Body = &tracedBody{ReadCloser: resp.Body, onClose: func() { t.report(rec.finish()) }}
+    return resp, nil
+}
+
+func (t *TracingTransport) report(timing RequestTiming) {
+    if t.Report != nil {
+        t.Report(timing)
+    }
+}
+
+// timingRecorder collects the phase timings of one request. The trace hooks can
+// fire from the transport's dialing goroutines, hence the mutex.
+type timingRecorder struct {
+    mu           sync.Mutex
+    timing       RequestTiming
+    start        time.Time
+    dnsStart     time.Time
+    connectStart time.Time
+    tlsStart     time.Time
+}
+
+func (r *timingRecorder) clientTrace() *httptrace.ClientTrace {
+    return &httptrace.ClientTrace{
+        DNSStart: func(httptrace.DNSStartInfo) {
+            r.mu.Lock()
+            defer r.mu.Unlock()
+            r.dnsStart = time.Now()
+        },
+        DNSDone: func(httptrace.DNSDoneInfo) {
+            r.mu.Lock()
+            defer r.mu.Unlock()
+            r.timing.DNSMs = millis(time.Since(r.dnsStart))
+        },
+        ConnectStart: func(string, string) {
+            r.mu.Lock()
+            defer r.mu.Unlock()
+            r.connectStart = time.Now()
+        },
+        ConnectDone: func(string, string, error) {
+            r.mu.Lock()
+            defer r.mu.Unlock()
+            r.timing.ConnectMs = millis(time.Since(r.connectStart))
+        },
+        TLSHandshakeStart: func() {
+            r.mu.Lock()
+            defer r.mu.Unlock()
+            r.tlsStart = time.Now()
+        },
+        TLSHandshakeDone: func(tls.ConnectionState, error) {
+            r.mu.Lock()
+            defer r.mu.Unlock()
+            r.timing.TLSMs = millis(time.Since(r.tlsStart))
+        },
+        GotFirstResponseByte: func() {
+            r.mu.Lock()
+            defer r.mu.Unlock()
+            r.timing.TTFBMs = millis(time.Since(r.start))
+        },
+    }
+}
+
+func (r *timingRecorder) finish() RequestTiming {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    r.timing.TotalMs = millis(time.Since(r.start))
+    return r.timing
+}
+
+// tracedBody calls onClose the first time the body is closed.
+type tracedBody struct {
+    io.ReadCloser
+    once    sync.Once
+    onClose func()
+}
+
+func (b *tracedBody) Close() error {
+    err := b.ReadCloser.Close()
+    b.once.Do(b.onClose)
+    return err
+}
+
+func millis(d time.Duration) float64 {
+    return float64(d) / float64(time.Millisecond)
+}
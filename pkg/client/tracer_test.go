@@ -0,0 +1,32 @@
+package client
+
+import (
+    "context"
+    "testing"
+    "time"
+)
+
+func TestTracingTransportReportsEveryRequest(t *testing.T) {
+    srv := newDelayedServer(time.Hour)
+    defer srv.Close()
+
+    timings := make(chan RequestTiming, 2)
+    c := newTestClient(srv.URL, WithTransport(NewTracingTransport(nil, func(rt RequestTiming) { timings <- rt })))
+
+    for i := 0; i < 2; i++ {
+        if _, err := c.RetrieveStatus(context.Background()); err != nil {
+            t.Fatalf("RetrieveStatus: %v", err)
+        }
+        select {
+        case rt := <-timings:
+            if rt.URL != srv.URL+"/status" {
+                t.Fatalf("unexpected URL %q", rt.URL)
+            }
+            if rt.TotalMs <= 0 || rt.TTFBMs <= 0 || rt.TTFBMs > rt.TotalMs {
+                t.Fatalf("implausible timing %+v", rt)
+            }
+        case <-time.After(time.Second):
+            t.Fatalf("no timing reported for request %d", i+1)
+        }
+    }
+}
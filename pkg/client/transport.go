@@ -0,0 +1,291 @@
+package client
+
+import (
+    "bytes"
+    "encoding/json"
+    "fmt"
+    "io"
+    "mime"
+    "net/http"
+    "strings"
+    "sync"
+    "sync/atomic"
+    "time"
+
+    "Video-Translation-Simulator/pkg/types"
+)
+
+// ContextPropagatingTransport is a RoundTripper which copies values from the
+// request's context into headers on the outgoing request, e.g. to pass trace or
+// tenant IDs on to the server.
+type ContextPropagatingTransport struct {
+    Base     http.RoundTripper      // Defaults to http.DefaultTransport when nil.
+    Mappings map[interface{}]string // Context key to header name.
+}
+
+// NewContextPropagatingTransport returns a ContextPropagatingTransport wrapping
+// base which sets a header for each context key in mappings.
+func NewContextPropagatingTransport(base http.RoundTripper, mappings map[interface{}]string) *ContextPropagatingTransport {
+    return &ContextPropagatingTransport{Base: base, Mappings: mappings}
+}
+
+// WithContextPropagation wraps the client's transport in a
+// ContextPropagatingTransport. It wraps whichever transport is set when the
+// option is applied, so pass it after WithTransport to layer the two.
+func WithContextPropagation(mappings map[interface{}]string) ClientOption {
+    return func(c *Client) {
+        c.httpClient.Transport = NewContextPropagatingTransport(c.httpClient.Transport, mappings)
+    }
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *ContextPropagatingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+    ctx := req.Context()
+    cloned := false
+    for key, header := range t.Mappings {
+        value := ctx.Value(key)
+        if value == nil {
+            continue
+        }
+        // A RoundTripper must not modify the caller's request.
+        if !cloned {
+            req = req.Clone(ctx)
+            cloned = true
+        }
+        req.Header.Set(header, fmt.Sprint(value))
+    }
+
+    base := t.Base
+    if base == nil {
+        base = http.DefaultTransport
+    }
+    return base.RoundTrip(req)
+}
+
+// APIKeyHeader is the header WithAPIKey sends the key in, as expected by the
+// server's API key middleware.
+const APIKeyHeader = "X-API-Key"
+
+// apiKeyTransport sets the API key header on every request.
+type apiKeyTransport struct {
+    base http.RoundTripper
+    key  string
+}
+
+// WithAPIKey sends key in the X-API-Key header of every request to the
+// server, including the WebSocket handshake. Like WithContextPropagation it
+// wraps the transport set when the option is applied, so pass it after
+// WithTransport.
+func WithAPIKey(key string) ClientOption {
+    return func(c *Client) {
+        c.apiKey = key
+        c.httpClient.Transport = &apiKeyTransport{base: c.httpClient.Transport, key: key}
+    }
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *apiKeyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+    req = req.Clone(req.Context())
+    req.Header.Set(APIKeyHeader, t.key)
+    base := t.base
+    if base == nil {
+        base = http.DefaultTransport
+    }
+    return base.RoundTrip(req)
+}
+
+// responseCacheSweepInterval is how often a ResponseCachingTransport drops
+// expired entries, so that jobs which are never asked for again don't stay
+// cached for good.
+const responseCacheSweepInterval = time.Minute
+
+// ResponseCachingTransport is a RoundTripper which caches responses reporting
+// a final job status for a fixed time, replaying them without contacting the
+// server. The final status of a job created with POST /jobs only changes when
+// the job is retried with POST /jobs/{id}/retry, which drops its entry when it
+// goes through this transport, so every caller of the http.Client benefits,
+// not just RetrieveStatus. Only
+// successful JSON responses to GET /jobs/{id}/status whose result is final are
+// cached, keyed by URL without its query. /status is never cached, as polling
+// it after a final status starts the next job, and neither are streams.
+// Bodies over MaxBodyBytes are passed through uncached without being read in
+// full, leaving the caller to reject them.
+type ResponseCachingTransport struct {
+    Base         http.RoundTripper // Defaults to http.DefaultTransport when nil.
+    TTL          time.Duration
+    MaxBodyBytes int64 // Defaults to DefaultMaxResponseSize when not positive.
+
+    maxBody func() int64 // Overrides MaxBodyBytes, set by WithResponseCaching.
+
+    entries sync.Map     // URL to *cachedResponse.
+    sweptAt atomic.Int64 // Unix nanoseconds of the last sweep of entries.
+    hits    atomic.Int64
+    misses  atomic.Int64
+}
+
+type cachedResponse struct {
+    statusCode int
+    status     string
+    header     http.Header
+    body       []byte
+    expires    time.Time
+}
+
+// NewResponseCachingTransport returns a ResponseCachingTransport wrapping base
+// which keeps final responses for ttl.
+func NewResponseCachingTransport(base http.RoundTripper, ttl time.Duration) *ResponseCachingTransport {
+    return &ResponseCachingTransport{Base: base, TTL: ttl}
+}
+
+// WithResponseCaching wraps the client's transport in a
+// ResponseCachingTransport keeping final responses for ttl. Pass it after
+// WithTransport to layer the two. Bodies are limited to the client's
+// WithMaxResponseSize.
+func WithResponseCaching(ttl time.Duration) ClientOption {
+    return func(c *Client) {
+        t := NewResponseCachingTransport(c.httpClient.Transport, ttl)
+        t.maxBody = func() int64 { return c.maxResponseBytes }
+        c.httpClient.Transport = t
+    }
+}
+
+// maxBodyBytes returns the largest body the transport reads to cache.
+func (t *ResponseCachingTransport) maxBodyBytes() int64 {
+    if t.maxBody != nil {
+        return t.maxBody()
+    }
+    if t.MaxBodyBytes > 0 {
+        return t.MaxBodyBytes
+    }
+    return DefaultMaxResponseSize
+}
+
+// Hits returns the number of requests answered from the cache.
+func (t *ResponseCachingTransport) Hits() int64 {
+    return t.hits.Load()
+}
+
+// Misses returns the number of cacheable requests sent on to the server.
+func (t *ResponseCachingTransport) Misses() int64 {
+    return t.misses.Load()
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *ResponseCachingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+    base := t.Base
+    if base == nil {
+        base = http.DefaultTransport
+    }
+    if req.Method == http.MethodPost && isJobPath(req.URL.Path, "/retry") {
+        resp, err := base.RoundTrip(req)
+        if err == nil && resp.StatusCode == http.StatusOK {
+            // The job starts again, so its cached final status no longer holds.
+            statusURL := *req.URL
+            statusURL.Path = strings.TrimSuffix(statusURL.Path, "/retry") + "/status"
+            if statusURL.RawPath != "" {
+                statusURL.RawPath = strings.TrimSuffix(statusURL.RawPath, "/retry") + "/status"
+            }
+            statusURL.RawQuery = ""
+            t.entries.Delete(statusURL.String())
+        }
+        return resp, err
+    }
+    if req.Method != http.MethodGet || !isJobPath(req.URL.Path, "/status") {
+        return base.RoundTrip(req)
+    }
+
+    now := time.Now()
+    if swept := t.sweptAt.Load(); now.Sub(time.Unix(0, swept)) >= responseCacheSweepInterval && t.sweptAt.CompareAndSwap(swept, now.UnixNano()) {
+        t.sweep(now)
+    }
+
+    keyURL := *req.URL
+    keyURL.RawQuery = ""
+    key := keyURL.String()
+    if v, ok := t.entries.Load(key); ok {
+        entry := v.(*cachedResponse)
+        if now.Before(entry.expires) {
+            t.hits.Add(1)
+            return entry.response(req), nil
+        }
+        t.entries.CompareAndDelete(key, v)
+    }
+
+    t.misses.Add(1)
+    resp, err := base.RoundTrip(req)
+    if err != nil || resp.StatusCode != http.StatusOK {
+        return resp, err
+    }
+    // Reading a stream to the end would hold it back until the job finishes.
+    if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType != "application/json" {
+        return resp, nil
+    }
+    body, err := io.ReadAll(io.LimitReader(resp.Body, t.maxBodyBytes()+1))
+    if err != nil {
+        resp.Body.Close()
+        return nil, err
+    }
+    if int64(len(body)) > t.maxBodyBytes() {
+        // Too large to cache: hand back what was read followed by the rest.
+        resp.Body = readCloser{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+        return resp, nil
+    }
+    resp.Body.Close()
+    resp.Body = io.NopCloser(bytes.NewReader(body))
+
+    var parsed struct {
+        Result string `json:"result"`
+    }
+    if json.Unmarshal(body, &parsed) == nil && types.Status(parsed.Result).IsTerminal() {
+        t.entries.Store(key, &cachedResponse{
+            statusCode: resp.StatusCode,
+            status:     resp.Status,
+            header:     resp.Header.Clone(),
+            body:       body,
+            expires:    time.Now().Add(t.TTL),
+        })
+    }
+    return resp, nil
+}
+
+// sweep drops the entries which have expired by now.
+func (t *ResponseCachingTransport) sweep(now time.Time) {
+    t.entries.Range(func(key, v any) bool {
+        if !now.Before(v.(*cachedResponse).expires) {
+            t.entries.CompareAndDelete(key, v)
+        }
+        return true
+    })
+}
+
+// readCloser reads from Reader and closes Closer.
+type readCloser struct {
+    io.Reader
+    io.Closer
+}
+
+// isJobPath reports whether path is suffix under a job created with POST
+// /jobs, e.g. /jobs/abc123/status for "/status", possibly behind a prefix.
+func isJobPath(path, suffix string) bool {
+    i := strings.LastIndex(path, "/jobs/")
+    if i < 0 {
+        return false
+    }
+    id, ok := strings.CutSuffix(path[i+len("/jobs/"):], suffix)
+    return ok && id != "" && !strings.Contains(id, "/")
+}
+
+// response builds a fresh response to req from the cached one.
+func (e *cachedResponse) response(req *http.Request) *http.Response {
+    return &http.Response{
+        Status:        e.status,
+        StatusCode:    e.statusCode,
+        Proto:         "HTTP/1.1",
+        ProtoMajor:    1,
+        ProtoMinor:    1,
+        Header:        e.header.Clone(),
+        Body:          io.NopCloser(bytes.NewReader(e.body)),
+        ContentLength: int64(len(e.body)),
+        Request:       req,
+    }
+}
@@ -0,0 +1,193 @@
+package client
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "errors"
+    "net/http"
+    "net/http/httptest"
+    "sync/atomic"
+    "testing"
+    "time"
+
+    "Video-Translation-Simulator/pkg/testutil"
+)
+
+type ctxKey string
+
+func TestContextPropagationSetsHeaders(t *testing.T) {
+    received := make(chan http.Header, 1)
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        received <- r.Header.Clone()
+        json.NewEncoder(w).Encode(map[string]string{"result": "pending"})
+    }))
+    defer srv.Close()
+
+    c := newTestClient(srv.URL, WithContextPropagation(map[interface{}]string{
+        ctxKey("trace"):  "X-Trace-Id",
+        ctxKey("tenant"): "X-Tenant-Id",
+    }))
+
+    // Only the trace ID is present, the tenant header must be left off.
+    ctx := context.WithValue(context.Background(), ctxKey("trace"), "abc123")
+    if _, err := c.RetrieveStatus(ctx); err != nil {
+        t.Fatalf("RetrieveStatus: %v", err)
+    }
+
+    header := <-received
+    if got := header.Get("X-Trace-Id"); got != "abc123" {
+        t.Fatalf("expected X-Trace-Id abc123, got %q", got)
+    }
+    if _, ok := header["X-Tenant-Id"]; ok {
+        t.Fatalf("expected no X-Tenant-Id header, got %q", header.Get("X-Tenant-Id"))
+    }
+}
+
+func TestResponseCachingTransport(t *testing.T) {
+    srv := testutil.NewDryRunServer()
+    defer srv.Close()
+    srv.QueueStatus("abc", "completed")
+
+    transport := NewResponseCachingTransport(nil, time.Minute)
+    httpClient := &http.Client{Transport: transport}
+    for i := 0; i < 5; i++ {
+        resp, err := httpClient.Get(srv.URL + "/jobs/abc/status")
+        if err != nil {
+            t.Fatalf("request %d: %v", i+1, err)
+        }
+        var body map[string]string
+        json.NewDecoder(resp.Body).Decode(&body)
+        resp.Body.Close()
+        if resp.StatusCode != http.StatusOK || body["result"] != "completed" {
+            t.Fatalf("request %d: expected completed, got %d %v", i+1, resp.StatusCode, body)
+        }
+    }
+    if transport.Misses() != 1 || transport.Hits() != 4 {
+        t.Fatalf("expected 1 miss and 4 hits, got %d and %d", transport.Misses(), transport.Hits())
+    }
+    if n := srv.Requests("abc"); n != 1 {
+        t.Fatalf("expected the server to be called once, got %d", n)
+    }
+}
+
+func TestResponseCachingTransportSweepsExpired(t *testing.T) {
+    srv := testutil.NewDryRunServer()
+    defer srv.Close()
+    srv.QueueStatus("abc", "completed")
+    srv.QueueStatus("def", "completed")
+    srv.QueueStatus("ghi", "pending")
+
+    transport := NewResponseCachingTransport(nil, 10*time.Millisecond)
+    httpClient := &http.Client{Transport: transport}
+    get := func(id string) {
+        resp, err := httpClient.Get(srv.URL + "/jobs/" + id + "/status")
+        if err != nil {
+            t.Fatalf("GET %s: %v", id, err)
+        }
+        resp.Body.Close()
+    }
+    entries := func() int {
+        n := 0
+        transport.entries.Range(func(_, _ any) bool { n++; return true })
+        return n
+    }
+
+    get("abc")
+    get("def")
+    if n := entries(); n != 2 {
+        t.Fatalf("expected 2 cached responses, got %d", n)
+    }
+
+    // Neither job is asked for again, so only a sweep can drop them.
+    time.Sleep(20 * time.Millisecond)
+    transport.sweptAt.Store(time.Now().Add(-responseCacheSweepInterval).UnixNano())
+    get("ghi")
+    if n := entries(); n != 0 {
+        t.Fatalf("expected the expired responses to be swept, got %d left", n)
+    }
+}
+
+func TestResponseCachingTransportSkipsStatusAndStreams(t *testing.T) {
+    srv := testutil.NewDryRunServer()
+    defer srv.Close()
+
+    // Each polling sequence on /status sees the server's next job.
+    srv.QueueStatus("", "pending", "completed", "pending", "completed")
+    c := newTestClient(srv.URL, WithBackoff(constantBackoff(0)), WithResponseCaching(time.Minute))
+    for i := 0; i < 2; i++ {
+        if status, err := c.WaitForCompletion(context.Background()); err != nil || status != "completed" {
+            t.Fatalf("sequence %d: expected completed, got %q, %v", i+1, status, err)
+        }
+    }
+    if n := srv.Requests(""); n != 4 {
+        t.Fatalf("expected /status not to be cached, got %d requests", n)
+    }
+
+    // A stream is handed over as soon as its headers arrive.
+    release := make(chan struct{})
+    stream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        w.Header().Set("Content-Type", NDJSONContentType)
+        w.(http.Flusher).Flush()
+        <-release
+    }))
+    defer stream.Close()
+    defer close(release)
+    httpClient := &http.Client{Transport: NewResponseCachingTransport(nil, time.Minute), Timeout: time.Second}
+    resp, err := httpClient.Get(stream.URL + "/jobs/abc/status")
+    if err != nil {
+        t.Fatalf("expected the stream's headers without waiting for its body, got %v", err)
+    }
+    resp.Body.Close()
+}
+
+func TestResponseCachingTransportSkipsPending(t *testing.T) {
+    srv := testutil.NewDryRunServer()
+    defer srv.Close()
+    srv.QueueStatus("", "pending", "pending", "completed")
+    srv.QueueStatus("abc", "completed")
+
+    c := newTestClient(srv.URL, WithBackoff(constantBackoff(0)), WithResponseCaching(time.Minute))
+    if status, err := c.WaitForCompletion(context.Background()); err != nil || status != "completed" {
+        t.Fatalf("expected completed, got %q, %v", status, err)
+    }
+    if n := srv.Requests(""); n != 3 {
+        t.Fatalf("expected pending responses not to be cached, got %d requests", n)
+    }
+
+    // Expired entries are fetched again.
+    transport := NewResponseCachingTransport(nil, time.Millisecond)
+    httpClient := &http.Client{Transport: transport}
+    for i := 0; i < 2; i++ {
+        resp, err := httpClient.Get(srv.URL + "/jobs/abc/status")
+        if err != nil {
+            t.Fatalf("request: %v", err)
+        }
+        resp.Body.Close()
+        time.Sleep(5 * time.Millisecond)
+    }
+    if transport.Misses() != 2 {
+        t.Fatalf("expected both requests to miss, got %d misses", transport.Misses())
+    }
+}
+
+func TestResponseCachingTransportLimitsBody(t *testing.T) {
+    var requests atomic.Int32
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        requests.Add(1)
+        w.Header().Set("Content-Type", "application/json")
+        w.Write([]byte(`{"result": "completed"}`))
+        w.Write(bytes.Repeat([]byte(" "), 2<<10))
+    }))
+    defer srv.Close()
+
+    c := newTestClient(srv.URL, WithJobID("abc"), WithMaxResponseSize(1<<10), WithResponseCaching(time.Minute))
+    for i := 0; i < 2; i++ {
+        if _, err := c.RetrieveStatus(context.Background()); !errors.Is(err, ErrResponseTooLarge) {
+            t.Fatalf("request %d: expected ErrResponseTooLarge, got %v", i+1, err)
+        }
+    }
+    if n := requests.Load(); n != 2 {
+        t.Fatalf("expected the oversized response not to be cached, got %d requests", n)
+    }
+}
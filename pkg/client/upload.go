@@ -0,0 +1,93 @@
+package client
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "fmt"
+    "io"
+    "mime/multipart"
+    "net/http"
+    "os"
+    "path/filepath"
+)
+
+// MultipartUploadClient submits jobs along with the file to process, as a
+// multipart/form-data POST /jobs.
+type MultipartUploadClient struct {
+    Client *Client
+}
+
+// NewMultipartUploadClient returns a MultipartUploadClient submitting through c.
+func NewMultipartUploadClient(c *Client) *MultipartUploadClient {
+    return &MultipartUploadClient{Client: c}
+}
+
+// SubmitFile creates a job for the file at path, streaming it to the server,
+// and returns the job's ID. The job's metadata is the client's, set with
+// WithJobMetadata, overridden by metadata. Files can be large, so only ctx
+// bounds the upload, not the client's timeout.
+func (u *MultipartUploadClient) SubmitFile(ctx context.Context, path string, metadata map[string]string) (jobID string, err error) {
+    c := u.Client
+    f, err := os.Open(path)
+    if err != nil {
+        return "", err
+    }
+    defer f.Close()
+
+    fields := make(map[string]string, len(c.jobMetadata)+len(metadata))
+    for key, value := range c.jobMetadata {
+        fields[key] = value
+    }
+    for key, value := range metadata {
+        fields[key] = value
+    }
+    body, w := io.Pipe()
+    form := multipart.NewWriter(w)
+    go func() {
+        w.CloseWithError(writeUploadForm(form, f, fields))
+    }()
+    // Unblocks the writer if the request gives up early.
+    defer body.Close()
+
+    req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.serverURL()+"/jobs", body)
+    if err != nil {
+        return "", err
+    }
+    for name, value := range c.jobHeaders {
+        req.Header.Set(name, value)
+    }
+    req.Header.Set("Content-Type", form.FormDataContentType())
+    resp, err := c.httpClient.Do(req)
+    if err != nil {
+        return "", err
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusAccepted {
+        msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+        return "", fmt.Errorf("uploading file: server responded with %d: %s", resp.StatusCode, bytes.TrimSpace(msg))
+    }
+
+    var submitted SubmitResponse
+    if err := json.NewDecoder(io.LimitReader(resp.Body, c.maxResponseBytes)).Decode(&submitted); err != nil {
+        return "", fmt.Errorf("decoding submitted job: %w", err)
+    }
+    return submitted.JobID, nil
+}
+
+// writeUploadForm writes the metadata fields, then the file, to form.
+func writeUploadForm(form *multipart.Writer, f *os.File, metadata map[string]string) error {
+    for key, value := range metadata {
+        if err := form.WriteField("metadata."+key, value); err != nil {
+            return err
+        }
+    }
+    part, err := form.CreateFormFile("file", filepath.Base(f.Name()))
+    if err != nil {
+        return err
+    }
+    if _, err := io.Copy(part, f); err != nil {
+        return err
+    }
+    return form.Close()
+}
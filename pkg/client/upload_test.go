@@ -0,0 +1,35 @@
+package client
+
+import (
+    "context"
+    "os"
+    "path/filepath"
+    "testing"
+
+    "Video-Translation-Simulator/pkg/testutil/fixtures"
+)
+
+func TestSubmitFile(t *testing.T) {
+    _, ts := fixtures.NewFixtureServer(fixtures.FixtureSlowServer)
+    defer ts.Close()
+    path := filepath.Join(t.TempDir(), "episode.mp4")
+    if err := os.WriteFile(path, make([]byte, 1<<20), 0o600); err != nil {
+        t.Fatal(err)
+    }
+    c := newTestClient(ts.URL)
+    jobID, err := NewMultipartUploadClient(c).SubmitFile(context.Background(), path, map[string]string{"source_lang": "en"})
+    if err != nil {
+        t.Fatalf("SubmitFile: %v", err)
+    }
+    if jobID == "" {
+        t.Fatal("expected a job ID")
+    }
+
+    jobs, err := c.ListJobs(context.Background(), JobFilter{})
+    if err != nil {
+        t.Fatalf("ListJobs: %v", err)
+    }
+    if len(jobs) != 1 || jobs[0].JobID != jobID || jobs[0].FileSizeBytes != 1<<20 || jobs[0].Metadata["source_lang"] != "en" {
+        t.Fatalf("unexpected jobs %+v", jobs)
+    }
+}
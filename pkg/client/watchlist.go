@@ -0,0 +1,76 @@
+package client
+
+import (
+    "context"
+    "reflect"
+    "time"
+)
+
+// DefaultJobListInterval is how often WatchJobList polls when its interval is
+// not positive.
+const DefaultJobListInterval = time.Second
+
+// WatchJobList polls the jobs matching filter with ListJobs every interval,
+// sending the list first as it stands, then each time it changes. Failed polls
+// are logged and tried again at the next interval. The channel is closed once
+// ctx is done.
+func (c *Client) WatchJobList(ctx context.Context, filter JobFilter, interval time.Duration) <-chan []*JobSummary {
+    if interval <= 0 {
+        interval = DefaultJobListInterval
+    }
+    lists := make(chan []*JobSummary)
+    go func() {
+        defer close(lists)
+        ticker := time.NewTicker(interval)
+        defer ticker.Stop()
+        var last []*JobSummary
+        sent := false
+        for {
+            jobs, err := c.ListJobs(ctx, filter)
+            switch {
+            case ctx.Err() != nil:
+                return
+            case err != nil:
+                c.logf("Error listing jobs: %v", err)
+            case !sent || !reflect.DeepEqual(jobs, last):
+                select {
+                case lists <- jobs:
+                case <-ctx.Done():
+                    return
+                }
+                last, sent = jobs, true
+            }
+            select {
+            case <-ctx.Done():
+                return
+            case <-ticker.C:
+            }
+        }
+    }()
+    return lists
+}
+
+// JobListDiff compares two lists sent by WatchJobList, matching jobs by ID. It
+// returns the jobs only in newList, those only in oldList, and those in both
+// which differ, as they are in newList.
+func JobListDiff(oldList, newList []*JobSummary) (added, removed, changed []*JobSummary) {
+    before := make(map[string]*JobSummary, len(oldList))
+    for _, job := range oldList {
+        before[job.JobID] = job
+    }
+    after := make(map[string]bool, len(newList))
+    for _, job := range newList {
+        after[job.JobID] = true
+        if old, ok := before[job.JobID]; !ok {
+            added = append(added, job)
+        } else if !reflect.DeepEqual(old, job) {
+            changed = append(changed, job)
+        }
+    }
+    for _, job := range oldList {
+        if !after[job.JobID] {
+            removed = append(removed, job)
+        }
+    }
+    return added, removed, changed
+}
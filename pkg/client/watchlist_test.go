@@ -0,0 +1,42 @@
+package client
+
+import (
+    "context"
+    "testing"
+    "time"
+
+    "Video-Translation-Simulator/pkg/testutil/fixtures"
+)
+
+func TestWatchJobList(t *testing.T) {
+    _, ts := fixtures.NewFixtureServer(fixtures.FixtureFastServer)
+    defer ts.Close()
+    c := newTestClient(ts.URL)
+    if _, err := c.SubmitJob(context.Background(), "soon"); err != nil {
+        t.Fatalf("SubmitJob: %v", err)
+    }
+    // Scheduled, so it stays unchanged while the first job completes.
+    if _, err := c.ScheduleJob(context.Background(), "later", time.Now().Add(time.Hour)); err != nil {
+        t.Fatalf("ScheduleJob: %v", err)
+    }
+
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+    lists := c.WatchJobList(ctx, JobFilter{}, 100*time.Millisecond)
+    first := <-lists
+    if len(first) != 2 || first[0].Status != "pending" || first[1].Status != "scheduled" {
+        t.Fatalf("unexpected first list %+v", first)
+    }
+    second, ok := <-lists
+    if !ok {
+        t.Fatal("expected the list to change before ctx was done")
+    }
+    added, removed, changed := JobListDiff(first, second)
+    if len(added) != 0 || len(removed) != 0 || len(changed) != 1 || changed[0].JobID != "soon" || changed[0].Status != "completed" {
+        t.Fatalf("unexpected diff: added %+v, removed %+v, changed %+v", added, removed, changed)
+    }
+
+    cancel()
+    for range lists {
+    }
+}
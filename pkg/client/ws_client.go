@@ -0,0 +1,208 @@
+package client
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "net/http"
+    "net/url"
+    "strings"
+    "time"
+
+    "github.com/gorilla/websocket"
+
+    "Video-Translation-Simulator/pkg/types"
+)
+
+// wsRequest subscribes to a job on the server's /ws endpoint.
+type wsRequest struct {
+    Action string `json:"action"`
+    JobID  string `json:"jobId"`
+}
+
+// wsFrame is a frame received on /ws: a status, or an error for a request.
+type wsFrame struct {
+    Result string `json:"result,omitempty"`
+    Error  string `json:"error,omitempty"`
+}
+
+var upgrader = websocket.Upgrader{}
+
+// ConnectWebSocket opens a WebSocket connection to the server's /ws endpoint,
+// replacing any idle one opened before. Requests to HandleStatusRequest asking
+// to upgrade to a WebSocket subscribe to their job over this connection
+// instead of polling. Subscriptions running at the same time each get a
+// connection of their own, and one is kept for the next subscription. The
+// transport set with WithTransport is not used for them.
+func (c *Client) ConnectWebSocket(ctx context.Context) error {
+    conn, err := c.dialWebSocket(ctx)
+    if err != nil {
+        return err
+    }
+    c.wsMu.Lock()
+    defer c.wsMu.Unlock()
+    if c.ws != nil {
+        c.ws.Close()
+    }
+    c.ws = conn
+    return nil
+}
+
+// CloseWebSocket closes the idle connection, if any. Subscriptions in progress
+// finish on their own connections.
+func (c *Client) CloseWebSocket() error {
+    c.wsMu.Lock()
+    defer c.wsMu.Unlock()
+    if c.ws == nil {
+        return nil
+    }
+    err := c.ws.Close()
+    c.ws = nil
+    return err
+}
+
+// dialWebSocket opens a new connection to the server's /ws endpoint.
+func (c *Client) dialWebSocket(ctx context.Context) (*websocket.Conn, error) {
+    u, err := url.Parse(c.BaseURL)
+    if err != nil {
+        return nil, err
+    }
+    switch u.Scheme {
+    case "http":
+        u.Scheme = "ws"
+    case "https":
+        u.Scheme = "wss"
+    }
+    u.Path = strings.TrimSuffix(u.Path, "/") + "/ws"
+
+    header := http.Header{}
+    if c.apiKey != "" {
+        header.Set(APIKeyHeader, c.apiKey)
+    }
+    dialer := websocket.Dialer{HandshakeTimeout: c.timeout}
+    conn, resp, err := dialer.DialContext(ctx, u.String(), header)
+    if err != nil {
+        if resp != nil {
+            return nil, fmt.Errorf("connecting to %s: %w (status %d)", u, err, resp.StatusCode)
+        }
+        return nil, fmt.Errorf("connecting to %s: %w", u, err)
+    }
+    c.logf("WebSocket: Connected to %s", u)
+    return conn, nil
+}
+
+// relayWebSocket serves a /status request upgrading to a WebSocket. It reads a
+// subscribe frame from the caller, subscribes to that job on the server and
+// passes each status frame on, closing the connection after the final status.
+// The subscription is cancelled if the caller goes away first. Like NDJSON
+// streams, it doesn't share polling state with other requests.
+func (c *Client) relayWebSocket(w http.ResponseWriter, r *http.Request) {
+    conn, err := upgrader.Upgrade(w, r, nil)
+    if err != nil {
+        // The upgrader has already responded with the error.
+        c.logf("WebSocket: Upgrade failed: %v", err)
+        return
+    }
+    defer conn.Close()
+
+    var req wsRequest
+    if err := conn.ReadJSON(&req); err != nil {
+        c.logf("WebSocket: Reading subscription failed: %v", err)
+        return
+    }
+    if req.Action != "subscribe" {
+        conn.WriteJSON(wsFrame{Error: "unknown action " + req.Action})
+        return
+    }
+
+    // The request's context isn't cancelled once the connection is hijacked,
+    // so watch for the caller closing it instead. Reading also handles the
+    // caller's control frames.
+    ctx, cancel := context.WithCancel(r.Context())
+    defer cancel()
+    go func() {
+        defer cancel()
+        for {
+            if _, _, err := conn.NextReader(); err != nil {
+                return
+            }
+        }
+    }()
+
+    status, err := c.subscribe(ctx, req.JobID, func(frame wsFrame) error {
+        return conn.WriteJSON(frame)
+    })
+    if err != nil {
+        c.logf("WebSocket: Subscription ended early: %v", err)
+        conn.WriteJSON(wsFrame{Error: err.Error()})
+        return
+    }
+    conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+    if c.onFinal != nil {
+        c.onFinal(status)
+    }
+}
+
+// subscribe subscribes to jobID on the server and calls forward with each
+// status until the final one, which it returns. It takes the idle connection,
+// or dials a new one if there is none, so concurrent subscriptions don't wait
+// on each other. Afterwards the connection is kept for the next subscription
+// unless another already is, or the subscription failed mid-way, since frames
+// left over from it would be read by the next one.
+func (c *Client) subscribe(ctx context.Context, jobID string, forward func(wsFrame) error) (status string, err error) {
+    c.wsMu.Lock()
+    conn := c.ws
+    c.ws = nil
+    c.wsMu.Unlock()
+    if conn == nil {
+        if conn, err = c.dialWebSocket(ctx); err != nil {
+            return "", err
+        }
+    }
+    defer func() {
+        if err != nil && !errors.Is(err, errServerReported) {
+            conn.Close()
+            return
+        }
+        c.wsMu.Lock()
+        defer c.wsMu.Unlock()
+        if c.ws != nil {
+            conn.Close()
+            return
+        }
+        c.ws = conn
+    }()
+
+    // Unblock the read below when ctx is done. A deadline set just after the
+    // last subscription finished is cleared first.
+    conn.SetReadDeadline(time.Time{})
+    stop := context.AfterFunc(ctx, func() { conn.SetReadDeadline(time.Now()) })
+    defer stop()
+
+    if err := conn.WriteJSON(wsRequest{Action: "subscribe", JobID: jobID}); err != nil {
+        return "", err
+    }
+    for {
+        var frame wsFrame
+        if err := conn.ReadJSON(&frame); err != nil {
+            if ctxErr := ctx.Err(); ctxErr != nil {
+                return "", ctxErr
+            }
+            return "", err
+        }
+        if frame.Error != "" {
+            return "", fmt.Errorf("%w: %s", errServerReported, frame.Error)
+        }
+        c.logf("WebSocket: Received status: %s", frame.Result)
+        if err := forward(frame); err != nil {
+            return "", err
+        }
+        if types.Status(frame.Result).IsTerminal() {
+            return frame.Result, nil
+        }
+    }
+}
+
+// errServerReported wraps errors the server sent in a frame, after which the
+// connection is still usable.
+var errServerReported = errors.New("server reported")
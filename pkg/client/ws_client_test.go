@@ -0,0 +1,182 @@
+package client
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/gorilla/websocket"
+)
+
+func TestWebSocketRelay(t *testing.T) {
+    // Create a job of our own so other tests polling /status don't interfere.
+    id := fmt.Sprintf("ws-relay-%d", time.Now().UnixNano())
+    resp, err := http.Post(serverURL+"/jobs", "application/json", strings.NewReader(`{"id":"`+id+`"}`))
+    if err != nil {
+        t.Fatalf("creating job: %v", err)
+    }
+    resp.Body.Close()
+
+    c := newTestClient(serverURL)
+    if err := c.ConnectWebSocket(context.Background()); err != nil {
+        t.Fatalf("ConnectWebSocket: %v", err)
+    }
+    defer c.CloseWebSocket()
+    relay := httptest.NewServer(http.HandlerFunc(c.HandleStatusRequest))
+    defer relay.Close()
+
+    conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(relay.URL, "http")+"/status", nil)
+    if err != nil {
+        t.Fatalf("dial: %v", err)
+    }
+    defer conn.Close()
+    conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+    if err := conn.WriteJSON(map[string]string{"action": "subscribe", "jobId": id}); err != nil {
+        t.Fatalf("subscribing: %v", err)
+    }
+
+    var results []string
+    for {
+        _, data, err := conn.ReadMessage()
+        if websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+            break
+        }
+        if err != nil {
+            t.Fatalf("reading: %v", err)
+        }
+        var frame wsFrame
+        if err := json.Unmarshal(data, &frame); err != nil || frame.Error != "" {
+            t.Fatalf("unexpected frame %s", data)
+        }
+        results = append(results, frame.Result)
+    }
+    if len(results) == 0 || results[0] != "pending" {
+        t.Fatalf("expected the job to start out pending, got %v", results)
+    }
+    if last := results[len(results)-1]; last != "completed" && last != "error" {
+        t.Fatalf("expected the relay to end on a final status, got %v", results)
+    }
+
+    // The plain REST path still works alongside it.
+    startJob(t)
+    rec := httptest.NewRecorder()
+    c.HandleStatusRequest(rec, httptest.NewRequest(http.MethodGet, "/status", nil))
+    if rec.Code != http.StatusOK {
+        t.Fatalf("expected 200 from the REST path, got %d", rec.Code)
+    }
+}
+
+func TestWebSocketReconnects(t *testing.T) {
+    upgrader := websocket.Upgrader{}
+    connections := make(chan struct{}, 10)
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        conn, err := upgrader.Upgrade(w, r, nil)
+        if err != nil {
+            return
+        }
+        defer conn.Close()
+        connections <- struct{}{}
+        var req wsRequest
+        if conn.ReadJSON(&req) == nil {
+            conn.WriteJSON(wsFrame{Result: "completed"})
+        }
+        // Drop the connection after a single subscription.
+    }))
+    defer srv.Close()
+
+    c := newTestClient(srv.URL)
+    defer c.CloseWebSocket()
+    ignore := func(wsFrame) error { return nil }
+    if status, err := c.subscribe(context.Background(), "", ignore); err != nil || status != "completed" {
+        t.Fatalf("expected completed, got %q, %v", status, err)
+    }
+    // The server has since dropped the connection, so this fails and the
+    // client forgets it.
+    if _, err := c.subscribe(context.Background(), "", ignore); err == nil {
+        t.Fatal("expected the dropped connection to fail the subscription")
+    }
+    if status, err := c.subscribe(context.Background(), "", ignore); err != nil || status != "completed" {
+        t.Fatalf("expected completed after reconnecting, got %q, %v", status, err)
+    }
+    if n := len(connections); n != 2 {
+        t.Fatalf("expected 2 connections, saw %d", n)
+    }
+}
+
+// newStubWSServer answers every subscription with pending, then completes
+// jobs named "fast" at once and leaves others pending until the connection is
+// closed. closed receives a value whenever a connection to it ends.
+func newStubWSServer(closed chan<- struct{}) *httptest.Server {
+    upgrader := websocket.Upgrader{}
+    return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        conn, err := upgrader.Upgrade(w, r, nil)
+        if err != nil {
+            return
+        }
+        defer func() {
+            conn.Close()
+            closed <- struct{}{}
+        }()
+        for {
+            var req wsRequest
+            if err := conn.ReadJSON(&req); err != nil {
+                return
+            }
+            conn.WriteJSON(wsFrame{Result: "pending"})
+            if req.JobID != "fast" {
+                continue
+            }
+            conn.WriteJSON(wsFrame{Result: "completed"})
+        }
+    }))
+}
+
+func TestWebSocketSubscriptionsRunConcurrently(t *testing.T) {
+    srv := newStubWSServer(make(chan struct{}, 10))
+    defer srv.Close()
+
+    c := newTestClient(srv.URL)
+    defer c.CloseWebSocket()
+    ignore := func(wsFrame) error { return nil }
+    go c.subscribe(context.Background(), "slow", ignore)
+    time.Sleep(50 * time.Millisecond)
+
+    ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+    defer cancel()
+    if status, err := c.subscribe(ctx, "fast", ignore); err != nil || status != "completed" {
+        t.Fatalf("expected a second subscription to complete while the first is pending, got %q, %v", status, err)
+    }
+}
+
+func TestWebSocketRelayStopsWhenCallerLeaves(t *testing.T) {
+    closed := make(chan struct{}, 10)
+    srv := newStubWSServer(closed)
+    defer srv.Close()
+
+    c := newTestClient(srv.URL)
+    defer c.CloseWebSocket()
+    relay := httptest.NewServer(http.HandlerFunc(c.HandleStatusRequest))
+    defer relay.Close()
+
+    conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(relay.URL, "http")+"/status", nil)
+    if err != nil {
+        t.Fatalf("dial: %v", err)
+    }
+    conn.WriteJSON(map[string]string{"action": "subscribe", "jobId": "slow"})
+    var frame wsFrame
+    if err := conn.ReadJSON(&frame); err != nil || frame.Result != "pending" {
+        t.Fatalf("expected pending, got %+v, %v", frame, err)
+    }
+    conn.Close()
+
+    select {
+    case <-closed:
+    case <-time.After(2 * time.Second):
+        t.Fatal("expected the relay to drop its server connection once the caller left")
+    }
+}
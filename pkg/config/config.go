@@ -0,0 +1,117 @@
+// Package config loads the server's settings from a JSON file, falling back to
+// defaults embedded in the binary so the server runs without any files on disk.
+package config
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+)
+
+//go:embed configs/default.json
+var defaultJSON []byte
+
+// Config holds the server's settings.
+type Config struct {
+	Addr         string `json:"addr"`
+	DelaySeconds int    `json:"delay_seconds"`
+	ErrorRate    int    `json:"error_rate"` // Percentage of jobs ending in "error", 0-100.
+	DebugAddr    string `json:"debug_addr"`
+	LogLevel     string `json:"log_level"` // debug, info, warn or error.
+}
+
+// Default returns the embedded default config.
+func Default() *Config {
+	var c Config
+	if err := json.Unmarshal(defaultJSON, &c); err != nil {
+		panic(fmt.Sprintf("config: embedded default.json is invalid: %v", err))
+	}
+	return &c
+}
+
+// LoadFile reads the config at path over the embedded defaults, so the file
+// only needs to set what it changes. Fields present in the file win even when
+// zero, e.g. "error_rate": 0. The file is checked against the config schema
+// first, reporting every broken setting. An empty path returns the defaults.
+func LoadFile(path string) (*Config, error) {
+	if path == "" {
+		return Default(), nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if errs := ValidateAgainstSchema(overDefaults(data)); errs != nil {
+		joined := make([]error, len(errs))
+		for i, e := range errs {
+			joined[i] = e
+		}
+		return nil, fmt.Errorf("%s: %w", path, errors.Join(joined...))
+	}
+	// Unmarshal leaves the fields the file doesn't mention at their defaults.
+	c := Default()
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if err := c.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return c, nil
+}
+
+// Validate reports settings the server cannot run with.
+func (c *Config) Validate() error {
+	if c.Addr == "" {
+		return fmt.Errorf("addr must be set")
+	}
+	if c.DelaySeconds < 0 {
+		return fmt.Errorf("delay_seconds must not be negative, got %d", c.DelaySeconds)
+	}
+	if c.ErrorRate < 0 || c.ErrorRate > 100 {
+		return fmt.Errorf("error_rate must be between 0 and 100, got %d", c.ErrorRate)
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.LogLevel)); err != nil {
+		return fmt.Errorf("log_level must be debug, info, warn or error, got %q", c.LogLevel)
+	}
+	return nil
+}
+
+// Level returns LogLevel as a slog.Level, info if it is not valid.
+func (c *Config) Level() slog.Level {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.LogLevel)); err != nil {
+		return slog.LevelInfo
+	}
+	return level
+}
+
+// MergeConfigs returns a copy of base with every non-zero field of override
+// applied on top. Neither argument is modified; a nil override returns a copy
+// of base. Note a zero value can't be used to override, e.g. to turn the error
+// rate down to 0.
+func MergeConfigs(base, override *Config) *Config {
+	merged := *base
+	if override == nil {
+		return &merged
+	}
+	if override.Addr != "" {
+		merged.Addr = override.Addr
+	}
+	if override.DelaySeconds != 0 {
+		merged.DelaySeconds = override.DelaySeconds
+	}
+	if override.ErrorRate != 0 {
+		merged.ErrorRate = override.ErrorRate
+	}
+	if override.DebugAddr != "" {
+		merged.DebugAddr = override.DebugAddr
+	}
+	if override.LogLevel != "" {
+		merged.LogLevel = override.LogLevel
+	}
+	return &merged
+}
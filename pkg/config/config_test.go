@@ -0,0 +1,125 @@
+package config
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestEmbeddedDefaultsAreValid(t *testing.T) {
+	c, err := LoadFile("")
+	if err != nil {
+		t.Fatalf("LoadFile: %v", err)
+	}
+	if err := c.Validate(); err != nil {
+		t.Fatalf("embedded defaults are invalid: %v", err)
+	}
+	want := Config{Addr: ":8080", DelaySeconds: 10, ErrorRate: 20, DebugAddr: "127.0.0.1:6060", LogLevel: "info"}
+	if *c != want {
+		t.Fatalf("expected %+v, got %+v", want, *c)
+	}
+}
+
+func TestMergeConfigsOverwritesOnlySetFields(t *testing.T) {
+	base := Default()
+	merged := MergeConfigs(base, &Config{DelaySeconds: 3})
+
+	want := *base
+	want.DelaySeconds = 3
+	if *merged != want {
+		t.Fatalf("expected %+v, got %+v", want, *merged)
+	}
+	if base.DelaySeconds != 10 {
+		t.Fatalf("MergeConfigs modified base: %+v", *base)
+	}
+	if got := MergeConfigs(base, nil); *got != *base || got == base {
+		t.Fatalf("expected a copy of base for a nil override, got %+v", *got)
+	}
+}
+
+func TestLoadFileMergesOverDefaults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"error_rate": 50, "addr": ":9000"}`), 0o600); err != nil {
+		t.Fatalf("writing config: %v", err)
+	}
+	c, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile: %v", err)
+	}
+	if c.ErrorRate != 50 || c.Addr != ":9000" || c.DelaySeconds != 10 {
+		t.Fatalf("unexpected config %+v", *c)
+	}
+
+	// Zero values in the file are applied, not mistaken for unset fields.
+	if err := os.WriteFile(path, []byte(`{"error_rate": 0, "delay_seconds": 0}`), 0o600); err != nil {
+		t.Fatalf("writing config: %v", err)
+	}
+	if c, err = LoadFile(path); err != nil || c.ErrorRate != 0 || c.DelaySeconds != 0 {
+		t.Fatalf("expected zero error rate and delay, got %+v, %v", c, err)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"error_rate": 150}`), 0o600); err != nil {
+		t.Fatalf("writing config: %v", err)
+	}
+	if _, err := LoadFile(path); err == nil {
+		t.Fatal("expected an out of range error rate to be rejected")
+	}
+}
+
+func TestDiffConfigs(t *testing.T) {
+	base := &Config{Addr: ":8080", DelaySeconds: 10, ErrorRate: 20, DebugAddr: "127.0.0.1:6060"}
+
+	tests := []struct {
+		name string
+		new  *Config
+		want []ConfigChange
+	}{
+		{"no changes", &Config{Addr: ":8080", DelaySeconds: 10, ErrorRate: 20, DebugAddr: "127.0.0.1:6060"}, nil},
+		{"zero values are unset", &Config{}, nil},
+		{"single change", &Config{DelaySeconds: 5}, []ConfigChange{
+			{Field: "delay_seconds", OldValue: "10", NewValue: "5"},
+		}},
+		{"multiple changes", &Config{Addr: ":9000", DelaySeconds: 10, ErrorRate: 50}, []ConfigChange{
+			{Field: "addr", OldValue: ":8080", NewValue: ":9000"},
+			{Field: "error_rate", OldValue: "20", NewValue: "50"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DiffConfigs(base, tt.new)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	if got := formatChanges(DiffConfigs(base, &Config{DelaySeconds: 5, ErrorRate: 50})); got != "delay_seconds: 10 -> 5, error_rate: 20 -> 50" {
+		t.Fatalf("unexpected log line %q", got)
+	}
+}
+
+func TestValidateDelay(t *testing.T) {
+	c := Default()
+	c.DelaySeconds = 0
+	if err := c.Validate(); err != nil {
+		t.Fatalf("expected a zero delay to be accepted, got %v", err)
+	}
+	c.DelaySeconds = -1
+	if err := c.Validate(); err == nil {
+		t.Fatal("expected a negative delay to be rejected")
+	}
+}
+
+func TestValidateLogLevel(t *testing.T) {
+	c := Default()
+	c.LogLevel = "debug"
+	if err := c.Validate(); err != nil || c.Level() != slog.LevelDebug {
+		t.Fatalf("expected debug to be accepted, got %v, %v", c.Level(), err)
+	}
+	c.LogLevel = "chatty"
+	if err := c.Validate(); err == nil {
+		t.Fatal("expected an unknown log level to be rejected")
+	}
+}
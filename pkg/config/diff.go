@@ -0,0 +1,74 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ConfigChange is one setting that differs between two configs, named by its
+// JSON key.
+type ConfigChange struct {
+	Field    string
+	OldValue string
+	NewValue string
+}
+
+func (c ConfigChange) String() string {
+	return fmt.Sprintf("%s: %s -> %s", c.Field, c.OldValue, c.NewValue)
+}
+
+// DiffConfigs returns the settings that new changes from old, in field order.
+// Zero values in new are treated as unset rather than as a change, matching
+// MergeConfigs. A nil old is compared as an empty config.
+func DiffConfigs(old, new *Config) []ConfigChange {
+	return diffConfigs(old, new, true)
+}
+
+// diffConfigs is DiffConfigs, comparing zero values in new too unless
+// skipZero is set. A reloaded config is complete, so a zero there is a change.
+func diffConfigs(old, new *Config, skipZero bool) []ConfigChange {
+	if new == nil {
+		return nil
+	}
+	if old == nil {
+		old = &Config{}
+	}
+	oldValue, newValue := reflect.ValueOf(old).Elem(), reflect.ValueOf(new).Elem()
+	t := oldValue.Type()
+
+	var changes []ConfigChange
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		o, n := oldValue.Field(i), newValue.Field(i)
+		if (skipZero && n.IsZero()) || reflect.DeepEqual(o.Interface(), n.Interface()) {
+			continue
+		}
+		changes = append(changes, ConfigChange{
+			Field:    fieldName(field),
+			OldValue: fmt.Sprint(o.Interface()),
+			NewValue: fmt.Sprint(n.Interface()),
+		})
+	}
+	return changes
+}
+
+// fieldName returns the JSON key of field, or its Go name without one.
+func fieldName(field reflect.StructField) string {
+	if name, _, _ := strings.Cut(field.Tag.Get("json"), ","); name != "" && name != "-" {
+		return name
+	}
+	return field.Name
+}
+
+// formatChanges joins changes into a single log friendly line.
+func formatChanges(changes []ConfigChange) string {
+	parts := make([]string, len(changes))
+	for i, c := range changes {
+		parts[i] = c.String()
+	}
+	return strings.Join(parts, ", ")
+}
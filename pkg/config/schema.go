@@ -0,0 +1,116 @@
+package config
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+//go:embed configs/config-schema.json
+var schemaJSON []byte
+
+// ValidationError is a setting which breaks the config schema, with a
+// message naming it, e.g. "error_rate must be between 0 and 100, got 150".
+type ValidationError struct {
+	Field   string // JSON key of the setting, "(root)" for the file as a whole.
+	Message string
+}
+
+func (e ValidationError) Error() string {
+	return e.Message
+}
+
+// schemaBounds are the minimum and maximum of each numeric setting, to name
+// both in the message when either is broken.
+type schemaBounds map[string]struct {
+	Minimum *float64 `json:"minimum"`
+	Maximum *float64 `json:"maximum"`
+}
+
+// loadSchema compiles the embedded schema once.
+var loadSchema = sync.OnceValues(func() (*gojsonschema.Schema, schemaBounds) {
+	schema, err := gojsonschema.NewSchema(gojsonschema.NewBytesLoader(schemaJSON))
+	if err != nil {
+		panic(fmt.Sprintf("config: embedded config-schema.json is invalid: %v", err))
+	}
+	var doc struct {
+		Properties schemaBounds `json:"properties"`
+	}
+	if err := json.Unmarshal(schemaJSON, &doc); err != nil {
+		panic(fmt.Sprintf("config: embedded config-schema.json is invalid: %v", err))
+	}
+	return schema, doc.Properties
+})
+
+// ValidateAgainstSchema checks a complete config against the embedded
+// configs/config-schema.json, returning one error per broken setting sorted
+// by field, or nil if data is valid. Every setting is required; LoadFile fills
+// in those a file leaves out from the defaults before checking it.
+func ValidateAgainstSchema(data []byte) []ValidationError {
+	schema, bounds := loadSchema()
+	result, err := schema.Validate(gojsonschema.NewBytesLoader(data))
+	if err != nil {
+		return []ValidationError{{Field: "(root)", Message: "invalid JSON: " + err.Error()}}
+	}
+	if result.Valid() {
+		return nil
+	}
+
+	var errs []ValidationError
+	for _, e := range result.Errors() {
+		field, details := e.Field(), e.Details()
+		var msg string
+		switch e.Type() {
+		case "required":
+			field = fmt.Sprint(details["property"])
+			msg = field + " is required"
+		case "invalid_type":
+			msg = fmt.Sprintf("%s must be of type %s, got %s", field, details["expected"], details["given"])
+		case "number_gte", "number_lte":
+			b := bounds[field]
+			switch {
+			case b.Minimum != nil && b.Maximum != nil:
+				msg = fmt.Sprintf("%s must be between %g and %g, got %v", field, *b.Minimum, *b.Maximum, e.Value())
+			case b.Minimum != nil:
+				msg = fmt.Sprintf("%s must be at least %g, got %v", field, *b.Minimum, e.Value())
+			default:
+				msg = fmt.Sprintf("%s must be at most %g, got %v", field, *b.Maximum, e.Value())
+			}
+		case "string_gte":
+			msg = field + " must not be empty"
+		case "enum":
+			msg = fmt.Sprintf("%s must be one of %s, got %q", field, strings.ReplaceAll(fmt.Sprint(details["allowed"]), `"`, ""), e.Value())
+		default:
+			msg = field + ": " + e.Description()
+		}
+		errs = append(errs, ValidationError{Field: field, Message: msg})
+	}
+	sort.SliceStable(errs, func(i, j int) bool { return errs[i].Field < errs[j].Field })
+	return errs
+}
+
+// overDefaults returns the config file data with the embedded defaults added
+// for the settings it leaves out, or data unchanged if it isn't a JSON object.
+func overDefaults(data []byte) []byte {
+	var file map[string]json.RawMessage
+	if err := json.Unmarshal(data, &file); err != nil || file == nil {
+		return data
+	}
+	var merged map[string]json.RawMessage
+	if err := json.Unmarshal(defaultJSON, &merged); err != nil {
+		panic(fmt.Sprintf("config: embedded default.json is invalid: %v", err))
+	}
+	for key, value := range file {
+		merged[key] = value
+	}
+	out, err := json.Marshal(merged)
+	if err != nil {
+		return data
+	}
+	return out
+}
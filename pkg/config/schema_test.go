@@ -0,0 +1,55 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidateAgainstSchema(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []ValidationError
+	}{
+		{"valid", string(defaultJSON), nil},
+		{"missing required field", `{"delay_seconds": 10, "error_rate": 20, "debug_addr": "", "log_level": "info"}`, []ValidationError{
+			{Field: "addr", Message: "addr is required"},
+		}},
+		{"out of range", `{"addr": ":8080", "delay_seconds": -1, "error_rate": 150, "debug_addr": "", "log_level": "info"}`, []ValidationError{
+			{Field: "delay_seconds", Message: "delay_seconds must be at least 0, got -1"},
+			{Field: "error_rate", Message: "error_rate must be between 0 and 100, got 150"},
+		}},
+		{"wrong type", `{"addr": ":8080", "delay_seconds": "10", "error_rate": 20, "debug_addr": "", "log_level": "info"}`, []ValidationError{
+			{Field: "delay_seconds", Message: "delay_seconds must be of type integer, got string"},
+		}},
+		{"unknown log level", `{"addr": ":8080", "delay_seconds": 10, "error_rate": 20, "debug_addr": "", "log_level": "verbose"}`, []ValidationError{
+			{Field: "log_level", Message: `log_level must be one of debug, info, warn, error, got "verbose"`},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ValidateAgainstSchema([]byte(tt.data)); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestLoadFileChecksSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"error_rate": "high", "delay_seconds": 1.5}`), 0o600); err != nil {
+		t.Fatalf("writing config: %v", err)
+	}
+	_, err := LoadFile(path)
+	if err == nil {
+		t.Fatal("expected the file to be rejected")
+	}
+	for _, want := range []string{"delay_seconds must be of type integer, got number", "error_rate must be of type integer, got string"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected %q in %q", want, err)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package config
+
+import (
+	"context"
+	"crypto/sha256"
+	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ConfigWatcher polls a config file and reloads it when its contents change.
+// Only the delay, error rate and log level can change while the server runs;
+// changes to the listen or debug address are logged and ignored until a
+// restart.
+type ConfigWatcher struct {
+	path     string
+	interval time.Duration
+	onReload func(*Config) error
+
+	current atomic.Pointer[Config]
+	sum     [sha256.Size]byte // Of the file contents last seen.
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewConfigWatcher loads the config at path and returns a watcher checking it
+// for changes every interval. onReload is called with each new config and
+// may reject it by returning an error, in which case the old one is kept.
+func NewConfigWatcher(path string, interval time.Duration, onReload func(*Config) error) (*ConfigWatcher, error) {
+	c, err := LoadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	w := &ConfigWatcher{path: path, interval: interval, onReload: onReload}
+	w.current.Store(c)
+	if data, err := os.ReadFile(path); err == nil {
+		w.sum = sha256.Sum256(data)
+	}
+	return w, nil
+}
+
+// Current returns the config most recently loaded.
+func (w *ConfigWatcher) Current() *Config {
+	return w.current.Load()
+}
+
+// Start begins polling in the background until ctx is done or Stop is called.
+// Calling Start on a running watcher has no effect.
+func (w *ConfigWatcher) Start(ctx context.Context) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.cancel != nil {
+		return
+	}
+	ctx, w.cancel = context.WithCancel(ctx)
+	w.done = make(chan struct{})
+
+	go func(done chan struct{}) {
+		defer close(done)
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if w.changed() {
+					if err := w.ValidateAndReload(); err != nil {
+						log.Printf("Keeping the current config, reloading %s failed: %v", w.path, err)
+					}
+				}
+			}
+		}
+	}(w.done)
+}
+
+// Stop stops polling and waits for a reload in progress to finish.
+func (w *ConfigWatcher) Stop() {
+	w.mu.Lock()
+	cancel, done := w.cancel, w.done
+	w.cancel, w.done = nil, nil
+	w.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+		<-done
+	}
+}
+
+// changed reports whether the file's contents differ from when they were last
+// seen. Comparing a hash rather than the modification time and size catches
+// edits within the file system's timestamp granularity that keep the size.
+// Only called from the polling goroutine.
+func (w *ConfigWatcher) changed() bool {
+	data, err := os.ReadFile(w.path)
+	if err != nil {
+		return false
+	}
+	sum := sha256.Sum256(data)
+	if sum == w.sum {
+		return false
+	}
+	w.sum = sum
+	return true
+}
+
+// ValidateAndReload loads the config file again and, if it is valid and
+// accepted by onReload, makes it the current config.
+func (w *ConfigWatcher) ValidateAndReload() error {
+	next, err := LoadFile(w.path)
+	if err != nil {
+		return err
+	}
+	old := w.current.Load()
+	if next.Addr != old.Addr {
+		log.Printf("Config change of addr to %s needs a restart, ignoring it", next.Addr)
+		next.Addr = old.Addr
+	}
+	if next.DebugAddr != old.DebugAddr {
+		log.Printf("Config change of debug_addr to %s needs a restart, ignoring it", next.DebugAddr)
+		next.DebugAddr = old.DebugAddr
+	}
+	if w.onReload != nil {
+		if err := w.onReload(next); err != nil {
+			return err
+		}
+	}
+	w.current.Store(next)
+	changes := diffConfigs(old, next, false)
+	log.Printf("Config reloaded from %s: changed %d fields: %s", w.path, len(changes), formatChanges(changes))
+	return nil
+}
@@ -0,0 +1,95 @@
+package config
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConfigWatcherKeepsConfigOnInvalidFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"delay_seconds": 5}`), 0o600); err != nil {
+		t.Fatalf("writing config: %v", err)
+	}
+	w, err := NewConfigWatcher(path, time.Hour, nil)
+	if err != nil {
+		t.Fatalf("NewConfigWatcher: %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"error_rate": 500}`), 0o600); err != nil {
+		t.Fatalf("writing config: %v", err)
+	}
+	if err := w.ValidateAndReload(); err == nil {
+		t.Fatal("expected an invalid error rate to be rejected")
+	}
+	if w.Current().DelaySeconds != 5 {
+		t.Fatalf("expected the previous config to be kept, got %+v", *w.Current())
+	}
+}
+
+func TestConfigWatcherSeesSameSizeEdits(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"delay_seconds": 5}`), 0o600); err != nil {
+		t.Fatalf("writing config: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("stat: %v", err)
+	}
+	w, err := NewConfigWatcher(path, time.Hour, nil)
+	if err != nil {
+		t.Fatalf("NewConfigWatcher: %v", err)
+	}
+	if w.changed() {
+		t.Fatal("expected an untouched file to be unchanged")
+	}
+
+	// Same size and modification time, different contents.
+	if err := os.WriteFile(path, []byte(`{"delay_seconds": 7}`), 0o600); err != nil {
+		t.Fatalf("writing config: %v", err)
+	}
+	if err := os.Chtimes(path, info.ModTime(), info.ModTime()); err != nil {
+		t.Fatalf("chtimes: %v", err)
+	}
+	if !w.changed() {
+		t.Fatal("expected the edit to be noticed")
+	}
+}
+
+func TestConfigWatcherReloadsZeroValues(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"error_rate": 50, "delay_seconds": 5}`), 0o600); err != nil {
+		t.Fatalf("writing config: %v", err)
+	}
+	var reloaded *Config
+	w, err := NewConfigWatcher(path, time.Hour, func(c *Config) error {
+		reloaded = c
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("NewConfigWatcher: %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"error_rate": 0, "delay_seconds": 0}`), 0o600); err != nil {
+		t.Fatalf("writing config: %v", err)
+	}
+	var logged bytes.Buffer
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(&logged)
+	if err := w.ValidateAndReload(); err != nil {
+		t.Fatalf("ValidateAndReload: %v", err)
+	}
+	if want := "changed 2 fields: delay_seconds: 5 -> 0, error_rate: 50 -> 0"; !strings.Contains(logged.String(), want) {
+		t.Fatalf("expected %q to be logged, got %q", want, logged.String())
+	}
+	if c := w.Current(); c.ErrorRate != 0 || c.DelaySeconds != 0 {
+		t.Fatalf("expected the error rate and delay turned down to 0, got %+v", *c)
+	}
+	if reloaded == nil || reloaded.ErrorRate != 0 {
+		t.Fatalf("expected onReload to get the zero error rate, got %+v", reloaded)
+	}
+}
@@ -0,0 +1,7 @@
+// Package grpc holds the gRPC definition of the translation service,
+// translation.proto, and the Go code generated from it. The service itself is
+// served by the translation server, see server.Server.StartGRPC, and used by
+// client.GRPCClient.
+package grpc
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative translation.proto
@@ -0,0 +1,187 @@
+// gRPC interface to the translation server, an alternative to polling the
+// HTTP /status endpoints. See doc.go for regenerating the Go code.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.4.0
+// - protoc             (unknown)
+// source: translation.proto
+
+package grpc
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.62.0 or later.
+const _ = grpc.SupportPackageIsVersion8
+
+const (
+	TranslationService_GetStatus_FullMethodName   = "/translation.TranslationService/GetStatus"
+	TranslationService_WatchStatus_FullMethodName = "/translation.TranslationService/WatchStatus"
+)
+
+// TranslationServiceClient is the client API for TranslationService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type TranslationServiceClient interface {
+	// GetStatus reports a job's status, like GET /status or
+	// GET /jobs/{id}/status.
+	GetStatus(ctx context.Context, in *StatusRequest, opts ...grpc.CallOption) (*StatusResponse, error)
+	// WatchStatus streams a job's status every second until it reaches a final
+	// status, like GET /status/stream.
+	WatchStatus(ctx context.Context, in *StatusRequest, opts ...grpc.CallOption) (TranslationService_WatchStatusClient, error)
+}
+
+type translationServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewTranslationServiceClient(cc grpc.ClientConnInterface) TranslationServiceClient {
+	return &translationServiceClient{cc}
+}
+
+func (c *translationServiceClient) GetStatus(ctx context.Context, in *StatusRequest, opts ...grpc.CallOption) (*StatusResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(StatusResponse)
+	err := c.cc.Invoke(ctx, TranslationService_GetStatus_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *translationServiceClient) WatchStatus(ctx context.Context, in *StatusRequest, opts ...grpc.CallOption) (TranslationService_WatchStatusClient, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &TranslationService_ServiceDesc.Streams[0], TranslationService_WatchStatus_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &translationServiceWatchStatusClient{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type TranslationService_WatchStatusClient interface {
+	Recv() (*StatusResponse, error)
+	grpc.ClientStream
+}
+
+type translationServiceWatchStatusClient struct {
+	grpc.ClientStream
+}
+
+func (x *translationServiceWatchStatusClient) Recv() (*StatusResponse, error) {
+	m := new(StatusResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// TranslationServiceServer is the server API for TranslationService service.
+// All implementations must embed UnimplementedTranslationServiceServer
+// for forward compatibility
+type TranslationServiceServer interface {
+	// GetStatus reports a job's status, like GET /status or
+	// GET /jobs/{id}/status.
+	GetStatus(context.Context, *StatusRequest) (*StatusResponse, error)
+	// WatchStatus streams a job's status every second until it reaches a final
+	// status, like GET /status/stream.
+	WatchStatus(*StatusRequest, TranslationService_WatchStatusServer) error
+	mustEmbedUnimplementedTranslationServiceServer()
+}
+
+// UnimplementedTranslationServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedTranslationServiceServer struct {
+}
+
+func (UnimplementedTranslationServiceServer) GetStatus(context.Context, *StatusRequest) (*StatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetStatus not implemented")
+}
+func (UnimplementedTranslationServiceServer) WatchStatus(*StatusRequest, TranslationService_WatchStatusServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchStatus not implemented")
+}
+func (UnimplementedTranslationServiceServer) mustEmbedUnimplementedTranslationServiceServer() {}
+
+// UnsafeTranslationServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to TranslationServiceServer will
+// result in compilation errors.
+type UnsafeTranslationServiceServer interface {
+	mustEmbedUnimplementedTranslationServiceServer()
+}
+
+func RegisterTranslationServiceServer(s grpc.ServiceRegistrar, srv TranslationServiceServer) {
+	s.RegisterService(&TranslationService_ServiceDesc, srv)
+}
+
+func _TranslationService_GetStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TranslationServiceServer).GetStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TranslationService_GetStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TranslationServiceServer).GetStatus(ctx, req.(*StatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TranslationService_WatchStatus_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StatusRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(TranslationServiceServer).WatchStatus(m, &translationServiceWatchStatusServer{ServerStream: stream})
+}
+
+type TranslationService_WatchStatusServer interface {
+	Send(*StatusResponse) error
+	grpc.ServerStream
+}
+
+type translationServiceWatchStatusServer struct {
+	grpc.ServerStream
+}
+
+func (x *translationServiceWatchStatusServer) Send(m *StatusResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// TranslationService_ServiceDesc is the grpc.ServiceDesc for TranslationService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var TranslationService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "translation.TranslationService",
+	HandlerType: (*TranslationServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetStatus",
+			Handler:    _TranslationService_GetStatus_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchStatus",
+			Handler:       _TranslationService_WatchStatus_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "translation.proto",
+}
@@ -0,0 +1,75 @@
+// Package notify sends desktop notifications, e.g. when a translation job
+// finishes while the client is running in the background.
+package notify
+
+import (
+	"log"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// Notifier delivers a notification to the user.
+type Notifier interface {
+	Notify(title, message string) error
+}
+
+// CommandNotifier sends notifications by running an external command.
+type CommandNotifier struct {
+	Name string
+	Args func(title, message string) []string
+}
+
+// Notify runs the notification command.
+func (n CommandNotifier) Notify(title, message string) error {
+	return exec.Command(n.Name, n.Args(title, message)...).Run()
+}
+
+// LogNotifier writes notifications to a logger, used where no desktop
+// notification command is available.
+type LogNotifier struct {
+	Logger *log.Logger
+}
+
+// Notify logs the notification.
+func (n LogNotifier) Notify(title, message string) error {
+	n.Logger.Printf("%s: %s", title, message)
+	return nil
+}
+
+// New returns a Notifier for the current platform: osascript on macOS and
+// notify-send on Linux. If the command isn't installed, or the platform isn't
+// supported, notifications are written to logger instead.
+func New(logger *log.Logger) Notifier {
+	if n, ok := forPlatform(runtime.GOOS); ok {
+		if _, err := exec.LookPath(n.Name); err == nil {
+			return n
+		}
+		logger.Printf("%s not found, notifications will be logged instead", n.Name)
+	}
+	return LogNotifier{Logger: logger}
+}
+
+// forPlatform returns the notification command for goos.
+func forPlatform(goos string) (CommandNotifier, bool) {
+	switch goos {
+	case "darwin":
+		return CommandNotifier{Name: "osascript", Args: func(title, message string) []string {
+			script := "display notification " + appleScriptString(message) + " with title " + appleScriptString(title)
+			return []string{"-e", script}
+		}}, true
+	case "linux":
+		return CommandNotifier{Name: "notify-send", Args: func(title, message string) []string {
+			return []string{title, message}
+		}}, true
+	default:
+		return CommandNotifier{}, false
+	}
+}
+
+// appleScriptString quotes s as an AppleScript string literal.
+func appleScriptString(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`
+}
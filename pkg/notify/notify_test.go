@@ -0,0 +1,43 @@
+package notify
+
+import (
+	"bytes"
+	"log"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestForPlatform(t *testing.T) {
+	tests := []struct {
+		goos string
+		name string
+		args []string
+	}{
+		{goos: "darwin", name: "osascript", args: []string{"-e", `display notification "job \"a\" completed" with title "Translation"`}},
+		{goos: "linux", name: "notify-send", args: []string{"Translation", `job "a" completed`}},
+	}
+	for _, tt := range tests {
+		n, ok := forPlatform(tt.goos)
+		if !ok || n.Name != tt.name {
+			t.Fatalf("%s: expected %s, got %q (ok=%v)", tt.goos, tt.name, n.Name, ok)
+		}
+		if args := n.Args("Translation", `job "a" completed`); !reflect.DeepEqual(args, tt.args) {
+			t.Fatalf("%s: expected args %q, got %q", tt.goos, tt.args, args)
+		}
+	}
+	if _, ok := forPlatform("windows"); ok {
+		t.Fatal("expected no notification command on windows")
+	}
+}
+
+func TestLogNotifier(t *testing.T) {
+	var buf bytes.Buffer
+	n := LogNotifier{Logger: log.New(&buf, "", 0)}
+	if err := n.Notify("Translation", "completed"); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	if !strings.Contains(buf.String(), "Translation: completed") {
+		t.Fatalf("unexpected log output %q", buf.String())
+	}
+}
@@ -0,0 +1,86 @@
+package server
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+)
+
+// AdminConfig is the body of PUT /admin/config. Fields left out keep their
+// current value.
+type AdminConfig struct {
+	ErrorRate    *int `json:"error_rate,omitempty"`
+	DelaySeconds *int `json:"delay_seconds,omitempty"`
+}
+
+// adminConfigHandler changes the error rate and delay at runtime. Both are
+// validated before either is applied, and the resulting settings are returned.
+// Each field given is recorded in the audit log, as a failure if the update
+// was rejected.
+func (s *Server) adminConfigHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		w.Header().Set("Allow", http.MethodPut)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req AdminConfig
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid JSON body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	cfg, err := s.applyAdminConfig(req)
+	s.auditConfigUpdate(adminActor(r), req, err)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(AdminConfig{ErrorRate: &cfg.ErrorRate, DelaySeconds: &cfg.DelaySeconds}); err != nil {
+		log.Printf("Error encoding admin config response: %v", err)
+	}
+}
+
+// applyAdminConfig validates the fields set in req and applies them in a
+// single config update, so a concurrent SetErrorRate or SetDelay touching the
+// other field is not overwritten. It returns the config that resulted.
+func (s *Server) applyAdminConfig(req AdminConfig) (Config, error) {
+	if req.DelaySeconds != nil {
+		if err := validateDelay(*req.DelaySeconds); err != nil {
+			return Config{}, err
+		}
+	}
+	if req.ErrorRate != nil {
+		if err := validateErrorRate(*req.ErrorRate); err != nil {
+			return Config{}, err
+		}
+	}
+	var updated Config
+	s.updateConfig(func(cfg *Config) {
+		if req.DelaySeconds != nil {
+			cfg.DelaySeconds = *req.DelaySeconds
+		}
+		if req.ErrorRate != nil {
+			cfg.ErrorRate = *req.ErrorRate
+		}
+		updated = *cfg
+	})
+	log.Printf("Config updated: delay of %d seconds and error rate of %d%%", updated.DelaySeconds, updated.ErrorRate)
+	return updated, nil
+}
+
+// auditConfigUpdate records an audit entry by actor for each field set in req.
+func (s *Server) auditConfigUpdate(actor string, req AdminConfig, err error) {
+	result := AuditSuccess
+	if err != nil {
+		result = AuditFailure
+	}
+	if req.ErrorRate != nil {
+		s.audit.Record(actor, "set_error_rate", "error_rate", result)
+	}
+	if req.DelaySeconds != nil {
+		s.audit.Record(actor, "set_delay", "delay_seconds", result)
+	}
+}
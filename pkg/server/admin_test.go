@@ -0,0 +1,128 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// finishJob polls a new job through to its final status.
+func finishJob(t *testing.T, s *Server) string {
+	t.Helper()
+	poll(t, s)
+	expireJob(s)
+	return decodeResult(t, poll(t, s))
+}
+
+func TestSetErrorRateChangesOutcomes(t *testing.T) {
+	s, err := NewServer(WithDelay(time.Second), WithErrorRate(0))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	if got := finishJob(t, s); got != "completed" {
+		t.Fatalf("expected completed at 0%%, got %q", got)
+	}
+
+	if err := s.SetErrorRate(100); err != nil {
+		t.Fatalf("SetErrorRate: %v", err)
+	}
+	if got := finishJob(t, s); got != "error" {
+		t.Fatalf("expected error at 100%%, got %q", got)
+	}
+
+	if err := s.SetErrorRate(101); err == nil {
+		t.Fatal("expected an error rate over 100 to be rejected")
+	}
+	if err := s.SetDelay(-1); err == nil {
+		t.Fatal("expected a negative delay to be rejected")
+	}
+	if err := s.SetDelay(3); err != nil {
+		t.Fatalf("SetDelay: %v", err)
+	}
+	if d := s.effectiveDelay().Seconds(); d != 3 {
+		t.Fatalf("expected a 3s delay, got %vs", d)
+	}
+}
+
+func TestAdminConfigEndpoint(t *testing.T) {
+	s, err := NewServer(WithDelay(time.Second), WithErrorRate(0), WithAPIKey("secret"))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	put := func(key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/admin/config", strings.NewReader(body))
+		if key != "" {
+			req.Header.Set(APIKeyHeader, key)
+		}
+		rec := httptest.NewRecorder()
+		s.routes().ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := put("", `{"error_rate": 100}`); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without a key, got %d", rec.Code)
+	}
+	if rec := put("wrong", `{"error_rate": 100}`); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 with the wrong key, got %d", rec.Code)
+	}
+	// Neither field is applied when one is invalid.
+	if rec := put("secret", `{"error_rate": 100, "delay_seconds": -1}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a negative delay, got %d", rec.Code)
+	}
+	if got := finishJob(t, s); got != "completed" {
+		t.Fatalf("expected the rejected update to leave the error rate at 0, got %q", got)
+	}
+
+	rec := put("secret", `{"error_rate": 100}`)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"error_rate":100`) ||
+		!strings.Contains(rec.Body.String(), `"delay_seconds":1`) {
+		t.Fatalf("unexpected response %d %q", rec.Code, rec.Body.String())
+	}
+	if got := finishJob(t, s); got != "error" {
+		t.Fatalf("expected error after raising the error rate, got %q", got)
+	}
+}
+
+func TestAdminConfigKeepsConcurrentUpdates(t *testing.T) {
+	s, err := NewServer(WithDelay(time.Second), WithErrorRate(0), WithAPIKey("secret"))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	for i := 1; i <= 50; i++ {
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func(delay int) {
+			defer wg.Done()
+			if err := s.SetDelay(delay); err != nil {
+				t.Errorf("SetDelay: %v", err)
+			}
+		}(i)
+		req := httptest.NewRequest(http.MethodPut, "/admin/config", strings.NewReader(`{"error_rate": 10}`))
+		req.Header.Set(APIKeyHeader, "secret")
+		rec := httptest.NewRecorder()
+		s.routes().ServeHTTP(rec, req)
+		wg.Wait()
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("unexpected response %d %q", rec.Code, rec.Body.String())
+		}
+		if cfg := s.config.Load(); cfg.DelaySeconds != i || cfg.ErrorRate != 10 {
+			t.Fatalf("expected a %ds delay and 10%% error rate, got %+v", i, *cfg)
+		}
+	}
+}
+
+func TestAdminConfigNotServedWithoutKey(t *testing.T) {
+	s, err := NewServer(WithDelay(time.Second), WithErrorRate(0))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	rec := httptest.NewRecorder()
+	s.routes().ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/admin/config", strings.NewReader(`{}`)))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 without an API key configured, got %d", rec.Code)
+	}
+}
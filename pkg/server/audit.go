@@ -0,0 +1,112 @@
+package server
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Results recorded in AuditEntry.Result.
+const (
+	AuditSuccess = "success"
+	AuditFailure = "failure"
+)
+
+// AuditEntry records one administrative action.
+type AuditEntry struct {
+	Timestamp time.Time `json:"timestamp"`
+	Actor     string    `json:"actor"`  // Who made the change, see adminActor.
+	Action    string    `json:"action"` // e.g. "set_error_rate".
+	Target    string    `json:"target"` // The config field changed.
+	Result    string    `json:"result"` // AuditSuccess or AuditFailure.
+}
+
+// AuditLog keeps every administrative action in memory, in the order they
+// were made, for GET /admin/audit. It is safe for concurrent use.
+type AuditLog struct {
+	mu      sync.Mutex
+	entries []AuditEntry
+}
+
+// NewAuditLog returns an empty AuditLog.
+func NewAuditLog() *AuditLog {
+	return &AuditLog{}
+}
+
+// Record adds an entry timestamped now.
+func (a *AuditLog) Record(actor, action, target, result string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.entries = append(a.entries, AuditEntry{
+		Timestamp: time.Now(),
+		Actor:     actor,
+		Action:    action,
+		Target:    target,
+		Result:    result,
+	})
+}
+
+// GetEntries returns a copy of the entries recorded at or after since, oldest
+// first. A zero since returns them all.
+func (a *AuditLog) GetEntries(since time.Time) []AuditEntry {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var entries []AuditEntry
+	for _, e := range a.entries {
+		if !e.Timestamp.Before(since) {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// AuditLog returns the log of administrative actions made through /admin/ or
+// the Server's SetErrorRate and SetDelay.
+func (s *Server) AuditLog() *AuditLog {
+	return s.audit
+}
+
+// localActor is the actor recorded for changes made by calling the Server's
+// methods, such as SetErrorRate, rather than over /admin/.
+const localActor = "local"
+
+// adminActor identifies who sent an admin request. There is a single API key,
+// so every caller holding it is "admin" and they are told apart by IP.
+func adminActor(r *http.Request) string {
+	ip := clientIP(r)
+	if ip == "" {
+		return "admin"
+	}
+	return "admin@" + ip
+}
+
+// adminAuditHandler serves the audit log as newline delimited JSON, one entry
+// per line. The optional since parameter, in RFC 3339 format, leaves out
+// earlier entries.
+func (s *Server) adminAuditHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var since time.Time
+	if v := r.URL.Query().Get("since"); v != "" {
+		var err error
+		if since, err = time.Parse(time.RFC3339Nano, v); err != nil {
+			http.Error(w, "invalid since, expected RFC 3339: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	enc := json.NewEncoder(w)
+	for _, e := range s.audit.GetEntries(since) {
+		if err := enc.Encode(e); err != nil {
+			log.Printf("Error encoding audit entry: %v", err)
+			return
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package server_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"Video-Translation-Simulator/pkg/server"
+	"Video-Translation-Simulator/pkg/testutil/fixtures"
+)
+
+func TestAuditLogRecordsAdminActions(t *testing.T) {
+	s, err := server.NewServer(server.WithConfig(fixtures.FixtureFastServer), server.WithAPIKey("secret"))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	do := func(method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.RemoteAddr = "192.0.2.7:4321"
+		req.Header.Set(server.APIKeyHeader, "secret")
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		return rec
+	}
+
+	start := time.Now()
+	if rec := do(http.MethodPut, "/admin/config", `{"error_rate": 50}`); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if rec := do(http.MethodPut, "/admin/config", `{"delay_seconds": 3}`); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if rec := do(http.MethodPut, "/admin/config", `{"error_rate": 101}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rec.Code)
+	}
+
+	rec := do(http.MethodGet, "/admin/audit?since="+url.QueryEscape(start.Format(time.RFC3339Nano)), "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Fatalf("expected NDJSON, got %q", ct)
+	}
+	var entries []server.AuditEntry
+	scanner := bufio.NewScanner(rec.Body)
+	for scanner.Scan() {
+		var e server.AuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatalf("decoding %q: %v", scanner.Text(), err)
+		}
+		entries = append(entries, e)
+	}
+
+	want := []server.AuditEntry{
+		{Actor: "admin@192.0.2.7", Action: "set_error_rate", Target: "error_rate", Result: server.AuditSuccess},
+		{Actor: "admin@192.0.2.7", Action: "set_delay", Target: "delay_seconds", Result: server.AuditSuccess},
+		{Actor: "admin@192.0.2.7", Action: "set_error_rate", Target: "error_rate", Result: server.AuditFailure},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("expected %d entries, got %d: %+v", len(want), len(entries), entries)
+	}
+	for i, e := range entries {
+		if e.Timestamp.Before(start) {
+			t.Errorf("entry %d: timestamp %v is before the actions started", i, e.Timestamp)
+		}
+		e.Timestamp = time.Time{}
+		if e != want[i] {
+			t.Errorf("entry %d: expected %+v, got %+v", i, want[i], e)
+		}
+	}
+
+	if rec := do(http.MethodGet, "/admin/audit?since="+url.QueryEscape(time.Now().Add(time.Hour).Format(time.RFC3339)), ""); rec.Body.Len() != 0 {
+		t.Fatalf("expected no entries in the future, got %q", rec.Body.String())
+	}
+	if rec := do(http.MethodGet, "/admin/audit?since=yesterday", ""); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an invalid since, got %d", rec.Code)
+	}
+}
+
+func TestAuditLogRecordsSetters(t *testing.T) {
+	s, err := server.NewServer(server.WithConfig(fixtures.FixtureFastServer))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	if err := s.SetErrorRate(30); err != nil {
+		t.Fatalf("SetErrorRate: %v", err)
+	}
+	if err := s.SetDelay(-1); err == nil {
+		t.Fatal("expected a negative delay to be rejected")
+	}
+
+	want := []server.AuditEntry{
+		{Actor: "local", Action: "set_error_rate", Target: "error_rate", Result: server.AuditSuccess},
+		{Actor: "local", Action: "set_delay", Target: "delay_seconds", Result: server.AuditFailure},
+	}
+	entries := s.AuditLog().GetEntries(time.Time{})
+	if len(entries) != len(want) {
+		t.Fatalf("expected %d entries, got %d: %+v", len(want), len(entries), entries)
+	}
+	for i, e := range entries {
+		e.Timestamp = time.Time{}
+		if e != want[i] {
+			t.Errorf("entry %d: expected %+v, got %+v", i, want[i], e)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package server
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// BlocklistReloadInterval is how often an IPBlocklist rereads its file.
+const BlocklistReloadInterval = 60 * time.Second
+
+// IPBlocklist rejects requests from the CIDR ranges listed in a file, one per
+// line. Blank lines and lines starting with # are ignored, and a bare IP
+// blocks just that address. The client IP is the one found by
+// RealIPMiddleware if it ran, otherwise the direct peer's.
+type IPBlocklist struct {
+	path    string
+	mu      sync.RWMutex
+	nets    []*net.IPNet
+	blocked atomic.Int64
+}
+
+// NewIPBlocklist loads the blocklist at path.
+func NewIPBlocklist(path string) (*IPBlocklist, error) {
+	b := &IPBlocklist{path: path}
+	if err := b.Reload(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// BlocklistMiddleware returns middleware rejecting requests from the ranges in
+// the file at path with 403. The file is reread every BlocklistReloadInterval
+// and on SIGHUP until stop is closed. It returns an error if the file cannot
+// be read, since serving without the blocklist would be unsafe.
+func BlocklistMiddleware(path string, stop <-chan struct{}) (func(http.Handler) http.Handler, error) {
+	b, err := NewIPBlocklist(path)
+	if err != nil {
+		return nil, fmt.Errorf("loading blocklist: %w", err)
+	}
+	go b.watch(BlocklistReloadInterval, stop)
+	return b.Middleware, nil
+}
+
+// WithBlocklist rejects requests from the ranges in the file at path with 403,
+// rereading it every BlocklistReloadInterval and on SIGHUP until Shutdown.
+// NewServer fails if the file cannot be loaded.
+func WithBlocklist(path string) ServerOption {
+	return func(s *Server) {
+		s.blocklistPath = path
+	}
+}
+
+// Reload rereads the blocklist file. On error the previous ranges stay in place.
+func (b *IPBlocklist) Reload() error {
+	f, err := os.Open(b.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var nets []*net.IPNet
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		entry := strings.TrimSpace(scanner.Text())
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			if ip := net.ParseIP(entry); ip != nil && ip.To4() != nil {
+				entry += "/32"
+			} else {
+				entry += "/128"
+			}
+		}
+		_, ipNet, err := net.ParseCIDR(entry)
+		if err != nil {
+			return fmt.Errorf("%s:%d: %w", b.path, line, err)
+		}
+		nets = append(nets, ipNet)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	b.nets = nets
+	b.mu.Unlock()
+	return nil
+}
+
+// Blocked reports whether ip is in one of the blocked ranges.
+func (b *IPBlocklist) Blocked(ip net.IP) bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, n := range b.nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// BlockedTotal returns the number of requests rejected so far.
+func (b *IPBlocklist) BlockedTotal() int64 {
+	return b.blocked.Load()
+}
+
+// Middleware rejects requests from blocked ranges with 403.
+func (b *IPBlocklist) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := net.ParseIP(RealIPFromContext(r.Context()))
+		if ip == nil {
+			ip = remoteIP(r)
+		}
+		if ip != nil && b.Blocked(ip) {
+			b.blocked.Add(1)
+			log.Printf("Rejecting %s %s from blocked address %s", r.Method, r.URL.Path, ip)
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// watch reloads the blocklist every interval and on SIGHUP until stop is
+// closed.
+func (b *IPBlocklist) watch(interval time.Duration, stop <-chan struct{}) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		case <-hup:
+			log.Printf("SIGHUP received, reloading blocklist %s", b.path)
+		}
+		if err := b.Reload(); err != nil {
+			log.Printf("Failed to reload blocklist, keeping the previous one: %v", err)
+		}
+	}
+}
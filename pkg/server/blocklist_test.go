@@ -0,0 +1,120 @@
+package server
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBlocklistReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocklist")
+	if err := os.WriteFile(path, []byte("# local clients\n127.0.0.0/8\n"), 0o644); err != nil {
+		t.Fatalf("write blocklist: %v", err)
+	}
+	s, err := NewServer(WithDelay(time.Second), WithErrorRate(0), WithBlocklist(path))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	defer s.Shutdown(context.Background())
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+
+	get := func() int {
+		resp, err := srv.Client().Get(srv.URL + "/status")
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if code := get(); code != http.StatusForbidden {
+		t.Fatalf("expected 403 from a blocked address, got %d", code)
+	}
+	if n := s.Metrics().BlockedRequestsTotal; n != 1 {
+		t.Fatalf("expected 1 blocked request, got %d", n)
+	}
+
+	if err := os.WriteFile(path, []byte("10.0.0.0/8\n"), 0o644); err != nil {
+		t.Fatalf("write blocklist: %v", err)
+	}
+	if err := s.blocklist.Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if code := get(); code != http.StatusOK {
+		t.Fatalf("expected 200 once unblocked, got %d", code)
+	}
+}
+
+func TestBlocklistParsing(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocklist")
+	os.WriteFile(path, []byte("192.0.2.7\n\n2001:db8::/32\n"), 0o644)
+	b, err := NewIPBlocklist(path)
+	if err != nil {
+		t.Fatalf("NewIPBlocklist: %v", err)
+	}
+	for ip, want := range map[string]bool{
+		"192.0.2.7":   true,
+		"192.0.2.8":   false,
+		"2001:db8::1": true,
+		"2001:db9::1": false,
+	} {
+		if got := b.Blocked(net.ParseIP(ip)); got != want {
+			t.Errorf("Blocked(%s) = %v, want %v", ip, got, want)
+		}
+	}
+
+	// A broken file leaves the previous ranges in place.
+	os.WriteFile(path, []byte("not a cidr\n"), 0o644)
+	if err := b.Reload(); err == nil {
+		t.Fatal("expected an error for an invalid entry")
+	}
+	if !b.Blocked(net.ParseIP("192.0.2.7")) {
+		t.Fatal("expected the previous blocklist to be kept")
+	}
+
+	if _, err := NewServer(WithDelay(time.Second), WithErrorRate(0), WithBlocklist(filepath.Join(t.TempDir(), "missing"))); err == nil {
+		t.Fatal("expected NewServer to fail without the blocklist file")
+	}
+	if _, err := BlocklistMiddleware(filepath.Join(t.TempDir(), "missing"), nil); err == nil {
+		t.Fatal("expected BlocklistMiddleware to fail without the blocklist file")
+	}
+}
+
+func TestBlocklistUsesAddressBehindTrustedProxy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocklist")
+	if err := os.WriteFile(path, []byte("198.51.100.2\n"), 0o644); err != nil {
+		t.Fatalf("write blocklist: %v", err)
+	}
+	get := func(s *Server) int {
+		req := httptest.NewRequest(http.MethodGet, "/status", nil)
+		req.RemoteAddr = "10.0.0.1:5555"
+		req.Header.Set("X-Forwarded-For", "198.51.100.2")
+		rec := httptest.NewRecorder()
+		s.Handler().ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	s, err := NewServer(WithDelay(time.Second), WithErrorRate(0), WithBlocklist(path), WithTrustedProxies(net.ParseIP("10.0.0.1")))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	defer s.Shutdown(context.Background())
+	if code := get(s); code != http.StatusForbidden {
+		t.Fatalf("expected 403 for a blocked client behind a trusted proxy, got %d", code)
+	}
+
+	untrusted, err := NewServer(WithDelay(time.Second), WithErrorRate(0), WithBlocklist(path))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	defer untrusted.Shutdown(context.Background())
+	if code := get(untrusted); code != http.StatusOK {
+		t.Fatalf("expected forwarding headers from an untrusted peer to be ignored, got %d", code)
+	}
+}
@@ -0,0 +1,269 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Limits on calling the CallbackURL of finished jobs.
+const (
+	maxCallbackURLLength = 2048
+	callbackQueueSize    = 64
+	callbackTimeout      = 5 * time.Second
+	maxFailedCallbacks   = 1000 // Kept by FailedCallbacks, oldest dropped first.
+)
+
+// DefaultOutboundWebhookConcurrency is how many callbacks are sent at once
+// when Config.OutboundWebhookConcurrency is zero.
+const DefaultOutboundWebhookConcurrency = 10
+
+// CallbackPayload is the body POSTed to a job's CallbackURL once it finishes.
+type CallbackPayload struct {
+	JobID  string `json:"job_id"`
+	Result string `json:"result"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// jobCallback is a CallbackPayload waiting to be sent to url.
+type jobCallback struct {
+	url     string
+	payload CallbackPayload
+}
+
+// validateCallbackURL returns an error unless raw is empty or an absolute
+// http or https URL.
+func validateCallbackURL(raw string) error {
+	if raw == "" {
+		return nil
+	}
+	if len(raw) > maxCallbackURLLength {
+		return errors.New("invalid callback_url, must be at most 2048 characters")
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("invalid callback_url, must be an http or https URL")
+	}
+	return nil
+}
+
+// DeliveryStats counts the callbacks sent to the CallbackURL of finished jobs.
+type DeliveryStats struct {
+	Pending   int64 // Queued or on their first attempt.
+	Succeeded int64
+	Failed    int64 // Given up on after their retries, see FailedCallbacks.
+	Retrying  int64 // Failed at least once, and waiting to be retried.
+}
+
+// FailedCallback is a callback given up on after its retries.
+type FailedCallback struct {
+	URL      string
+	Payload  CallbackPayload
+	Attempts int
+	Error    string // Why the last attempt failed.
+	FailedAt time.Time
+}
+
+// callbackDeliveries tracks the callbacks sent by the dispatcher.
+type callbackDeliveries struct {
+	pending, succeeded, failed, retrying atomic.Int64
+	seq                                  atomic.Int64 // Numbers the RetryEntry IDs.
+
+	mu              sync.Mutex
+	failedCallbacks []FailedCallback // The dead letter queue, oldest first.
+}
+
+// WebhookDeliveryStats returns how the callbacks of finished jobs have fared.
+func (s *Server) WebhookDeliveryStats() DeliveryStats {
+	d := &s.deliveries
+	return DeliveryStats{
+		Pending:   d.pending.Load(),
+		Succeeded: d.succeeded.Load(),
+		Failed:    d.failed.Load(),
+		Retrying:  d.retrying.Load(),
+	}
+}
+
+// FailedCallbacks returns the dead letter queue: the most recent 1000
+// callbacks given up on after their retries, oldest first.
+func (s *Server) FailedCallbacks() []FailedCallback {
+	d := &s.deliveries
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]FailedCallback(nil), d.failedCallbacks...)
+}
+
+// queueCallback queues a call to the CallbackURL of job if it has one and has
+// just finished. One is dropped rather than hold up the caller when the queue
+// is full. The caller must hold s.mu.
+func (s *Server) queueCallback(job *jobState) {
+	if job.callbackURL == "" || !finished(job.status) {
+		return
+	}
+	select {
+	case s.callbacks <- jobCallback{url: job.callbackURL, payload: CallbackPayload{JobID: job.id, Result: job.status, Reason: job.reason}}:
+		s.deliveries.pending.Add(1)
+	default:
+		log.Printf("Callback queue is full, dropping the callback of job %s.", job.id)
+	}
+}
+
+// dispatchCallbacks starts the goroutine sending queued callbacks, unless it
+// is already running or Shutdown has been called. It sends up to
+// OutboundWebhookConcurrency at once, as read when it starts, so a burst of
+// finished jobs can't use up the server's connections. Failed callbacks wait
+// in a WebhookRetryQueue, which the goroutine checks every second (or every
+// WebhookRetryInterval if shorter). Callbacks still queued or waiting to be
+// retried at Shutdown are dropped.
+func (s *Server) dispatchCallbacks() {
+	s.goBackground(&s.dispatching, func() {
+		// Cut short callbacks in flight at Shutdown.
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			<-s.stop
+			cancel()
+		}()
+		cfg := s.config.Load()
+		d := &callbackDispatch{
+			client:     &http.Client{Timeout: callbackTimeout},
+			retries:    NewWebhookRetryQueue(cfg.WebhookRetryInterval),
+			maxRetries: cfg.MaxWebhookRetries,
+		}
+		if d.maxRetries == 0 {
+			d.maxRetries = DefaultMaxWebhookRetries
+		}
+		concurrency := cfg.OutboundWebhookConcurrency
+		if concurrency == 0 {
+			concurrency = DefaultOutboundWebhookConcurrency
+		}
+		slots := make(chan struct{}, concurrency)
+		var inFlight sync.WaitGroup
+		defer inFlight.Wait()
+		// send runs deliver once a slot is free, returning false at Shutdown.
+		send := func(deliver func()) bool {
+			select {
+			case <-s.stop:
+				return false
+			case slots <- struct{}{}:
+			}
+			inFlight.Add(1)
+			go func() {
+				defer inFlight.Done()
+				defer func() { <-slots }()
+				deliver()
+			}()
+			return true
+		}
+
+		ticker := time.NewTicker(min(time.Second, d.retries.initial))
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.stop:
+				return
+			case cb := <-s.callbacks:
+				if !send(func() { s.deliverCallback(ctx, d, cb) }) {
+					return
+				}
+			case <-ticker.C:
+				for _, entry := range d.retries.NextDue() {
+					entry := entry
+					if !send(func() { s.retryCallback(ctx, d, entry) }) {
+						return
+					}
+				}
+			}
+		}
+	})
+}
+
+// callbackDispatch is what the goroutine started by dispatchCallbacks sends
+// callbacks with.
+type callbackDispatch struct {
+	client     *http.Client
+	retries    *WebhookRetryQueue
+	maxRetries int
+}
+
+// deliverCallback makes the first attempt at sending cb, queueing it to be
+// retried if it fails.
+func (s *Server) deliverCallback(ctx context.Context, d *callbackDispatch, cb jobCallback) {
+	stats := &s.deliveries
+	err := sendCallback(ctx, d.client, cb)
+	stats.pending.Add(-1)
+	if err == nil {
+		stats.succeeded.Add(1)
+		return
+	}
+	log.Printf("Error calling back job %s: %v", cb.payload.JobID, err)
+	stats.retrying.Add(1)
+	d.retries.Enqueue(RetryEntry{
+		ID:        fmt.Sprintf("%s#%d", cb.payload.JobID, stats.seq.Add(1)),
+		URL:       cb.url,
+		Payload:   cb.payload,
+		Attempts:  1,
+		LastError: err.Error(),
+	})
+}
+
+// retryCallback sends entry again. If it fails once more, it is queued for
+// another retry, or put in the dead letter queue once it has been retried
+// MaxWebhookRetries times.
+func (s *Server) retryCallback(ctx context.Context, d *callbackDispatch, entry RetryEntry) {
+	stats := &s.deliveries
+	err := sendCallback(ctx, d.client, jobCallback{url: entry.URL, payload: entry.Payload})
+	if err == nil {
+		d.retries.MarkSucceeded(entry.ID)
+		stats.retrying.Add(-1)
+		stats.succeeded.Add(1)
+		return
+	}
+	entry.Attempts++
+	entry.LastError = err.Error()
+	log.Printf("Error calling back job %s, attempt %d: %v", entry.Payload.JobID, entry.Attempts, err)
+	if entry.Attempts <= d.maxRetries {
+		d.retries.Enqueue(entry)
+		return
+	}
+	d.retries.Drop(entry.ID)
+	stats.retrying.Add(-1)
+	stats.failed.Add(1)
+
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	if len(stats.failedCallbacks) == maxFailedCallbacks {
+		stats.failedCallbacks = stats.failedCallbacks[1:]
+	}
+	stats.failedCallbacks = append(stats.failedCallbacks, FailedCallback{URL: entry.URL, Payload: entry.Payload, Attempts: entry.Attempts, Error: entry.LastError, FailedAt: time.Now()})
+}
+
+// sendCallback POSTs cb's payload to its URL once, returning an error if the
+// request fails or isn't answered with a 2xx status.
+func sendCallback(ctx context.Context, client *http.Client, cb jobCallback) error {
+	body, err := json.Marshal(cb.payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cb.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("answered with %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,214 @@
+package server_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"Video-Translation-Simulator/pkg/server"
+	"Video-Translation-Simulator/pkg/testutil/fixtures"
+)
+
+func TestJobCallbackURL(t *testing.T) {
+	var mu sync.Mutex
+	var got []server.CallbackPayload
+	called := make(chan struct{}, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var p server.CallbackPayload
+		if r.Method != http.MethodPost || json.NewDecoder(r.Body).Decode(&p) != nil {
+			t.Errorf("expected a JSON POST, got %s", r.Method)
+		}
+		mu.Lock()
+		got = append(got, p)
+		mu.Unlock()
+		called <- struct{}{}
+	}))
+	defer receiver.Close()
+
+	s, err := server.NewServer(server.WithConfig(fixtures.FixtureInstantComplete))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	defer s.Shutdown(context.Background())
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/jobs", strings.NewReader(`{"id":"called","callback_url":"`+receiver.URL+`/done"}`)))
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d", rec.Code)
+	}
+
+	// The job finishes as it is polled, and polling it again changes nothing.
+	for i := 0; i < 3; i++ {
+		s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/jobs/called/status", nil))
+	}
+	select {
+	case <-called:
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected the callback URL to be called")
+	}
+	select {
+	case <-called:
+		t.Fatal("expected the callback URL to be called only once")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if want := (server.CallbackPayload{JobID: "called", Result: "completed"}); len(got) != 1 || got[0] != want {
+		t.Fatalf("expected %+v, got %+v", want, got)
+	}
+}
+
+func TestJobCallbackURLIsValidated(t *testing.T) {
+	s, err := server.NewServer(server.WithConfig(fixtures.FixtureFastServer))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	for _, callback := range []string{"ftp://example.com/done", "/done", "http://"} {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/jobs", strings.NewReader(`{"callback_url":"`+callback+`"}`)))
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d", callback, rec.Code)
+		}
+	}
+}
+
+// waitForDeliveries waits for n callbacks of s to have succeeded or failed.
+func waitForDeliveries(t *testing.T, s *server.Server, n int64) server.DeliveryStats {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		stats := s.WebhookDeliveryStats()
+		if stats.Succeeded+stats.Failed >= n {
+			return stats
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d callbacks to be delivered, got %+v", n, stats)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestJobCallbackConcurrency(t *testing.T) {
+	var inFlight, peak atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+		}
+		time.Sleep(20 * time.Millisecond)
+	}))
+	defer receiver.Close()
+
+	cfg := *fixtures.FixtureInstantComplete
+	cfg.OutboundWebhookConcurrency = 5
+	s, err := server.NewServer(server.WithConfig(&cfg))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	defer s.Shutdown(context.Background())
+	for i := 0; i < 50; i++ {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/jobs", strings.NewReader(fmt.Sprintf(`{"id":"job-%d","callback_url":%q}`, i, receiver.URL))))
+		if rec.Code != http.StatusAccepted {
+			t.Fatalf("job %d: expected 202, got %d", i, rec.Code)
+		}
+	}
+	// Finish all 50 at once.
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, fmt.Sprintf("/jobs/job-%d/status", i), nil))
+		}(i)
+	}
+	wg.Wait()
+
+	stats := waitForDeliveries(t, s, 50)
+	if want := (server.DeliveryStats{Succeeded: 50}); stats != want {
+		t.Fatalf("expected %+v, got %+v", want, stats)
+	}
+	if n := peak.Load(); n > 5 {
+		t.Fatalf("expected at most 5 callbacks at once, got %d", n)
+	} else if n < 2 {
+		t.Fatalf("expected callbacks to be sent concurrently, got %d at most", n)
+	}
+}
+
+func TestJobCallbackDeadLetters(t *testing.T) {
+	var calls atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		http.Error(w, "down", http.StatusInternalServerError)
+	}))
+	defer receiver.Close()
+
+	cfg := *fixtures.FixtureInstantComplete
+	cfg.MaxWebhookRetries = 3
+	cfg.WebhookRetryInterval = 10 * time.Millisecond
+	s, err := server.NewServer(server.WithConfig(&cfg))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	defer s.Shutdown(context.Background())
+	s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/jobs", strings.NewReader(`{"id":"unlucky","callback_url":"`+receiver.URL+`"}`)))
+	s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/jobs/unlucky/status", nil))
+
+	if stats, want := waitForDeliveries(t, s, 1), (server.DeliveryStats{Failed: 1}); stats != want {
+		t.Fatalf("expected %+v, got %+v", want, stats)
+	}
+	if n := calls.Load(); n != 4 {
+		t.Fatalf("expected the callback to be tried 4 times, got %d", n)
+	}
+	failed := s.FailedCallbacks()
+	if len(failed) != 1 || failed[0].Payload.JobID != "unlucky" || failed[0].Attempts != 4 || !strings.Contains(failed[0].Error, "500") {
+		t.Fatalf("expected the callback in the dead letter queue, got %+v", failed)
+	}
+}
+
+func TestJobCallbackRetries(t *testing.T) {
+	var calls atomic.Int32
+	var last time.Time
+	var gaps []time.Duration
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !last.IsZero() {
+			gaps = append(gaps, time.Since(last))
+		}
+		last = time.Now()
+		if calls.Add(1) <= 5 {
+			http.Error(w, "down", http.StatusServiceUnavailable)
+		}
+	}))
+	defer receiver.Close()
+
+	cfg := *fixtures.FixtureInstantComplete
+	cfg.WebhookRetryInterval = 20 * time.Millisecond
+	s, err := server.NewServer(server.WithConfig(&cfg))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	defer s.Shutdown(context.Background())
+	s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/jobs", strings.NewReader(`{"id":"flaky","callback_url":"`+receiver.URL+`"}`)))
+	s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/jobs/flaky/status", nil))
+
+	if stats, want := waitForDeliveries(t, s, 1), (server.DeliveryStats{Succeeded: 1}); stats != want {
+		t.Fatalf("expected %+v, got %+v", want, stats)
+	}
+	if n := calls.Load(); n != 6 {
+		t.Fatalf("expected the callback to succeed on its 6th attempt, got %d attempts", n)
+	}
+	if len(s.FailedCallbacks()) != 0 {
+		t.Fatalf("expected no dead letters, got %+v", s.FailedCallbacks())
+	}
+	// The retries back off: the last waits at least half of 20ms * 2^4.
+	if gap := gaps[len(gaps)-1]; gap < 160*time.Millisecond {
+		t.Fatalf("expected the retries to back off, waited %v before the last", gap)
+	}
+}
@@ -0,0 +1,68 @@
+package server
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"Video-Translation-Simulator/pkg/types"
+)
+
+// CancelResult is the body answering DELETE /jobs.
+type CancelResult struct {
+	CancelledCount int      `json:"cancelled_count"`
+	NotCancelled   []string `json:"not_cancelled,omitempty"` // Matching jobs which had already finished.
+}
+
+// cancelJobsHandler cancels the jobs matching the status, tag, label and
+// selector parameters, see parseJobFilter, all at once: no job changes while
+// they are picked out and cancelled. It responds with 200 and a CancelResult,
+// or 409 if some of the jobs had already finished and so weren't cancelled,
+// listing them. At least one parameter is required, so a bare DELETE /jobs
+// can't cancel every job.
+func (s *Server) cancelJobsHandler(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseJobFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if filter.empty() {
+		http.Error(w, "missing status, tag, label or selector parameter", http.StatusBadRequest)
+		return
+	}
+
+	machine := types.NewStatusMachine()
+	var result CancelResult
+	s.mu.Lock()
+	s.evictJobs()
+	for _, job := range s.matchJobs(filter) {
+		if !machine.IsValid(types.Status(job.status), types.StatusCancelled) {
+			result.NotCancelled = append(result.NotCancelled, job.id)
+			continue
+		}
+		s.cancelJob(job)
+		result.CancelledCount++
+	}
+	s.mu.Unlock()
+
+	code := http.StatusOK
+	if len(result.NotCancelled) > 0 {
+		code = http.StatusConflict
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		log.Printf("Error encoding cancel result: %v", err)
+	}
+}
+
+// cancelJob ends the scheduled or pending job as "cancelled", without starting
+// its next job. The caller must hold s.mu.
+func (s *Server) cancelJob(job *jobState) {
+	old := job.status
+	job.status = string(types.StatusCancelled)
+	job.finishedAt = time.Now()
+	s.notifyWatchers(job, old)
+	s.queueCallback(job)
+}
@@ -0,0 +1,73 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCancelJobs(t *testing.T) {
+	s, err := NewServer(WithDelay(time.Hour), WithErrorRate(0))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	do := func(method, target, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.routes().ServeHTTP(rec, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return rec
+	}
+	for _, body := range []string{
+		`{"id":"a","metadata":{"team":"nlp"},"labels":{"env":"staging"}}`,
+		`{"id":"b","labels":{"env":"staging"}}`,
+		`{"id":"c","labels":{"env":"prod"}}`,
+		`{"id":"d","labels":{"env":"staging"}}`,
+	} {
+		if rec := do(http.MethodPost, "/jobs", body); rec.Code != http.StatusAccepted {
+			t.Fatalf("expected 202, got %d", rec.Code)
+		}
+	}
+	s.mu.Lock()
+	for i, id := range []string{"d", "c", "b", "a"} {
+		s.jobs[id].createdAt = time.Now().Add(-time.Duration(i) * time.Second)
+	}
+	s.jobs["d"].status, s.jobs["d"].finishedAt = "completed", time.Now()
+	s.mu.Unlock()
+
+	tests := []struct {
+		query        string
+		code         int
+		cancelled    int
+		notCancelled []string
+	}{
+		// d has already completed, so only a and b are cancelled.
+		{"?label=env:staging", http.StatusConflict, 2, []string{"d"}},
+		{"?status=pending", http.StatusOK, 1, nil},
+		{"?tag=team:nlp", http.StatusConflict, 0, []string{"a"}},
+		{"?status=scheduled", http.StatusOK, 0, nil},
+	}
+	for _, tt := range tests {
+		rec := do(http.MethodDelete, "/jobs"+tt.query, "")
+		var result CancelResult
+		if err := json.NewDecoder(rec.Body).Decode(&result); err != nil {
+			t.Fatalf("%s: decoding result: %v", tt.query, err)
+		}
+		if rec.Code != tt.code || result.CancelledCount != tt.cancelled || !reflect.DeepEqual(result.NotCancelled, tt.notCancelled) {
+			t.Errorf("%s: expected %d %d %v, got %d %+v", tt.query, tt.code, tt.cancelled, tt.notCancelled, rec.Code, result)
+		}
+	}
+
+	for id, want := range map[string]string{"a": "cancelled", "b": "cancelled", "c": "cancelled", "d": "completed"} {
+		var resp Response
+		if err := json.NewDecoder(do(http.MethodGet, "/jobs/"+id+"/status", "").Body).Decode(&resp); err != nil || resp.Result != want {
+			t.Errorf("job %s: expected %s, got %+v, %v", id, want, resp, err)
+		}
+	}
+	var list JobList
+	if err := json.NewDecoder(do(http.MethodGet, "/jobs?status=cancelled", "").Body).Decode(&list); err != nil || list.Total != 3 {
+		t.Errorf("expected 3 cancelled jobs, got %+v, %v", list, err)
+	}
+}
@@ -0,0 +1,115 @@
+package server
+
+import (
+	"log"
+	"net/http"
+	"time"
+)
+
+// Fault is a failure injected into /status requests with Server.InjectFault,
+// for deterministic tests of how clients cope with a misbehaving server.
+type Fault interface {
+	// Repetitions is the number of requests the fault applies to.
+	Repetitions() int
+	// Apply injects the fault into a request. It returns true if it has dealt
+	// with the request, or false to carry on with normal processing.
+	Apply(w http.ResponseWriter, r *http.Request) bool
+}
+
+// repetitions treats anything below one as a single request.
+func repetitions(times int) int {
+	if times < 1 {
+		return 1
+	}
+	return times
+}
+
+// HTTPErrorFault responds with Code and Body instead of the job status.
+type HTTPErrorFault struct {
+	Code  int
+	Body  string
+	Times int // Number of requests to fail, defaults to 1.
+}
+
+func (f HTTPErrorFault) Repetitions() int { return repetitions(f.Times) }
+
+func (f HTTPErrorFault) Apply(w http.ResponseWriter, r *http.Request) bool {
+	http.Error(w, f.Body, f.Code)
+	return true
+}
+
+// DelayFault holds the request for Extra before answering it normally.
+type DelayFault struct {
+	Extra time.Duration
+	Times int // Number of requests to delay, defaults to 1.
+}
+
+func (f DelayFault) Repetitions() int { return repetitions(f.Times) }
+
+func (f DelayFault) Apply(w http.ResponseWriter, r *http.Request) bool {
+	select {
+	case <-time.After(f.Extra):
+	case <-r.Context().Done():
+	}
+	return false
+}
+
+// DropConnectionFault closes the connection without sending a response.
+type DropConnectionFault struct {
+	Times int // Number of requests to drop, defaults to 1.
+}
+
+func (f DropConnectionFault) Repetitions() int { return repetitions(f.Times) }
+
+func (f DropConnectionFault) Apply(w http.ResponseWriter, r *http.Request) bool {
+	// ResponseController sees through middleware wrapping w, via Unwrap.
+	conn, _, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		// Not hijackable (e.g. HTTP/2), aborting the handler resets the stream instead.
+		panic(http.ErrAbortHandler)
+	}
+	conn.Close()
+	return true
+}
+
+// PanicFault panics in the handler, exercising net/http's panic recovery.
+type PanicFault struct {
+	Times int // Number of requests to panic on, defaults to 1.
+}
+
+func (f PanicFault) Repetitions() int { return repetitions(f.Times) }
+
+func (f PanicFault) Apply(w http.ResponseWriter, r *http.Request) bool {
+	panic("injected fault")
+}
+
+// injectedFault is a queued fault and the number of requests it has left.
+type injectedFault struct {
+	fault     Fault
+	remaining int
+}
+
+// InjectFault queues fault to be applied to the next fault.Repetitions()
+// /status requests, after any faults already queued.
+func (s *Server) InjectFault(fault Fault) {
+	s.faultMu.Lock()
+	defer s.faultMu.Unlock()
+	s.faults = append(s.faults, &injectedFault{fault: fault, remaining: fault.Repetitions()})
+	log.Printf("Injected fault %T for the next %d requests", fault, fault.Repetitions())
+}
+
+// nextFault takes one repetition of the first queued fault, or returns nil
+// if there are none.
+func (s *Server) nextFault() Fault {
+	s.faultMu.Lock()
+	defer s.faultMu.Unlock()
+	if len(s.faults) == 0 {
+		return nil
+	}
+	f := s.faults[0]
+	f.remaining--
+	if f.remaining <= 0 {
+		s.faults = s.faults[1:]
+	}
+	return f.fault
+}
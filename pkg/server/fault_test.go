@@ -0,0 +1,153 @@
+package server
+
+import (
+	"io"
+	"log"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newFaultServer serves s over HTTP, with the panics from PanicFault kept out of the test output.
+func newFaultServer(t *testing.T, s *Server) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewUnstartedServer(s.routes())
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0)
+	srv.Start()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// getStatus fetches /status, returning the response code and body.
+func getStatus(t *testing.T, srv *httptest.Server) (int, string, error) {
+	t.Helper()
+	resp, err := srv.Client().Get(srv.URL + "/status")
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(body), err
+}
+
+// expectNormal checks the fault queue is drained and requests are answered normally again.
+func expectNormal(t *testing.T, srv *httptest.Server) {
+	t.Helper()
+	code, body, err := getStatus(t, srv)
+	if err != nil || code != http.StatusOK || !strings.Contains(body, `"result"`) {
+		t.Fatalf("expected a normal response after the fault, got %d %q %v", code, body, err)
+	}
+}
+
+func TestHTTPErrorFault(t *testing.T) {
+	s, err := NewServer(WithDelay(time.Second), WithErrorRate(0))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	srv := newFaultServer(t, s)
+	s.InjectFault(HTTPErrorFault{Code: http.StatusInternalServerError, Body: "boom", Times: 2})
+
+	for i := 0; i < 2; i++ {
+		code, body, err := getStatus(t, srv)
+		if err != nil || code != http.StatusInternalServerError || strings.TrimSpace(body) != "boom" {
+			t.Fatalf("request %d: expected 500 boom, got %d %q %v", i+1, code, body, err)
+		}
+	}
+	expectNormal(t, srv)
+}
+
+func TestDelayFault(t *testing.T) {
+	s, err := NewServer(WithDelay(time.Second), WithErrorRate(0))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	srv := newFaultServer(t, s)
+	s.InjectFault(DelayFault{Extra: 200 * time.Millisecond})
+
+	start := time.Now()
+	code, body, err := getStatus(t, srv)
+	if err != nil || code != http.StatusOK || !strings.Contains(body, "pending") {
+		t.Fatalf("expected the delayed request to be answered normally, got %d %q %v", code, body, err)
+	}
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Fatalf("expected the request to be delayed by 200ms, took %v", elapsed)
+	}
+
+	start = time.Now()
+	expectNormal(t, srv)
+	if elapsed := time.Since(start); elapsed >= 200*time.Millisecond {
+		t.Fatalf("expected only one request to be delayed, the next took %v", elapsed)
+	}
+}
+
+func TestDropConnectionFault(t *testing.T) {
+	s, err := NewServer(WithDelay(time.Second), WithErrorRate(0))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	srv := newFaultServer(t, s)
+	s.InjectFault(DropConnectionFault{})
+
+	if _, _, err := getStatus(t, srv); err == nil {
+		t.Fatal("expected the connection to be dropped")
+	}
+	expectNormal(t, srv)
+}
+
+func TestDropConnectionFaultBehindMiddleware(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	var panicked atomic.Bool
+	h := SlowReadMiddleware(1)(BodyLoggingMiddleware(10, logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if p := recover(); p != nil {
+				panicked.Store(true)
+				panic(p)
+			}
+		}()
+		DropConnectionFault{}.Apply(w, r)
+	})))
+	srv := httptest.NewUnstartedServer(h)
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0)
+	srv.Start()
+	defer srv.Close()
+
+	if _, _, err := getStatus(t, srv); err == nil {
+		t.Fatal("expected the connection to be dropped")
+	}
+	if panicked.Load() {
+		t.Fatal("expected the connection to be hijacked through the wrapped writers, not the handler aborted")
+	}
+}
+
+func TestPanicFault(t *testing.T) {
+	s, err := NewServer(WithDelay(time.Second), WithErrorRate(0))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	srv := newFaultServer(t, s)
+	s.InjectFault(PanicFault{})
+
+	if _, _, err := getStatus(t, srv); err == nil {
+		t.Fatal("expected the request to fail when the handler panics")
+	}
+	expectNormal(t, srv)
+}
+
+func TestFaultsApplyInOrder(t *testing.T) {
+	s, err := NewServer(WithDelay(time.Second), WithErrorRate(0))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	s.InjectFault(HTTPErrorFault{Code: http.StatusBadGateway})
+	s.InjectFault(HTTPErrorFault{Code: http.StatusGatewayTimeout})
+
+	for _, want := range []int{http.StatusBadGateway, http.StatusGatewayTimeout, http.StatusOK} {
+		if rec := poll(t, s); rec.Code != want {
+			t.Fatalf("expected %d, got %d", want, rec.Code)
+		}
+	}
+}
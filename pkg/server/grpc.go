@@ -0,0 +1,141 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"log"
+	"net"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	pb "Video-Translation-Simulator/pkg/grpc"
+)
+
+/*
+	gRPC front end to the same jobs as the HTTP endpoints. GetStatus polls a
+	job like GET /status, WatchStatus streams it like GET /status/stream.
+*/
+
+// grpcService implements pb.TranslationServiceServer on top of a Server.
+type grpcService struct {
+	pb.UnimplementedTranslationServiceServer
+	s *Server
+}
+
+// RegisterGRPC registers the translation service on r, serving the server's
+// jobs. Use it to add the service to a grpc.Server of your own; StartGRPC
+// runs one for you.
+func (s *Server) RegisterGRPC(r grpc.ServiceRegistrar) {
+	pb.RegisterTranslationServiceServer(r, &grpcService{s: s})
+}
+
+// StartGRPC serves the translation service over gRPC on address until
+// Shutdown is called. When WithAuthExclude requires the API key on every
+// endpoint, gRPC calls must send it in x-api-key metadata.
+func (s *Server) StartGRPC(address string) error {
+	lis, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	log.Printf("gRPC server is listening on %s", address)
+	return s.serveGRPC(lis)
+}
+
+// serveGRPC serves the translation service on lis until Shutdown is called.
+func (s *Server) serveGRPC(lis net.Listener) error {
+	var opts []grpc.ServerOption
+	if s.apiKey != "" && s.authExclude != nil {
+		opts = append(opts,
+			grpc.UnaryInterceptor(func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+				if err := s.checkGRPCKey(ctx); err != nil {
+					return nil, err
+				}
+				return handler(ctx, req)
+			}),
+			grpc.StreamInterceptor(func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+				if err := s.checkGRPCKey(ss.Context()); err != nil {
+					return err
+				}
+				return handler(srv, ss)
+			}),
+		)
+	}
+	gs := grpc.NewServer(opts...)
+	s.RegisterGRPC(gs)
+
+	// Checked under srvMu, so either Shutdown sees grpcSrv or we see stop closed.
+	s.srvMu.Lock()
+	select {
+	case <-s.stop:
+		s.srvMu.Unlock()
+		lis.Close()
+		return nil
+	default:
+	}
+	s.grpcSrv = gs
+	s.srvMu.Unlock()
+	if err := gs.Serve(lis); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+		return err
+	}
+	return nil
+}
+
+// checkGRPCKey rejects calls without the API key in their metadata.
+func (s *Server) checkGRPCKey(ctx context.Context) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, given := range md.Get(strings.ToLower(APIKeyHeader)) {
+		if subtle.ConstantTimeCompare([]byte(given), []byte(s.apiKey)) == 1 {
+			return nil
+		}
+	}
+	log.Println("Rejecting gRPC call with a missing or invalid API key")
+	return status.Error(codes.Unauthenticated, "invalid API key")
+}
+
+// GetStatus advances the job and reports its status.
+func (g *grpcService) GetStatus(ctx context.Context, req *pb.StatusRequest) (*pb.StatusResponse, error) {
+	g.s.counters.totalRequests.Add(1)
+	job, err := g.s.jobFor(req.GetJobId())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	g.s.mu.Lock()
+	defer g.s.mu.Unlock()
+	g.s.advanceJob(job)
+	g.s.counters.recordResponse(job.status)
+	return &pb.StatusResponse{Result: job.status, Reason: job.reason}, nil
+}
+
+// WatchStatus streams the job's status until it reaches a final status.
+func (g *grpcService) WatchStatus(req *pb.StatusRequest, stream pb.TranslationService_WatchStatusServer) error {
+	g.s.counters.totalRequests.Add(1)
+	job, err := g.s.jobFor(req.GetJobId())
+	if err != nil {
+		return grpcError(err)
+	}
+	err = g.s.watchJob(stream.Context(), job, func(response Response) error {
+		return stream.Send(&pb.StatusResponse{Result: response.Result, Reason: response.Reason})
+	})
+	if ctxErr := stream.Context().Err(); ctxErr != nil {
+		return status.FromContextError(ctxErr).Err()
+	}
+	return err
+}
+
+// grpcError maps errors from jobFor to gRPC status codes.
+func grpcError(err error) error {
+	switch {
+	case errors.Is(err, errPaused):
+		return status.Error(codes.Unavailable, err.Error())
+	case errors.Is(err, errJobNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
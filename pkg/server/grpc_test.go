@@ -0,0 +1,93 @@
+package server
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	pb "Video-Translation-Simulator/pkg/grpc"
+)
+
+func TestGRPCService(t *testing.T) {
+	s, err := NewServer(WithDelay(time.Second), WithErrorRate(0), WithAPIKey("secret"), WithAuthExclude(ExcludePaths()))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	s.streamInterval = 100 * time.Millisecond
+	lis := listenLocal(t)
+	served := make(chan error, 1)
+	go func() { served <- s.serveGRPC(lis) }()
+
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	defer conn.Close()
+	client := pb.NewTranslationServiceClient(conn)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := client.GetStatus(ctx, &pb.StatusRequest{}); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("expected Unauthenticated without the key, got %v", err)
+	}
+	ctx = metadata.AppendToOutgoingContext(ctx, "x-api-key", "secret")
+	if _, err := client.GetStatus(ctx, &pb.StatusRequest{JobId: "nope"}); status.Code(err) != codes.NotFound {
+		t.Fatalf("expected NotFound for an unknown job, got %v", err)
+	}
+	if resp, err := client.GetStatus(ctx, &pb.StatusRequest{}); err != nil || resp.GetResult() != "pending" {
+		t.Fatalf("expected pending, got %v, %v", resp, err)
+	}
+
+	stream, err := client.WatchStatus(ctx, &pb.StatusRequest{})
+	if err != nil {
+		t.Fatalf("WatchStatus: %v", err)
+	}
+	var results []string
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Recv: %v", err)
+		}
+		results = append(results, resp.GetResult())
+	}
+	if len(results) < 3 || results[len(results)-1] != "completed" {
+		t.Fatalf("expected pending messages and then completed, got %v", results)
+	}
+
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	if err := <-served; err != nil {
+		t.Fatalf("expected the gRPC server to stop cleanly, got %v", err)
+	}
+}
+
+func TestServeGRPCAfterShutdown(t *testing.T) {
+	s, err := NewServer(WithDelay(time.Second), WithErrorRate(0))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	served := make(chan error, 1)
+	go func() { served <- s.serveGRPC(listenLocal(t)) }()
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Fatalf("expected nil once shut down, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected serveGRPC to return after Shutdown instead of serving")
+	}
+}
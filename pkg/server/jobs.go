@@ -0,0 +1,990 @@
+package server
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"Video-Translation-Simulator/pkg/types"
+)
+
+/*
+	Jobs created explicitly with POST /jobs, each polled on its own
+	/jobs/{id}/status. Unlike the implicit job behind /status, polling a
+	finished job keeps reporting its final status rather than starting a new
+	one, so any number of jobs can run side by side.
+*/
+
+// maxJobIDLength bounds client supplied job IDs, which are kept in memory.
+const maxJobIDLength = 64
+
+// maxJobChainLength bounds the jobs chained with NextJob in one POST /jobs,
+// the first included.
+const maxJobChainLength = 32
+
+// JobCorrelationHeaderPrefix starts the headers sent to POST /jobs which are
+// kept with the job and echoed back on each of its status responses, e.g.
+// X-Job-Correlation-Id.
+const JobCorrelationHeaderPrefix = "X-Job-Correlation-"
+
+// Limits on the correlation headers kept per job, which are held in memory.
+const (
+	maxCorrelationHeaders     = 16
+	maxCorrelationHeaderBytes = 256
+)
+
+// Limits on the Metadata, and likewise the Labels, kept per job, which are
+// held in memory.
+const (
+	maxMetadataEntries = 32
+	maxMetadataBytes   = 256
+)
+
+// DefaultMaxJobRetries is how many times a job may be retried with POST
+// /jobs/{id}/retry when its JobRequest doesn't say.
+const DefaultMaxJobRetries = 3
+
+// Defaults used by NewServer unless WithMaxJobs or WithJobTTL are given.
+const (
+	DefaultMaxJobs = 1000
+	DefaultJobTTL  = 10 * time.Minute
+)
+
+// WithMaxJobs caps the number of jobs created with POST /jobs that are kept
+// at once. Further jobs are rejected with 503 until finished ones expire. A
+// non-positive n is logged and the default of 1000 is kept.
+func WithMaxJobs(n int) ServerOption {
+	return func(s *Server) {
+		if n <= 0 {
+			log.Printf("Invalid maximum of %d jobs. Using default of %d.", n, DefaultMaxJobs)
+			return
+		}
+		s.maxJobs = n
+	}
+}
+
+// WithJobTTL sets how long a job created with POST /jobs keeps reporting its
+// final status before it is forgotten. A non-positive ttl is logged and the
+// default of 10 minutes is kept.
+func WithJobTTL(ttl time.Duration) ServerOption {
+	return func(s *Server) {
+		if ttl <= 0 {
+			log.Printf("Invalid job TTL %v. Using default of %v.", ttl, DefaultJobTTL)
+			return
+		}
+		s.jobTTL = ttl
+	}
+}
+
+// JobRequest is the body of POST /jobs.
+type JobRequest struct {
+	ID            string `json:"id"`              // Generated by the server when left empty.
+	MaxJobRetries int    `json:"max_job_retries"` // Retries allowed after an error, DefaultMaxJobRetries when zero.
+	// TimeoutSeconds fails the job with reason "job_timeout" if it is still
+	// pending this long after it started. Zero means no timeout.
+	TimeoutSeconds int `json:"timeout_seconds"`
+	// ExternalID is the caller's own ID for the job, e.g. from an upstream
+	// system. Creating a job with the ExternalID of one that exists answers
+	// with that job instead, and GET /jobs?external_id= finds it.
+	ExternalID string `json:"external_id"`
+	// CallbackURL, an http or https URL, is sent a CallbackPayload with POST
+	// once the job finishes. A failed call is retried with exponential backoff,
+	// up to Config.MaxWebhookRetries times.
+	CallbackURL string `json:"callback_url"`
+	// ScheduledAt, if in the future, keeps the job "scheduled" until then,
+	// after which it is pending and its delay starts.
+	ScheduledAt *time.Time `json:"scheduled_at"`
+	// Metadata describes the job, e.g. {"source_lang": "en"}. GET /jobs
+	// filters on it with tag=source_lang:en.
+	Metadata map[string]string `json:"metadata"`
+	// Labels group the job, e.g. {"env": "prod", "team": "nlp"}. GET /jobs
+	// picks jobs by them with selector=env=prod,team=nlp. Keys can't contain
+	// '=' or ',', nor values ','.
+	Labels map[string]string `json:"labels"`
+	// Priority is the caller's own ranking of the job, reported by GET /jobs.
+	// The server doesn't order jobs by it.
+	Priority int `json:"priority"`
+	// NextJob is created once this job completes, and its ID is given as
+	// next_job_id by this job's status. Jobs ending in error don't start
+	// theirs, unless retried until they complete.
+	NextJob *JobSpec `json:"next_job"`
+}
+
+// JobResponse is the body of the 202 Accepted answering POST /jobs.
+type JobResponse struct {
+	JobID                 string `json:"job_id"`
+	PollURL               string `json:"poll_url"` // Also sent as the Location header.
+	EstimatedDelaySeconds int    `json:"estimated_delay_seconds"`
+	FileSizeBytes         int64  `json:"file_size_bytes,omitempty"` // Of the file uploaded with the job, if any.
+}
+
+// createJobHandler starts a job under the ID given in the body, keeping its
+// X-Job-Correlation-* headers and, with a multipart/form-data body, the file
+// uploaded (see readJobUpload), responding with 202, a Location header to poll
+// and a JobResponse, 409 if a job with that ID already exists, or 503 if there
+// are already WithMaxJobs jobs. If a job with the ExternalID given already
+// exists it responds with 200 and that job instead. GET /jobs is handed to
+// findJobHandler with an external_id parameter, and to listJobsHandler
+// without. DELETE /jobs is handed to cancelJobsHandler.
+func (s *Server) createJobHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet {
+		if r.URL.Query().Has("external_id") {
+			s.findJobHandler(w, r)
+		} else {
+			s.listJobsHandler(w, r)
+		}
+		return
+	}
+	if r.Method == http.MethodDelete {
+		s.cancelJobsHandler(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "DELETE, GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req JobRequest
+	var upload *jobUpload
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "multipart/form-data" {
+		var err error
+		if upload, err = readJobUpload(r, &req); errors.Is(err, errUploadTooLarge) {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		} else if err != nil {
+			http.Error(w, "invalid multipart body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		// Unless kept with the job once it is created.
+		defer func() {
+			if upload != nil {
+				os.Remove(upload.path)
+			}
+		}()
+	} else if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid JSON body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if err := req.validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	correlation, err := correlationHeaders(r.Header)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	if s.paused {
+		s.mu.Unlock()
+		log.Println("Server is paused. Rejecting new job.")
+		http.Error(w, "server is paused", http.StatusServiceUnavailable)
+		return
+	}
+	s.evictJobs()
+	if id, ok := s.externalIndex[req.ExternalID]; ok && req.ExternalID != "" {
+		s.mu.Unlock()
+		log.Printf("Job %s already exists for external ID %s.", id, req.ExternalID)
+		s.writeJob(w, r, id, http.StatusOK)
+		return
+	}
+	job, err := s.addJob(req, correlation)
+	if err == nil && upload != nil {
+		job.filePath, job.fileSize = upload.path, upload.size
+		upload = nil
+	}
+	s.mu.Unlock()
+	switch {
+	case errors.Is(err, errJobExists):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case errors.Is(err, errTooManyJobs):
+		log.Printf("Rejecting new job: %v.", err)
+		w.Header().Set("Retry-After", "1")
+		http.Error(w, "too many jobs", http.StatusServiceUnavailable)
+		return
+	}
+	s.startJob(job)
+
+	s.writeJob(w, r, job.id, http.StatusAccepted)
+}
+
+// Errors from addJob.
+var (
+	errJobExists   = errors.New("job already exists")
+	errTooManyJobs = errors.New("too many jobs")
+)
+
+// addJob creates the job described by req, which has been validated, keeping
+// its correlation headers. It returns errJobExists if a job with its ID
+// exists, or errTooManyJobs if there are already WithMaxJobs jobs. The caller
+// must hold s.mu, and call startJob once it no longer needs to.
+func (s *Server) addJob(req JobRequest, correlation http.Header) (*jobState, error) {
+	if req.ID == "" {
+		req.ID = newJobID()
+	}
+	if req.MaxJobRetries == 0 {
+		req.MaxJobRetries = DefaultMaxJobRetries
+	}
+	if _, exists := s.jobs[req.ID]; exists {
+		return nil, fmt.Errorf("%w: %s", errJobExists, req.ID)
+	}
+	if len(s.jobs) >= s.maxJobs {
+		return nil, fmt.Errorf("%w, %d already kept", errTooManyJobs, len(s.jobs))
+	}
+	now := time.Now()
+	job := &jobState{id: req.ID, createdAt: now, startTime: now, status: "pending", maxRetries: req.MaxJobRetries, correlationHeaders: correlation,
+		timeout: time.Duration(req.TimeoutSeconds) * time.Second, externalID: req.ExternalID,
+		callbackURL: req.CallbackURL, metadata: req.Metadata, labels: req.Labels, priority: req.Priority, next: req.NextJob,
+		workerID: s.workers.WorkerFor(req.ID)}
+	if req.ScheduledAt != nil && req.ScheduledAt.After(job.startTime) {
+		job.startTime, job.status = *req.ScheduledAt, "scheduled"
+	}
+	s.jobs[req.ID] = job
+	if req.ExternalID != "" {
+		s.externalIndex[req.ExternalID] = req.ID
+	}
+	return job, nil
+}
+
+// startJob logs the creation of job by addJob, hands it to its worker and
+// starts the other background goroutines it needs. It may be called with
+// s.mu held.
+func (s *Server) startJob(job *jobState) {
+	s.dispatchJob(job)
+	if job.status == "scheduled" {
+		log.Printf("Job %s created, scheduled for %v.", job.id, job.startTime)
+		s.scheduleJobs()
+	} else {
+		log.Printf("Job %s created.", job.id)
+	}
+	if job.timeout > 0 {
+		s.sweepTimeouts()
+	}
+	if job.callbackURL != "" {
+		s.dispatchCallbacks()
+	}
+}
+
+// startNextJob creates the job chained after job with NextJob, now that job
+// has completed, and records its ID in job. A chained job which can't be
+// created is logged and dropped, along with the rest of the chain. The caller
+// must hold s.mu.
+func (s *Server) startNextJob(job *jobState) {
+	next, err := s.addJob(job.next.request(), job.correlationHeaders)
+	if err != nil {
+		log.Printf("Not starting the job chained after %s: %v", job.id, err)
+		return
+	}
+	job.nextJobID = next.id
+	log.Printf("Job %s completed, starting job %s chained after it.", job.id, next.id)
+	s.startJob(next)
+}
+
+// validate checks req and the jobs chained after it, returning an error to
+// respond with 400.
+func (req *JobRequest) validate() error {
+	if len(req.ID) > maxJobIDLength || strings.Contains(req.ID, "/") {
+		return errors.New("invalid job ID, must be at most 64 characters without a '/'")
+	}
+	if req.MaxJobRetries < 0 {
+		return errors.New("invalid max_job_retries, must not be negative")
+	}
+	if len(req.ExternalID) > maxJobIDLength {
+		return errors.New("invalid external_id, must be at most 64 characters")
+	}
+	if err := validateCallbackURL(req.CallbackURL); err != nil {
+		return err
+	}
+	if req.TimeoutSeconds < 0 {
+		return errors.New("invalid timeout_seconds, must not be negative")
+	}
+	if err := validateMetadata(req.Metadata); err != nil {
+		return err
+	}
+	if err := validateLabels(req.Labels); err != nil {
+		return err
+	}
+	length, ids := 1, map[string]bool{req.ID: true}
+	for next := req.NextJob; next != nil; next = next.NextJob {
+		if length++; length > maxJobChainLength {
+			return fmt.Errorf("too many chained jobs, at most %d are kept", maxJobChainLength)
+		}
+		// Its own NextJob is checked by the loop.
+		spec := next.request()
+		spec.NextJob = nil
+		if err := spec.validate(); err != nil {
+			return fmt.Errorf("invalid next_job: %w", err)
+		}
+		if spec.ID != "" && ids[spec.ID] {
+			return fmt.Errorf("invalid next_job: job ID %s is already in the chain", spec.ID)
+		}
+		ids[spec.ID] = true
+	}
+	return nil
+}
+
+// JobSpec describes a job chained after another with NextJob, created when
+// that job completes. The job ID is generated by the server when left empty.
+type JobSpec struct {
+	ID             string            `json:"id"`
+	MaxJobRetries  int               `json:"max_job_retries"`
+	TimeoutSeconds int               `json:"timeout_seconds"`
+	CallbackURL    string            `json:"callback_url"`
+	Metadata       map[string]string `json:"metadata"`
+	Labels         map[string]string `json:"labels"`
+	Priority       int               `json:"priority"`
+	NextJob        *JobSpec          `json:"next_job"`
+}
+
+// request returns the JobRequest creating the job spec describes.
+func (spec *JobSpec) request() JobRequest {
+	return JobRequest{ID: spec.ID, MaxJobRetries: spec.MaxJobRetries, TimeoutSeconds: spec.TimeoutSeconds,
+		CallbackURL: spec.CallbackURL, Metadata: spec.Metadata, Labels: spec.Labels, Priority: spec.Priority, NextJob: spec.NextJob}
+}
+
+// findJobHandler serves GET /jobs?external_id=, responding with the
+// JobResponse and Location of the job created with that ExternalID, 400 if it
+// is empty, or 404 if there is no such job.
+func (s *Server) findJobHandler(w http.ResponseWriter, r *http.Request) {
+	externalID := r.URL.Query().Get("external_id")
+	if externalID == "" {
+		http.Error(w, "missing external_id", http.StatusBadRequest)
+		return
+	}
+	s.mu.Lock()
+	s.evictJobs()
+	id, ok := s.externalIndex[externalID]
+	s.mu.Unlock()
+	if !ok {
+		http.Error(w, "no job with external ID "+externalID, http.StatusNotFound)
+		return
+	}
+	s.writeJob(w, r, id, http.StatusOK)
+}
+
+// writeJob responds with code, a Location header to poll job id and a
+// JobResponse.
+func (s *Server) writeJob(w http.ResponseWriter, r *http.Request, id string, code int) {
+	resp := JobResponse{
+		JobID:                 id,
+		PollURL:               versionPrefix(r.Context()) + "/jobs/" + url.PathEscape(id) + "/status",
+		EstimatedDelaySeconds: int(s.effectiveDelay().Seconds()),
+	}
+	s.mu.Lock()
+	if job := s.jobs[id]; job != nil {
+		resp.FileSizeBytes = job.fileSize
+	}
+	s.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", resp.PollURL)
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		log.Printf("Error encoding job response: %v", err)
+	}
+}
+
+// JobSummary describes a job in the JobList served by GET /jobs.
+type JobSummary struct {
+	JobID         string            `json:"job_id"`
+	Status        string            `json:"status"`
+	Metadata      map[string]string `json:"metadata,omitempty"`
+	Labels        map[string]string `json:"labels,omitempty"`
+	Priority      int               `json:"priority"`
+	WorkerID      int               `json:"worker_id"` // Of the worker owning the job, for debugging.
+	FileSizeBytes int64             `json:"file_size_bytes,omitempty"`
+	CreatedAt     time.Time         `json:"created_at"`
+	NextJobID     string            `json:"next_job_id,omitempty"` // Set once a job with a NextJob completes.
+}
+
+// JobList is the body answering GET /jobs.
+type JobList struct {
+	Jobs  []JobSummary `json:"jobs"`
+	Total int          `json:"total"` // Jobs matching the filter, before limit and offset.
+}
+
+// listJobsHandler serves GET /jobs, responding with a JobList of the jobs
+// matching the filter given by parseJobFilter, oldest first. The limit and
+// offset parameters page through them.
+func (s *Server) listJobsHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter, err := parseJobFilter(query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	limit, err := queryInt(query, "limit")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	offset, err := queryInt(query, "offset")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	s.evictJobs()
+	matched := s.matchJobs(filter)
+	list := JobList{Jobs: []JobSummary{}, Total: len(matched)}
+	if offset < len(matched) {
+		matched = matched[offset:]
+		if limit > 0 && limit < len(matched) {
+			matched = matched[:limit]
+		}
+		for _, job := range matched {
+			list.Jobs = append(list.Jobs, job.summary())
+		}
+	}
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(list); err != nil {
+		log.Printf("Error encoding job list: %v", err)
+	}
+}
+
+// matchJobs returns the jobs matching filter, oldest first. The caller must
+// hold s.mu, and should have advanced the jobs.
+func (s *Server) matchJobs(filter jobFilter) []*jobState {
+	var matched []*jobState
+	for _, job := range s.jobs {
+		if filter.matches(job) {
+			matched = append(matched, job)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		if !matched[i].createdAt.Equal(matched[j].createdAt) {
+			return matched[i].createdAt.Before(matched[j].createdAt)
+		}
+		return matched[i].id < matched[j].id
+	})
+	return matched
+}
+
+// summary describes the job for GET /jobs and PATCH /jobs/{id}. The caller
+// must hold s.mu.
+func (j *jobState) summary() JobSummary {
+	return JobSummary{JobID: j.id, Status: j.status, Metadata: maps.Clone(j.metadata), Labels: maps.Clone(j.labels), Priority: j.priority, WorkerID: j.workerID, FileSizeBytes: j.fileSize, CreatedAt: j.createdAt, NextJobID: j.nextJobID}
+}
+
+// ListBySelector returns the jobs created with POST /jobs whose labels match
+// sel, oldest first.
+func (s *Server) ListBySelector(sel LabelSelector) []JobSummary {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.evictJobs()
+	var summaries []JobSummary
+	for _, job := range s.matchJobs(jobFilter{selector: sel}) {
+		summaries = append(summaries, job.summary())
+	}
+	return summaries
+}
+
+// jobFilter picks out jobs by the query parameters of GET /jobs.
+type jobFilter struct {
+	statuses []string // Any may match.
+	tags     []string // All must match, see hasTag.
+	selector LabelSelector
+}
+
+// parseJobFilter reads the filter from any number of status, tag, label and
+// selector parameters. A job matches any of the statuses given. A tag
+// key:value matches jobs whose Metadata sets key to value, and a bare key
+// those which set key at all. A selector, see ParseLabelSelector, matches jobs
+// by their Labels, and a label key:value is the selector key=value.
+func parseJobFilter(query url.Values) (jobFilter, error) {
+	var f jobFilter
+	for _, status := range query["status"] {
+		switch types.Status(status) {
+		case types.StatusScheduled, types.StatusPending, types.StatusCompleted, types.StatusError, types.StatusCancelled:
+			f.statuses = append(f.statuses, status)
+		default:
+			return jobFilter{}, fmt.Errorf("invalid status %q", status)
+		}
+	}
+	for _, tag := range query["tag"] {
+		if key, _, _ := strings.Cut(tag, ":"); key == "" {
+			return jobFilter{}, fmt.Errorf("invalid tag %q, expected key or key:value", tag)
+		}
+		f.tags = append(f.tags, tag)
+	}
+	// Several selectors must all match, as if written as one.
+	var terms []string
+	for _, raw := range query["selector"] {
+		if raw != "" {
+			terms = append(terms, raw)
+		}
+	}
+	for _, label := range query["label"] {
+		key, value, ok := strings.Cut(label, ":")
+		if !ok || key == "" {
+			return jobFilter{}, fmt.Errorf("invalid label %q, expected key:value", label)
+		}
+		terms = append(terms, key+"="+value)
+	}
+	var err error
+	if f.selector, err = ParseLabelSelector(strings.Join(terms, ",")); err != nil {
+		return jobFilter{}, err
+	}
+	return f, nil
+}
+
+// matches reports whether job passes every part of the filter. The caller
+// must hold s.mu.
+func (f jobFilter) matches(job *jobState) bool {
+	if len(f.statuses) > 0 && !slices.Contains(f.statuses, job.status) {
+		return false
+	}
+	for _, tag := range f.tags {
+		if !hasTag(job.metadata, tag) {
+			return false
+		}
+	}
+	return f.selector.Matches(job.labels)
+}
+
+// empty reports whether the filter matches every job.
+func (f jobFilter) empty() bool {
+	return len(f.statuses) == 0 && len(f.tags) == 0 && len(f.selector) == 0
+}
+
+// LabelSelector picks jobs by their Labels: a job matches if it has every
+// label of the selector, with the same value. The empty selector matches
+// every job.
+type LabelSelector map[string]string
+
+// ParseLabelSelector parses a selector written key=value,key=value, as given
+// to GET /jobs?selector=. The empty string is the empty selector.
+func ParseLabelSelector(raw string) (LabelSelector, error) {
+	sel := LabelSelector{}
+	if raw == "" {
+		return sel, nil
+	}
+	for _, term := range strings.Split(raw, ",") {
+		key, value, ok := strings.Cut(term, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid selector %q, expected key=value,key=value", raw)
+		}
+		if other, exists := sel[key]; exists && other != value {
+			return nil, fmt.Errorf("invalid selector, %s can't be both %s and %s", key, other, value)
+		}
+		sel[key] = value
+	}
+	return sel, nil
+}
+
+// Matches reports whether labels has every label of the selector.
+func (sel LabelSelector) Matches(labels map[string]string) bool {
+	for key, value := range sel {
+		if got, ok := labels[key]; !ok || got != value {
+			return false
+		}
+	}
+	return true
+}
+
+// hasTag reports whether metadata has tag, key:value or a bare key.
+func hasTag(metadata map[string]string, tag string) bool {
+	key, value, withValue := strings.Cut(tag, ":")
+	got, ok := metadata[key]
+	return ok && (!withValue || got == value)
+}
+
+// queryInt returns the non-negative integer parameter name, or 0 if it is
+// absent.
+func queryInt(query url.Values, name string) (int, error) {
+	raw := query.Get(name)
+	if raw == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid %s %q, expected a non-negative integer", name, raw)
+	}
+	return n, nil
+}
+
+// validateMetadata checks metadata is within the limits of what is kept with
+// a job.
+func validateMetadata(metadata map[string]string) error {
+	if len(metadata) > maxMetadataEntries {
+		return fmt.Errorf("too many metadata entries, at most %d are kept", maxMetadataEntries)
+	}
+	for key, value := range metadata {
+		if key == "" || len(key) > maxMetadataBytes || len(value) > maxMetadataBytes {
+			return fmt.Errorf("invalid metadata %q, keys must be non-empty and keys and values at most %d bytes", key, maxMetadataBytes)
+		}
+	}
+	return nil
+}
+
+// validateLabels checks labels are within the limits of what is kept with a
+// job, and can be written in a LabelSelector.
+func validateLabels(labels map[string]string) error {
+	if len(labels) > maxMetadataEntries {
+		return fmt.Errorf("too many labels, at most %d are kept", maxMetadataEntries)
+	}
+	for key, value := range labels {
+		if key == "" || len(key) > maxMetadataBytes || len(value) > maxMetadataBytes || strings.ContainsAny(key, "=,") || strings.Contains(value, ",") {
+			return fmt.Errorf("invalid label %q, keys must be non-empty without '=' or ',', values without ',', and both at most %d bytes", key, maxMetadataBytes)
+		}
+	}
+	return nil
+}
+
+// correlationHeaders returns the X-Job-Correlation-* headers in h, or nil if
+// there are none, and an error if there are more than 16 of them or a value
+// is over 256 bytes.
+func correlationHeaders(h http.Header) (http.Header, error) {
+	var kept http.Header
+	for name, values := range h {
+		if !strings.HasPrefix(name, JobCorrelationHeaderPrefix) {
+			continue
+		}
+		if len(kept) == maxCorrelationHeaders {
+			return nil, fmt.Errorf("too many %s* headers, at most %d are kept", JobCorrelationHeaderPrefix, maxCorrelationHeaders)
+		}
+		for _, v := range values {
+			if len(v) > maxCorrelationHeaderBytes {
+				return nil, fmt.Errorf("header %s is too long, at most %d bytes are kept", name, maxCorrelationHeaderBytes)
+			}
+		}
+		if kept == nil {
+			kept = http.Header{}
+		}
+		kept[name] = append([]string(nil), values...)
+	}
+	return kept, nil
+}
+
+// jobStatusHandler serves GET /jobs/{id}/status for jobs created with
+// createJobHandler, GET /jobs/{id}/status/stream to stream it,
+// POST /jobs/{id}/retry to retry it, PATCH /jobs/{id} to update it and
+// /jobs/{id}/progress for its progress.
+func (s *Server) jobStatusHandler(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/jobs/")
+	if id, ok := strings.CutSuffix(path, "/retry"); ok && id != "" && !strings.Contains(id, "/") {
+		s.retryJob(w, r, id)
+		return
+	}
+	if id, ok := strings.CutSuffix(path, "/progress"); ok && id != "" && !strings.Contains(id, "/") {
+		s.jobProgress(w, r, id)
+		return
+	}
+	if path != "" && !strings.Contains(path, "/") {
+		s.patchJob(w, r, path)
+		return
+	}
+	id, stream := strings.CutSuffix(path, "/status/stream")
+	if !stream {
+		var ok bool
+		if id, ok = strings.CutSuffix(path, "/status"); !ok {
+			id = ""
+		}
+	}
+	if id == "" || strings.Contains(id, "/") {
+		http.NotFound(w, r)
+		return
+	}
+
+	lookup := func() *jobState {
+		job := s.jobs[id]
+		if job == nil {
+			http.Error(w, "job "+id+" not found", http.StatusNotFound)
+		}
+		return job
+	}
+	if stream {
+		s.streamJobStatus(w, r, lookup)
+		return
+	}
+	s.serveJobStatus(w, r, lookup)
+}
+
+// RetryResponse is the body answering POST /jobs/{id}/retry.
+type RetryResponse struct {
+	JobID      string `json:"job_id"`
+	RetryCount int    `json:"retry_count"`
+	Status     string `json:"status"`
+}
+
+// retryJob starts the job with id again after it ended in "error", as if it
+// had just been created, responding with a RetryResponse. It responds with 409
+// if the job hasn't failed or has used up its MaxJobRetries.
+func (s *Server) retryJob(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	s.mu.Lock()
+	job := s.jobs[id]
+	if job == nil {
+		s.mu.Unlock()
+		http.Error(w, "job "+id+" not found", http.StatusNotFound)
+		return
+	}
+	s.advanceJob(job)
+	if job.status != "error" {
+		s.mu.Unlock()
+		http.Error(w, "job "+id+" is "+job.status+", only failed jobs can be retried", http.StatusConflict)
+		return
+	}
+	if job.retryCount >= job.maxRetries {
+		s.mu.Unlock()
+		http.Error(w, fmt.Sprintf("job %s has used all of its %d retries", id, job.maxRetries), http.StatusConflict)
+		return
+	}
+	job.retryCount++
+	job.startTime, job.status, job.reason, job.finishedAt = time.Now(), "pending", "", time.Time{}
+	job.progressUpdates = nil // Reported afresh by the new run.
+	s.notifyWatchers(job, "error")
+	resp := RetryResponse{JobID: id, RetryCount: job.retryCount, Status: job.status}
+	s.mu.Unlock()
+	log.Printf("Job %s retried, %d of %d.", id, resp.RetryCount, job.maxRetries)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		log.Printf("Error encoding retry response: %v", err)
+	}
+}
+
+// patchJob serves PATCH /jobs/{id}, updating the metadata and priority of the
+// job with id by a JSON merge patch (RFC 7396), e.g. {"metadata": {"team":
+// null}} to remove the team entry, and responding with its JobSummary. Its
+// other fields can't be changed. It responds with 404 if there is no such job,
+// or 409 once it has finished.
+func (s *Server) patchJob(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method != http.MethodPatch {
+		w.Header().Set("Allow", http.MethodPatch)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var patch map[string]json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil || patch == nil {
+		http.Error(w, "invalid merge patch, expected a JSON object", http.StatusBadRequest)
+		return
+	}
+	// A nil value in metadataPatch removes the key, and a null metadata all of them.
+	var metadataPatch map[string]*string
+	patchMetadata, clearMetadata := false, false
+	var priority *int
+	for field, raw := range patch {
+		null := string(bytes.TrimSpace(raw)) == "null"
+		var err error
+		switch field {
+		case "metadata":
+			patchMetadata, clearMetadata = true, null
+			if !null {
+				err = json.Unmarshal(raw, &metadataPatch)
+			}
+		case "priority":
+			priority = new(int)
+			if !null {
+				err = json.Unmarshal(raw, priority)
+			}
+		default:
+			http.Error(w, "field "+field+" can't be changed, only metadata and priority", http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(w, "invalid "+field+": "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	s.mu.Lock()
+	job := s.jobs[id]
+	if job == nil {
+		s.mu.Unlock()
+		http.Error(w, "job "+id+" not found", http.StatusNotFound)
+		return
+	}
+	s.advanceJob(job)
+	if finished(job.status) {
+		s.mu.Unlock()
+		http.Error(w, "job "+id+" is "+job.status+", finished jobs can't be changed", http.StatusConflict)
+		return
+	}
+	metadata := job.metadata
+	if patchMetadata {
+		if clearMetadata {
+			metadata = nil
+		} else {
+			metadata = maps.Clone(metadata)
+			if metadata == nil {
+				metadata = map[string]string{}
+			}
+		}
+		for key, value := range metadataPatch {
+			if value == nil {
+				delete(metadata, key)
+			} else {
+				metadata[key] = *value
+			}
+		}
+		if err := validateMetadata(metadata); err != nil {
+			s.mu.Unlock()
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if len(metadata) == 0 {
+			metadata = nil
+		}
+	}
+	job.metadata = metadata
+	if priority != nil {
+		job.priority = *priority
+	}
+	summary := job.summary()
+	s.mu.Unlock()
+	log.Printf("Job %s updated.", id)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(summary); err != nil {
+		log.Printf("Error encoding job summary: %v", err)
+	}
+}
+
+// jobTimeoutSweepInterval is how often jobs with a timeout are checked, so
+// they fail on time even if nobody polls them.
+const jobTimeoutSweepInterval = time.Second
+
+// sweepTimeouts starts the goroutine failing timed out jobs, unless it is
+// already running or Shutdown has been called. It runs until Shutdown.
+func (s *Server) sweepTimeouts() {
+	s.goBackground(&s.sweepingTimeouts, func() {
+		ticker := time.NewTicker(jobTimeoutSweepInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				s.mu.Lock()
+				for _, job := range s.jobs {
+					if job.timeout > 0 {
+						s.advanceJob(job)
+					}
+				}
+				s.mu.Unlock()
+			}
+		}
+	})
+}
+
+// scheduleJobs wakes the goroutine starting scheduled jobs, first starting it
+// unless it is already running or Shutdown has been called. It sleeps until
+// the earliest job is due, so jobs start on time even if nobody polls them.
+func (s *Server) scheduleJobs() {
+	s.goBackground(&s.scheduling, func() {
+		timer := time.NewTimer(0)
+		defer timer.Stop()
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-timer.C:
+			case <-s.rescheduled:
+			}
+
+			s.mu.Lock()
+			var next time.Time
+			for _, job := range s.jobs {
+				if job.status != "scheduled" {
+					continue
+				}
+				if s.advanceJob(job); job.status == "scheduled" && (next.IsZero() || job.startTime.Before(next)) {
+					next = job.startTime
+				}
+			}
+			s.mu.Unlock()
+
+			if !timer.Stop() {
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
+			if !next.IsZero() {
+				timer.Reset(time.Until(next))
+			}
+		}
+	})
+	select {
+	case s.rescheduled <- struct{}{}:
+	default:
+		// Already woken, and it will see this job too.
+	}
+}
+
+// goBackground runs fn in a background goroutine which Shutdown waits for,
+// unless started is already set or Shutdown has been called, then sets
+// started. fn must return once s.stop is closed. started is guarded by srvMu.
+func (s *Server) goBackground(started *bool, fn func()) {
+	// Checked under srvMu, so either Shutdown waits for fn or we see stop closed.
+	s.srvMu.Lock()
+	defer s.srvMu.Unlock()
+	if *started {
+		return
+	}
+	select {
+	case <-s.stop:
+		return
+	default:
+	}
+	*started = true
+	s.background.Add(1)
+	go func() {
+		defer s.background.Done()
+		fn()
+	}()
+}
+
+// evictJobs forgets jobs that finished more than jobTTL ago, closing any
+// watchers waiting on a retry. Jobs nobody polled are advanced first, so they
+// expire too. The caller must hold s.mu.
+func (s *Server) evictJobs() {
+	for id, job := range s.jobs {
+		s.advanceJob(job)
+		if finished(job.status) && time.Since(job.finishedAt) >= s.jobTTL {
+			delete(s.jobs, id)
+			s.closeWatchers(id)
+			removeUpload(job)
+			if job.externalID != "" {
+				delete(s.externalIndex, job.externalID)
+			}
+		}
+	}
+}
+
+// newJobID returns a random 16 character hex ID.
+func newJobID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		// crypto/rand does not fail on supported platforms.
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
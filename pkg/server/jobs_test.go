@@ -0,0 +1,736 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// createJob posts a job with id to ts, returning the ID the server assigned.
+func createJob(t *testing.T, ts *httptest.Server, id string) string {
+	t.Helper()
+	resp, err := http.Post(ts.URL+"/jobs", "application/json", strings.NewReader(`{"id":"`+id+`"}`))
+	if err != nil {
+		t.Errorf("POST /jobs: %v", err)
+		return ""
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		t.Errorf("expected 202 creating job %q, got %d", id, resp.StatusCode)
+		return ""
+	}
+	var job JobResponse
+	if err := json.NewDecoder(resp.Body).Decode(&job); err != nil {
+		t.Errorf("decoding job: %v", err)
+	}
+	return job.JobID
+}
+
+func TestCreateJobAnswersWithPollURL(t *testing.T) {
+	s, err := NewServer(WithDelay(7*time.Second), WithErrorRate(0))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	rec := httptest.NewRecorder()
+	s.routes().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/jobs", strings.NewReader(`{"id":"abc123"}`)))
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d", rec.Code)
+	}
+	if loc := rec.Header().Get("Location"); loc != "/jobs/abc123/status" {
+		t.Fatalf("expected Location /jobs/abc123/status, got %q", loc)
+	}
+	var job JobResponse
+	if err := json.NewDecoder(rec.Body).Decode(&job); err != nil {
+		t.Fatalf("decoding job: %v", err)
+	}
+	want := JobResponse{JobID: "abc123", PollURL: "/jobs/abc123/status", EstimatedDelaySeconds: 7}
+	if job != want {
+		t.Fatalf("expected %+v, got %+v", want, job)
+	}
+}
+
+// jobStatus polls the status of job id on ts.
+func jobStatus(t *testing.T, ts *httptest.Server, id string) string {
+	t.Helper()
+	resp, err := http.Get(ts.URL + "/jobs/" + id + "/status")
+	if err != nil {
+		t.Errorf("GET status of %s: %v", id, err)
+		return ""
+	}
+	defer resp.Body.Close()
+	var r Response
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		t.Errorf("decoding status of %s: %v", id, err)
+	}
+	return r.Result
+}
+
+func TestConcurrentJobsProgressIndependently(t *testing.T) {
+	s, err := NewServer(WithDelay(time.Second), WithErrorRate(0))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	ts := httptest.NewServer(s.Handler())
+	defer ts.Close()
+
+	if id := createJob(t, ts, "first"); id != "first" {
+		t.Fatalf("expected job ID first, got %q", id)
+	}
+	time.Sleep(600 * time.Millisecond)
+
+	// Polling both jobs at once must not restart either one's timer.
+	var wg sync.WaitGroup
+	second := make(chan string, 1)
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 5; i++ {
+			jobStatus(t, ts, "first")
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		second <- createJob(t, ts, "")
+	}()
+	wg.Wait()
+	secondID := <-second
+	if secondID == "" {
+		t.Fatal("expected a generated ID for the second job")
+	}
+
+	time.Sleep(500 * time.Millisecond)
+	results := make(map[string]string)
+	var mu sync.Mutex
+	for _, id := range []string{"first", secondID} {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			result := jobStatus(t, ts, id)
+			mu.Lock()
+			results[id] = result
+			mu.Unlock()
+		}(id)
+	}
+	wg.Wait()
+
+	if results["first"] != "completed" {
+		t.Errorf("expected the first job to have completed after 1.1s, got %q", results["first"])
+	}
+	if results[secondID] != "pending" {
+		t.Errorf("expected the second job to still be pending after 0.5s, got %q", results[secondID])
+	}
+	// Finished jobs keep their status instead of starting over.
+	if got := jobStatus(t, ts, "first"); got != "completed" {
+		t.Errorf("expected the first job to stay completed, got %q", got)
+	}
+}
+
+func TestJobEndpointErrors(t *testing.T) {
+	s, err := NewServer(WithDelay(time.Second), WithErrorRate(0))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	do := func(method, target, body string) int {
+		rec := httptest.NewRecorder()
+		s.routes().ServeHTTP(rec, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return rec.Code
+	}
+
+	if code := do(http.MethodPost, "/jobs", `{"id":"abc123"}`); code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d", code)
+	}
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		code   int
+	}{
+		{"duplicate ID", http.MethodPost, "/jobs", `{"id":"abc123"}`, http.StatusConflict},
+		{"ID with a slash", http.MethodPost, "/jobs", `{"id":"a/b"}`, http.StatusBadRequest},
+		{"invalid body", http.MethodPost, "/jobs", `{`, http.StatusBadRequest},
+		{"wrong method", http.MethodPut, "/jobs", "", http.StatusMethodNotAllowed},
+		{"lookup without external ID", http.MethodGet, "/jobs?external_id=", "", http.StatusBadRequest},
+		{"negative limit", http.MethodGet, "/jobs?limit=-1", "", http.StatusBadRequest},
+		{"tag without key", http.MethodGet, "/jobs?tag=:en", "", http.StatusBadRequest},
+		{"selector without value", http.MethodGet, "/jobs?selector=env", "", http.StatusBadRequest},
+		{"conflicting selectors", http.MethodGet, "/jobs?selector=env=prod&selector=env=dev", "", http.StatusBadRequest},
+		{"label without value", http.MethodGet, "/jobs?label=env", "", http.StatusBadRequest},
+		{"unknown status", http.MethodGet, "/jobs?status=done", "", http.StatusBadRequest},
+		{"cancel without filter", http.MethodDelete, "/jobs", "", http.StatusBadRequest},
+		{"label key with '='", http.MethodPost, "/jobs", `{"labels":{"a=b":"c"}}`, http.StatusBadRequest},
+		{"empty metadata key", http.MethodPost, "/jobs", `{"metadata":{"":"x"}}`, http.StatusBadRequest},
+		{"invalid next job", http.MethodPost, "/jobs", `{"next_job":{"callback_url":"ftp://example.com"}}`, http.StatusBadRequest},
+		{"ID repeated in chain", http.MethodPost, "/jobs", `{"id":"x","next_job":{"next_job":{"id":"x"}}}`, http.StatusBadRequest},
+		{"unknown job", http.MethodGet, "/jobs/nope/status", "", http.StatusNotFound},
+		{"get without status suffix", http.MethodGet, "/jobs/abc123", "", http.StatusMethodNotAllowed},
+		{"patch unknown job", http.MethodPatch, "/jobs/nope", `{"priority":1}`, http.StatusNotFound},
+		{"patch status", http.MethodPatch, "/jobs/abc123", `{"status":"completed"}`, http.StatusBadRequest},
+		{"patch not an object", http.MethodPatch, "/jobs/abc123", `null`, http.StatusBadRequest},
+		{"patch metadata not strings", http.MethodPatch, "/jobs/abc123", `{"metadata":{"a":1}}`, http.StatusBadRequest},
+		{"known job", http.MethodGet, "/jobs/abc123/status", "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := do(tt.method, tt.target, tt.body); code != tt.code {
+				t.Fatalf("expected %d, got %d", tt.code, code)
+			}
+		})
+	}
+
+	s.Pause()
+	if code := do(http.MethodPost, "/jobs", `{"id":"paused"}`); code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 while paused, got %d", code)
+	}
+}
+
+func TestJobsAreCappedAndExpire(t *testing.T) {
+	s, err := NewServer(WithDelay(time.Second), WithErrorRate(0), WithMaxJobs(2), WithJobTTL(time.Minute))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	ts := httptest.NewServer(s.Handler())
+	defer ts.Close()
+
+	createJob(t, ts, "a")
+	createJob(t, ts, "b")
+	resp, err := http.Post(ts.URL+"/jobs", "application/json", strings.NewReader(`{"id":"c"}`))
+	if err != nil {
+		t.Fatalf("POST /jobs: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 over the cap, got %d", resp.StatusCode)
+	}
+
+	// Polling a finished job many times counts it once.
+	s.mu.Lock()
+	s.jobs["a"].startTime = time.Now().Add(-time.Second)
+	s.mu.Unlock()
+	for i := 0; i < 5; i++ {
+		if got := jobStatus(t, ts, "a"); got != "completed" {
+			t.Fatalf("expected a to have completed, got %q", got)
+		}
+	}
+	if m := s.Metrics(); m.CompletedCount != 1 {
+		t.Fatalf("expected one completed job, got %d", m.CompletedCount)
+	}
+
+	// Once a has been finished for longer than the TTL it makes room for c.
+	s.mu.Lock()
+	s.jobs["a"].finishedAt = time.Now().Add(-time.Minute)
+	s.mu.Unlock()
+	createJob(t, ts, "c")
+	resp, err = http.Get(ts.URL + "/jobs/a/status")
+	if err != nil {
+		t.Fatalf("GET status of a: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected the expired job to be gone, got %d", resp.StatusCode)
+	}
+}
+
+func TestRetryJob(t *testing.T) {
+	s, err := NewServer(WithDelay(time.Second), WithErrorRate(100))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	ts := httptest.NewServer(s.Handler())
+	defer ts.Close()
+	resp, err := http.Post(ts.URL+"/jobs", "application/json", strings.NewReader(`{"id":"flaky","max_job_retries":1}`))
+	if err != nil {
+		t.Fatalf("POST /jobs: %v", err)
+	}
+	resp.Body.Close()
+	events := mustWatch(t, s, "flaky")
+
+	retry := func() (int, RetryResponse) {
+		t.Helper()
+		resp, err := http.Post(ts.URL+"/jobs/flaky/retry", "application/json", nil)
+		if err != nil {
+			t.Fatalf("POST retry: %v", err)
+		}
+		defer resp.Body.Close()
+		var body RetryResponse
+		if resp.StatusCode == http.StatusOK {
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+				t.Fatalf("decoding retry: %v", err)
+			}
+		}
+		return resp.StatusCode, body
+	}
+	fail := func() {
+		t.Helper()
+		s.mu.Lock()
+		s.jobs["flaky"].startTime = time.Now().Add(-time.Second)
+		s.mu.Unlock()
+		if got := jobStatus(t, ts, "flaky"); got != "error" {
+			t.Fatalf("expected the job to fail, got %q", got)
+		}
+	}
+
+	if code, _ := retry(); code != http.StatusConflict {
+		t.Fatalf("expected a pending job not to be retried, got %d", code)
+	}
+	fail()
+	code, body := retry()
+	if want := (RetryResponse{JobID: "flaky", RetryCount: 1, Status: "pending"}); code != http.StatusOK || body != want {
+		t.Fatalf("expected %+v, got %d %+v", want, code, body)
+	}
+	if got := jobStatus(t, ts, "flaky"); got != "pending" {
+		t.Fatalf("expected the retried job to be pending, got %q", got)
+	}
+
+	// Its one retry is used up.
+	fail()
+	if code, _ := retry(); code != http.StatusConflict {
+		t.Fatalf("expected the retries to run out, got %d", code)
+	}
+
+	// Its watchers follow it through the retry, until it can't be retried.
+	var got []string
+	for _, e := range collect(t, events) {
+		got = append(got, e.OldStatus+">"+e.NewStatus)
+	}
+	if want := []string{"pending>error", "error>pending", "pending>error"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected events %v, got %v", want, got)
+	}
+
+	resp, err = http.Post(ts.URL+"/jobs/missing/retry", "application/json", nil)
+	if err != nil {
+		t.Fatalf("POST retry: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown job, got %d", resp.StatusCode)
+	}
+}
+
+func TestJobCorrelationHeadersAreEchoed(t *testing.T) {
+	s, err := NewServer(WithDelay(time.Hour), WithErrorRate(0))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/jobs", strings.NewReader(`{"id":"correlated"}`))
+	req.Header.Set("X-Job-Correlation-Id", "order-42")
+	req.Header.Set("X-Other", "not kept")
+	rec := httptest.NewRecorder()
+	s.routes().ServeHTTP(rec, req)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d", rec.Code)
+	}
+
+	for i := 0; i < 3; i++ {
+		rec = httptest.NewRecorder()
+		s.routes().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/jobs/correlated/status", nil))
+		if got := rec.Header().Get("X-Job-Correlation-Id"); got != "order-42" {
+			t.Fatalf("poll %d: expected X-Job-Correlation-Id order-42, got %q", i+1, got)
+		}
+		if got := rec.Header().Get("X-Other"); got != "" {
+			t.Fatalf("poll %d: expected X-Other not to be echoed, got %q", i+1, got)
+		}
+	}
+
+	// Too many correlation headers are refused rather than silently dropped.
+	req = httptest.NewRequest(http.MethodPost, "/jobs", strings.NewReader(`{"id":"crowded"}`))
+	for i := 0; i <= maxCorrelationHeaders; i++ {
+		req.Header.Set(fmt.Sprintf("X-Job-Correlation-H%d", i), "v")
+	}
+	rec = httptest.NewRecorder()
+	s.routes().ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for too many correlation headers, got %d", rec.Code)
+	}
+}
+
+func TestJobTimeout(t *testing.T) {
+	s, err := NewServer(WithDelay(5*time.Second), WithErrorRate(0))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	defer s.Shutdown(context.Background())
+	rec := httptest.NewRecorder()
+	s.routes().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/jobs", strings.NewReader(`{"id":"slow","timeout_seconds":1}`)))
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d", rec.Code)
+	}
+
+	// Nobody polls the job, so it is the background sweep which fails it.
+	start := time.Now()
+	events := mustWatch(t, s, "slow")
+	select {
+	case e := <-events:
+		if e.NewStatus != "error" {
+			t.Fatalf("expected the job to fail, got %+v", e)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected the job to time out within 2s")
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Fatalf("expected the job to run for its 1s timeout, failed after %v", elapsed)
+	}
+
+	rec = httptest.NewRecorder()
+	s.routes().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/jobs/slow/status", nil))
+	var r Response
+	if err := json.NewDecoder(rec.Body).Decode(&r); err != nil {
+		t.Fatalf("decoding status: %v", err)
+	}
+	if r != (Response{Result: "error", Reason: ReasonJobTimeout}) {
+		t.Fatalf("expected error with reason %s, got %+v", ReasonJobTimeout, r)
+	}
+	if m := s.Metrics(); m.TimeoutedCount != 1 || m.ErrorCount != 1 {
+		t.Fatalf("expected 1 timed out job counted as an error, got %+v", m)
+	}
+
+	rec = httptest.NewRecorder()
+	s.routes().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/jobs", strings.NewReader(`{"timeout_seconds":-1}`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a negative timeout, got %d", rec.Code)
+	}
+}
+
+func TestJobExternalID(t *testing.T) {
+	s, err := NewServer(WithDelay(time.Hour), WithErrorRate(0), WithJobTTL(time.Minute))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	do := func(method, target, body string) (int, JobResponse) {
+		rec := httptest.NewRecorder()
+		s.routes().ServeHTTP(rec, httptest.NewRequest(method, target, strings.NewReader(body)))
+		var job JobResponse
+		json.NewDecoder(rec.Body).Decode(&job)
+		return rec.Code, job
+	}
+
+	code, first := do(http.MethodPost, "/jobs", `{"external_id":"upstream-1"}`)
+	if code != http.StatusAccepted || first.JobID == "" {
+		t.Fatalf("expected 202 with a job, got %d %+v", code, first)
+	}
+	// Submitting again, even under another ID, answers with the same job.
+	if code, again := do(http.MethodPost, "/jobs", `{"id":"other","external_id":"upstream-1"}`); code != http.StatusOK || again != first {
+		t.Fatalf("expected 200 with %+v, got %d %+v", first, code, again)
+	}
+	s.mu.Lock()
+	n := len(s.jobs)
+	s.mu.Unlock()
+	if n != 1 {
+		t.Fatalf("expected the duplicate not to create a job, got %d jobs", n)
+	}
+
+	if code, found := do(http.MethodGet, "/jobs?external_id=upstream-1", ""); code != http.StatusOK || found != first {
+		t.Fatalf("expected 200 with %+v, got %d %+v", first, code, found)
+	}
+	if code, _ := do(http.MethodGet, "/jobs?external_id=upstream-2", ""); code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown external ID, got %d", code)
+	}
+
+	// The index entry expires along with its job.
+	s.mu.Lock()
+	job := s.jobs[first.JobID]
+	job.status, job.finishedAt = "completed", time.Now().Add(-time.Minute)
+	s.mu.Unlock()
+	if code, _ := do(http.MethodGet, "/jobs?external_id=upstream-1", ""); code != http.StatusNotFound {
+		t.Fatalf("expected 404 once the job expired, got %d", code)
+	}
+	if code, _ := do(http.MethodPost, "/jobs", `{"external_id":"upstream-1"}`); code != http.StatusAccepted {
+		t.Fatalf("expected a new job once the old one expired, got %d", code)
+	}
+}
+
+func TestScheduledJob(t *testing.T) {
+	s, err := NewServer(WithDelay(time.Second), WithErrorRate(0))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	defer s.Shutdown(context.Background())
+	ts := httptest.NewServer(s.routes())
+	defer ts.Close()
+
+	start := time.Now()
+	body := fmt.Sprintf(`{"id":"later","scheduled_at":%q}`, start.Add(200*time.Millisecond).Format(time.RFC3339Nano))
+	resp, err := http.Post(ts.URL+"/jobs", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("POST /jobs: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d", resp.StatusCode)
+	}
+	events := mustWatch(t, s, "later")
+
+	time.Sleep(time.Until(start.Add(100 * time.Millisecond)))
+	if status := jobStatus(t, ts, "later"); status != "scheduled" {
+		t.Fatalf("expected scheduled at 100ms, got %q", status)
+	}
+
+	// The scheduler starts the job on time without it being polled.
+	select {
+	case e := <-events:
+		if e.OldStatus != "scheduled" || e.NewStatus != "pending" {
+			t.Fatalf("expected scheduled to pending, got %+v", e)
+		}
+		if elapsed := e.Timestamp.Sub(start); elapsed < 200*time.Millisecond {
+			t.Fatalf("expected the job to start after 200ms, started after %v", elapsed)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the job to start")
+	}
+	time.Sleep(time.Until(start.Add(250 * time.Millisecond)))
+	if status := jobStatus(t, ts, "later"); status != "pending" {
+		t.Fatalf("expected pending at 250ms, got %q", status)
+	}
+}
+
+func TestListJobs(t *testing.T) {
+	s, err := NewServer(WithDelay(time.Hour), WithErrorRate(0))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	for _, body := range []string{
+		`{"id":"a","metadata":{"source_lang":"en","team":"nlp"}}`,
+		`{"id":"b","metadata":{"source_lang":"de"}}`,
+		`{"id":"c","metadata":{"source_lang":"en"}}`,
+	} {
+		rec := httptest.NewRecorder()
+		s.routes().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/jobs", strings.NewReader(body)))
+		if rec.Code != http.StatusAccepted {
+			t.Fatalf("expected 202, got %d", rec.Code)
+		}
+	}
+	// Created in order, but too close together to sort by time alone.
+	s.mu.Lock()
+	for i, id := range []string{"c", "b", "a"} {
+		s.jobs[id].createdAt = time.Now().Add(-time.Duration(i) * time.Second)
+	}
+	s.mu.Unlock()
+
+	tests := []struct {
+		query string
+		ids   []string
+		total int
+	}{
+		{"", []string{"a", "b", "c"}, 3},
+		{"?tag=source_lang:en", []string{"a", "c"}, 2},
+		{"?tag=source_lang:en&tag=team", []string{"a"}, 1},
+		{"?tag=source_lang:fr", nil, 0},
+		{"?tag=source_lang:en&limit=1", []string{"a"}, 2},
+		{"?tag=source_lang:en&limit=1&offset=1", []string{"c"}, 2},
+		{"?limit=5&offset=2", []string{"c"}, 3},
+		{"?offset=3", nil, 3},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		s.routes().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/jobs"+tt.query, nil))
+		var list JobList
+		if err := json.NewDecoder(rec.Body).Decode(&list); err != nil {
+			t.Fatalf("%s: decoding list: %v", tt.query, err)
+		}
+		var ids []string
+		for _, job := range list.Jobs {
+			ids = append(ids, job.JobID)
+		}
+		if rec.Code != http.StatusOK || !reflect.DeepEqual(ids, tt.ids) || list.Total != tt.total {
+			t.Fatalf("%s: expected 200 with %v of %d, got %d with %v of %d", tt.query, tt.ids, tt.total, rec.Code, ids, list.Total)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	s.routes().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/jobs?tag=team:nlp", nil))
+	var list JobList
+	json.NewDecoder(rec.Body).Decode(&list)
+	if want := map[string]string{"source_lang": "en", "team": "nlp"}; len(list.Jobs) != 1 || list.Jobs[0].Status != "pending" || !reflect.DeepEqual(list.Jobs[0].Metadata, want) {
+		t.Fatalf("expected job a, pending with its metadata, got %+v", list.Jobs)
+	}
+}
+
+func TestJobChain(t *testing.T) {
+	s, err := NewServer(WithConfig(&Config{DelaySeconds: 0, ErrorRate: 0}))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	rec := httptest.NewRecorder()
+	s.routes().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/jobs",
+		strings.NewReader(`{"id":"first","next_job":{"id":"second","metadata":{"step":"2"},"next_job":{}}}`)))
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d", rec.Code)
+	}
+
+	poll := func(id string) Response {
+		rec := httptest.NewRecorder()
+		s.routes().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/jobs/"+id+"/status", nil))
+		var resp Response
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("%s: decoding status: %v", id, err)
+		}
+		return resp
+	}
+	s.mu.Lock()
+	if _, ok := s.jobs["second"]; ok {
+		t.Fatal("expected the second job to wait for the first to complete")
+	}
+	s.mu.Unlock()
+	if resp := poll("first"); resp != (Response{Result: "completed", NextJobID: "second"}) {
+		t.Fatalf("expected first to complete with next job second, got %+v", resp)
+	}
+	resp := poll("second")
+	if resp.Result != "completed" || len(resp.NextJobID) != 16 {
+		t.Fatalf("expected second to complete with a generated next job ID, got %+v", resp)
+	}
+	if last := poll(resp.NextJobID); last != (Response{Result: "completed"}) {
+		t.Fatalf("expected the last job to complete without a next job, got %+v", last)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if got := s.jobs["second"].metadata["step"]; got != "2" {
+		t.Fatalf("expected the second job to have its metadata, got %q", got)
+	}
+}
+
+func TestPatchJob(t *testing.T) {
+	s, err := NewServer(WithDelay(time.Hour), WithErrorRate(0))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	do := func(method, target, body string) (int, JobSummary) {
+		rec := httptest.NewRecorder()
+		s.routes().ServeHTTP(rec, httptest.NewRequest(method, target, strings.NewReader(body)))
+		var summary JobSummary
+		if rec.Code == http.StatusOK {
+			if err := json.NewDecoder(rec.Body).Decode(&summary); err != nil {
+				t.Fatalf("decoding summary: %v", err)
+			}
+		}
+		return rec.Code, summary
+	}
+	for _, body := range []string{`{"id":"live","priority":2,"metadata":{"team":"nlp","source_lang":"en"}}`, `{"id":"done"}`} {
+		if code, _ := do(http.MethodPost, "/jobs", body); code != http.StatusAccepted {
+			t.Fatalf("expected 202, got %d", code)
+		}
+	}
+
+	code, summary := do(http.MethodPatch, "/jobs/live", `{"metadata":{"team":null,"target_lang":"de"},"priority":5}`)
+	want := map[string]string{"source_lang": "en", "target_lang": "de"}
+	if code != http.StatusOK || summary.JobID != "live" || summary.Status != "pending" || summary.Priority != 5 || !reflect.DeepEqual(summary.Metadata, want) {
+		t.Fatalf("expected 200 with priority 5 and %v, got %d with %+v", want, code, summary)
+	}
+	// Fields left out are kept, and null clears them.
+	if code, summary = do(http.MethodPatch, "/jobs/live", `{"metadata":null}`); code != http.StatusOK || summary.Priority != 5 || summary.Metadata != nil {
+		t.Fatalf("expected 200 with priority 5 and no metadata, got %d with %+v", code, summary)
+	}
+	if code, summary = do(http.MethodPatch, "/jobs/live", `{"priority":null}`); code != http.StatusOK || summary.Priority != 0 {
+		t.Fatalf("expected 200 with priority 0, got %d with %+v", code, summary)
+	}
+
+	s.mu.Lock()
+	s.jobs["done"].status = "completed"
+	s.mu.Unlock()
+	if code, _ := do(http.MethodPatch, "/jobs/done", `{"priority":1}`); code != http.StatusConflict {
+		t.Fatalf("expected 409 for a finished job, got %d", code)
+	}
+	if code, _ := do(http.MethodPatch, "/jobs/missing", `{"priority":1}`); code != http.StatusNotFound {
+		t.Fatalf("expected 404 for a missing job, got %d", code)
+	}
+}
+
+func TestLabelSelector(t *testing.T) {
+	labels := map[string]string{"env": "prod", "team": "nlp", "priority": "high"}
+	tests := []struct {
+		selector string
+		want     bool
+	}{
+		{"", true},
+		{"env=prod", true},
+		{"env=prod,priority=high", true},
+		{"env=prod,priority=low", false},
+		{"env=dev", false},
+		{"region=eu", false},
+		{"team=", false},
+	}
+	for _, tt := range tests {
+		sel, err := ParseLabelSelector(tt.selector)
+		if err != nil {
+			t.Fatalf("%q: %v", tt.selector, err)
+		}
+		if got := sel.Matches(labels); got != tt.want {
+			t.Fatalf("%q: expected %v, got %v", tt.selector, tt.want, got)
+		}
+	}
+	if sel, _ := ParseLabelSelector("team="); !sel.Matches(map[string]string{"team": ""}) {
+		t.Fatal("expected team= to match an empty team label")
+	}
+	for _, bad := range []string{"env", "=prod", "env=prod,", "env=prod,env=dev"} {
+		if _, err := ParseLabelSelector(bad); err == nil {
+			t.Fatalf("%q: expected an error", bad)
+		}
+	}
+}
+
+func TestListJobsBySelector(t *testing.T) {
+	s, err := NewServer(WithDelay(time.Hour), WithErrorRate(0))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	for _, body := range []string{
+		`{"id":"a","labels":{"env":"prod","priority":"high"}}`,
+		`{"id":"b","labels":{"env":"prod","priority":"low"}}`,
+		`{"id":"c","labels":{"env":"dev","priority":"high"}}`,
+		`{"id":"d"}`,
+	} {
+		rec := httptest.NewRecorder()
+		s.routes().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/jobs", strings.NewReader(body)))
+		if rec.Code != http.StatusAccepted {
+			t.Fatalf("expected 202, got %d", rec.Code)
+		}
+	}
+	s.mu.Lock()
+	for i, id := range []string{"d", "c", "b", "a"} {
+		s.jobs[id].createdAt = time.Now().Add(-time.Duration(i) * time.Second)
+	}
+	s.mu.Unlock()
+
+	tests := []struct {
+		query string
+		ids   []string
+	}{
+		{"", []string{"a", "b", "c", "d"}},
+		{"?selector=", []string{"a", "b", "c", "d"}},
+		{"?selector=env=prod", []string{"a", "b"}},
+		{"?selector=env=prod,priority=high", []string{"a"}},
+		{"?selector=env=prod&selector=priority=high", []string{"a"}},
+		{"?selector=priority=high", []string{"a", "c"}},
+		{"?selector=env=staging", nil},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		s.routes().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/jobs"+tt.query, nil))
+		var list JobList
+		if err := json.NewDecoder(rec.Body).Decode(&list); err != nil {
+			t.Fatalf("%s: decoding list: %v", tt.query, err)
+		}
+		var ids []string
+		for _, job := range list.Jobs {
+			ids = append(ids, job.JobID)
+		}
+		if rec.Code != http.StatusOK || !reflect.DeepEqual(ids, tt.ids) {
+			t.Fatalf("%s: expected 200 with %v, got %d with %v", tt.query, tt.ids, rec.Code, ids)
+		}
+	}
+
+	jobs := s.ListBySelector(LabelSelector{"env": "dev"})
+	if want := map[string]string{"env": "dev", "priority": "high"}; len(jobs) != 1 || jobs[0].JobID != "c" || !reflect.DeepEqual(jobs[0].Labels, want) {
+		t.Fatalf("expected job c with its labels, got %+v", jobs)
+	}
+	if all := s.ListBySelector(nil); len(all) != 4 {
+		t.Fatalf("expected the empty selector to list all 4 jobs, got %d", len(all))
+	}
+}
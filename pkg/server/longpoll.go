@@ -0,0 +1,62 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// MaxLongPollWait caps the wait parameter of a long poll, so a request cannot
+// hold a connection open indefinitely.
+const MaxLongPollWait = 60 * time.Second
+
+// parseWait reads the wait parameter of a status request, e.g. wait=30s,
+// capped at MaxLongPollWait. It returns zero when the parameter is absent.
+func parseWait(r *http.Request) (time.Duration, error) {
+	raw := r.URL.Query().Get("wait")
+	if raw == "" {
+		return 0, nil
+	}
+	wait, err := time.ParseDuration(raw)
+	if err != nil || wait < 0 {
+		return 0, fmt.Errorf("invalid wait %q, expected a duration such as 30s", raw)
+	}
+	if wait > MaxLongPollWait {
+		wait = MaxLongPollWait
+	}
+	return wait, nil
+}
+
+// awaitJob returns once job is no longer pending, wait has passed or ctx is
+// done. It checks the job when its delay should be over, and at least every
+// streamInterval in case the delay is changed meanwhile. The caller must not
+// hold s.mu.
+func (s *Server) awaitJob(ctx context.Context, job *jobState, wait time.Duration) {
+	timeout := time.NewTimer(wait)
+	defer timeout.Stop()
+	for {
+		s.mu.Lock()
+		elapsed, delay := s.advanceJob(job)
+		done := finished(job.status)
+		s.mu.Unlock()
+		if done {
+			return
+		}
+
+		next := delay - elapsed
+		if next > s.streamInterval {
+			next = s.streamInterval
+		}
+		check := time.NewTimer(next)
+		select {
+		case <-ctx.Done():
+			check.Stop()
+			return
+		case <-timeout.C:
+			check.Stop()
+			return
+		case <-check.C:
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLongPoll(t *testing.T) {
+	s, err := NewServer(WithDelay(time.Second), WithErrorRate(0))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	s.streamInterval = 50 * time.Millisecond
+	get := func(target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.routes().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+
+	if rec := get("/status?wait=soon"); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an invalid wait, got %d", rec.Code)
+	}
+
+	// The wait runs out before the job finishes.
+	start := time.Now()
+	rec := get("/status?wait=200ms")
+	if rec.Code != http.StatusAccepted || decodeResult(t, rec) != "pending" {
+		t.Fatalf("expected 202 pending once the wait ran out, got %d %q", rec.Code, rec.Body.String())
+	}
+	if waited := time.Since(start); waited < 200*time.Millisecond {
+		t.Fatalf("expected the response to be held for the wait, returned after %v", waited)
+	}
+
+	// The job finishes during the wait, which ends it early.
+	start = time.Now()
+	rec = get("/status?wait=30s")
+	if rec.Code != http.StatusOK || decodeResult(t, rec) != "completed" {
+		t.Fatalf("expected 200 completed, got %d %q", rec.Code, rec.Body.String())
+	}
+	if waited := time.Since(start); waited > 2*time.Second {
+		t.Fatalf("expected the response as soon as the job finished, took %v", waited)
+	}
+
+	// Jobs created with POST /jobs can be long-polled too, and without a wait
+	// nothing changes.
+	createRec := httptest.NewRecorder()
+	s.routes().ServeHTTP(createRec, httptest.NewRequest(http.MethodPost, "/jobs", nil))
+	var job JobResponse
+	if err := json.NewDecoder(createRec.Body).Decode(&job); err != nil {
+		t.Fatalf("decoding job: %v", err)
+	}
+	if rec := get(job.PollURL); rec.Code != http.StatusOK || decodeResult(t, rec) != "pending" {
+		t.Fatalf("expected an immediate 200 pending, got %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestParseWaitCapped(t *testing.T) {
+	wait, err := parseWait(httptest.NewRequest(http.MethodGet, "/status?wait=1h", nil))
+	if err != nil || wait != MaxLongPollWait {
+		t.Fatalf("expected the wait capped at %v, got %v, %v", MaxLongPollWait, wait, err)
+	}
+}
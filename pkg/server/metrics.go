@@ -0,0 +1,219 @@
+package server
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// ServerMetrics is a snapshot of the server's counters, for library users who
+// want to export them into their own metrics system.
+type ServerMetrics struct {
+	TotalRequests  int64   // All /status requests, including rejected ones.
+	PendingCount   int64   // Responses reporting "pending".
+	CompletedCount int64   // Jobs that finished as "completed".
+	ErrorCount     int64   // Jobs that finished as "error".
+	AverageDelayMs float64 // Mean time from a job starting to its final status.
+
+	BlockedRequestsTotal int64 // Requests rejected by the blocklist set with WithBlocklist.
+	AgeExceededCount     int64 // Jobs failed for exceeding MaxPendingAge, included in ErrorCount.
+	TimeoutedCount       int64 // Jobs failed for exceeding their TimeoutSeconds, included in ErrorCount.
+}
+
+// counters are updated atomically so Metrics never contends with statusHandler.
+type counters struct {
+	totalRequests atomic.Int64
+	pending       atomic.Int64
+	completed     atomic.Int64
+	errored       atomic.Int64
+	totalDelayMs  atomic.Int64
+	ageExceeded   atomic.Int64
+	timedOut      atomic.Int64
+	latency       LatencyHistogram
+}
+
+// recordResponse counts a response reporting status.
+func (c *counters) recordResponse(status string) {
+	if status == "pending" {
+		c.pending.Add(1)
+	}
+}
+
+// recordFinished counts a job reaching its final status, and how long it took.
+func (c *counters) recordFinished(status string, jobDuration time.Duration) {
+	switch status {
+	case "completed":
+		c.completed.Add(1)
+	case "error":
+		c.errored.Add(1)
+	}
+	c.totalDelayMs.Add(jobDuration.Milliseconds())
+	c.latency.Record(jobDuration)
+}
+
+// Metrics returns a snapshot of the server's counters.
+func (s *Server) Metrics() ServerMetrics {
+	m := ServerMetrics{
+		TotalRequests:  s.counters.totalRequests.Load(),
+		PendingCount:   s.counters.pending.Load(),
+		CompletedCount: s.counters.completed.Load(),
+		ErrorCount:     s.counters.errored.Load(),
+
+		AgeExceededCount: s.counters.ageExceeded.Load(),
+		TimeoutedCount:   s.counters.timedOut.Load(),
+	}
+	if finished := m.CompletedCount + m.ErrorCount; finished > 0 {
+		m.AverageDelayMs = float64(s.counters.totalDelayMs.Load()) / float64(finished)
+	}
+	if s.blocklist != nil {
+		m.BlockedRequestsTotal = s.blocklist.BlockedTotal()
+	}
+	return m
+}
+
+const (
+	// firstBucketMicros is the upper bound of the smallest latency bucket.
+	firstBucketMicros = 100
+	// latencyBuckets doubles from 100µs up to roughly 30 hours; slower jobs
+	// all land in the last bucket.
+	latencyBuckets = 31
+)
+
+// LatencyHistogram counts latencies in exponentially spaced buckets, like a
+// Prometheus histogram, so percentiles can be estimated in constant memory.
+// Bucket i holds latencies up to 100µs * 2^i. It is safe for concurrent use
+// and the zero value is ready to use.
+type LatencyHistogram struct {
+	counts   [latencyBuckets]atomic.Uint64
+	count    atomic.Uint64
+	sumMicro atomic.Int64
+}
+
+// HistogramBucket is the number of latencies at most UpperBound, and above
+// the previous bucket's bound.
+type HistogramBucket struct {
+	UpperBound time.Duration `json:"upper_bound"`
+	Count      uint64        `json:"count"`
+}
+
+// HistogramSnapshot is a copy of a LatencyHistogram's counts.
+type HistogramSnapshot struct {
+	Buckets []HistogramBucket `json:"buckets"`
+	Count   uint64            `json:"count"`
+	Sum     time.Duration     `json:"sum"`
+}
+
+// bucketBound returns the upper bound of bucket i.
+func bucketBound(i int) time.Duration {
+	return time.Duration(firstBucketMicros<<i) * time.Microsecond
+}
+
+// Record adds a latency to the histogram.
+func (h *LatencyHistogram) Record(d time.Duration) {
+	i := 0
+	for i < latencyBuckets-1 && d > bucketBound(i) {
+		i++
+	}
+	h.counts[i].Add(1)
+	h.count.Add(1)
+	h.sumMicro.Add(d.Microseconds())
+}
+
+// Snapshot returns a copy of the histogram's counts. Under concurrent
+// Records the total may be slightly ahead of the buckets.
+func (h *LatencyHistogram) Snapshot() HistogramSnapshot {
+	snap := HistogramSnapshot{
+		Buckets: make([]HistogramBucket, latencyBuckets),
+		Count:   h.count.Load(),
+		Sum:     time.Duration(h.sumMicro.Load()) * time.Microsecond,
+	}
+	for i := range snap.Buckets {
+		snap.Buckets[i] = HistogramBucket{UpperBound: bucketBound(i), Count: h.counts[i].Load()}
+	}
+	return snap
+}
+
+// Percentile estimates the p-th percentile latency, for p between 0 and 100,
+// by interpolating linearly within the bucket it falls in. It returns zero
+// when nothing has been recorded.
+func (h *LatencyHistogram) Percentile(p float64) time.Duration {
+	snap := h.Snapshot()
+	var total uint64
+	for _, b := range snap.Buckets {
+		total += b.Count
+	}
+	if total == 0 {
+		return 0
+	}
+	if p < 0 {
+		p = 0
+	} else if p > 100 {
+		p = 100
+	}
+
+	rank := p / 100 * float64(total)
+	var seen uint64
+	for i, b := range snap.Buckets {
+		if b.Count == 0 || float64(seen+b.Count) < rank {
+			seen += b.Count
+			continue
+		}
+		var lower time.Duration
+		if i > 0 {
+			lower = bucketBound(i - 1)
+		}
+		fraction := (rank - float64(seen)) / float64(b.Count)
+		return lower + time.Duration(fraction*float64(b.UpperBound-lower))
+	}
+	return snap.Buckets[latencyBuckets-1].UpperBound
+}
+
+// Latency returns the histogram of job latencies, from a job starting to its
+// final status.
+func (s *Server) Latency() *LatencyHistogram {
+	return &s.counters.latency
+}
+
+// statsResponse is the body of GET /stats.
+type statsResponse struct {
+	TotalRequests  int64   `json:"total_requests"`
+	PendingCount   int64   `json:"pending_count"`
+	CompletedCount int64   `json:"completed_count"`
+	ErrorCount     int64   `json:"error_count"`
+	AverageDelayMs float64 `json:"average_delay_ms"`
+	LatencyP50Ms   float64 `json:"latency_p50_ms"`
+	LatencyP95Ms   float64 `json:"latency_p95_ms"`
+	LatencyP99Ms   float64 `json:"latency_p99_ms"`
+
+	BlockedRequestsTotal int64 `json:"blocked_requests_total"`
+	AgeExceededCount     int64 `json:"age_exceeded_count"`
+	TimeoutedCount       int64 `json:"timeouted_count"`
+}
+
+// statsHandler serves the server's metrics and job latency percentiles as JSON.
+func (s *Server) statsHandler(w http.ResponseWriter, r *http.Request) {
+	m := s.Metrics()
+	latency := s.Latency()
+	ms := func(p float64) float64 {
+		return float64(latency.Percentile(p)) / float64(time.Millisecond)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(statsResponse{
+		TotalRequests:  m.TotalRequests,
+		PendingCount:   m.PendingCount,
+		CompletedCount: m.CompletedCount,
+		ErrorCount:     m.ErrorCount,
+		AverageDelayMs: m.AverageDelayMs,
+		LatencyP50Ms:   ms(50),
+		LatencyP95Ms:   ms(95),
+		LatencyP99Ms:   ms(99),
+
+		BlockedRequestsTotal: m.BlockedRequestsTotal,
+		AgeExceededCount:     m.AgeExceededCount,
+		TimeoutedCount:       m.TimeoutedCount,
+	}); err != nil {
+		log.Printf("Error encoding stats response: %v", err)
+	}
+}
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/quick"
+	"time"
+)
+
+func TestPercentilesAreOrdered(t *testing.T) {
+	ordered := func(latenciesMicros []uint32) bool {
+		var h LatencyHistogram
+		for _, us := range latenciesMicros {
+			h.Record(time.Duration(us) * time.Microsecond)
+		}
+		p50, p95, p99 := h.Percentile(50), h.Percentile(95), h.Percentile(99)
+		return p50 <= p95 && p95 <= p99
+	}
+	if err := quick.Check(ordered, &quick.Config{MaxCount: 500}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPercentileWithinBucket(t *testing.T) {
+	var h LatencyHistogram
+	if h.Percentile(50) != 0 {
+		t.Fatal("expected zero for an empty histogram")
+	}
+	// 90 fast jobs and 10 slow ones.
+	for i := 0; i < 90; i++ {
+		h.Record(50 * time.Microsecond)
+	}
+	for i := 0; i < 10; i++ {
+		h.Record(10 * time.Second)
+	}
+	if p50 := h.Percentile(50); p50 > bucketBound(0) {
+		t.Fatalf("expected p50 in the first bucket, got %v", p50)
+	}
+	if p99 := h.Percentile(99); p99 < 5*time.Second || p99 > 15*time.Second {
+		t.Fatalf("expected p99 near 10s, got %v", p99)
+	}
+
+	snap := h.Snapshot()
+	if snap.Count != 100 || snap.Buckets[0].Count != 90 {
+		t.Fatalf("unexpected snapshot counts: total %d, first bucket %d", snap.Count, snap.Buckets[0].Count)
+	}
+}
+
+func TestStatsEndpointReportsLatency(t *testing.T) {
+	s, err := NewServer(WithDelay(time.Second), WithErrorRate(0))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		poll(t, s)
+		expireJob(s)
+		poll(t, s)
+	}
+
+	rec := httptest.NewRecorder()
+	s.routes().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/stats", nil))
+	var stats statsResponse
+	if err := json.NewDecoder(rec.Body).Decode(&stats); err != nil {
+		t.Fatalf("decoding stats: %v", err)
+	}
+	if stats.CompletedCount != 3 {
+		t.Fatalf("expected 3 completed jobs, got %+v", stats)
+	}
+	// Every job took just over a second.
+	if stats.LatencyP50Ms < 1000 || stats.LatencyP99Ms > 2100 || stats.LatencyP50Ms > stats.LatencyP99Ms {
+		t.Fatalf("unexpected latency percentiles %+v", stats)
+	}
+}
@@ -0,0 +1,339 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"io"
+	"log"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"Video-Translation-Simulator/pkg/trace"
+)
+
+/*
+	HTTP middleware wrapped around the server's routes. Each middleware is a
+	func(http.Handler) http.Handler so they compose in Server.handler.
+*/
+
+// trackConcurrency counts in-flight requests and, when a maximum concurrency is
+// configured, rejects requests beyond it with 503 instead of queueing them.
+func (s *Server) trackConcurrency(next http.Handler) http.Handler {
+	var slots chan struct{}
+	if s.maxConcurrency > 0 {
+		slots = make(chan struct{}, s.maxConcurrency)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if slots != nil {
+			select {
+			case slots <- struct{}{}:
+				defer func() { <-slots }()
+			default:
+				log.Printf("Concurrency limit of %d reached, rejecting %s", s.maxConcurrency, r.URL.Path)
+				w.Header().Set("Retry-After", "1")
+				http.Error(w, "server is at capacity", http.StatusServiceUnavailable)
+				return
+			}
+		}
+
+		active := s.active.Add(1)
+		defer s.active.Add(-1)
+		for {
+			peak := s.peak.Load()
+			if active <= peak || s.peak.CompareAndSwap(peak, active) {
+				break
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// realIPKey is the context key RealIPMiddleware stores the client IP under.
+type realIPKey struct{}
+
+// RealIPMiddleware works out the IP of the client behind a proxy. Forwarding
+// headers are only believed when the direct peer is one of trustedProxies,
+// since anyone else can set them to whatever they like. X-Forwarded-For is read
+// right to left, skipping trusted proxies, with X-Real-IP as the fallback. The
+// result is available to later handlers through RealIPFromContext.
+func RealIPMiddleware(trustedProxies []net.IP) func(http.Handler) http.Handler {
+	trusted := func(ip net.IP) bool {
+		for _, p := range trustedProxies {
+			if p.Equal(ip) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := remoteIP(r)
+			if ip != nil && trusted(ip) {
+				if forwarded := forwardedIP(r, trusted); forwarded != nil {
+					ip = forwarded
+				}
+			}
+			if ip != nil {
+				r = r.WithContext(context.WithValue(r.Context(), realIPKey{}, ip.String()))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RealIPFromContext returns the client IP set by RealIPMiddleware, or an empty
+// string when the middleware did not run.
+func RealIPFromContext(ctx context.Context) string {
+	ip, _ := ctx.Value(realIPKey{}).(string)
+	return ip
+}
+
+// clientIP returns the client's IP as worked out by RealIPMiddleware, falling
+// back to the direct peer, or an empty string if neither is known.
+func clientIP(r *http.Request) string {
+	if ip := RealIPFromContext(r.Context()); ip != "" {
+		return ip
+	}
+	if remote := remoteIP(r); remote != nil {
+		return remote.String()
+	}
+	return ""
+}
+
+// remoteIP returns the IP of the direct peer.
+func remoteIP(r *http.Request) net.IP {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return net.ParseIP(host)
+}
+
+// forwardedIP returns the first untrusted address in X-Forwarded-For counting
+// from the right, or X-Real-IP if there is none.
+func forwardedIP(r *http.Request, trusted func(net.IP) bool) net.IP {
+	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+		hops := strings.Split(xff, ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			ip := net.ParseIP(strings.TrimSpace(hops[i]))
+			if ip == nil {
+				break
+			}
+			if !trusted(ip) || i == 0 {
+				return ip
+			}
+		}
+	}
+	return net.ParseIP(strings.TrimSpace(r.Header.Get("X-Real-IP")))
+}
+
+// APIKeyHeader is the request header APIKeyMiddleware reads the key from.
+const APIKeyHeader = "X-API-Key"
+
+// APIKeyMiddleware rejects requests with 401 unless they carry key in the
+// X-API-Key header.
+func APIKeyMiddleware(key string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			given := r.Header.Get(APIKeyHeader)
+			if subtle.ConstantTimeCompare([]byte(given), []byte(key)) != 1 {
+				log.Printf("Rejecting %s %s with a missing or invalid API key", r.Method, r.URL.Path)
+				http.Error(w, "invalid API key", http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// WithAuthExclusions wraps an authentication middleware so requests matching
+// exclude bypass it.
+func WithAuthExclusions(exclude func(r *http.Request) bool, auth func(http.Handler) http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		authed := auth(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if exclude(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			authed.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ExcludePaths returns a predicate matching requests for exactly one of paths.
+func ExcludePaths(paths ...string) func(r *http.Request) bool {
+	set := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		set[p] = true
+	}
+	return func(r *http.Request) bool {
+		return set[r.URL.Path]
+	}
+}
+
+// SlowReadMiddleware limits the response body to bytesPerMs bytes per
+// millisecond, flushing each chunk, to test how clients cope with a body that
+// trickles in. Values below 1 are treated as 1.
+func SlowReadMiddleware(bytesPerMs int) func(http.Handler) http.Handler {
+	if bytesPerMs < 1 {
+		bytesPerMs = 1
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(&slowWriter{ResponseWriter: w, chunk: bytesPerMs}, r)
+		})
+	}
+}
+
+// slowWriter writes chunk bytes at a time, pausing a millisecond between them.
+type slowWriter struct {
+	http.ResponseWriter
+	chunk int
+}
+
+func (w *slowWriter) Write(p []byte) (int, error) {
+	flusher, _ := w.ResponseWriter.(http.Flusher)
+	written := 0
+	for written < len(p) {
+		end := written + w.chunk
+		if end > len(p) {
+			end = len(p)
+		}
+		n, err := w.ResponseWriter.Write(p[written:end])
+		written += n
+		if err != nil {
+			return written, err
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return written, nil
+}
+
+// Flush passes through to the underlying writer, so streaming handlers such
+// as SSE still see an http.Flusher.
+func (w *slowWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *slowWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// BodyLoggingMiddleware logs up to maxBytes of each request and response body
+// at debug level, as request_body_sample and response_body_sample, for
+// debugging what clients send and get back. The handler and the client still
+// see the bodies in full. It does nothing unless logger has debug enabled. A
+// nil logger means slog.Default().
+func BodyLoggingMiddleware(maxBytes int, logger *slog.Logger) func(http.Handler) http.Handler {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if maxBytes <= 0 || !logger.Enabled(r.Context(), slog.LevelDebug) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			var requestSample []byte
+			if r.Body != nil && r.Body != http.NoBody {
+				requestSample, _ = io.ReadAll(io.LimitReader(r.Body, int64(maxBytes)))
+				// Put back what was read in front of the rest of the body.
+				r.Body = readCloser{io.MultiReader(bytes.NewReader(requestSample), r.Body), r.Body}
+			}
+
+			cw := &capturingWriter{ResponseWriter: w, max: maxBytes, code: http.StatusOK}
+			next.ServeHTTP(cw, r)
+
+			logger.DebugContext(r.Context(), "request bodies",
+				"method", r.Method,
+				"path", r.URL.Path,
+				"status", cw.code,
+				"request_body_sample", string(requestSample),
+				"response_body_sample", cw.sample.String(),
+			)
+		})
+	}
+}
+
+// readCloser reads from Reader and closes Closer.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// capturingWriter keeps a copy of the status code and the first max bytes
+// written, passing everything through unchanged.
+type capturingWriter struct {
+	http.ResponseWriter
+	max    int
+	code   int
+	sample bytes.Buffer
+}
+
+func (w *capturingWriter) WriteHeader(code int) {
+	w.code = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *capturingWriter) Write(p []byte) (int, error) {
+	if room := w.max - w.sample.Len(); room > 0 {
+		if len(p) < room {
+			room = len(p)
+		}
+		w.sample.Write(p[:room])
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// Flush passes through to the underlying writer, so streaming handlers such
+// as SSE still see an http.Flusher.
+func (w *capturingWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *capturingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// TraceIDHeader carries the trace ID of a request handled with WithTracing.
+const TraceIDHeader = "X-Trace-Id"
+
+// maxTraceIDLength bounds trace IDs taken from requests, which end up in memory.
+const maxTraceIDLength = 64
+
+// tracing starts a trace for each request, continuing the one named in the
+// X-Trace-Id header if there is one, and stores it for GetTrace.
+func (s *Server) tracing(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var t *trace.Trace
+		if id := r.Header.Get(TraceIDHeader); id != "" && len(id) <= maxTraceIDLength {
+			t = trace.NewTraceWithID(id)
+		} else {
+			t = trace.NewTrace()
+		}
+		t.Attributes["method"] = r.Method
+		t.Attributes["path"] = r.URL.Path
+		s.traces.Add(t)
+
+		w.Header().Set(TraceIDHeader, t.TraceID)
+		next.ServeHTTP(w, r.WithContext(trace.ContextWithTrace(r.Context(), t)))
+	})
+}
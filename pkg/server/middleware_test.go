@@ -0,0 +1,306 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"Video-Translation-Simulator/pkg/client"
+)
+
+func TestMaxConcurrency(t *testing.T) {
+	s, err := NewServer(WithDelay(time.Second), WithErrorRate(0), WithMaxConcurrency(5))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+
+	var inFlight, maxSeen atomic.Int64
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			m := maxSeen.Load()
+			if n <= m || maxSeen.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+	})
+	srv := httptest.NewServer(s.trackConcurrency(slow))
+	defer srv.Close()
+
+	var wg sync.WaitGroup
+	var ok, rejected atomic.Int64
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := http.Get(srv.URL + "/status")
+			if err != nil {
+				t.Errorf("request failed: %v", err)
+				return
+			}
+			resp.Body.Close()
+			switch resp.StatusCode {
+			case http.StatusOK:
+				ok.Add(1)
+			case http.StatusServiceUnavailable:
+				if resp.Header.Get("Retry-After") != "1" {
+					t.Errorf("expected Retry-After: 1 on rejection")
+				}
+				rejected.Add(1)
+			default:
+				t.Errorf("unexpected status %d", resp.StatusCode)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if maxSeen.Load() > 5 {
+		t.Fatalf("handler saw %d concurrent requests, limit is 5", maxSeen.Load())
+	}
+	if peak := s.PeakConcurrency(); peak > 5 || peak < 1 {
+		t.Fatalf("expected peak concurrency between 1 and 5, got %d", peak)
+	}
+	if ok.Load()+rejected.Load() != 100 || ok.Load() == 0 {
+		t.Fatalf("expected all requests answered with some served, got %d ok and %d rejected", ok.Load(), rejected.Load())
+	}
+	if s.ActiveRequests() != 0 {
+		t.Fatalf("expected no active requests after completion, got %d", s.ActiveRequests())
+	}
+}
+
+func TestRealIPMiddleware(t *testing.T) {
+	proxy := net.ParseIP("10.0.0.1")
+	handler := RealIPMiddleware([]net.IP{proxy})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(RealIPFromContext(r.Context())))
+	}))
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		headers    map[string]string
+		want       string
+	}{
+		{
+			name:       "direct connection uses RemoteAddr",
+			remoteAddr: "203.0.113.7:5555",
+			want:       "203.0.113.7",
+		},
+		{
+			name:       "trusted proxy uses X-Forwarded-For",
+			remoteAddr: "10.0.0.1:5555",
+			headers:    map[string]string{"X-Forwarded-For": "198.51.100.2"},
+			want:       "198.51.100.2",
+		},
+		{
+			name:       "trusted proxy skips itself in the chain",
+			remoteAddr: "10.0.0.1:5555",
+			headers:    map[string]string{"X-Forwarded-For": "192.0.2.99, 198.51.100.2, 10.0.0.1"},
+			want:       "198.51.100.2",
+		},
+		{
+			name:       "trusted proxy falls back to X-Real-IP",
+			remoteAddr: "10.0.0.1:5555",
+			headers:    map[string]string{"X-Real-IP": "198.51.100.3"},
+			want:       "198.51.100.3",
+		},
+		{
+			name:       "untrusted proxy headers are ignored",
+			remoteAddr: "203.0.113.7:5555",
+			headers:    map[string]string{"X-Forwarded-For": "198.51.100.2", "X-Real-IP": "198.51.100.3"},
+			want:       "203.0.113.7",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/status", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if got := rec.Body.String(); got != tt.want {
+				t.Fatalf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSlowReadMiddleware(t *testing.T) {
+	body := `{"result":"completed","padding":"xxxxxxxxxxxxxx"}` + "\n"
+	if len(body) != 50 {
+		t.Fatalf("test body is %d bytes, expected 50", len(body))
+	}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, body)
+	})
+	srv := httptest.NewServer(SlowReadMiddleware(1)(handler))
+	defer srv.Close()
+
+	c := client.NewClient(srv.URL, log.New(io.Discard, "", 0))
+	start := time.Now()
+	status, err := c.RetrieveStatus(context.Background())
+	elapsed := time.Since(start)
+	if err != nil || status != "completed" {
+		t.Fatalf("expected completed, got %q, %v", status, err)
+	}
+	if elapsed < 50*time.Millisecond {
+		t.Fatalf("expected the body to take at least 50ms, took %v", elapsed)
+	}
+	if elapsed > 200*time.Millisecond {
+		t.Fatalf("expected the body within 200ms, took %v", elapsed)
+	}
+}
+
+func TestSlowReadMiddlewareKeepsFlusher(t *testing.T) {
+	h := SlowReadMiddleware(1)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := w.(http.Flusher); !ok {
+			http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+			return
+		}
+		io.WriteString(w, "ok")
+	}))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/events", nil))
+	if rec.Code != http.StatusOK || !rec.Flushed {
+		t.Fatalf("expected the handler to stream through the middleware, got %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestAuthExclude(t *testing.T) {
+	s, err := NewServer(WithDelay(time.Second), WithErrorRate(0), WithAPIKey("secret"), WithAuthExclude(ExcludePaths("/public")))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	h := s.handler()
+	get := func(path, key string) int {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if key != "" {
+			req.Header.Set(APIKeyHeader, key)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	tests := []struct {
+		path, key string
+		want      int
+	}{
+		{path: "/status", want: http.StatusUnauthorized},
+		{path: "/status", key: "wrong", want: http.StatusUnauthorized},
+		{path: "/status", key: "secret", want: http.StatusOK},
+		{path: "/healthz", want: http.StatusOK},
+		{path: "/stats", want: http.StatusOK},
+		{path: "/public", want: http.StatusNotFound}, // Excluded, so it reaches the mux.
+	}
+	for _, tt := range tests {
+		if got := get(tt.path, tt.key); got != tt.want {
+			t.Errorf("GET %s with key %q: expected %d, got %d", tt.path, tt.key, tt.want, got)
+		}
+	}
+}
+
+func TestAuthOnlyOnAdminWithoutExclude(t *testing.T) {
+	s, err := NewServer(WithDelay(time.Second), WithErrorRate(0), WithAPIKey("secret"))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	rec := httptest.NewRecorder()
+	s.handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/status", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected /status to stay open without WithAuthExclude, got %d", rec.Code)
+	}
+}
+
+func TestBodyLoggingMiddleware(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	requestBody := strings.Repeat("r", 100)
+	responseBody := strings.Repeat("s", 100)
+	var received string
+	h := BodyLoggingMiddleware(10, logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = string(body)
+		w.WriteHeader(http.StatusCreated)
+		io.WriteString(w, responseBody[:50])
+		io.WriteString(w, responseBody[50:])
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/status", strings.NewReader(requestBody)))
+
+	if received != requestBody {
+		t.Fatalf("handler got a %d byte body, expected %d", len(received), len(requestBody))
+	}
+	if rec.Code != http.StatusCreated || rec.Body.String() != responseBody {
+		t.Fatalf("response was changed: %d, %d bytes", rec.Code, rec.Body.Len())
+	}
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(logs.Bytes(), &entry); err != nil {
+		t.Fatalf("decoding log line %q: %v", logs.String(), err)
+	}
+	if entry["request_body_sample"] != requestBody[:10] || entry["response_body_sample"] != responseBody[:10] {
+		t.Fatalf("expected 10 byte samples, got %v", entry)
+	}
+	if entry["status"] != float64(http.StatusCreated) {
+		t.Fatalf("expected status 201 in the log, got %v", entry["status"])
+	}
+}
+
+func TestBodyLoggingMiddlewareNeedsDebug(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: slog.LevelInfo}))
+	h := BodyLoggingMiddleware(10, logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/status", strings.NewReader("body")))
+	if logs.Len() != 0 {
+		t.Fatalf("expected nothing logged without debug, got %s", logs.String())
+	}
+}
+
+func TestBodyLoggingMiddlewareKeepsFlusher(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	h := BodyLoggingMiddleware(10, logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := w.(http.Flusher); !ok {
+			http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+			return
+		}
+		io.WriteString(w, "ok")
+	}))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/events", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected the handler to stream through the middleware, got %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestBodyLoggingMiddlewareNilLogger(t *testing.T) {
+	h := BodyLoggingMiddleware(10, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/status", strings.NewReader("body")))
+	if rec.Body.String() != "ok" {
+		t.Fatalf("expected the response through a nil logger, got %q", rec.Body.String())
+	}
+}
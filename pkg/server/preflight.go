@@ -0,0 +1,124 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+)
+
+// PreFlightCheck verifies one requirement of the environment before the
+// server starts.
+type PreFlightCheck interface {
+	Check() error
+	Name() string
+}
+
+// WithPreFlightChecks adds checks for NewServer to run on top of the built-in
+// config check. NewServer fails if any of them does.
+func WithPreFlightChecks(checks ...PreFlightCheck) ServerOption {
+	return func(s *Server) {
+		s.preFlights = append(s.preFlights, checks...)
+	}
+}
+
+// RunPreFlights runs every check, not stopping at the first failure, and
+// returns their errors joined together, each prefixed with the check's name.
+func RunPreFlights(checks []PreFlightCheck) error {
+	var errs []error
+	for _, c := range checks {
+		if err := c.Check(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", c.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// PortAvailableCheck checks nothing else is listening on Port.
+type PortAvailableCheck struct {
+	Port int
+}
+
+func (c PortAvailableCheck) Name() string {
+	return "port " + strconv.Itoa(c.Port)
+}
+
+func (c PortAvailableCheck) Check() error {
+	ln, err := net.Listen("tcp", ":"+strconv.Itoa(c.Port))
+	if err != nil {
+		return fmt.Errorf("port is not available: %w", err)
+	}
+	return ln.Close()
+}
+
+// ConfigValidCheck checks the delay, error rate and error windows in Config
+// are within range.
+type ConfigValidCheck struct {
+	Config *Config
+}
+
+func (c ConfigValidCheck) Name() string {
+	return "config"
+}
+
+func (c ConfigValidCheck) Check() error {
+	if c.Config == nil {
+		return errors.New("no config given")
+	}
+	if err := validateDelay(c.Config.DelaySeconds); err != nil {
+		return err
+	}
+	if err := validateErrorRate(c.Config.ErrorRate); err != nil {
+		return err
+	}
+	if p := c.Config.ProgressiveDelay; p != nil && (p.BaseDelay <= 0 || p.DoublingInterval <= 0) {
+		return fmt.Errorf("invalid progressive delay %ds every %d jobs", p.BaseDelay, p.DoublingInterval)
+	}
+	if c.Config.MaxPendingAge < 0 {
+		return fmt.Errorf("invalid max pending age %v, must not be negative", c.Config.MaxPendingAge)
+	}
+	if c.Config.OutboundWebhookConcurrency < 0 {
+		return fmt.Errorf("invalid outbound webhook concurrency %d, must not be negative", c.Config.OutboundWebhookConcurrency)
+	}
+	if c.Config.MaxWebhookRetries < 0 {
+		return fmt.Errorf("invalid max webhook retries %d, must not be negative", c.Config.MaxWebhookRetries)
+	}
+	if c.Config.WebhookRetryInterval < 0 {
+		return fmt.Errorf("invalid webhook retry interval %v, must not be negative", c.Config.WebhookRetryInterval)
+	}
+	for _, w := range c.Config.ErrorWindows {
+		if err := validateErrorRate(w.Rate); err != nil {
+			return fmt.Errorf("error window %v-%v: %w", w.StartOffset, w.EndOffset, err)
+		}
+		if w.EndOffset <= w.StartOffset {
+			return fmt.Errorf("error window %v-%v ends before it starts", w.StartOffset, w.EndOffset)
+		}
+	}
+	return nil
+}
+
+// WritableDirectoryCheck checks Path is a directory files can be created in.
+type WritableDirectoryCheck struct {
+	Path string
+}
+
+func (c WritableDirectoryCheck) Name() string {
+	return "directory " + c.Path
+}
+
+func (c WritableDirectoryCheck) Check() error {
+	info, err := os.Stat(c.Path)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return errors.New("not a directory")
+	}
+	f, err := os.CreateTemp(c.Path, ".preflight-*")
+	if err != nil {
+		return fmt.Errorf("not writable: %w", err)
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
@@ -0,0 +1,121 @@
+package server_test
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"Video-Translation-Simulator/pkg/server"
+	"Video-Translation-Simulator/pkg/testutil/fixtures"
+)
+
+func TestPortAvailableCheck(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	if err := (server.PortAvailableCheck{Port: port}).Check(); err == nil {
+		t.Fatal("expected a port in use to fail the check")
+	}
+	ln.Close()
+	if err := (server.PortAvailableCheck{Port: port}).Check(); err != nil {
+		t.Fatalf("expected a free port to pass, got %v", err)
+	}
+}
+
+func TestConfigValidCheck(t *testing.T) {
+	tests := []struct {
+		name   string
+		config *server.Config
+		valid  bool
+	}{
+		{"valid", fixtures.FixtureFlakeyServer, true},
+		{"nil", nil, false},
+		{"zero delay", fixtures.FixtureInstantComplete, true},
+		{"negative delay", &server.Config{DelaySeconds: -1, ErrorRate: 20}, false},
+		{"error rate over 100", &server.Config{DelaySeconds: 5, ErrorRate: 101}, false},
+		{"bad progressive delay", &server.Config{DelaySeconds: 5, ProgressiveDelay: &server.ProgressiveDelayConfig{BaseDelay: 1}}, false},
+		{"negative max pending age", &server.Config{DelaySeconds: 5, MaxPendingAge: -time.Second}, false},
+		{"bad error window", &server.Config{DelaySeconds: 5, ErrorWindows: []server.TimeWindow{{StartOffset: time.Second, EndOffset: time.Second, Rate: 50}}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := server.ConfigValidCheck{Config: tt.config}.Check()
+			if tt.valid && err != nil {
+				t.Fatalf("expected the config to pass, got %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Fatal("expected the config to fail")
+			}
+		})
+	}
+}
+
+func TestWritableDirectoryCheck(t *testing.T) {
+	dir := t.TempDir()
+	if err := (server.WritableDirectoryCheck{Path: dir}).Check(); err != nil {
+		t.Fatalf("expected a temp dir to pass, got %v", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Fatalf("expected the check to clean up after itself, found %d files", len(entries))
+	}
+
+	if err := (server.WritableDirectoryCheck{Path: filepath.Join(dir, "missing")}).Check(); err == nil {
+		t.Fatal("expected a missing directory to fail")
+	}
+	file := filepath.Join(dir, "file")
+	if err := os.WriteFile(file, nil, 0o644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	if err := (server.WritableDirectoryCheck{Path: file}).Check(); err == nil {
+		t.Fatal("expected a regular file to fail")
+	}
+	if os.Geteuid() != 0 {
+		readOnly := filepath.Join(dir, "ro")
+		os.Mkdir(readOnly, 0o555)
+		if err := (server.WritableDirectoryCheck{Path: readOnly}).Check(); err == nil {
+			t.Fatal("expected a read-only directory to fail")
+		}
+	}
+}
+
+type failingCheck struct{ name string }
+
+func (c failingCheck) Name() string { return c.name }
+func (c failingCheck) Check() error { return errors.New("failed") }
+
+func TestRunPreFlightsReportsEveryFailure(t *testing.T) {
+	err := server.RunPreFlights([]server.PreFlightCheck{
+		failingCheck{"first"},
+		server.ConfigValidCheck{Config: fixtures.FixtureFastServer},
+		failingCheck{"second"},
+	})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, name := range []string{"first", "second"} {
+		if !strings.Contains(err.Error(), name+": failed") {
+			t.Errorf("expected %q in %q", name, err)
+		}
+	}
+
+	if err := server.RunPreFlights(nil); err != nil {
+		t.Fatalf("expected no checks to pass, got %v", err)
+	}
+}
+
+func TestNewServerRunsPreFlights(t *testing.T) {
+	if _, err := server.NewServer(server.WithConfig(fixtures.FixtureFastServer), server.WithPreFlightChecks(failingCheck{"custom"})); err == nil || !strings.Contains(err.Error(), "custom") {
+		t.Fatalf("expected server.NewServer to fail on the custom check, got %v", err)
+	}
+	if _, err := server.NewServer(server.WithConfig(fixtures.FixtureFastServer), server.WithPreFlightChecks(server.WritableDirectoryCheck{Path: t.TempDir()})); err != nil {
+		t.Fatalf("expected server.NewServer to pass, got %v", err)
+	}
+}
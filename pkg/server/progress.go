@@ -0,0 +1,100 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// Limits on the progress kept per job, which is held in memory.
+const (
+	maxProgressUpdates      = 100 // Oldest dropped first.
+	maxProgressSubtaskBytes = 64
+	maxProgressMessageBytes = 256
+)
+
+// ProgressUpdate is a report of a job's progress, sent by a worker with POST
+// /jobs/{id}/progress.
+type ProgressUpdate struct {
+	Subtask    string    `json:"subtask"` // e.g. "transcription".
+	Percent    int       `json:"percent"` // Of the whole job, 0 to 100.
+	Message    string    `json:"message,omitempty"`
+	ReportedAt time.Time `json:"reported_at"` // Set by the server.
+}
+
+// ProgressHistory is the body answering GET /jobs/{id}/progress.
+type ProgressHistory struct {
+	JobID   string           `json:"job_id"`
+	Updates []ProgressUpdate `json:"updates"` // Oldest first, the most recent 100.
+}
+
+// jobProgress serves POST /jobs/{id}/progress, recording a ProgressUpdate for
+// the job with id and responding with 204, and GET /jobs/{id}/progress,
+// responding with its ProgressHistory. The latest percent is also given as
+// progress by the job's status. It responds with 404 if there is no such job,
+// and 409 to updates once the job has finished.
+func (s *Server) jobProgress(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var update ProgressUpdate
+	if r.Method == http.MethodPost {
+		if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+			http.Error(w, "invalid JSON body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := update.validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		update.ReportedAt = time.Now()
+	}
+
+	s.mu.Lock()
+	job := s.jobs[id]
+	if job == nil {
+		s.mu.Unlock()
+		http.Error(w, "job "+id+" not found", http.StatusNotFound)
+		return
+	}
+	s.advanceJob(job)
+	if r.Method == http.MethodGet {
+		history := ProgressHistory{JobID: id, Updates: append([]ProgressUpdate{}, job.progressUpdates...)}
+		s.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(history); err != nil {
+			log.Printf("Error encoding progress history: %v", err)
+		}
+		return
+	}
+	if finished(job.status) {
+		s.mu.Unlock()
+		http.Error(w, "job "+id+" is "+job.status+", finished jobs take no progress", http.StatusConflict)
+		return
+	}
+	if len(job.progressUpdates) == maxProgressUpdates {
+		job.progressUpdates = job.progressUpdates[1:]
+	}
+	job.progressUpdates = append(job.progressUpdates, update)
+	s.mu.Unlock()
+	log.Printf("Job %s at %d%%, %s: %s", id, update.Percent, update.Subtask, update.Message)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// validate checks u is within the limits of what is kept with a job.
+func (u *ProgressUpdate) validate() error {
+	if u.Percent < 0 || u.Percent > 100 {
+		return fmt.Errorf("invalid percent %d, must be 0 to 100", u.Percent)
+	}
+	if len(u.Subtask) > maxProgressSubtaskBytes {
+		return fmt.Errorf("subtask too long, at most %d bytes are kept", maxProgressSubtaskBytes)
+	}
+	if len(u.Message) > maxProgressMessageBytes {
+		return fmt.Errorf("message too long, at most %d bytes are kept", maxProgressMessageBytes)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJobProgress(t *testing.T) {
+	s, err := NewServer(WithDelay(time.Hour), WithErrorRate(0))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	do := func(method, target, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.routes().ServeHTTP(rec, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return rec
+	}
+	status := func() Response {
+		var resp Response
+		if err := json.NewDecoder(do(http.MethodGet, "/jobs/long/status", "").Body).Decode(&resp); err != nil {
+			t.Fatalf("decoding status: %v", err)
+		}
+		return resp
+	}
+	if rec := do(http.MethodPost, "/jobs", `{"id":"long"}`); rec.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d", rec.Code)
+	}
+	if resp := status(); resp.Progress != nil {
+		t.Fatalf("expected no progress before any is reported, got %d", *resp.Progress)
+	}
+
+	for _, body := range []string{
+		`{"subtask":"transcription","percent":20,"message":"processing frame 200/1000"}`,
+		`{"subtask":"transcription","percent":45,"message":"processing frame 450/1000"}`,
+	} {
+		if rec := do(http.MethodPost, "/jobs/long/progress", body); rec.Code != http.StatusNoContent {
+			t.Fatalf("expected 204, got %d: %s", rec.Code, rec.Body)
+		}
+	}
+	if resp := status(); resp.Result != "pending" || resp.Progress == nil || *resp.Progress != 45 {
+		t.Fatalf("expected pending at 45%%, got %+v", resp)
+	}
+
+	var history ProgressHistory
+	if err := json.NewDecoder(do(http.MethodGet, "/jobs/long/progress", "").Body).Decode(&history); err != nil {
+		t.Fatalf("decoding history: %v", err)
+	}
+	if history.JobID != "long" || len(history.Updates) != 2 {
+		t.Fatalf("expected 2 updates of job long, got %+v", history)
+	}
+	if u := history.Updates[1]; u.Subtask != "transcription" || u.Percent != 45 || u.Message != "processing frame 450/1000" || u.ReportedAt.IsZero() {
+		t.Fatalf("unexpected latest update %+v", u)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		code   int
+	}{
+		{"percent over 100", http.MethodPost, "/jobs/long/progress", `{"percent":101}`, http.StatusBadRequest},
+		{"invalid body", http.MethodPost, "/jobs/long/progress", `{`, http.StatusBadRequest},
+		{"unknown job", http.MethodGet, "/jobs/nope/progress", "", http.StatusNotFound},
+		{"wrong method", http.MethodDelete, "/jobs/long/progress", "", http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		if rec := do(tt.method, tt.target, tt.body); rec.Code != tt.code {
+			t.Fatalf("%s: expected %d, got %d", tt.name, tt.code, rec.Code)
+		}
+	}
+
+	s.mu.Lock()
+	s.jobs["long"].status = "completed"
+	s.mu.Unlock()
+	if rec := do(http.MethodPost, "/jobs/long/progress", `{"percent":100}`); rec.Code != http.StatusConflict {
+		t.Fatalf("expected 409 once the job finished, got %d", rec.Code)
+	}
+}
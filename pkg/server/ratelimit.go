@@ -0,0 +1,227 @@
+package server
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// rateLimitSweepInterval is how often a QueuingRateLimiter forgets clients
+// whose bucket has refilled, so it doesn't keep one for every IP it has seen.
+const rateLimitSweepInterval = time.Minute
+
+// QueuingRateLimiter limits each client, told apart by IP, to a token rate,
+// holding requests over the rate in a bounded queue until a token frees up
+// instead of rejecting them. Only once the client's queue is full are its
+// requests turned away with 429.
+type QueuingRateLimiter struct {
+	mu       sync.Mutex
+	rate     float64 // Tokens per second.
+	burst    float64
+	queueCap int
+	clients  map[string]*rateBucket
+	sweptAt  time.Time
+}
+
+// rateBucket is the token bucket and queue of a single client.
+type rateBucket struct {
+	tokens    float64 // Goes negative for tokens reserved by queued requests.
+	updatedAt time.Time
+	seenAt    time.Time // When a request last fetched the bucket.
+
+	queue chan struct{} // Holds a slot for every queued request.
+}
+
+// NewQueuingRateLimiter allows each client rps requests per second with
+// bursts of up to burst, and queues up to queueCap of its requests beyond that.
+// With a non-positive rps no tokens come back, so each client gets burst
+// requests and every one after is rejected rather than queued.
+func NewQueuingRateLimiter(rps float64, burst, queueCap int) *QueuingRateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	if queueCap < 0 {
+		queueCap = 0
+	}
+	return &QueuingRateLimiter{
+		rate:     rps,
+		burst:    float64(burst),
+		queueCap: queueCap,
+		clients:  make(map[string]*rateBucket),
+		sweptAt:  time.Now(),
+	}
+}
+
+// WithQueuingRateLimiter rate limits every request to the server with a
+// QueuingRateLimiter. It is the server's only rate limit, so responses carry
+// the X-RateLimit headers only when it is configured.
+func WithQueuingRateLimiter(rps float64, burst, queueCap int) ServerOption {
+	return func(s *Server) {
+		s.rateLimiter = NewQueuingRateLimiter(rps, burst, queueCap)
+	}
+}
+
+// QueueDepth returns the number of requests waiting for a token, across all
+// clients.
+func (l *QueuingRateLimiter) QueueDepth() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	depth := 0
+	for _, b := range l.clients {
+		depth += len(b.queue)
+	}
+	return depth
+}
+
+// Middleware applies the rate limit to next, keyed by the IP found by
+// RealIPMiddleware. Every response carries the client's limit in the
+// X-RateLimit-Limit, X-RateLimit-Remaining and X-RateLimit-Reset headers. Reset
+// is the Unix time in seconds when the next request will be let through if
+// none remain, and otherwise when the bucket is full again. It is left out
+// when the limiter has no rate to refill at.
+func (l *QueuingRateLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b := l.bucket(clientIP(r))
+		if l.take(b) {
+			l.setHeaders(w.Header(), b)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		reject := func(reason string) {
+			l.setHeaders(w.Header(), b)
+			log.Printf("%s, rejecting %s", reason, r.URL.Path)
+			w.Header().Set("Retry-After", "1")
+			http.Error(w, "too many requests", http.StatusTooManyRequests)
+		}
+		// Without a rate a queued request would never get a token.
+		if l.rate <= 0 {
+			reject("Rate limit has no tokens left")
+			return
+		}
+		select {
+		case b.queue <- struct{}{}:
+		default:
+			reject("Rate limit queue is full")
+			return
+		}
+		wait := l.reserve(b)
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-r.Context().Done():
+			// The reserved token is not handed back; the next request waits a little longer.
+			timer.Stop()
+			<-b.queue
+			return
+		}
+		<-b.queue
+		l.setHeaders(w.Header(), b)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// setHeaders sets the rate limit headers for the client owning b.
+func (l *QueuingRateLimiter) setHeaders(h http.Header, b *rateBucket) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	l.refill(b, now)
+	remaining := int(math.Floor(b.tokens))
+	if remaining < 0 {
+		remaining = 0
+	}
+	h.Set("X-RateLimit-Limit", strconv.Itoa(int(l.burst)))
+	h.Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+	if l.rate > 0 {
+		// Clients wait for Reset when none remain, so then it is the next token.
+		target := l.burst
+		if remaining == 0 {
+			target = 1
+		}
+		at := now.Add(time.Duration((target - b.tokens) / l.rate * float64(time.Second)))
+		reset := at.Unix()
+		if at.After(time.Unix(reset, 0)) {
+			reset++ // Round up, so clients don't come back early.
+		}
+		h.Set("X-RateLimit-Reset", strconv.FormatInt(reset, 10))
+	}
+}
+
+// bucket returns the bucket for the client at ip, creating a full one for a
+// client not seen before.
+func (l *QueuingRateLimiter) bucket(ip string) *rateBucket {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	if now.Sub(l.sweptAt) >= rateLimitSweepInterval {
+		l.sweep(now)
+	}
+	b, ok := l.clients[ip]
+	if !ok {
+		b = &rateBucket{
+			tokens:    l.burst,
+			updatedAt: now,
+			queue:     make(chan struct{}, l.queueCap),
+		}
+		l.clients[ip] = b
+	}
+	b.seenAt = now
+	return b
+}
+
+// sweep drops the buckets which are full with nothing queued, as they are no
+// different from a new one. A bucket fetched within the time it takes to
+// refill is kept even so, as the request which fetched it may not have taken
+// its token yet; dropping it would let the client's next request start over
+// with a full bucket. Called with mu held.
+func (l *QueuingRateLimiter) sweep(now time.Time) {
+	window := time.Duration(math.MaxInt64)
+	if l.rate > 0 {
+		window = time.Duration(l.burst / l.rate * float64(time.Second))
+	}
+	for ip, b := range l.clients {
+		l.refill(b, now)
+		if now.Sub(b.seenAt) > window && b.tokens >= l.burst && len(b.queue) == 0 {
+			delete(l.clients, ip)
+		}
+	}
+	l.sweptAt = now
+}
+
+// refill adds the tokens b earned since its last update. Called with mu held.
+func (l *QueuingRateLimiter) refill(b *rateBucket, now time.Time) {
+	b.tokens += now.Sub(b.updatedAt).Seconds() * l.rate
+	if b.tokens > l.burst {
+		b.tokens = l.burst
+	}
+	b.updatedAt = now
+}
+
+// take uses one of b's tokens if one is available right now.
+func (l *QueuingRateLimiter) take(b *rateBucket) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refill(b, time.Now())
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// reserve takes the next of b's tokens to become available and returns how
+// long until it does.
+func (l *QueuingRateLimiter) reserve(b *rateBucket) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refill(b, time.Now())
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / l.rate * float64(time.Second))
+}
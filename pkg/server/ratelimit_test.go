@@ -0,0 +1,196 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestQueuingRateLimiterQueuesBurst(t *testing.T) {
+	// Ten tokens up front, refilled slowly enough that none come back while the burst arrives.
+	limiter := NewQueuingRateLimiter(10, 10, 5)
+	h := limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	var wg sync.WaitGroup
+	codes := make(chan int, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/status", nil))
+			codes <- rec.Code
+		}()
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for limiter.QueueDepth() < 5 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if depth := limiter.QueueDepth(); depth != 5 {
+		t.Fatalf("expected 5 queued requests, got %d", depth)
+	}
+
+	wg.Wait()
+	close(codes)
+	counts := map[int]int{}
+	for code := range codes {
+		counts[code]++
+	}
+	if counts[http.StatusOK] != 15 || counts[http.StatusTooManyRequests] != 5 {
+		t.Fatalf("expected 15 served and 5 rejected, got %v", counts)
+	}
+	if depth := limiter.QueueDepth(); depth != 0 {
+		t.Fatalf("expected the queue to drain, got %d", depth)
+	}
+}
+
+func TestWithQueuingRateLimiter(t *testing.T) {
+	s, err := NewServer(WithDelay(time.Second), WithErrorRate(0), WithQueuingRateLimiter(0, 1, 0))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	srv := httptest.NewServer(s.handler())
+	defer srv.Close()
+
+	for i, want := range []int{http.StatusOK, http.StatusTooManyRequests} {
+		resp, err := srv.Client().Get(srv.URL + "/healthz")
+		if err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Fatalf("request %d: expected %d, got %d", i, want, resp.StatusCode)
+		}
+	}
+}
+
+func TestQueuingRateLimiterWithoutRateRejects(t *testing.T) {
+	// The queue is never used, as no token would ever free up.
+	limiter := NewQueuingRateLimiter(0, 2, 5)
+	h := limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	for i, want := range []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests, http.StatusTooManyRequests} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/status", nil))
+		if rec.Code != want {
+			t.Fatalf("request %d: expected %d, got %d", i+1, want, rec.Code)
+		}
+	}
+	if depth := limiter.QueueDepth(); depth != 0 {
+		t.Fatalf("expected nothing queued, got %d", depth)
+	}
+}
+
+func TestQueuingRateLimiterIsPerClient(t *testing.T) {
+	limiter := NewQueuingRateLimiter(0, 1, 0)
+	h := RealIPMiddleware(nil)(limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})))
+	get := func(remoteAddr string) int {
+		req := httptest.NewRequest(http.MethodGet, "/status", nil)
+		req.RemoteAddr = remoteAddr
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := get("192.0.2.1:1000"); code != http.StatusOK {
+		t.Fatalf("expected the first client's request to be served, got %d", code)
+	}
+	if code := get("192.0.2.1:1001"); code != http.StatusTooManyRequests {
+		t.Fatalf("expected the first client to be limited on another port, got %d", code)
+	}
+	if code := get("192.0.2.2:1000"); code != http.StatusOK {
+		t.Fatalf("expected a second client to have its own limit, got %d", code)
+	}
+}
+
+func TestQueuingRateLimiterSetsHeaders(t *testing.T) {
+	limiter := NewQueuingRateLimiter(1, 2, 0)
+	h := limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	start := time.Now()
+	for i, want := range []struct {
+		code      int
+		remaining string
+	}{
+		{http.StatusOK, "1"},
+		{http.StatusOK, "0"},
+		{http.StatusTooManyRequests, "0"},
+	} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/status", nil))
+		if rec.Code != want.code {
+			t.Fatalf("request %d: expected %d, got %d", i, want.code, rec.Code)
+		}
+		if got := rec.Header().Get("X-RateLimit-Limit"); got != "2" {
+			t.Fatalf("request %d: expected a limit of 2, got %q", i, got)
+		}
+		if got := rec.Header().Get("X-RateLimit-Remaining"); got != want.remaining {
+			t.Fatalf("request %d: expected %s remaining, got %q", i, want.remaining, got)
+		}
+		reset, err := strconv.ParseInt(rec.Header().Get("X-RateLimit-Reset"), 10, 64)
+		if err != nil {
+			t.Fatalf("request %d: bad reset header: %v", i, err)
+		}
+		// Refilling the bucket takes at most 2s at 1 token a second.
+		if reset < start.Unix() || reset > start.Add(3*time.Second).Unix() {
+			t.Fatalf("request %d: reset %d is not within a few seconds of %d", i, reset, start.Unix())
+		}
+	}
+}
+
+func TestQueuingRateLimiterResetIsNextToken(t *testing.T) {
+	limiter := NewQueuingRateLimiter(1, 10, 0)
+	h := limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	start := time.Now()
+	var rec *httptest.ResponseRecorder
+	for i := 0; i < 10; i++ {
+		rec = httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/status", nil))
+	}
+	if got := rec.Header().Get("X-RateLimit-Remaining"); got != "0" {
+		t.Fatalf("expected the burst to be used up, got %q remaining", got)
+	}
+	reset, err := strconv.ParseInt(rec.Header().Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		t.Fatalf("bad reset header: %v", err)
+	}
+	// A token comes back after a second, long before the bucket of 10 is full.
+	if reset > start.Add(2*time.Second).Unix() {
+		t.Fatalf("expected reset within 2s of %d for the next token, got %d", start.Unix(), reset)
+	}
+}
+
+func TestQueuingRateLimiterSweepKeepsFetchedBuckets(t *testing.T) {
+	// Refills from empty in 200ms.
+	limiter := NewQueuingRateLimiter(10, 2, 0)
+	b := limiter.bucket("192.0.2.1")
+	sweep := func(after time.Duration) bool {
+		limiter.mu.Lock()
+		defer limiter.mu.Unlock()
+		limiter.sweep(b.seenAt.Add(after))
+		_, kept := limiter.clients["192.0.2.1"]
+		return kept
+	}
+
+	// Still full, but its request may be about to take a token.
+	if !sweep(100 * time.Millisecond) {
+		t.Fatal("expected a bucket fetched within the refill window to be kept")
+	}
+	if sweep(300 * time.Millisecond) {
+		t.Fatal("expected an idle full bucket to be dropped")
+	}
+}
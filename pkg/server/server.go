@@ -1,12 +1,27 @@
 package server
 
 import (
+    "bytes"
+    "context"
     "encoding/json"
+    "errors"
+    "fmt"
+    "io"
     "log"
+    "log/slog"
+    "net"
     "net/http"
+    "net/http/pprof"
+    "strconv"
     "sync"
+    "sync/atomic"
     "time"
 		"math/rand"
+
+    "google.golang.org/grpc"
+
+    "Video-Translation-Simulator/pkg/trace"
+    "Video-Translation-Simulator/pkg/types"
 )
 
 /*
@@ -18,93 +33,1038 @@ import (
 
 */
 
+// RetryBudgetHeader is set on "pending" and "scheduled" responses to the
+// number of seconds the job is expected to keep running, counting any wait
+// before a scheduled job starts.
+const RetryBudgetHeader = "X-Retry-Budget-Remaining"
+
 // Config holds the server configuration options.
 type Config struct {
 	DelaySeconds int // Delay before returning final status.
 	ErrorRate    int // Probability of returning "error" instead of "completed".
+
+	// ProgressiveDelay, when set, replaces DelaySeconds with a delay that grows
+	// as jobs complete, simulating a server under increasing load.
+	ProgressiveDelay *ProgressiveDelayConfig
+
+	// ErrorWindows override ErrorRate while a job's elapsed time falls inside one
+	// of them. The first matching window wins. They are only looked up when the
+	// job's delay has passed, so a window ending before the delay never applies.
+	ErrorWindows []TimeWindow
+
+	// MaxPendingAge, when set, fails jobs still pending after this long with
+	// reason "max_pending_age_exceeded", even if their delay is longer.
+	MaxPendingAge time.Duration
+
+	// OutboundWebhookConcurrency caps the callbacks to the CallbackURL of
+	// finished jobs sent at once, DefaultOutboundWebhookConcurrency when zero.
+	OutboundWebhookConcurrency int
+
+	// MaxWebhookRetries is how many times a failed callback is retried
+	// before it is given up on, DefaultMaxWebhookRetries when zero.
+	MaxWebhookRetries int
+
+	// WebhookRetryInterval is about how long the first retry of a failed
+	// callback waits, DefaultWebhookRetryInterval when zero. Later retries
+	// wait twice as long as the one before, up to 10s.
+	WebhookRetryInterval time.Duration
+}
+
+// TimeWindow applies Rate as the error rate for jobs which finish with an
+// elapsed time in [StartOffset, EndOffset). Jobs are not failed early while
+// pending inside a window.
+type TimeWindow struct {
+	StartOffset time.Duration
+	EndOffset   time.Duration
+	Rate        int
 }
 
+// ProgressiveDelayConfig makes jobs take BaseDelay seconds at first, doubling
+// every DoublingInterval completed jobs.
+type ProgressiveDelayConfig struct {
+	BaseDelay        int
+	DoublingInterval int
+}
+
+// maxDelayDoublings caps the progressive delay growth so it cannot overflow.
+const maxDelayDoublings = 20
+
 // Response represents the JSON structure returned by the server.
 type Response struct {
-    Result string `json:"result"`
+    Result    string `json:"result"`
+    Reason    string `json:"reason,omitempty"`      // Why a job failed, when not by chance.
+    NextJobID string `json:"next_job_id,omitempty"` // Of the job chained after a completed one.
+    Progress  *int   `json:"progress,omitempty"`    // Latest percent from POST /jobs/{id}/progress, if any.
+}
+
+// ReasonMaxPendingAge is the Response reason for jobs failed by MaxPendingAge.
+const ReasonMaxPendingAge = "max_pending_age_exceeded"
+
+// ReasonJobTimeout is the Response reason for jobs failed for running past the
+// TimeoutSeconds of their JobRequest.
+const ReasonJobTimeout = "job_timeout"
+
+// jobState tracks the progress of one job.
+type jobState struct {
+    id            string // Empty for the implicit job.
+    createdAt     time.Time
+    startTime     time.Time // When a scheduled job is due to start.
+    status        string
+    reason        string // Response reason for the job's status, if any.
+    finishedAt    time.Time
+    retryCount    int    // Times restarted with POST /jobs/{id}/retry.
+    maxRetries    int
+    correlationHeaders http.Header // X-Job-Correlation-* headers from POST /jobs, echoed on status responses.
+    timeout       time.Duration // From TimeoutSeconds in JobRequest, none when zero.
+    externalID    string        // From JobRequest, if given.
+    callbackURL   string        // From JobRequest, called once the job finishes.
+    metadata      map[string]string // From JobRequest, changed by PATCH /jobs/{id}.
+    priority      int               // Likewise.
+    labels        map[string]string // From JobRequest.
+    next          *JobSpec // From JobRequest, created once the job completes.
+    nextJobID     string   // Of the job created from next.
+    workerID      int      // Of the worker in Server.workers owning the job.
+    filePath      string   // Of the file uploaded with the job, if any, removed once the job expires.
+    fileSize      int64
+    progressUpdates []ProgressUpdate // From POST /jobs/{id}/progress, oldest first.
 }
 
 // Server represents the video translation server.
 type Server struct {
-    startTime     time.Time
-    config 				*Config
-    status        string
-    mu            sync.Mutex
+    job           *jobState              // The implicit job behind /status, replaced when the next one starts.
+    jobs          map[string]*jobState   // Jobs created with POST /jobs, by ID.
+    maxJobs       int
+    workerCount   int         // Set by WithWorkers.
+    workers       *WorkerPool // Advances the jobs in jobs.
+    jobTTL        time.Duration          // How long finished jobs are kept in jobs.
+    watchers      map[string][]chan JobEvent // Registered by WatchJob, by job ID.
+    externalIndex map[string]string      // Job IDs by the ExternalID they were created with.
+    config        atomic.Pointer[Config] // Swapped as a whole by ApplyConfig, never modified in place once serving.
+    paused        bool
+    mu            sync.Mutex             // Guards the jobs, watchers, externalIndex and paused.
+
+    pprofEnabled  bool
+    debugAddr     string
+    certProvider  CertProvider
+
+    readHeaderTimeout time.Duration
+
+    counters      counters
+
+    jobsCompleted atomic.Int64
+
+    maxConcurrency int
+    rateLimiter    *QueuingRateLimiter
+    active         atomic.Int64
+    peak           atomic.Int64
+
+    apiKey         string
+    authExclude    func(*http.Request) bool
+
+    createdAt      time.Time
+    logger         *slog.Logger
+    heartbeat      time.Duration
+    stopOnce       sync.Once
+    stop           chan struct{} // Closed by Shutdown to stop background goroutines.
+    background     sync.WaitGroup
+
+    srvMu          sync.Mutex
+    sweepingTimeouts bool         // Set once the goroutine failing timed out jobs runs, guarded by srvMu.
+    dispatching   bool          // Set once the goroutine calling CallbackURLs runs, guarded by srvMu.
+    scheduling    bool          // Set once the goroutine starting scheduled jobs runs, guarded by srvMu.
+    working       bool          // Set once the workers run, guarded by srvMu.
+    rescheduled   chan struct{} // Wakes that goroutine when a job is scheduled.
+    callbacks     chan jobCallback // Queued for that goroutine by advanceJob.
+    deliveries    callbackDeliveries // Outcomes of the callbacks sent by that goroutine.
+    srv            *http.Server
+    debugSrv       *http.Server // Serves pprof, set by Start with WithPPROF.
+    grpcSrv        *grpc.Server // Set by StartGRPC.
+
+    // faultMu guards faults separately from mu, so a DelayFault doesn't block other requests.
+    faultMu        sync.Mutex
+    faults         []*injectedFault
+
+    preFlights     []PreFlightCheck
+
+    blocklistPath  string
+    blocklist      *IPBlocklist
+
+    trustedProxies []net.IP // Whose forwarding headers RealIPMiddleware believes.
+
+    serveRobots    bool
+    robotsTxt      string
+
+    handlerOnce    sync.Once
+    httpHandler    http.Handler // Built on first use by Handler.
+
+    traces         *trace.TraceStore
+
+    opts           []ServerOption // As given to NewServer, replayed by Clone.
+
+    audit          *AuditLog
+
+    streamInterval time.Duration // Between events on a status stream.
+}
+
+// ServerOption configures optional settings on a Server created by NewServer.
+type ServerOption func(*Server)
+
+// DefaultDebugAddr is where the pprof endpoints listen unless WithDebugAddr is used.
+// It is bound to the loopback interface so profiles are not reachable from other hosts.
+const DefaultDebugAddr = "127.0.0.1:6060"
+
+// WithPPROF enables the net/http/pprof endpoints under /debug/pprof/.
+//
+// They are served from their own listener on the debug address, never from the
+// main one, since profiles expose memory contents, goroutine stacks and command
+// line arguments, and CPU profiling is expensive enough to be used for denial of
+// service. Only bind the debug address to a public interface on trusted networks.
+func WithPPROF(enabled bool) ServerOption {
+	return func(s *Server) {
+		s.pprofEnabled = enabled
+	}
+}
+
+// WithDebugAddr sets the address the pprof endpoints listen on. See WithPPROF
+// for the security implications of exposing it beyond 127.0.0.1.
+func WithDebugAddr(address string) ServerOption {
+	return func(s *Server) {
+		s.debugAddr = address
+	}
+}
+
+// WithCertProvider makes Start serve HTTPS using the certificate from p.
+func WithCertProvider(p CertProvider) ServerOption {
+	return func(s *Server) {
+		s.certProvider = p
+	}
+}
+
+// WithReadHeaderTimeout limits how long a client may take to send the request
+// headers. This defends against slow loris clients holding connections open by
+// trickling headers a byte at a time. Zero means no limit.
+func WithReadHeaderTimeout(d time.Duration) ServerOption {
+	return func(s *Server) {
+		s.readHeaderTimeout = d
+	}
+}
+
+// WithAPIKey enables the admin endpoints under /admin/, which require key in
+// the X-API-Key header. They are not served at all without a key. See
+// WithAuthExclude to require the key on the other endpoints too.
+func WithAPIKey(key string) ServerOption {
+	return func(s *Server) {
+		s.apiKey = key
+	}
+}
+
+// WithAuthExclude requires the API key set with WithAPIKey on every endpoint,
+// not just /admin/, apart from requests matching exclude. /healthz, /stats and
+// /robots.txt never require it. Use ExcludePaths to build exclude from a list of paths.
+func WithAuthExclude(exclude func(r *http.Request) bool) ServerOption {
+	return func(s *Server) {
+		s.authExclude = exclude
+	}
+}
+
+// WithLogger sets the structured logger used for the heartbeat. It defaults
+// to slog.Default().
+func WithLogger(logger *slog.Logger) ServerOption {
+	return func(s *Server) {
+		s.logger = logger
+	}
+}
+
+// WithHeartbeat logs a summary of the server's counters every interval, from
+// Start until Shutdown is called. Zero, the default, disables it.
+func WithHeartbeat(interval time.Duration) ServerOption {
+	return func(s *Server) {
+		s.heartbeat = interval
+	}
+}
+
+// WithRobotsTxt serves GET /robots.txt. With disallowAll it asks crawlers to
+// stay away from every path, otherwise it is empty and allows everything.
+func WithRobotsTxt(disallowAll bool) ServerOption {
+	return func(s *Server) {
+		s.serveRobots = true
+		s.robotsTxt = ""
+		if disallowAll {
+			s.robotsTxt = "User-agent: *\nDisallow: /\n"
+		}
+	}
+}
+
+// WithMaxPendingAge fails jobs that are still pending after age with reason
+// "max_pending_age_exceeded". It only matters when age is shorter than the delay.
+func WithMaxPendingAge(age time.Duration) ServerOption {
+	return func(s *Server) {
+		s.config.Load().MaxPendingAge = age
+	}
+}
+
+// WithTracing starts a trace for every request, keeping the last maxTraces
+// in memory for GetTrace. A request carrying an X-Trace-Id header continues
+// that trace, and every response reports its trace ID in the same header.
+func WithTracing(maxTraces int) ServerOption {
+	return func(s *Server) {
+		s.traces = trace.NewTraceStore(maxTraces)
+	}
+}
+
+// WithMaxConcurrency caps the number of requests handled at the same time.
+// Requests over the cap get an immediate 503 with Retry-After: 1, which keeps
+// goroutine and memory usage bounded under heavy load. Zero means no cap.
+func WithMaxConcurrency(n int) ServerOption {
+	return func(s *Server) {
+		s.maxConcurrency = n
+	}
+}
+
+// WithProgressiveDelay makes the first doublingInterval jobs take baseDelay
+// seconds, the next doublingInterval twice as long and so on.
+func WithProgressiveDelay(baseDelay, doublingInterval int) ServerOption {
+	return func(s *Server) {
+		if baseDelay <= 0 || doublingInterval <= 0 {
+			log.Printf("Invalid progressive delay %ds every %d jobs. Using a fixed delay.", baseDelay, doublingInterval)
+			return
+		}
+		s.config.Load().ProgressiveDelay = &ProgressiveDelayConfig{BaseDelay: baseDelay, DoublingInterval: doublingInterval}
+	}
 }
 
-// NewServer initializes a new Server instance.
-func NewServer(delaySeconds int, errorRate int) (*Server, error) {
-	
-	// Validate the inputs
-	if delaySeconds <= 0 {
-			log.Printf("Invalid delay value %d. Using default of 10 seconds.", delaySeconds)
-			delaySeconds = 10
+// WithErrorWindows sets time windows, relative to job start, that use their own
+// error rate instead of the default one for jobs finishing inside them. A job
+// finishes on the first poll after its delay, so windows should cover some
+// time after the delay to have any effect. Windows with an invalid rate are
+// dropped.
+func WithErrorWindows(windows ...TimeWindow) ServerOption {
+	return func(s *Server) {
+		for _, w := range windows {
+			if w.Rate < 0 || w.Rate > 100 || w.EndOffset <= w.StartOffset {
+				log.Printf("Ignoring invalid error window %v-%v at %d%%.", w.StartOffset, w.EndOffset, w.Rate)
+				continue
+			}
+			cfg := s.config.Load()
+			cfg.ErrorWindows = append(cfg.ErrorWindows, w)
+		}
+	}
+}
+
+// WithTrustedProxies makes the server believe the X-Forwarded-For and
+// X-Real-IP headers of requests from proxies, so the blocklist and audit log
+// use the address of the client behind them. See RealIPMiddleware.
+func WithTrustedProxies(proxies ...net.IP) ServerOption {
+	return func(s *Server) {
+		s.trustedProxies = append(s.trustedProxies, proxies...)
+	}
+}
+
+// WithConfig replaces the whole config, including the progressive delay, error
+// windows and max pending age, with a copy of cfg. Options given after it
+// change the copy. NewServer fails if cfg is invalid.
+func WithConfig(cfg *Config) ServerOption {
+	return func(s *Server) {
+		s.config.Store(cfg.clone())
+	}
+}
+
+// Defaults used by NewServer unless WithDelay or WithErrorRate are given.
+const (
+	DefaultDelay     = 10 * time.Second
+	DefaultErrorRate = 20
+)
+
+// WithDelay sets how long jobs take before reaching a final status. Delays
+// are counted in whole seconds, so d is rounded up. A non-positive d is
+// logged and the default of 10 seconds is kept.
+func WithDelay(d time.Duration) ServerOption {
+	return func(s *Server) {
+		if d <= 0 {
+			log.Printf("Invalid delay value %v. Using default of %v.", d, DefaultDelay)
+			return
+		}
+		s.config.Load().DelaySeconds = int((d + time.Second - 1) / time.Second)
 	}
-	if errorRate < 0 || errorRate > 100 {
-			log.Printf("Invalid error rate value %d. Using default of 20%%.", errorRate)
-			errorRate = 20
+}
+
+// WithErrorRate sets the percentage of jobs that end in "error" rather than
+// "completed". A rate outside 0-100 is logged and the default of 20% is kept.
+func WithErrorRate(pct int) ServerOption {
+	return func(s *Server) {
+		if err := validateErrorRate(pct); err != nil {
+			log.Printf("Invalid error rate value %d. Using default of %d%%.", pct, DefaultErrorRate)
+			return
+		}
+		s.config.Load().ErrorRate = pct
 	}
+}
 
+// NewServer initializes a new Server instance with a delay of 10 seconds and
+// an error rate of 20%, then applies opts.
+func NewServer(opts ...ServerOption) (*Server, error) {
 	config := &Config{
-			DelaySeconds: delaySeconds,
-			ErrorRate:    errorRate,
+			DelaySeconds: int(DefaultDelay / time.Second),
+			ErrorRate:    DefaultErrorRate,
 	}
 
+	return newServer(config, opts, true)
+}
+
+// newServer builds a Server around config and applies opts. Clone skips the
+// pre-flight checks added with WithPreFlightChecks, as a port check would fail
+// on the port the original is already listening on.
+func newServer(config *Config, opts []ServerOption, preFlights bool) (*Server, error) {
 	// Seed the random number generator for non deterministic random nos.
 	rand.Seed(time.Now().UnixNano()) 
-	return &Server{
-			config:    config,
-			startTime: time.Now(),
-			status:    "pending",
-	}, nil
+	s := &Server{
+			job:       &jobState{startTime: time.Now(), status: "pending"},
+			jobs:      make(map[string]*jobState),
+			watchers:  make(map[string][]chan JobEvent),
+			externalIndex: make(map[string]string),
+			callbacks: make(chan jobCallback, callbackQueueSize),
+			rescheduled: make(chan struct{}, 1),
+			maxJobs:   DefaultMaxJobs,
+			workerCount: DefaultWorkers,
+			jobTTL:    DefaultJobTTL,
+			debugAddr: DefaultDebugAddr,
+			createdAt: time.Now(),
+			logger:    slog.Default(),
+			stop:      make(chan struct{}),
+			opts:      opts,
+			audit:     NewAuditLog(),
+			streamInterval: DefaultStreamInterval,
+	}
+	s.config.Store(config)
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.workers = newWorkerPool(s.workerCount)
+	checks := []PreFlightCheck{ConfigValidCheck{Config: s.config.Load()}}
+	if preFlights {
+		checks = append(checks, s.preFlights...)
+	}
+	if err := RunPreFlights(checks); err != nil {
+		return nil, fmt.Errorf("pre-flight checks failed: %w", err)
+	}
+	if s.blocklistPath != "" {
+		blocklist, err := NewIPBlocklist(s.blocklistPath)
+		if err != nil {
+			return nil, fmt.Errorf("loading blocklist: %w", err)
+		}
+		s.blocklist = blocklist
+		s.background.Add(1)
+		go func() {
+			defer s.background.Done()
+			blocklist.watch(BlocklistReloadInterval, s.stop)
+		}()
+	}
+	return s, nil
+}
+
+// Clone returns a new Server with the same options as s and a deep copy of its
+// current config, so tests can change one without affecting the other. The
+// clone starts a fresh job with zeroed counters and no injected faults. State
+// built by the options is its own rather than shared with s: it gets a new
+// rate limiter and trace store, reloads the blocklist from its file, and runs
+// its own background goroutines, such as blocklist reloads, until its own
+// Shutdown. It fails if the options no longer apply, e.g. because the
+// blocklist file has since been removed.
+func (s *Server) Clone() (*Server, error) {
+	clone, err := newServer(s.config.Load().clone(), s.opts, false)
+	if err != nil {
+		return nil, fmt.Errorf("cloning server: %w", err)
+	}
+	// Options like WithErrorWindows add to the config, so replace it after
+	// they have run to pick up any changes made since NewServer.
+	clone.config.Store(s.config.Load().clone())
+	return clone, nil
+}
+
+// clone returns a deep copy of c.
+func (c *Config) clone() *Config {
+	cp := *c
+	if c.ProgressiveDelay != nil {
+		pd := *c.ProgressiveDelay
+		cp.ProgressiveDelay = &pd
+	}
+	cp.ErrorWindows = append([]TimeWindow(nil), c.ErrorWindows...)
+	return &cp
+}
+
+// ApplyConfig changes the delay and error rate while the server is running,
+// e.g. when its config file is reloaded. They take effect from the next poll,
+// including for the job in flight.
+func (s *Server) ApplyConfig(delaySeconds, errorRate int) error {
+	if err := validateDelay(delaySeconds); err != nil {
+		return err
+	}
+	if err := validateErrorRate(errorRate); err != nil {
+		return err
+	}
+	s.updateConfig(func(cfg *Config) {
+		cfg.DelaySeconds = delaySeconds
+		cfg.ErrorRate = errorRate
+	})
+	log.Printf("Config updated: delay of %d seconds and error rate of %d%%", delaySeconds, errorRate)
+	return nil
+}
+
+// SetErrorRate changes the error rate while the server is running, e.g. to
+// simulate a service gradually degrading. It takes effect from the next poll.
+// Like PUT /admin/config, the change is recorded in the audit log.
+func (s *Server) SetErrorRate(rate int) error {
+	req := AdminConfig{ErrorRate: &rate}
+	_, err := s.applyAdminConfig(req)
+	s.auditConfigUpdate(localActor, req, err)
+	return err
 }
 
+// SetDelay changes how long jobs take while the server is running. It takes
+// effect from the next poll, including for the job in flight. A zero delay
+// finishes jobs on their first poll. Like PUT /admin/config, the change is
+// recorded in the audit log.
+func (s *Server) SetDelay(seconds int) error {
+	req := AdminConfig{DelaySeconds: &seconds}
+	_, err := s.applyAdminConfig(req)
+	s.auditConfigUpdate(localActor, req, err)
+	return err
+}
 
-// Start begins listening for HTTP requests on the specified address.
+func validateDelay(seconds int) error {
+	if seconds < 0 {
+		return fmt.Errorf("invalid delay %d, must not be negative", seconds)
+	}
+	return nil
+}
+
+func validateErrorRate(rate int) error {
+	if rate < 0 || rate > 100 {
+		return fmt.Errorf("invalid error rate %d, must be between 0 and 100", rate)
+	}
+	return nil
+}
+
+// updateConfig swaps in a copy of the config with update applied. The mutex
+// keeps concurrent updates from losing each other's changes.
+func (s *Server) updateConfig(update func(*Config)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cfg := *s.config.Load()
+	update(&cfg)
+	s.config.Store(&cfg)
+}
+
+// Start begins listening for HTTP requests on the specified address, along
+// with the pprof endpoints and the heartbeat if enabled. Once Shutdown has
+// been called it returns http.ErrServerClosed straight away.
 func (s *Server) Start(address string) error {
-	http.HandleFunc("/status", s.statusHandler)
+	srv := s.httpServer(address)
+	var debugSrv *http.Server
+	if s.pprofEnabled {
+		debugSrv = &http.Server{Addr: s.debugAddr, Handler: s.debugHandler()}
+	}
+
+	// Checked under srvMu, so either Shutdown sees the servers or we see stop closed.
+	s.srvMu.Lock()
+	select {
+	case <-s.stop:
+		s.srvMu.Unlock()
+		return http.ErrServerClosed
+	default:
+	}
+	s.srv, s.debugSrv = srv, debugSrv
+	if s.heartbeat > 0 {
+		s.background.Add(1)
+		go s.runHeartbeat()
+	}
+	s.srvMu.Unlock()
+
+	if debugSrv != nil {
+		go func() {
+			log.Printf("Debug server with pprof endpoints is starting on %s", s.debugAddr)
+			if err := debugSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Printf("Debug server failed: %v", err)
+			}
+		}()
+	}
+
 	log.Printf("Server is starting on %s with a delay of %d seconds and error rate of %d%%",
-			address, s.config.DelaySeconds, s.config.ErrorRate)
-	return http.ListenAndServe(address, nil)
+			address, s.config.Load().DelaySeconds, s.config.Load().ErrorRate)
+	if s.certProvider != nil {
+		srv.TLSConfig = tlsConfig(s.certProvider)
+		return srv.ListenAndServeTLS("", "")
+	}
+	return srv.ListenAndServe()
+}
+
+// Shutdown stops the server's background goroutines, waiting for them to
+// finish, and if Start was called gracefully shuts down the listener and the
+// pprof endpoints, after which Start returns http.ErrServerClosed. A gRPC
+// server run by StartGRPC is stopped the same way, and StartGRPC returns nil.
+func (s *Server) Shutdown(ctx context.Context) error {
+	// Closed under srvMu, so Start and StartGRPC can't start anything after.
+	s.srvMu.Lock()
+	s.stopOnce.Do(func() { close(s.stop) })
+	srv, debugSrv, grpcSrv := s.srv, s.debugSrv, s.grpcSrv
+	s.srvMu.Unlock()
+	s.background.Wait()
+
+	if debugSrv != nil {
+		if err := debugSrv.Shutdown(ctx); err != nil {
+			log.Printf("Debug server did not shut down cleanly: %v", err)
+		}
+	}
+	if grpcSrv != nil {
+		// Let calls in flight finish, then cut off streams still open when ctx is done.
+		stopped := make(chan struct{})
+		go func() {
+			grpcSrv.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-ctx.Done():
+			grpcSrv.Stop()
+		}
+	}
+	var err error
+	if srv != nil {
+		err = srv.Shutdown(ctx)
+	}
+	// Requests in flight are done with the uploaded files now.
+	s.mu.Lock()
+	for _, job := range s.jobs {
+		removeUpload(job)
+	}
+	s.mu.Unlock()
+	return err
+}
+
+// runHeartbeat calls logHeartbeat every heartbeat interval until Shutdown.
+func (s *Server) runHeartbeat() {
+	defer s.background.Done()
+	ticker := time.NewTicker(s.heartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.logHeartbeat()
+		}
+	}
+}
+
+// logHeartbeat logs the server's counters, for keeping an eye on long running
+// servers without querying /stats.
+func (s *Server) logHeartbeat() {
+	m := s.Metrics()
+	s.logger.Info("heartbeat",
+		"pending", m.PendingCount,
+		"completed", m.CompletedCount,
+		"error", m.ErrorCount,
+		"uptime_seconds", int64(time.Since(s.createdAt).Seconds()))
+}
+
+// httpServer builds the http.Server used by Start.
+func (s *Server) httpServer(address string) *http.Server {
+	return &http.Server{
+		Addr:              address,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: s.readHeaderTimeout,
+	}
+}
+
+// Handler returns the server's endpoints wrapped in its middleware, as served
+// by Start. Use it to run the server in-process, e.g. with httptest.NewServer.
+// The chain is built once and shared with Start and ServeHTTP.
+func (s *Server) Handler() http.Handler {
+	s.handlerOnce.Do(func() { s.httpHandler = s.handler() })
+	return s.httpHandler
+}
+
+// ServeHTTP makes the Server an http.Handler, so it can be mounted in an
+// existing application instead of being started with Start. Paths are the
+// same as when standalone; strip any prefix it is mounted under, e.g.
+//
+//	mux.Handle("/translate/", http.StripPrefix("/translate", srv))
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.Handler().ServeHTTP(w, r)
+}
+
+// handler wraps the routes in the server's middleware.
+func (s *Server) handler() http.Handler {
+	return s.withMiddleware(s.routes())
+}
+
+// withMiddleware wraps h in the server's middleware.
+func (s *Server) withMiddleware(h http.Handler) http.Handler {
+	if s.traces != nil {
+		h = s.tracing(h)
+	}
+	if s.apiKey != "" && s.authExclude != nil {
+		// Health checks and metrics scrapers don't carry the key.
+		exclude := func(r *http.Request) bool {
+			return publicPaths(r) || s.authExclude(r)
+		}
+		h = WithAuthExclusions(exclude, APIKeyMiddleware(s.apiKey))(h)
+	}
+	h = s.trackConcurrency(h)
+	if s.rateLimiter != nil {
+		// Outside the concurrency limit, so queued requests don't hold slots.
+		h = s.rateLimiter.Middleware(h)
+	}
+	if s.blocklist != nil {
+		// Outside the rate limit, so blocked clients don't use it up.
+		h = s.blocklist.Middleware(h)
+	}
+	// Outermost, so the blocklist and audit log see the client behind a proxy.
+	h = RealIPMiddleware(s.trustedProxies)(h)
+	return h
+}
+
+// publicPaths matches the endpoints which never require authentication.
+var publicPaths = ExcludePaths("/healthz", "/stats", "/robots.txt")
+
+// ActiveRequests returns the number of requests currently being handled.
+func (s *Server) ActiveRequests() int {
+	return int(s.active.Load())
+}
+
+// PeakConcurrency returns the highest number of requests handled at once so far.
+func (s *Server) PeakConcurrency() int {
+	return int(s.peak.Load())
+}
+
+// routes registers the server's endpoints on a fresh mux.
+func (s *Server) routes() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status", s.statusHandler)
+	mux.HandleFunc("/status/stream", s.statusStreamHandler)
+	mux.HandleFunc("/ws", s.wsHandler)
+	mux.HandleFunc("/jobs", s.createJobHandler)
+	mux.HandleFunc("/jobs/", s.jobStatusHandler)
+	mux.HandleFunc("/healthz", s.healthzHandler)
+	mux.HandleFunc("/stats", s.statsHandler)
+	if s.serveRobots {
+		mux.HandleFunc("/robots.txt", s.robotsHandler)
+	}
+	if s.apiKey != "" {
+		mux.Handle("/admin/config", APIKeyMiddleware(s.apiKey)(http.HandlerFunc(s.adminConfigHandler)))
+		mux.Handle("/admin/audit", APIKeyMiddleware(s.apiKey)(http.HandlerFunc(s.adminAuditHandler)))
+	}
+	return mux
+}
+
+// debugHandler serves the pprof endpoints. It is kept off the main mux on purpose.
+func (s *Server) debugHandler() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	return mux
 }
 
 // statusHandler handles incoming requests to the /status endpoint.
 func (s *Server) statusHandler(w http.ResponseWriter, r *http.Request) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	s.serveJobStatus(w, r, func() *jobState { return s.lookupCurrentJob(w) })
+}
 
+// currentJob returns the implicit job, first starting a new one if the last
+// has finished. While paused it returns nil rather than start one. The caller
+// must hold s.mu.
+func (s *Server) currentJob() *jobState {
 	// Reset the timer and status if the current status is not "pending" 
 	// --> Simulating a new job that could have been posted
-	if s.status != "pending" {
-			s.startTime = time.Now()
-			s.status = "pending"
-			log.Println("New request received. Resetting timer and status to 'pending'.")
+	if finished(s.job.status) {
+		// While paused we keep reporting on the current job but refuse to start a new one.
+		if s.paused {
+			log.Println("Server is paused. Rejecting new job.")
+			return nil
+		}
+		// A new job rather than a reset one, so that anyone still watching the
+		// last job sees it as it finished.
+		s.job = &jobState{startTime: time.Now(), status: "pending"}
+		log.Println("New request received. Resetting timer and status to 'pending'.")
+	}
+	return s.job
+}
+
+// lookupCurrentJob is currentJob for HTTP handlers, responding with 503 when
+// it returns nil.
+func (s *Server) lookupCurrentJob(w http.ResponseWriter) *jobState {
+	job := s.currentJob()
+	if job == nil {
+		http.Error(w, "server is paused", http.StatusServiceUnavailable)
+	}
+	return job
+}
+
+// serveJobStatus advances the job returned by lookup and responds with its
+// status. lookup is called with s.mu held, and returns nil once it has
+// written an error response itself. With a wait parameter, a pending job is
+// long-polled: the response is held until the job finishes, or once the wait
+// is over sent as 202 Accepted with the pending status.
+func (s *Server) serveJobStatus(w http.ResponseWriter, r *http.Request, lookup func() *jobState) {
+	s.counters.totalRequests.Add(1)
+
+	// result is the status responded with, if the request got that far.
+	var result string
+	if t, ok := trace.TraceFromContext(r.Context()); ok && s.traces != nil {
+		span := t.StartSpan("statusHandler")
+		defer func() {
+			span.Attributes["result"] = result
+			span.End()
+			s.traces.AddSpan(span)
+		}()
 	}
 
-	elapsed := time.Since(s.startTime)
-	if s.status == "pending" && elapsed >= time.Duration(s.config.DelaySeconds)*time.Second {
-			s.status = s.randomStatus()
+	wait, err := parseWait(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	response := Response{Result: s.status}
+	// Injected faults take precedence over normal processing.
+	if fault := s.nextFault(); fault != nil && fault.Apply(w, r) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job := lookup()
+	if job == nil {
+		return
+	}
+
+	elapsed, delay := s.advanceJob(job)
+	code := http.StatusOK
+	if wait > 0 && !finished(job.status) {
+		s.mu.Unlock()
+		s.awaitJob(r.Context(), job, wait)
+		s.mu.Lock()
+		if elapsed, delay = s.advanceJob(job); !finished(job.status) {
+			code = http.StatusAccepted
+		}
+	}
+	s.counters.recordResponse(job.status)
+
+	for name, values := range job.correlationHeaders {
+		w.Header()[name] = values
+	}
 	w.Header().Set("Content-Type", "application/json")
+	if !finished(job.status) {
+		// Roughly how many seconds the job still needs, so clients can bound their own retries.
+		remaining := int(delay.Seconds()) - int(elapsed.Seconds())
+		if remaining < 0 {
+			remaining = 0
+		}
+		w.Header().Set(RetryBudgetHeader, strconv.Itoa(remaining))
+	}
 
-	if err := json.NewEncoder(w).Encode(response); err != nil {
+	result = job.status
+	w.WriteHeader(code)
+	if err := writeResponse(w, job.response()); err != nil {
 			log.Printf("Error encoding response: %v", err)
 	}
 
-	log.Printf("Handled /status request. Responded with: %s", s.status)
+	log.Printf("Handled %s request. Responded with: %s", r.URL.Path, job.status)
+}
+
+// advanceJob starts a scheduled job once its time has come, gives job its
+// final status once its delay has passed, or fails it once pending for longer
+// than its timeout or MaxPendingAge, counting the job as finished and telling
+// its watchers and CallbackURL when it does. It returns how long the job has
+// been running, negative while scheduled, and how long it takes. A job which
+// completes starts the job chained after it, if any. The caller must hold
+// s.mu.
+func (s *Server) advanceJob(job *jobState) (elapsed, delay time.Duration) {
+	old := job.status
+	defer func() {
+		if job.status != old {
+			if job.status == "completed" && job.next != nil {
+				s.startNextJob(job)
+			}
+			s.notifyWatchers(job, old)
+			s.queueCallback(job)
+		}
+	}()
+	if job.status == "scheduled" && !time.Now().Before(job.startTime) {
+		// Its time has come, and its delay counts from then.
+		job.status = "pending"
+	}
+	elapsed = time.Since(job.startTime)
+	delay = s.effectiveDelay()
+	if job.status == "pending" && job.timeout > 0 && job.timeout < delay && elapsed >= job.timeout {
+			log.Printf("Job %s pending for %v, over its timeout of %v. Failing it.", job.id, elapsed, job.timeout)
+			job.status = "error"
+			job.reason = ReasonJobTimeout
+			job.finishedAt = time.Now()
+			s.counters.timedOut.Add(1)
+			s.jobsCompleted.Add(1)
+			s.counters.recordFinished(job.status, elapsed)
+	} else if job.status == "pending" && elapsed >= delay {
+			job.status = s.randomStatus(elapsed)
+			job.finishedAt = time.Now()
+			s.jobsCompleted.Add(1)
+			s.counters.recordFinished(job.status, elapsed)
+	} else if maxAge := s.config.Load().MaxPendingAge; job.status == "pending" && maxAge > 0 && elapsed > maxAge {
+			log.Printf("Job pending for %v, over the maximum of %v. Failing it.", elapsed, maxAge)
+			job.status = "error"
+			job.reason = ReasonMaxPendingAge
+			job.finishedAt = time.Now()
+			s.counters.ageExceeded.Add(1)
+			s.jobsCompleted.Add(1)
+			s.counters.recordFinished(job.status, elapsed)
+	}
+	return elapsed, delay
+}
+
+// response is the status response for the job. The caller must hold s.mu.
+func (j *jobState) response() Response {
+	resp := Response{Result: j.status, Reason: j.reason, NextJobID: j.nextJobID}
+	if n := len(j.progressUpdates); n > 0 {
+		percent := j.progressUpdates[n-1].Percent
+		resp.Progress = &percent
+	}
+	return resp
+}
+
+// settled reports whether the job has finished and can't be retried, after
+// which it won't change.
+func (j *jobState) settled() bool {
+	return finished(j.status) && !(j.status == "error" && j.retryCount < j.maxRetries)
+}
+
+// finished reports whether status is final, after which a job won't change.
+func finished(status string) bool {
+	return types.Status(status).IsTerminal()
+}
+
+// responseEncoder is a pooled Response with a buffer and encoder to write it.
+type responseEncoder struct {
+	response Response
+	buf      bytes.Buffer
+	enc      *json.Encoder
+}
+
+// responsePool reuses response encoders across /status requests, which would
+// otherwise allocate a Response and an Encoder each time. Reuse is safe since
+// writeResponse resets both the Response and the buffer before use, copies the
+// bytes out to the ResponseWriter, and puts the encoder back only when done,
+// so nothing from one request is visible to or retained by another.
+var responsePool = sync.Pool{
+	New: func() any {
+		re := &responseEncoder{}
+		re.enc = json.NewEncoder(&re.buf)
+		return re
+	},
+}
+
+// writeResponse writes resp as the JSON body to w.
+func writeResponse(w http.ResponseWriter, resp Response) error {
+	re := responsePool.Get().(*responseEncoder)
+	defer responsePool.Put(re)
+
+	re.response = resp
+	re.buf.Reset()
+	if err := re.enc.Encode(&re.response); err != nil {
+		return err
+	}
+	_, err := w.Write(re.buf.Bytes())
+	return err
 }
 
-// randomStatus determines the final status based on the error rate.
-func (s *Server) randomStatus() string {
-	if rand.Intn(100) < s.config.ErrorRate {
+// Pause stops the server from accepting new jobs. Polling of the job already
+// in flight keeps working so it can drain before a deployment.
+func (s *Server) Pause() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.paused = true
+	log.Println("Server paused. New jobs will be rejected.")
+}
+
+// Resume allows new jobs to be started again after a Pause.
+func (s *Server) Resume() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.paused = false
+	log.Println("Server resumed. Accepting new jobs.")
+}
+
+// IsPaused reports whether the server is currently rejecting new jobs.
+func (s *Server) IsPaused() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.paused
+}
+
+// healthzHandler reports that the server is up along with its pause state.
+func (s *Server) healthzHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"status": "ok",
+		"paused": s.IsPaused(),
+	}); err != nil {
+		log.Printf("Error encoding health response: %v", err)
+	}
+}
+
+// robotsHandler serves the robots.txt set with WithRobotsTxt.
+func (s *Server) robotsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	io.WriteString(w, s.robotsTxt)
+}
+
+// effectiveDelay returns how long the current job takes: DelaySeconds, or with
+// a progressive delay BaseDelay * 2^(jobsCompleted / DoublingInterval).
+func (s *Server) effectiveDelay() time.Duration {
+	cfg := s.config.Load()
+	p := cfg.ProgressiveDelay
+	if p == nil {
+		return time.Duration(cfg.DelaySeconds) * time.Second
+	}
+	doublings := s.jobsCompleted.Load() / int64(p.DoublingInterval)
+	if doublings > maxDelayDoublings {
+		doublings = maxDelayDoublings
+	}
+	return time.Duration(p.BaseDelay) * time.Second << doublings
+}
+
+// errorRateAt returns the error rate that applies after elapsed time of a job.
+func (s *Server) errorRateAt(elapsed time.Duration) int {
+	cfg := s.config.Load()
+	for _, w := range cfg.ErrorWindows {
+		if elapsed >= w.StartOffset && elapsed < w.EndOffset {
+			return w.Rate
+		}
+	}
+	return cfg.ErrorRate
+}
+
+// randomStatus determines the final status based on the error rate in effect
+// after elapsed time.
+func (s *Server) randomStatus(elapsed time.Duration) string {
+	if rand.Intn(100) < s.errorRateAt(elapsed) {
 			return "error"
 	}
 	return "completed"
 }
+
+// GetTrace returns a copy of a trace recorded with WithTracing, including the
+// spans added by the handlers.
+func (s *Server) GetTrace(traceID string) (*trace.Trace, error) {
+	if s.traces == nil {
+		return nil, errors.New("tracing is not enabled, see WithTracing")
+	}
+	return s.traces.Get(traceID)
+}
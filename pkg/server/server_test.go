@@ -0,0 +1,804 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"io"
+	"log"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"Video-Translation-Simulator/pkg/config"
+)
+
+// TestMain keeps the standard logger quiet unless -v is given, since the server
+// logs a line for every request.
+func TestMain(m *testing.M) {
+	flag.Parse()
+	if !testing.Verbose() {
+		log.SetOutput(io.Discard)
+	}
+	os.Exit(m.Run())
+}
+
+// listenLocal listens on a loopback port picked by the OS, so tests running in
+// parallel, or next to a real server, never fight over a fixed port.
+func listenLocal(t *testing.T) net.Listener {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	return ln
+}
+
+// poll sends a single /status request straight to the handler.
+func poll(t *testing.T, s *Server) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	s.statusHandler(rec, httptest.NewRequest(http.MethodGet, "/status", nil))
+	return rec
+}
+
+// expireJob moves the current job's start time back so the next poll finishes it.
+func expireJob(s *Server) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.job.startTime = time.Now().Add(-s.effectiveDelay())
+}
+
+func TestPauseRejectsNewJobsUntilResumed(t *testing.T) {
+	s, err := NewServer(WithDelay(time.Second), WithErrorRate(0))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+
+	if rec := poll(t, s); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 for the first job, got %d", rec.Code)
+	}
+
+	s.Pause()
+	if !s.IsPaused() {
+		t.Fatal("expected server to report paused")
+	}
+
+	// The job in flight can still be polled to completion.
+	expireJob(s)
+	rec := poll(t, s)
+	var resp Response
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if rec.Code != http.StatusOK || resp.Result != "completed" {
+		t.Fatalf("expected in-flight job to complete, got %d %q", rec.Code, resp.Result)
+	}
+
+	// Starting another job is refused while paused.
+	if rec := poll(t, s); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 while paused, got %d", rec.Code)
+	}
+
+	s.Resume()
+	rec = poll(t, s)
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if rec.Code != http.StatusOK || resp.Result != "pending" {
+		t.Fatalf("expected new job after resume, got %d %q", rec.Code, resp.Result)
+	}
+}
+
+func TestHealthzReportsPauseState(t *testing.T) {
+	s, err := NewServer(WithDelay(time.Second), WithErrorRate(0))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	s.Pause()
+
+	rec := httptest.NewRecorder()
+	s.healthzHandler(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	var body struct {
+		Status string `json:"status"`
+		Paused bool   `json:"paused"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if body.Status != "ok" || !body.Paused {
+		t.Fatalf("unexpected health response: %+v", body)
+	}
+}
+
+func TestRetryBudgetHeaderDecreases(t *testing.T) {
+	s, err := NewServer(WithDelay(5*time.Second), WithErrorRate(0))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+
+	previous := 6
+	for i := 0; i < 5; i++ {
+		rec := poll(t, s)
+		remaining, err := strconv.Atoi(rec.Header().Get(RetryBudgetHeader))
+		if err != nil {
+			t.Fatalf("poll %d: bad %s header: %v", i+1, RetryBudgetHeader, err)
+		}
+		if remaining >= previous {
+			t.Fatalf("poll %d: expected budget below %d, got %d", i+1, previous, remaining)
+		}
+		previous = remaining
+
+		// Pretend another second of the job has passed.
+		s.mu.Lock()
+		s.job.startTime = s.job.startTime.Add(-time.Second)
+		s.mu.Unlock()
+	}
+
+	if rec := poll(t, s); rec.Header().Get(RetryBudgetHeader) != "" {
+		t.Fatalf("expected no budget header once the job finished, got %q", rec.Header().Get(RetryBudgetHeader))
+	}
+}
+
+func TestDelayAndErrorRateOptions(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      []ServerOption
+		delay     int
+		errorRate int
+	}{
+		{"defaults", nil, 10, 20},
+		{"valid", []ServerOption{WithDelay(3 * time.Second), WithErrorRate(0)}, 3, 0},
+		{"rounded up", []ServerOption{WithDelay(1500 * time.Millisecond)}, 2, 20},
+		{"invalid kept at defaults", []ServerOption{WithDelay(-time.Second), WithErrorRate(101)}, 10, 20},
+		{"invalid leaves earlier value", []ServerOption{WithErrorRate(5), WithErrorRate(-1)}, 10, 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewServer(tt.opts...)
+			if err != nil {
+				t.Fatalf("NewServer: %v", err)
+			}
+			if cfg := s.config.Load(); cfg.DelaySeconds != tt.delay || cfg.ErrorRate != tt.errorRate {
+				t.Fatalf("expected %ds at %d%%, got %ds at %d%%", tt.delay, tt.errorRate, cfg.DelaySeconds, cfg.ErrorRate)
+			}
+		})
+	}
+}
+
+func TestPPROFEndpointsOnDebugHandler(t *testing.T) {
+	s, err := NewServer(WithDelay(time.Second), WithErrorRate(0), WithPPROF(true))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	if s.debugAddr != DefaultDebugAddr {
+		t.Fatalf("expected default debug address %s, got %s", DefaultDebugAddr, s.debugAddr)
+	}
+
+	debug := httptest.NewServer(s.debugHandler())
+	defer debug.Close()
+
+	resp, err := http.Get(debug.URL + "/debug/pprof/")
+	if err != nil {
+		t.Fatalf("GET /debug/pprof/: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 from pprof index, got %d", resp.StatusCode)
+	}
+
+	// The profiles must not leak onto the main mux.
+	rec := httptest.NewRecorder()
+	s.routes().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/pprof/", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected pprof to be absent from the main routes, got %d", rec.Code)
+	}
+}
+
+func TestSlowLorisDefense(t *testing.T) {
+	s, err := NewServer(WithDelay(time.Second), WithErrorRate(0), WithReadHeaderTimeout(500*time.Millisecond))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	ln := listenLocal(t)
+	srv := s.httpServer(ln.Addr().String())
+	go srv.Serve(ln)
+	defer srv.Close()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	start := time.Now()
+
+	// Notice when the server hangs up on us.
+	closed := make(chan time.Duration, 1)
+	go func() {
+		io.Copy(io.Discard, conn)
+		closed <- time.Since(start)
+	}()
+
+	// Trickle the headers one byte every 100ms, which would take ~4s in total.
+	// Writes may start failing once the server hangs up.
+	request := "GET /status HTTP/1.1\r\nHost: localhost\r\n\r\n"
+	var elapsed time.Duration
+send:
+	for i := 0; i < len(request); i++ {
+		select {
+		case elapsed = <-closed:
+			break send
+		case <-time.After(100 * time.Millisecond):
+		}
+		conn.Write([]byte{request[i]})
+	}
+	if elapsed == 0 {
+		t.Fatal("server kept the slow connection open until the headers were fully sent")
+	}
+	if elapsed < 400*time.Millisecond || elapsed > 1500*time.Millisecond {
+		t.Fatalf("expected connection closed after ~500ms, got %v", elapsed)
+	}
+}
+
+func TestMetricsCountEveryOutcome(t *testing.T) {
+	s, err := NewServer(WithDelay(time.Second), WithErrorRate(0))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+
+	// Two pending polls, then a completed job.
+	poll(t, s)
+	poll(t, s)
+	expireJob(s)
+	poll(t, s)
+
+	// A second job which errors.
+	s.config.Load().ErrorRate = 100
+	poll(t, s)
+	expireJob(s)
+	poll(t, s)
+
+	// A rejected request only counts towards the total.
+	s.Pause()
+	poll(t, s)
+
+	m := s.Metrics()
+	want := ServerMetrics{TotalRequests: 6, PendingCount: 3, CompletedCount: 1, ErrorCount: 1}
+	if m.TotalRequests != want.TotalRequests || m.PendingCount != want.PendingCount ||
+		m.CompletedCount != want.CompletedCount || m.ErrorCount != want.ErrorCount {
+		t.Fatalf("expected %+v, got %+v", want, m)
+	}
+	// Both jobs were pushed one second into the past before finishing.
+	if m.AverageDelayMs < 1000 || m.AverageDelayMs > 1500 {
+		t.Fatalf("expected average delay around 1000ms, got %v", m.AverageDelayMs)
+	}
+}
+
+func TestProgressiveDelayDoublesPerBatch(t *testing.T) {
+	s, err := NewServer(WithDelay(time.Second), WithErrorRate(0), WithProgressiveDelay(1, 3))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+
+	want := []time.Duration{1, 1, 1, 2, 2, 2, 4, 4, 4, 8}
+	for job, seconds := range want {
+		delay := seconds * time.Second
+
+		// Start the job, it must still be pending just before its delay is up.
+		poll(t, s)
+		s.mu.Lock()
+		s.job.startTime = time.Now().Add(-delay + 100*time.Millisecond)
+		s.mu.Unlock()
+		if got := decodeResult(t, poll(t, s)); got != "pending" {
+			t.Fatalf("job %d: expected pending before %v, got %q", job+1, delay, got)
+		}
+
+		// And finish once it is.
+		s.mu.Lock()
+		s.job.startTime = time.Now().Add(-delay)
+		s.mu.Unlock()
+		if got := decodeResult(t, poll(t, s)); got != "completed" {
+			t.Fatalf("job %d: expected completed after %v, got %q", job+1, delay, got)
+		}
+	}
+}
+
+func decodeResult(t *testing.T, rec *httptest.ResponseRecorder) string {
+	t.Helper()
+	var resp Response
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	return resp.Result
+}
+
+func TestErrorWindows(t *testing.T) {
+	s, err := NewServer(WithDelay(time.Second), WithErrorRate(0), WithErrorWindows(
+		TimeWindow{StartOffset: 0, EndOffset: 2 * time.Second, Rate: 100},
+		TimeWindow{StartOffset: 8 * time.Second, EndOffset: 10 * time.Second, Rate: 100},
+		TimeWindow{StartOffset: 5 * time.Second, EndOffset: 4 * time.Second, Rate: 50}, // Invalid, dropped.
+	))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	if windows := s.config.Load().ErrorWindows; len(windows) != 2 {
+		t.Fatalf("expected the invalid window to be dropped, got %+v", windows)
+	}
+
+	tests := []struct {
+		elapsed time.Duration
+		rate    int
+		status  string
+	}{
+		{elapsed: 0, rate: 100, status: "error"},
+		{elapsed: 1999 * time.Millisecond, rate: 100, status: "error"},
+		{elapsed: 2 * time.Second, rate: 0, status: "completed"},
+		{elapsed: 5 * time.Second, rate: 0, status: "completed"},
+		{elapsed: 9 * time.Second, rate: 100, status: "error"},
+		{elapsed: 10 * time.Second, rate: 0, status: "completed"},
+	}
+	for _, tt := range tests {
+		if rate := s.errorRateAt(tt.elapsed); rate != tt.rate {
+			t.Fatalf("at %v: expected rate %d, got %d", tt.elapsed, tt.rate, rate)
+		}
+		if status := s.randomStatus(tt.elapsed); status != tt.status {
+			t.Fatalf("at %v: expected %q, got %q", tt.elapsed, tt.status, status)
+		}
+	}
+}
+
+func TestErrorWindowsApplyWhenJobsFinish(t *testing.T) {
+	s, err := NewServer(WithDelay(time.Second), WithErrorRate(0), WithErrorWindows(
+		TimeWindow{StartOffset: 0, EndOffset: 500 * time.Millisecond, Rate: 100},
+	))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	// Polled inside the window while pending, but finishing after it.
+	if got := decodeResult(t, poll(t, s)); got != "pending" {
+		t.Fatalf("expected the job to stay pending inside the window, got %q", got)
+	}
+	expireJob(s)
+	if got := decodeResult(t, poll(t, s)); got != "completed" {
+		t.Fatalf("expected the default rate once the window has passed, got %q", got)
+	}
+}
+
+func TestConfigReloadChangesDelay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"delay_seconds": 60, "error_rate": 100}`), 0o600); err != nil {
+		t.Fatalf("writing config: %v", err)
+	}
+	s, err := NewServer(WithDelay(60*time.Second), WithErrorRate(100))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+
+	reloaded := make(chan *config.Config, 1)
+	w, err := config.NewConfigWatcher(path, 10*time.Millisecond, func(c *config.Config) error {
+		if err := s.ApplyConfig(c.DelaySeconds, c.ErrorRate); err != nil {
+			return err
+		}
+		reloaded <- c
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("NewConfigWatcher: %v", err)
+	}
+	w.Start(context.Background())
+	defer w.Stop()
+
+	// Start a job, a second in it is nowhere near done.
+	poll(t, s)
+	s.mu.Lock()
+	s.job.startTime = time.Now().Add(-time.Second)
+	s.mu.Unlock()
+	if got := decodeResult(t, poll(t, s)); got != "pending" {
+		t.Fatalf("expected pending with the original delay, got %q", got)
+	}
+
+	// Shorten the delay, and change the address which needs a restart.
+	if err := os.WriteFile(path, []byte(`{"delay_seconds": 1, "error_rate": 100, "addr": ":9999"}`), 0o600); err != nil {
+		t.Fatalf("writing config: %v", err)
+	}
+	select {
+	case c := <-reloaded:
+		if c.DelaySeconds != 1 || c.Addr != ":8080" {
+			t.Fatalf("unexpected reloaded config %+v", *c)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("watcher did not pick up the config change")
+	}
+
+	// The same job is now past its delay.
+	if got := decodeResult(t, poll(t, s)); got != "error" {
+		t.Fatalf("expected the job to finish with the new delay, got %q", got)
+	}
+}
+
+// syncBuffer is a bytes.Buffer safe to write from the heartbeat goroutine.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestHeartbeatLogsUntilShutdown(t *testing.T) {
+	var out syncBuffer
+	s, err := NewServer(WithDelay(time.Second), WithErrorRate(0),
+		WithLogger(slog.New(slog.NewJSONHandler(&out, nil))),
+		WithHeartbeat(100*time.Millisecond))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	poll(t, s)
+
+	// The heartbeat only starts with the server.
+	time.Sleep(250 * time.Millisecond)
+	if strings.Contains(out.String(), `"msg":"heartbeat"`) {
+		t.Fatalf("expected no heartbeat before Start: %s", out.String())
+	}
+	started := make(chan error, 1)
+	go func() { started <- s.Start("127.0.0.1:0") }()
+
+	time.Sleep(500 * time.Millisecond)
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	if err := <-started; !errors.Is(err, http.ErrServerClosed) {
+		t.Fatalf("expected Start to return http.ErrServerClosed, got %v", err)
+	}
+	beats := strings.Count(out.String(), `"msg":"heartbeat"`)
+	if beats < 3 {
+		t.Fatalf("expected at least 3 heartbeats in 500ms, got %d: %s", beats, out.String())
+	}
+	if !strings.Contains(out.String(), `"pending":1`) || !strings.Contains(out.String(), `"uptime_seconds":0`) {
+		t.Fatalf("heartbeat is missing the counters: %s", out.String())
+	}
+
+	time.Sleep(250 * time.Millisecond)
+	if after := strings.Count(out.String(), `"msg":"heartbeat"`); after != beats {
+		t.Fatalf("heartbeat kept logging after Shutdown: %d, then %d", beats, after)
+	}
+}
+
+func BenchmarkStatusHandler(b *testing.B) {
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(io.Discard)
+	s, err := NewServer(WithDelay(60*time.Second), WithErrorRate(0))
+	if err != nil {
+		b.Fatalf("NewServer: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/status", nil)
+	w := discardResponseWriter{header: http.Header{}}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.statusHandler(w, req)
+	}
+}
+
+// discardResponseWriter throws the response away, so the benchmark only
+// measures the handler's own allocations.
+type discardResponseWriter struct {
+	header http.Header
+}
+
+func (w discardResponseWriter) Header() http.Header         { return w.header }
+func (w discardResponseWriter) Write(p []byte) (int, error) { return len(p), nil }
+func (w discardResponseWriter) WriteHeader(int)             {}
+
+func TestRobotsTxt(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []ServerOption
+		code int
+		body string
+	}{
+		{"not configured", nil, http.StatusNotFound, ""},
+		{"disallow all", []ServerOption{WithRobotsTxt(true)}, http.StatusOK, "User-agent: *\nDisallow: /\n"},
+		{"allow all", []ServerOption{WithRobotsTxt(false)}, http.StatusOK, ""},
+		// Crawlers never carry the API key.
+		{"behind auth", []ServerOption{WithRobotsTxt(true), WithAPIKey("secret"), WithAuthExclude(ExcludePaths())}, http.StatusOK, "User-agent: *\nDisallow: /\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewServer(tt.opts...)
+			if err != nil {
+				t.Fatalf("NewServer: %v", err)
+			}
+			rec := httptest.NewRecorder()
+			s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/robots.txt", nil))
+			if rec.Code != tt.code {
+				t.Fatalf("expected %d, got %d", tt.code, rec.Code)
+			}
+			if tt.code == http.StatusOK && rec.Body.String() != tt.body {
+				t.Fatalf("expected body %q, got %q", tt.body, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestMaxPendingAge(t *testing.T) {
+	s, err := NewServer(WithDelay(5*time.Second), WithErrorRate(0), WithMaxPendingAge(100*time.Millisecond))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+
+	var resp Response
+	json.NewDecoder(poll(t, s).Body).Decode(&resp)
+	if resp.Result != "pending" || resp.Reason != "" {
+		t.Fatalf("expected a fresh job to be pending, got %+v", resp)
+	}
+
+	time.Sleep(150 * time.Millisecond)
+	json.NewDecoder(poll(t, s).Body).Decode(&resp)
+	if resp.Result != "error" || resp.Reason != ReasonMaxPendingAge {
+		t.Fatalf("expected the job to fail with %q, got %+v", ReasonMaxPendingAge, resp)
+	}
+	if m := s.Metrics(); m.AgeExceededCount != 1 || m.ErrorCount != 1 {
+		t.Fatalf("expected one job failed for its age, got %+v", m)
+	}
+
+	// The next job starts over, without the reason.
+	resp = Response{}
+	json.NewDecoder(poll(t, s).Body).Decode(&resp)
+	if resp.Result != "pending" || resp.Reason != "" {
+		t.Fatalf("expected a new pending job, got %+v", resp)
+	}
+}
+
+func TestHandlerIsSharedWithServeHTTP(t *testing.T) {
+	s, err := NewServer(WithDelay(time.Second), WithErrorRate(0))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	s.Handler()
+	// Swap the cached chain to see which one ServeHTTP and Handler use.
+	s.httpHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	for name, h := range map[string]http.Handler{"ServeHTTP": s, "Handler": s.Handler()} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/status", nil))
+		if rec.Code != http.StatusTeapot {
+			t.Fatalf("%s built its own handler, got %d", name, rec.Code)
+		}
+	}
+}
+
+func TestServeHTTPMounted(t *testing.T) {
+	s, err := NewServer(WithDelay(60*time.Second), WithErrorRate(0))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	parent := http.NewServeMux()
+	parent.Handle("/translate/", http.StripPrefix("/translate", s))
+	parent.HandleFunc("/other", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "other")
+	})
+	srv := httptest.NewServer(parent)
+	defer srv.Close()
+
+	resp, err := srv.Client().Get(srv.URL + "/translate/status")
+	if err != nil {
+		t.Fatalf("GET /translate/status: %v", err)
+	}
+	var body Response
+	json.NewDecoder(resp.Body).Decode(&body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || body.Result != "pending" {
+		t.Fatalf("expected a pending status, got %d %+v", resp.StatusCode, body)
+	}
+	if n := s.Metrics().TotalRequests; n != 1 {
+		t.Fatalf("expected statusHandler to count 1 request, got %d", n)
+	}
+
+	// The parent's own routes and unknown paths under the prefix still behave.
+	for path, want := range map[string]int{"/other": http.StatusOK, "/translate/missing": http.StatusNotFound, "/status": http.StatusNotFound} {
+		resp, err := srv.Client().Get(srv.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("GET %s: expected %d, got %d", path, want, resp.StatusCode)
+		}
+	}
+}
+
+func TestTracing(t *testing.T) {
+	s, err := NewServer(WithDelay(60*time.Second), WithErrorRate(0), WithTracing(10))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/status", nil))
+	traceID := rec.Header().Get(TraceIDHeader)
+	if traceID == "" {
+		t.Fatal("expected a trace ID in the response")
+	}
+
+	tr, err := s.GetTrace(traceID)
+	if err != nil {
+		t.Fatalf("GetTrace: %v", err)
+	}
+	if tr.Attributes["path"] != "/status" || len(tr.Spans) != 1 {
+		t.Fatalf("expected a /status trace with one span, got %+v", tr)
+	}
+	span := tr.Spans[0]
+	if span.Name != "statusHandler" || span.ParentSpanID != tr.SpanID || span.Attributes["result"] != "pending" {
+		t.Fatalf("expected a statusHandler child span, got %+v", span)
+	}
+
+	// An incoming trace ID is continued.
+	req := httptest.NewRequest(http.MethodGet, "/status", nil)
+	req.Header.Set(TraceIDHeader, "upstream-trace")
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	if got := rec.Header().Get(TraceIDHeader); got != "upstream-trace" {
+		t.Fatalf("expected the upstream trace ID back, got %q", got)
+	}
+	if _, err := s.GetTrace("upstream-trace"); err != nil {
+		t.Fatalf("GetTrace: %v", err)
+	}
+
+	if _, err := (&Server{}).GetTrace(traceID); err == nil {
+		t.Fatal("expected an error without tracing enabled")
+	}
+}
+
+func TestCloneIsIsolated(t *testing.T) {
+	s, err := NewServer(WithDelay(5*time.Second), WithErrorRate(10), WithErrorWindows(TimeWindow{StartOffset: 0, EndOffset: time.Second, Rate: 50}), WithRobotsTxt(true))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	if err := s.SetErrorRate(30); err != nil {
+		t.Fatalf("SetErrorRate: %v", err)
+	}
+
+	a, err := s.Clone()
+	if err != nil {
+		t.Fatalf("Clone: %v", err)
+	}
+	b, err := s.Clone()
+	if err != nil {
+		t.Fatalf("Clone: %v", err)
+	}
+	defer a.Shutdown(context.Background())
+	defer b.Shutdown(context.Background())
+	if got := b.config.Load().ErrorRate; got != 30 {
+		t.Fatalf("expected clone to copy the current error rate of 30, got %d", got)
+	}
+	if got := len(b.config.Load().ErrorWindows); got != 1 {
+		t.Fatalf("expected 1 error window on the clone, got %d", got)
+	}
+
+	if err := a.SetErrorRate(90); err != nil {
+		t.Fatalf("SetErrorRate: %v", err)
+	}
+	a.config.Load().ErrorWindows[0].Rate = 100
+	if got := b.config.Load().ErrorRate; got != 30 {
+		t.Fatalf("expected the other clone to keep an error rate of 30, got %d", got)
+	}
+	if got := b.config.Load().ErrorWindows[0].Rate; got != 50 {
+		t.Fatalf("expected the other clone's window to keep a rate of 50, got %d", got)
+	}
+	if got := s.config.Load().ErrorRate; got != 30 {
+		t.Fatalf("expected the original to keep an error rate of 30, got %d", got)
+	}
+
+	// Options are applied to the clone too.
+	rec := httptest.NewRecorder()
+	b.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/robots.txt", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected robots.txt on the clone, got %d", rec.Code)
+	}
+}
+
+func TestCloneDoesNotShareState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocklist.txt")
+	if err := os.WriteFile(path, []byte("192.0.2.0/24\n"), 0o600); err != nil {
+		t.Fatalf("writing blocklist: %v", err)
+	}
+	s, err := NewServer(WithDelay(time.Second), WithErrorRate(0), WithQueuingRateLimiter(0, 1, 0), WithTracing(10), WithBlocklist(path))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	defer s.Shutdown(context.Background())
+	get := func(srv *Server) int {
+		req := httptest.NewRequest(http.MethodGet, "/status", nil)
+		req.RemoteAddr = "198.51.100.1:1234"
+		rec := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rec, req)
+		return rec.Code
+	}
+	// Use up the original's only token and record a trace.
+	if code := get(s); code != http.StatusOK {
+		t.Fatalf("expected the first request to be served, got %d", code)
+	}
+
+	clone, err := s.Clone()
+	if err != nil {
+		t.Fatalf("Clone: %v", err)
+	}
+	defer clone.Shutdown(context.Background())
+	if clone.rateLimiter == s.rateLimiter || clone.traces == s.traces || clone.blocklist == s.blocklist {
+		t.Fatal("expected the clone to have its own rate limiter, trace store and blocklist")
+	}
+	if code := get(clone); code != http.StatusOK {
+		t.Fatalf("expected the clone's rate limit to be untouched, got %d", code)
+	}
+	if code := get(s); code != http.StatusTooManyRequests {
+		t.Fatalf("expected the original to stay rate limited, got %d", code)
+	}
+
+	// Options which no longer apply are reported rather than panicking.
+	os.Remove(path)
+	if _, err := s.Clone(); err == nil {
+		t.Fatal("expected an error cloning without the blocklist file")
+	}
+}
+
+// freeAddr returns a loopback address nothing is listening on.
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	ln := listenLocal(t)
+	ln.Close()
+	return ln.Addr().String()
+}
+
+func TestShutdownStopsDebugServer(t *testing.T) {
+	debugAddr := freeAddr(t)
+	s, err := NewServer(WithDelay(time.Second), WithErrorRate(0), WithPPROF(true), WithDebugAddr(debugAddr))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	go s.Start(freeAddr(t))
+
+	get := func() error {
+		resp, err := http.Get("http://" + debugAddr + "/debug/pprof/")
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for get() != nil {
+		if time.Now().After(deadline) {
+			t.Fatal("debug server did not start")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	if get() == nil {
+		t.Fatal("expected the debug server to be closed by Shutdown")
+	}
+}
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// DefaultStreamInterval is how often a status stream reports on its job.
+const DefaultStreamInterval = time.Second
+
+// statusStreamHandler serves GET /status/stream, pushing the implicit job's
+// status as server-sent events instead of making the client poll.
+func (s *Server) statusStreamHandler(w http.ResponseWriter, r *http.Request) {
+	s.streamJobStatus(w, r, func() *jobState { return s.lookupCurrentJob(w) })
+}
+
+// streamJobStatus sends the status of the job returned by lookup as a
+// server-sent event, data: {"result": ...}, every streamInterval until the
+// job reaches a final status, then sends that and ends the stream. lookup is
+// called once with s.mu held, and returns nil once it has written an error
+// response itself.
+func (s *Server) streamJobStatus(w http.ResponseWriter, r *http.Request, lookup func() *jobState) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	s.counters.totalRequests.Add(1)
+
+	s.mu.Lock()
+	job := lookup()
+	s.mu.Unlock()
+	if job == nil {
+		return
+	}
+
+	for name, values := range job.correlationHeaders {
+		w.Header()[name] = values
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	var last string
+	err := s.watchJob(r.Context(), job, func(response Response) error {
+		data, err := json.Marshal(response)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+			return err
+		}
+		flusher.Flush()
+		last = response.Result
+		return nil
+	})
+	if err != nil {
+		log.Printf("Status stream for %s ended early: %v", r.URL.Path, err)
+		return
+	}
+	log.Printf("Handled %s stream. Ended with: %s", r.URL.Path, last)
+}
+
+// watchJob calls send with the job's status every streamInterval, advancing
+// it first, until the job reaches a final status, send fails or ctx is done.
+// It returns nil once the final status has been sent.
+func (s *Server) watchJob(ctx context.Context, job *jobState, send func(Response) error) error {
+	ticker := time.NewTicker(s.streamInterval)
+	defer ticker.Stop()
+	for {
+		s.mu.Lock()
+		s.advanceJob(job)
+		s.counters.recordResponse(job.status)
+		response := job.response()
+		s.mu.Unlock()
+
+		if err := send(response); err != nil {
+			return err
+		}
+		if finished(response.Result) {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
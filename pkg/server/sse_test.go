@@ -0,0 +1,96 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStatusStream(t *testing.T) {
+	s, err := NewServer(WithDelay(time.Second), WithErrorRate(0))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	s.streamInterval = 100 * time.Millisecond
+	ts := httptest.NewServer(s.Handler())
+	defer ts.Close()
+
+	for _, path := range []string{"/status/stream", "/jobs/streamed/status/stream"} {
+		t.Run(path, func(t *testing.T) {
+			if strings.HasPrefix(path, "/jobs/") {
+				createJob(t, ts, "streamed")
+			}
+			resp, err := http.Get(ts.URL + path)
+			if err != nil {
+				t.Fatalf("GET %s: %v", path, err)
+			}
+			defer resp.Body.Close()
+			if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+				t.Fatalf("expected an event stream, got %q", ct)
+			}
+
+			var events []string
+			scanner := bufio.NewScanner(resp.Body)
+			for scanner.Scan() {
+				if data, ok := strings.CutPrefix(scanner.Text(), "data: "); ok {
+					events = append(events, data)
+				}
+			}
+			if len(events) < 3 {
+				t.Fatalf("expected pending events every 100ms for about a second, got %q", events)
+			}
+			for _, e := range events[:len(events)-1] {
+				if e != `{"result":"pending"}` {
+					t.Fatalf("expected pending before the final event, got %q", events)
+				}
+			}
+			if last := events[len(events)-1]; last != `{"result":"completed"}` {
+				t.Fatalf("expected the stream to end on completed, got %q", last)
+			}
+		})
+	}
+}
+
+func TestStatusStreamUnknownJob(t *testing.T) {
+	s, err := NewServer()
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	rec := httptest.NewRecorder()
+	s.routes().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/jobs/nope/status/stream", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rec.Code)
+	}
+}
+
+func TestWatchedImplicitJobOutlivesTheNextOne(t *testing.T) {
+	s, err := NewServer(WithDelay(time.Second), WithErrorRate(0))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	s.mu.Lock()
+	job := s.currentJob()
+	s.mu.Unlock()
+
+	// Polls finish the watched job and start the next one before the watcher looks again.
+	expireJob(s)
+	if rec := poll(t, s); !strings.Contains(rec.Body.String(), "completed") {
+		t.Fatalf("expected the job to complete, got %s", rec.Body.String())
+	}
+	if rec := poll(t, s); !strings.Contains(rec.Body.String(), "pending") {
+		t.Fatalf("expected the next job to start, got %s", rec.Body.String())
+	}
+
+	var got []string
+	err = s.watchJob(context.Background(), job, func(r Response) error {
+		got = append(got, r.Result)
+		return nil
+	})
+	if err != nil || strings.Join(got, ",") != "completed" {
+		t.Fatalf("expected the watched job's completed, got %v, %v", got, err)
+	}
+}
@@ -0,0 +1,188 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+/*
+	TLS support for the server. Where the certificate comes from is abstracted
+	behind CertProvider so it can be read from disk, from the environment (as
+	injected by Vault agents or Kubernetes secrets), or anything else, and
+	refreshed without restarting the server.
+*/
+
+// CertProvider supplies the server certificate and, optionally, the CAs used
+// to verify client certificates. A nil pool from GetClientCAs disables client
+// certificate verification.
+type CertProvider interface {
+	GetCertificate() (*tls.Certificate, error)
+	GetClientCAs() (*x509.CertPool, error)
+}
+
+// FileCertProvider reads a PEM encoded certificate and key from disk on every call.
+type FileCertProvider struct {
+	CertFile     string
+	KeyFile      string
+	ClientCAFile string // Optional.
+}
+
+// GetCertificate implements CertProvider.
+func (p *FileCertProvider) GetCertificate() (*tls.Certificate, error) {
+	cert, err := tls.LoadX509KeyPair(p.CertFile, p.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("loading certificate from %s and %s: %w", p.CertFile, p.KeyFile, err)
+	}
+	return &cert, nil
+}
+
+// GetClientCAs implements CertProvider.
+func (p *FileCertProvider) GetClientCAs() (*x509.CertPool, error) {
+	if p.ClientCAFile == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(p.ClientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("reading client CAs: %w", err)
+	}
+	return certPoolFromPEM(data)
+}
+
+// Environment variables read by EnvCertProvider.
+const (
+	EnvTLSCert     = "TLS_CERT"
+	EnvTLSKey      = "TLS_KEY"
+	EnvTLSClientCA = "TLS_CLIENT_CA"
+)
+
+// EnvCertProvider reads PEM content from the TLS_CERT and TLS_KEY environment
+// variables, and client CAs from TLS_CLIENT_CA when set.
+type EnvCertProvider struct{}
+
+// GetCertificate implements CertProvider.
+func (EnvCertProvider) GetCertificate() (*tls.Certificate, error) {
+	certPEM, keyPEM := os.Getenv(EnvTLSCert), os.Getenv(EnvTLSKey)
+	if certPEM == "" || keyPEM == "" {
+		return nil, fmt.Errorf("%s and %s must both be set", EnvTLSCert, EnvTLSKey)
+	}
+	cert, err := tls.X509KeyPair([]byte(certPEM), []byte(keyPEM))
+	if err != nil {
+		return nil, fmt.Errorf("parsing certificate from environment: %w", err)
+	}
+	return &cert, nil
+}
+
+// GetClientCAs implements CertProvider.
+func (EnvCertProvider) GetClientCAs() (*x509.CertPool, error) {
+	caPEM := os.Getenv(EnvTLSClientCA)
+	if caPEM == "" {
+		return nil, nil
+	}
+	return certPoolFromPEM([]byte(caPEM))
+}
+
+// CachingCertProvider wraps another provider and only asks it again once
+// Interval has passed, so rotated certificates are picked up without hitting
+// the underlying source on every handshake. If a refresh fails the previous
+// values keep being served, and the next attempt waits for another Interval.
+type CachingCertProvider struct {
+	Provider CertProvider
+	Interval time.Duration
+
+	mu        sync.Mutex
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+	fetchedAt time.Time
+}
+
+// NewCachingCertProvider returns a CachingCertProvider refreshing p every interval.
+func NewCachingCertProvider(p CertProvider, interval time.Duration) *CachingCertProvider {
+	return &CachingCertProvider{Provider: p, Interval: interval}
+}
+
+// GetCertificate implements CertProvider.
+func (p *CachingCertProvider) GetCertificate() (*tls.Certificate, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := p.refresh(); err != nil {
+		return nil, err
+	}
+	return p.cert, nil
+}
+
+// GetClientCAs implements CertProvider.
+func (p *CachingCertProvider) GetClientCAs() (*x509.CertPool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := p.refresh(); err != nil {
+		return nil, err
+	}
+	return p.clientCAs, nil
+}
+
+// refresh reloads from the wrapped provider when the cache is stale. Must be called with p.mu held.
+func (p *CachingCertProvider) refresh() error {
+	if p.cert != nil && time.Since(p.fetchedAt) < p.Interval {
+		return nil
+	}
+	cert, err := p.Provider.GetCertificate()
+	if err == nil {
+		var clientCAs *x509.CertPool
+		if clientCAs, err = p.Provider.GetClientCAs(); err == nil {
+			p.cert, p.clientCAs, p.fetchedAt = cert, clientCAs, time.Now()
+			return nil
+		}
+	}
+	if p.cert != nil {
+		// Back off rather than asking the failing source again on every handshake.
+		log.Printf("Refreshing TLS certificate failed, serving the cached one: %v", err)
+		p.fetchedAt = time.Now()
+		return nil
+	}
+	return err
+}
+
+// tlsConfig builds a TLS config that asks provider for the certificate on
+// every handshake, so a CachingCertProvider can rotate it underneath us.
+func tlsConfig(provider CertProvider) *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		// Also set GetCertificate so net/http recognises the config as carrying a certificate.
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return provider.GetCertificate()
+		},
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			cert, err := provider.GetCertificate()
+			if err != nil {
+				return nil, err
+			}
+			clientCAs, err := provider.GetClientCAs()
+			if err != nil {
+				return nil, err
+			}
+			config := &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*cert},
+			}
+			if clientCAs != nil {
+				config.ClientCAs = clientCAs
+				config.ClientAuth = tls.RequireAndVerifyClientCert
+			}
+			return config, nil
+		},
+	}
+}
+
+func certPoolFromPEM(data []byte) (*x509.CertPool, error) {
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, errors.New("no valid certificates found in client CA PEM")
+	}
+	return pool, nil
+}
@@ -0,0 +1,180 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// selfSignedPEM generates a throwaway certificate and key for commonName.
+func selfSignedPEM(t *testing.T, commonName string) (certPEM, keyPEM []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generating key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: commonName},
+		DNSNames:              []string{commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("creating certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshalling key: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func TestEnvCertProviderReadsPEM(t *testing.T) {
+	certPEM, keyPEM := selfSignedPEM(t, "localhost")
+	t.Setenv(EnvTLSCert, string(certPEM))
+	t.Setenv(EnvTLSKey, string(keyPEM))
+	t.Setenv(EnvTLSClientCA, string(certPEM))
+
+	var p EnvCertProvider
+	cert, err := p.GetCertificate()
+	if err != nil {
+		t.Fatalf("GetCertificate: %v", err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatalf("parsing leaf: %v", err)
+	}
+	if leaf.Subject.CommonName != "localhost" {
+		t.Fatalf("unexpected certificate subject %q", leaf.Subject.CommonName)
+	}
+
+	pool, err := p.GetClientCAs()
+	if err != nil || pool == nil {
+		t.Fatalf("expected client CA pool, got %v, %v", pool, err)
+	}
+}
+
+func TestEnvCertProviderMissingVars(t *testing.T) {
+	t.Setenv(EnvTLSCert, "")
+	t.Setenv(EnvTLSKey, "")
+	t.Setenv(EnvTLSClientCA, "")
+
+	var p EnvCertProvider
+	if _, err := p.GetCertificate(); err == nil {
+		t.Fatal("expected an error when TLS_CERT and TLS_KEY are unset")
+	}
+	if pool, err := p.GetClientCAs(); pool != nil || err != nil {
+		t.Fatalf("expected no client CAs, got %v, %v", pool, err)
+	}
+}
+
+func TestEnvCertProviderInvalidPEM(t *testing.T) {
+	t.Setenv(EnvTLSCert, "not a certificate")
+	t.Setenv(EnvTLSKey, "not a key")
+
+	var p EnvCertProvider
+	if _, err := p.GetCertificate(); err == nil {
+		t.Fatal("expected an error for invalid PEM content")
+	}
+}
+
+// countingProvider counts calls and can be made to fail.
+type countingProvider struct {
+	calls int
+	cert  *tls.Certificate
+	err   error
+}
+
+func (p *countingProvider) GetCertificate() (*tls.Certificate, error) {
+	p.calls++
+	return p.cert, p.err
+}
+
+func (p *countingProvider) GetClientCAs() (*x509.CertPool, error) {
+	return nil, nil
+}
+
+func TestCachingCertProviderRefreshesAfterInterval(t *testing.T) {
+	inner := &countingProvider{cert: &tls.Certificate{}}
+	p := NewCachingCertProvider(inner, 50*time.Millisecond)
+
+	for i := 0; i < 3; i++ {
+		if _, err := p.GetCertificate(); err != nil {
+			t.Fatalf("GetCertificate: %v", err)
+		}
+	}
+	if inner.calls != 1 {
+		t.Fatalf("expected a single fetch while cached, got %d", inner.calls)
+	}
+
+	// A failed refresh keeps serving the cached certificate.
+	time.Sleep(60 * time.Millisecond)
+	inner.err = errors.New("vault unavailable")
+	if cert, err := p.GetCertificate(); err != nil || cert == nil {
+		t.Fatalf("expected cached certificate after failed refresh, got %v, %v", cert, err)
+	}
+	if inner.calls != 2 {
+		t.Fatalf("expected a refresh after the interval, got %d fetches", inner.calls)
+	}
+
+	// The failure is not retried until another interval has passed.
+	for i := 0; i < 3; i++ {
+		if _, err := p.GetCertificate(); err != nil {
+			t.Fatalf("GetCertificate: %v", err)
+		}
+	}
+	if inner.calls != 2 {
+		t.Fatalf("expected no fetches while backing off, got %d", inner.calls)
+	}
+	time.Sleep(60 * time.Millisecond)
+	p.GetCertificate()
+	if inner.calls != 3 {
+		t.Fatalf("expected a retry after the interval, got %d fetches", inner.calls)
+	}
+}
+
+func TestTLSConfigServesProviderCertificate(t *testing.T) {
+	certPEM, keyPEM := selfSignedPEM(t, "localhost")
+	t.Setenv(EnvTLSCert, string(certPEM))
+	t.Setenv(EnvTLSKey, string(keyPEM))
+	t.Setenv(EnvTLSClientCA, "")
+
+	s, err := NewServer(WithDelay(time.Second), WithErrorRate(0), WithCertProvider(EnvCertProvider{}))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	srv := httptest.NewUnstartedServer(s.routes())
+	srv.TLS = tlsConfig(s.certProvider)
+	srv.StartTLS()
+	defer srv.Close()
+
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(certPEM)
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots, ServerName: "localhost"}}}
+
+	resp, err := client.Get(srv.URL + "/healthz")
+	if err != nil {
+		t.Fatalf("GET over TLS: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,116 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// maxUploadBytes bounds the file uploaded with a multipart POST /jobs.
+const maxUploadBytes = 1 << 30
+
+// MetadataFieldPrefix starts the names of the fields of a multipart POST
+// /jobs setting the job's Metadata, e.g. metadata.source_lang.
+const MetadataFieldPrefix = "metadata."
+
+// errUploadTooLarge is returned by readJobUpload for a file over
+// maxUploadBytes.
+var errUploadTooLarge = fmt.Errorf("file too large, at most %d bytes are accepted", maxUploadBytes)
+
+// jobUpload is a file uploaded with a multipart POST /jobs.
+type jobUpload struct {
+	path string // In os.TempDir(), removed with the job.
+	size int64
+}
+
+// readJobUpload reads the multipart/form-data body of r into req and a
+// temporary file. The file is the part named "file", and is required. A
+// field named "id" sets the job's ID, and fields named metadata.key set key
+// in its Metadata. Once it returns a jobUpload the caller must remove the
+// file unless it is kept with a job.
+func readJobUpload(r *http.Request, req *JobRequest) (*jobUpload, error) {
+	parts, err := r.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+	var upload *jobUpload
+	fail := func(err error) (*jobUpload, error) {
+		if upload != nil {
+			os.Remove(upload.path)
+		}
+		return nil, err
+	}
+	for {
+		part, err := parts.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fail(err)
+		}
+		name := part.FormName()
+		switch {
+		case name == "file":
+			if upload != nil {
+				return fail(errors.New("more than one file part"))
+			}
+			if upload, err = saveUpload(part); err != nil {
+				return fail(err)
+			}
+		case name == "id" || strings.HasPrefix(name, MetadataFieldPrefix):
+			value, err := io.ReadAll(io.LimitReader(part, maxMetadataBytes+1))
+			if err != nil {
+				return fail(err)
+			}
+			if name == "id" {
+				req.ID = string(value)
+				continue
+			}
+			if req.Metadata == nil {
+				req.Metadata = map[string]string{}
+			}
+			// Over-long values are caught by validate.
+			req.Metadata[strings.TrimPrefix(name, MetadataFieldPrefix)] = string(value)
+		default:
+			return fail(fmt.Errorf("unexpected part %q, expected file, id or %s*", name, MetadataFieldPrefix))
+		}
+	}
+	if upload == nil {
+		return nil, errors.New("missing file part")
+	}
+	return upload, nil
+}
+
+// saveUpload copies the file part to a new temporary file.
+func saveUpload(part io.Reader) (*jobUpload, error) {
+	f, err := os.CreateTemp(os.TempDir(), "job-upload-*")
+	if err != nil {
+		return nil, err
+	}
+	n, err := io.Copy(f, io.LimitReader(part, maxUploadBytes+1))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && n > maxUploadBytes {
+		err = errUploadTooLarge
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return nil, err
+	}
+	return &jobUpload{path: f.Name(), size: n}, nil
+}
+
+// removeUpload removes the file uploaded with job, if any.
+func removeUpload(job *jobState) {
+	if job.filePath == "" {
+		return
+	}
+	if err := os.Remove(job.filePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Printf("Error removing the file uploaded with job %s: %v", job.id, err)
+	}
+}
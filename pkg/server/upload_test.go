@@ -0,0 +1,76 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUploadJob(t *testing.T) {
+	s, err := NewServer(WithDelay(time.Hour), WithErrorRate(0))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	upload := func(fields map[string]string, file []byte) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		for name, value := range fields {
+			form.WriteField(name, value)
+		}
+		if file != nil {
+			part, _ := form.CreateFormFile("file", "episode.mp4")
+			part.Write(file)
+		}
+		form.Close()
+		req := httptest.NewRequest(http.MethodPost, "/jobs", &body)
+		req.Header.Set("Content-Type", form.FormDataContentType())
+		rec := httptest.NewRecorder()
+		s.routes().ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := upload(map[string]string{"id": "episode", "metadata.source_lang": "en"}, []byte("frames"))
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", rec.Code, rec.Body)
+	}
+	var resp JobResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil || resp.JobID != "episode" || resp.FileSizeBytes != 6 {
+		t.Fatalf("unexpected response %+v, %v", resp, err)
+	}
+	s.mu.Lock()
+	job := s.jobs["episode"]
+	s.mu.Unlock()
+	if data, err := os.ReadFile(job.filePath); err != nil || string(data) != "frames" || job.metadata["source_lang"] != "en" {
+		t.Fatalf("unexpected upload %q, %v, metadata %v", data, err, job.metadata)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		fields map[string]string
+		file   []byte
+		code   int
+	}{
+		{"existing job", map[string]string{"id": "episode"}, []byte("frames"), http.StatusConflict},
+		{"missing file", map[string]string{"id": "other"}, nil, http.StatusBadRequest},
+		{"unknown field", map[string]string{"callback_url": "http://example.com"}, []byte("frames"), http.StatusBadRequest},
+		{"invalid metadata", map[string]string{"metadata.lang": strings.Repeat("x", maxMetadataBytes+1)}, []byte("frames"), http.StatusBadRequest},
+	} {
+		if rec := upload(tc.fields, tc.file); rec.Code != tc.code {
+			t.Errorf("%s: expected %d, got %d: %s", tc.name, tc.code, rec.Code, rec.Body)
+		}
+	}
+
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	if _, err := os.Stat(job.filePath); !os.IsNotExist(err) {
+		t.Fatalf("expected the upload removed at Shutdown, got %v", err)
+	}
+}
@@ -0,0 +1,100 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+/*
+	Serving more than one version of the API side by side, for clients which
+	migrate from the single job /status API (v1) to jobs created with POST
+	/jobs (v2) at their own pace. Both versions are served by the same Server,
+	so they share its jobs, config, counters and middleware state.
+*/
+
+// VersionRouter serves each request with the handler registered for the first
+// segment of its path, e.g. /v1 for /v1/status, with that prefix stripped.
+// Requests for any other prefix get a 404.
+type VersionRouter struct {
+	handlers map[string]http.Handler
+}
+
+// NewVersionRouter returns a VersionRouter without any versions.
+func NewVersionRouter() *VersionRouter {
+	return &VersionRouter{handlers: make(map[string]http.Handler)}
+}
+
+// Handle serves requests under prefix, e.g. "/v2", with h.
+func (vr *VersionRouter) Handle(prefix string, h http.Handler) {
+	vr.handlers[prefix] = http.StripPrefix(prefix, h)
+}
+
+// ServeHTTP implements http.Handler.
+func (vr *VersionRouter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	segment, _, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	prefix := "/" + segment
+	h, ok := vr.handlers[prefix]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), versionPrefixKey{}, prefix)))
+}
+
+// versionPrefixKey is the context key of the prefix a VersionRouter stripped.
+type versionPrefixKey struct{}
+
+// versionPrefix returns the prefix a VersionRouter stripped from the request
+// with ctx, so URLs sent back to clients include it, or "" if there is none.
+func versionPrefix(ctx context.Context) string {
+	prefix, _ := ctx.Value(versionPrefixKey{}).(string)
+	return prefix
+}
+
+// MultiVersionServer serves the v1 API, the implicit job behind /v1/status
+// and /v1/status/stream, alongside the v2 API, jobs created with POST
+// /v2/jobs and polled on /v2/jobs/{id}/status. /healthz and /stats are served
+// under both prefixes.
+type MultiVersionServer struct {
+	server  *Server
+	handler http.Handler
+}
+
+// NewMultiVersionServer returns a MultiVersionServer with cfg, changed by any
+// opts, for both versions. It fails if NewServer would.
+func NewMultiVersionServer(cfg Config, opts ...ServerOption) (*MultiVersionServer, error) {
+	s, err := NewServer(append([]ServerOption{WithConfig(&cfg)}, opts...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	v1 := http.NewServeMux()
+	v1.HandleFunc("/status", s.statusHandler)
+	v1.HandleFunc("/status/stream", s.statusStreamHandler)
+	v2 := http.NewServeMux()
+	v2.HandleFunc("/jobs", s.createJobHandler)
+	v2.HandleFunc("/jobs/", s.jobStatusHandler)
+	for _, mux := range []*http.ServeMux{v1, v2} {
+		mux.HandleFunc("/healthz", s.healthzHandler)
+		mux.HandleFunc("/stats", s.statsHandler)
+	}
+
+	// Each version has its own middleware chain, which sees paths without the
+	// prefix, e.g. to exclude /healthz from authentication.
+	router := NewVersionRouter()
+	router.Handle("/v1", s.withMiddleware(v1))
+	router.Handle("/v2", s.withMiddleware(v2))
+	return &MultiVersionServer{server: s, handler: router}, nil
+}
+
+// Server returns the Server behind both versions, e.g. to read its Metrics or
+// Shutdown its background goroutines.
+func (m *MultiVersionServer) Server() *Server {
+	return m.server
+}
+
+// ServeHTTP implements http.Handler.
+func (m *MultiVersionServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.handler.ServeHTTP(w, r)
+}
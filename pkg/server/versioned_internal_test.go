@@ -0,0 +1,32 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestVersionRouter(t *testing.T) {
+	router := NewVersionRouter()
+	router.Handle("/v1", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("v1 " + r.URL.Path + " " + versionPrefix(r.Context())))
+	}))
+
+	tests := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/v1/status", http.StatusOK, "v1 /status /v1"},
+		{"/v1", http.StatusOK, "v1  /v1"},
+		{"/v10/status", http.StatusNotFound, ""},
+		{"/", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if rec.Code != tt.code || (tt.body != "" && rec.Body.String() != tt.body) {
+			t.Fatalf("%s: expected %d %q, got %d %q", tt.path, tt.code, tt.body, rec.Code, rec.Body.String())
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package server_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"Video-Translation-Simulator/pkg/server"
+	"Video-Translation-Simulator/pkg/testutil/fixtures"
+)
+
+// getResult polls url, returning the response code and its result.
+func getResult(t *testing.T, url string) (int, string) {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("GET %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+	var r server.Response
+	json.NewDecoder(resp.Body).Decode(&r)
+	return resp.StatusCode, r.Result
+}
+
+func TestMultiVersionServer(t *testing.T) {
+	m, err := server.NewMultiVersionServer(*fixtures.FixtureFastServer)
+	if err != nil {
+		t.Fatalf("NewMultiVersionServer: %v", err)
+	}
+	defer m.Server().Shutdown(context.Background())
+	ts := httptest.NewServer(m)
+	defer ts.Close()
+
+	// v1: the implicit job.
+	if code, result := getResult(t, ts.URL+"/v1/status"); code != http.StatusOK || result != "pending" {
+		t.Fatalf("v1: expected 200 pending, got %d %q", code, result)
+	}
+
+	// v2: a job of its own, polled on the URL the server hands back.
+	resp, err := http.Post(ts.URL+"/v2/jobs", "application/json", strings.NewReader(`{"id":"v2job"}`))
+	if err != nil {
+		t.Fatalf("POST /v2/jobs: %v", err)
+	}
+	var job server.JobResponse
+	json.NewDecoder(resp.Body).Decode(&job)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted || job.PollURL != "/v2/jobs/v2job/status" || resp.Header.Get("Location") != job.PollURL {
+		t.Fatalf("v2: expected 202 with poll URL /v2/jobs/v2job/status, got %d %+v, Location %q", resp.StatusCode, job, resp.Header.Get("Location"))
+	}
+	if code, result := getResult(t, ts.URL+job.PollURL); code != http.StatusOK || result != "pending" {
+		t.Fatalf("v2: expected 200 pending, got %d %q", code, result)
+	}
+
+	// Both finish side by side, and polling v1 again doesn't touch the v2 job.
+	time.Sleep(1100 * time.Millisecond)
+	if _, result := getResult(t, ts.URL+"/v1/status"); result != "completed" {
+		t.Fatalf("v1: expected completed, got %q", result)
+	}
+	if _, result := getResult(t, ts.URL+"/v1/status"); result != "pending" {
+		t.Fatalf("v1: expected the next implicit job to start, got %q", result)
+	}
+	if _, result := getResult(t, ts.URL+job.PollURL); result != "completed" {
+		t.Fatalf("v2: expected completed, got %q", result)
+	}
+
+	// The versions share one server, so its counters see both.
+	if m := m.Server().Metrics(); m.CompletedCount != 2 {
+		t.Fatalf("expected 2 completed jobs across both versions, got %+v", m)
+	}
+
+	// Each version only has its own endpoints.
+	for _, path := range []string{"/v1/jobs", "/v2/status", "/v3/status", "/status", "/jobs/v2job/status"} {
+		if code, _ := getResult(t, ts.URL+path); code != http.StatusNotFound {
+			t.Fatalf("%s: expected 404, got %d", path, code)
+		}
+	}
+	for _, path := range []string{"/v1/healthz", "/v2/healthz"} {
+		if code, _ := getResult(t, ts.URL+path); code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d", path, code)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+)
+
+// watcherBuffer is how many events a JobEvent channel holds. Events for a
+// watcher which has fallen this far behind are dropped.
+const watcherBuffer = 16
+
+// ErrJobNotFound is returned by WatchJob for IDs of jobs the server doesn't
+// hold, never created with POST /jobs or since expired.
+var ErrJobNotFound = errors.New("job not found")
+
+// JobEvent is a change in the status of a job created with POST /jobs.
+type JobEvent struct {
+	JobID     string
+	OldStatus string
+	NewStatus string
+	Timestamp time.Time
+}
+
+// WatchJob returns a channel receiving an event whenever the job created with
+// jobID changes status, closed once the job reaches a final status it can't be
+// retried from, expires or ctx is done. A failed job with retries left keeps
+// its watchers, who see it go back to pending if it is retried. A job that has
+// already settled gets a closed channel. Sends don't
+// block the server: a watcher whose channel is full misses the event.
+func (s *Server) WatchJob(ctx context.Context, jobID string) (<-chan JobEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job := s.jobs[jobID]
+	if job == nil {
+		return nil, ErrJobNotFound
+	}
+	events := make(chan JobEvent, watcherBuffer)
+	if s.advanceJob(job); job.settled() {
+		close(events)
+		return events, nil
+	}
+	s.watchers[jobID] = append(s.watchers[jobID], events)
+	context.AfterFunc(ctx, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.removeWatcher(jobID, events)
+	})
+	return events, nil
+}
+
+// removeWatcher unregisters and closes events, unless the job's final status
+// already has. The caller must hold s.mu.
+func (s *Server) removeWatcher(jobID string, events chan JobEvent) {
+	watchers := s.watchers[jobID]
+	for i, ch := range watchers {
+		if ch == events {
+			close(ch)
+			watchers = append(watchers[:i], watchers[i+1:]...)
+			break
+		}
+	}
+	if len(watchers) == 0 {
+		delete(s.watchers, jobID)
+		return
+	}
+	s.watchers[jobID] = watchers
+}
+
+// notifyWatchers sends the change of job from status old to the watchers of
+// its ID, closing their channels once it has settled. The caller must hold
+// s.mu.
+func (s *Server) notifyWatchers(job *jobState, old string) {
+	watchers := s.watchers[job.id]
+	if job.id == "" || len(watchers) == 0 {
+		return
+	}
+	event := JobEvent{JobID: job.id, OldStatus: old, NewStatus: job.status, Timestamp: time.Now()}
+	for _, ch := range watchers {
+		select {
+		case ch <- event:
+		default:
+			log.Printf("Watcher of job %s is full, dropping its %s event.", job.id, job.status)
+		}
+	}
+	if job.settled() {
+		s.closeWatchers(job.id)
+	}
+}
+
+// closeWatchers closes and unregisters the watchers of job id. The caller must
+// hold s.mu.
+func (s *Server) closeWatchers(id string) {
+	for _, ch := range s.watchers[id] {
+		close(ch)
+	}
+	delete(s.watchers, id)
+}
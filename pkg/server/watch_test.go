@@ -0,0 +1,106 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// collect reads events until the channel is closed, failing after a second.
+func collect(t *testing.T, events <-chan JobEvent) []JobEvent {
+	t.Helper()
+	var got []JobEvent
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				return got
+			}
+			got = append(got, e)
+		case <-timeout:
+			t.Fatalf("channel not closed, got %v so far", got)
+		}
+	}
+}
+
+// mustWatch watches job id, failing the test if it can't.
+func mustWatch(t *testing.T, s *Server, id string) <-chan JobEvent {
+	t.Helper()
+	events, err := s.WatchJob(context.Background(), id)
+	if err != nil {
+		t.Fatalf("WatchJob: %v", err)
+	}
+	return events
+}
+
+func TestWatchJobFansOutToWatchers(t *testing.T) {
+	s, err := NewServer(WithDelay(time.Second), WithErrorRate(0))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	rec := httptest.NewRecorder()
+	s.routes().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/jobs", strings.NewReader(`{"id":"watched"}`)))
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d", rec.Code)
+	}
+
+	first, err := s.WatchJob(context.Background(), "watched")
+	if err != nil {
+		t.Fatalf("WatchJob: %v", err)
+	}
+	second, err := s.WatchJob(context.Background(), "watched")
+	if err != nil {
+		t.Fatalf("WatchJob: %v", err)
+	}
+
+	s.mu.Lock()
+	s.jobs["watched"].startTime = time.Now().Add(-time.Second)
+	s.mu.Unlock()
+	rec = httptest.NewRecorder()
+	s.routes().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/jobs/watched/status", nil))
+
+	for i, events := range []<-chan JobEvent{first, second} {
+		got := collect(t, events)
+		if len(got) != 1 || got[0].JobID != "watched" || got[0].OldStatus != "pending" || got[0].NewStatus != "completed" || got[0].Timestamp.IsZero() {
+			t.Fatalf("watcher %d: expected pending to completed, got %+v", i+1, got)
+		}
+	}
+
+	// The job is finished, so there is nothing more to watch.
+	if got := collect(t, mustWatch(t, s, "watched")); len(got) != 0 {
+		t.Fatalf("expected no events for a finished job, got %+v", got)
+	}
+	if _, err := s.WatchJob(context.Background(), "missing"); !errors.Is(err, ErrJobNotFound) {
+		t.Fatalf("expected ErrJobNotFound, got %v", err)
+	}
+}
+
+func TestWatchJobClosedWithContext(t *testing.T) {
+	s, err := NewServer(WithDelay(time.Hour), WithErrorRate(0))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	s.mu.Lock()
+	s.jobs["long"] = &jobState{id: "long", startTime: time.Now(), status: "pending"}
+	s.mu.Unlock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events, err := s.WatchJob(ctx, "long")
+	if err != nil {
+		t.Fatalf("WatchJob: %v", err)
+	}
+	cancel()
+	if got := collect(t, events); len(got) != 0 {
+		t.Fatalf("expected no events, got %+v", got)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if n := len(s.watchers["long"]); n != 0 {
+		t.Fatalf("expected the watcher to be removed, got %d", n)
+	}
+}
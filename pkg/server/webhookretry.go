@@ -0,0 +1,113 @@
+package server
+
+import (
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Defaults for retrying failed callbacks.
+const (
+	DefaultMaxWebhookRetries    = 10
+	DefaultWebhookRetryInterval = 500 * time.Millisecond
+	maxWebhookRetryInterval     = 10 * time.Second
+)
+
+// RetryEntry is a failed callback waiting in a WebhookRetryQueue.
+type RetryEntry struct {
+	ID        string // Unique within the queue.
+	URL       string
+	Payload   CallbackPayload
+	Attempts  int    // Failed attempts so far, the first included.
+	LastError string // Why the last attempt failed.
+	Delay     time.Duration
+	NextRetry time.Time // Set by Enqueue, Delay after the entry was enqueued.
+}
+
+// WebhookRetryQueue holds failed callbacks until they are due to be sent
+// again. Each time an entry is enqueued its delay doubles, starting from the
+// queue's initial interval up to 10s (or the initial interval if longer), and is reduced at random by up to half
+// so callbacks failing together don't all retry together. This is the same
+// backoff as the client's default RetryPolicy. Entries are kept in memory,
+// for the life of the server.
+type WebhookRetryQueue struct {
+	initial time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*queuedRetry
+}
+
+type queuedRetry struct {
+	entry RetryEntry
+	taken bool // Returned by NextDue, and not enqueued again since.
+}
+
+// NewWebhookRetryQueue returns an empty queue whose first retries wait about
+// initial, or DefaultWebhookRetryInterval if it isn't positive.
+func NewWebhookRetryQueue(initial time.Duration) *WebhookRetryQueue {
+	if initial <= 0 {
+		initial = DefaultWebhookRetryInterval
+	}
+	return &WebhookRetryQueue{initial: initial, entries: make(map[string]*queuedRetry)}
+}
+
+// Enqueue adds entry, or replaces the entry with the same ID, to be retried
+// once its backoff has passed. The backoff grows from entry.Delay, the delay
+// before its previous retry, which is zero for a first retry.
+func (q *WebhookRetryQueue) Enqueue(entry RetryEntry) {
+	delay := entry.Delay * 2
+	if delay == 0 {
+		delay = q.initial
+	}
+	if limit := max(q.initial, maxWebhookRetryInterval); delay > limit {
+		delay = limit
+	}
+	entry.Delay = delay
+	wait := delay
+	if spread := delay / 2; spread > 0 {
+		wait -= time.Duration(rand.Int63n(int64(spread)))
+	}
+	entry.NextRetry = time.Now().Add(wait)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.entries[entry.ID] = &queuedRetry{entry: entry}
+}
+
+// NextDue returns the entries due to be retried, oldest first. Each is
+// returned once: it stays in the queue, but isn't returned again until it is
+// enqueued again after failing once more.
+func (q *WebhookRetryQueue) NextDue() []RetryEntry {
+	now := time.Now()
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var due []RetryEntry
+	for _, r := range q.entries {
+		if !r.taken && !r.entry.NextRetry.After(now) {
+			r.taken = true
+			due = append(due, r.entry)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].NextRetry.Before(due[j].NextRetry) })
+	return due
+}
+
+// MarkSucceeded removes the entry with id, whose retry succeeded.
+func (q *WebhookRetryQueue) MarkSucceeded(id string) {
+	q.Drop(id)
+}
+
+// Drop removes the entry with id, which is given up on.
+func (q *WebhookRetryQueue) Drop(id string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.entries, id)
+}
+
+// Len returns how many entries the queue holds, due or not.
+func (q *WebhookRetryQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.entries)
+}
@@ -0,0 +1,43 @@
+package server
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWebhookRetryQueue(t *testing.T) {
+	q := NewWebhookRetryQueue(20 * time.Millisecond)
+	q.Enqueue(RetryEntry{ID: "a"})
+	q.Enqueue(RetryEntry{ID: "b", Delay: time.Hour})
+	if due := q.NextDue(); len(due) != 0 {
+		t.Fatalf("expected nothing due yet, got %+v", due)
+	}
+
+	time.Sleep(25 * time.Millisecond)
+	due := q.NextDue()
+	if len(due) != 1 || due[0].ID != "a" || due[0].Delay != 20*time.Millisecond {
+		t.Fatalf("expected a due after 20ms, got %+v", due)
+	}
+	if again := q.NextDue(); len(again) != 0 {
+		t.Fatalf("expected a to be returned once, got %+v", again)
+	}
+
+	// Enqueued again, the delay doubles.
+	q.Enqueue(due[0])
+	time.Sleep(45 * time.Millisecond)
+	if due = q.NextDue(); len(due) != 1 || due[0].Delay != 40*time.Millisecond {
+		t.Fatalf("expected a due again after 40ms, got %+v", due)
+	}
+	q.MarkSucceeded("a")
+	if n := q.Len(); n != 1 {
+		t.Fatalf("expected only b left, got %d entries", n)
+	}
+
+	// b's delay is capped at 10s.
+	q.mu.Lock()
+	delay := q.entries["b"].entry.Delay
+	q.mu.Unlock()
+	if delay != maxWebhookRetryInterval {
+		t.Fatalf("expected b to wait 10s, got %v", delay)
+	}
+}
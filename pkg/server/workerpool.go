@@ -0,0 +1,126 @@
+package server
+
+import (
+	"hash/fnv"
+	"log"
+	"sync"
+	"time"
+)
+
+// DefaultWorkers is the size of the WorkerPool unless WithWorkers is given.
+const DefaultWorkers = 4
+
+// workerTickInterval is how often each worker advances the jobs it owns.
+const workerTickInterval = time.Second
+
+// workerInboxSize is how many new jobs a worker's channel holds.
+const workerInboxSize = 64
+
+// WithWorkers sets how many workers the WorkerPool advancing jobs created
+// with POST /jobs has. A non-positive n is logged and the default of 4 is
+// kept.
+func WithWorkers(n int) ServerOption {
+	return func(s *Server) {
+		if n <= 0 {
+			log.Printf("Invalid %d workers. Using default of %d.", n, DefaultWorkers)
+			return
+		}
+		s.workerCount = n
+	}
+}
+
+// WorkerPool spreads the jobs created with POST /jobs over N workers. Each
+// worker has its own goroutine and map of the jobs it owns, those whose ID
+// hashes to it (see WorkerFor), and is handed them over its channel as they
+// are created. Every second a worker advances its jobs, so they finish, time
+// out and call back on time even if nobody polls them, and forgets those
+// which have expired. The jobs themselves are still guarded by Server.mu,
+// under which status requests advance them too.
+type WorkerPool struct {
+	workers []*jobWorker
+}
+
+// jobWorker is one worker of a WorkerPool.
+type jobWorker struct {
+	id    int
+	inbox chan *jobState       // New jobs owned by the worker.
+	owned map[string]*jobState // Only touched by the worker's goroutine.
+}
+
+// newWorkerPool returns a pool of n workers, which run once started by run.
+func newWorkerPool(n int) *WorkerPool {
+	p := &WorkerPool{workers: make([]*jobWorker, n)}
+	for i := range p.workers {
+		p.workers[i] = &jobWorker{id: i, inbox: make(chan *jobState, workerInboxSize), owned: make(map[string]*jobState)}
+	}
+	return p
+}
+
+// Size returns the number of workers.
+func (p *WorkerPool) Size() int {
+	return len(p.workers)
+}
+
+// WorkerFor returns the ID of the worker owning the job with id, the FNV-1a
+// hash of id modulo the number of workers.
+func (p *WorkerPool) WorkerFor(id string) int {
+	h := fnv.New32a()
+	h.Write([]byte(id))
+	return int(h.Sum32() % uint32(len(p.workers)))
+}
+
+// dispatchJob hands job, which must have its workerID set, to its worker,
+// first starting the workers unless they are already running or Shutdown has
+// been called. It may be called with s.mu held.
+func (s *Server) dispatchJob(job *jobState) {
+	s.goBackground(&s.working, func() {
+		var wg sync.WaitGroup
+		for _, w := range s.workers.workers {
+			wg.Add(1)
+			go func(w *jobWorker) {
+				defer wg.Done()
+				s.runWorker(w)
+			}(w)
+		}
+		wg.Wait()
+	})
+	w := s.workers.workers[job.workerID]
+	select {
+	case w.inbox <- job:
+	default:
+		// The worker may be waiting for s.mu, held by our caller, so don't
+		// wait for it here.
+		go func() {
+			select {
+			case w.inbox <- job:
+			case <-s.stop:
+			}
+		}()
+	}
+}
+
+// runWorker runs w until Shutdown, taking the jobs handed to it and
+// advancing them every workerTickInterval.
+func (s *Server) runWorker(w *jobWorker) {
+	ticker := time.NewTicker(workerTickInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case job := <-w.inbox:
+			w.owned[job.id] = job
+		case <-ticker.C:
+			s.mu.Lock()
+			for id, job := range w.owned {
+				if s.jobs[id] != job {
+					// Expired, and maybe replaced by a job on another run.
+					delete(w.owned, id)
+					continue
+				}
+				s.advanceJob(job)
+			}
+			s.mu.Unlock()
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWorkerPoolSpreadsJobs(t *testing.T) {
+	s, err := NewServer(WithConfig(&Config{DelaySeconds: 0, ErrorRate: 0}), WithWorkers(4))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	defer s.Shutdown(context.Background())
+	for i := 0; i < 10; i++ {
+		rec := httptest.NewRecorder()
+		s.routes().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/jobs", strings.NewReader(fmt.Sprintf(`{"id":"job-%d"}`, i))))
+		if rec.Code != http.StatusAccepted {
+			t.Fatalf("expected 202, got %d", rec.Code)
+		}
+	}
+
+	perWorker := make([]int, s.workers.Size())
+	s.mu.Lock()
+	for id, job := range s.jobs {
+		if want := s.workers.WorkerFor(id); job.workerID != want {
+			t.Fatalf("%s: expected worker %d, got %d", id, want, job.workerID)
+		}
+		perWorker[job.workerID]++
+	}
+	s.mu.Unlock()
+	for _, n := range perWorker {
+		if n == 0 || n > 5 {
+			t.Fatalf("expected the 10 jobs spread over all 4 workers, got %v", perWorker)
+		}
+	}
+
+	// The workers finish the jobs without them being polled.
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		s.mu.Lock()
+		pending := 0
+		for _, job := range s.jobs {
+			if !finished(job.status) {
+				pending++
+			}
+		}
+		s.mu.Unlock()
+		if pending == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the workers to finish every job, %d still pending", pending)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
@@ -0,0 +1,124 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/websocket"
+)
+
+/*
+	WebSocket alternative to polling /status. After connecting to /ws a
+	client sends {"action": "subscribe", "jobId": "..."} and receives a
+	{"result": ...} frame every streamInterval until the job reaches a final
+	status, as on the SSE streams. The connection then stays open for the next
+	subscription. An empty jobId subscribes to the implicit /status job.
+	Problems with a request are reported as {"error": ...} frames.
+*/
+
+// WSRequest is a frame sent by a client on the /ws endpoint.
+type WSRequest struct {
+	Action string `json:"action"`
+	JobID  string `json:"jobId"`
+}
+
+// WSError is the frame sent when a WSRequest cannot be served.
+type WSError struct {
+	Error string `json:"error"`
+}
+
+var upgrader = websocket.Upgrader{}
+
+// wsHandler upgrades the request to a WebSocket and serves subscriptions on
+// it until the client disconnects or the server shuts down.
+func (s *Server) wsHandler(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader has already responded with the error.
+		log.Printf("WebSocket upgrade failed: %v", err)
+		return
+	}
+	defer conn.Close()
+	s.counters.totalRequests.Add(1)
+
+	// Hijacked connections are not closed by http.Server.Shutdown.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-s.stop:
+			conn.Close()
+		case <-ctx.Done():
+		}
+	}()
+
+	for {
+		var req WSRequest
+		if err := conn.ReadJSON(&req); err != nil {
+			var closeErr *websocket.CloseError
+			if !errors.As(err, &closeErr) {
+				log.Printf("WebSocket read failed: %v", err)
+			}
+			return
+		}
+		if req.Action != "subscribe" {
+			if conn.WriteJSON(WSError{Error: "unknown action " + req.Action}) != nil {
+				return
+			}
+			continue
+		}
+
+		job, err := s.jobFor(req.JobID)
+		if err != nil {
+			if conn.WriteJSON(WSError{Error: err.Error()}) != nil {
+				return
+			}
+			continue
+		}
+		err = s.watchJob(ctx, job, func(response Response) error {
+			return conn.WriteJSON(response)
+		})
+		if err != nil {
+			log.Printf("WebSocket subscription ended early: %v", err)
+			return
+		}
+	}
+}
+
+// Errors from jobFor.
+var (
+	errPaused      = errors.New("server is paused")
+	errJobNotFound = errors.New("job not found")
+)
+
+// jobFor returns the job a subscription is for, like the /status and
+// /jobs/{id}/status handlers would, or an error wrapping errPaused or
+// errJobNotFound.
+func (s *Server) jobFor(id string) (*jobState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if id == "" {
+		if job := s.currentJob(); job != nil {
+			return job, nil
+		}
+		return nil, errPaused
+	}
+	if job := s.jobs[id]; job != nil {
+		return job, nil
+	}
+	return nil, jobNotFoundError(id)
+}
+
+// jobNotFoundError is the error jobFor returns for an unknown job ID. It
+// reads like the 404 from /jobs/{id}/status and matches errJobNotFound.
+type jobNotFoundError string
+
+func (e jobNotFoundError) Error() string {
+	return "job " + string(e) + " not found"
+}
+
+func (e jobNotFoundError) Is(target error) bool {
+	return target == errJobNotFound
+}
@@ -0,0 +1,61 @@
+package server
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestWebSocketSubscriptions(t *testing.T) {
+	s, err := NewServer(WithDelay(time.Second), WithErrorRate(0))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	s.streamInterval = 100 * time.Millisecond
+	ts := httptest.NewServer(s.Handler())
+	defer ts.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/ws", nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	var wsErr WSError
+	conn.WriteJSON(WSRequest{Action: "unsubscribe"})
+	if err := conn.ReadJSON(&wsErr); err != nil || wsErr.Error != "unknown action unsubscribe" {
+		t.Fatalf("expected an unknown action error, got %+v, %v", wsErr, err)
+	}
+	conn.WriteJSON(WSRequest{Action: "subscribe", JobID: "nope"})
+	if err := conn.ReadJSON(&wsErr); err != nil || wsErr.Error != "job nope not found" {
+		t.Fatalf("expected a not found error, got %+v, %v", wsErr, err)
+	}
+
+	// The connection stays open for further subscriptions after each one.
+	for _, id := range []string{"", "ws"} {
+		if id != "" {
+			createJob(t, ts, id)
+		}
+		if err := conn.WriteJSON(WSRequest{Action: "subscribe", JobID: id}); err != nil {
+			t.Fatalf("subscribing to %q: %v", id, err)
+		}
+		var results []string
+		for {
+			var r Response
+			if err := conn.ReadJSON(&r); err != nil {
+				t.Fatalf("reading status of %q: %v", id, err)
+			}
+			results = append(results, r.Result)
+			if r.Result != "pending" {
+				break
+			}
+		}
+		if len(results) < 3 || results[len(results)-1] != "completed" {
+			t.Fatalf("job %q: expected pending frames and then completed, got %v", id, results)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package testutil
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+)
+
+// DryRunServer is an httptest.Server which answers status polls from a queue
+// of statuses set up by the test, without any timers. Each poll returns the
+// next queued status for the job; once the queue is drained the last status
+// keeps being returned. Jobs are polled at /jobs/{id}/status, and the plain
+// /status endpoint used by the client is served as the job with an empty ID.
+type DryRunServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	queues   map[string][]string
+	last     map[string]string
+	requests map[string]int
+}
+
+// NewDryRunServer starts a DryRunServer. Close it when done.
+func NewDryRunServer() *DryRunServer {
+	d := &DryRunServer{
+		queues:   make(map[string][]string),
+		last:     make(map[string]string),
+		requests: make(map[string]int),
+	}
+	d.Server = httptest.NewServer(http.HandlerFunc(d.handleStatus))
+	return d
+}
+
+// QueueStatus appends statuses to be returned, in order, for jobID.
+func (d *DryRunServer) QueueStatus(jobID string, statuses ...string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.queues[jobID] = append(d.queues[jobID], statuses...)
+}
+
+// Requests returns how many times jobID has been polled.
+func (d *DryRunServer) Requests(jobID string) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.requests[jobID]
+}
+
+func (d *DryRunServer) handleStatus(w http.ResponseWriter, r *http.Request) {
+	jobID, ok := jobIDFromPath(r.URL.Path)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	d.mu.Lock()
+	d.requests[jobID]++
+	if queue := d.queues[jobID]; len(queue) > 0 {
+		d.last[jobID] = queue[0]
+		d.queues[jobID] = queue[1:]
+	}
+	status, known := d.last[jobID]
+	d.mu.Unlock()
+
+	if !known {
+		http.Error(w, "no status queued for job", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"result": status})
+}
+
+// jobIDFromPath extracts the job ID from /status or /jobs/{id}/status.
+func jobIDFromPath(path string) (string, bool) {
+	if path == "/status" {
+		return "", true
+	}
+	id, ok := strings.CutPrefix(path, "/jobs/")
+	if !ok {
+		return "", false
+	}
+	id, ok = strings.CutSuffix(id, "/status")
+	if !ok || id == "" || strings.Contains(id, "/") {
+		return "", false
+	}
+	return id, true
+}
@@ -0,0 +1,34 @@
+package testutil
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestDryRunServerDequeuesPerJob(t *testing.T) {
+	d := NewDryRunServer()
+	defer d.Close()
+	d.QueueStatus("a", "pending", "completed")
+	d.QueueStatus("b", "error")
+
+	for _, want := range []string{`{"result":"pending"}`, `{"result":"completed"}`, `{"result":"completed"}`} {
+		if got := get(t, http.DefaultClient, d.URL+"/jobs/a/status"); got != want+"\n" {
+			t.Fatalf("job a: expected %s, got %s", want, got)
+		}
+	}
+	if got := get(t, http.DefaultClient, d.URL+"/jobs/b/status"); got != `{"result":"error"}`+"\n" {
+		t.Fatalf("job b: unexpected %s", got)
+	}
+	if d.Requests("a") != 3 || d.Requests("b") != 1 {
+		t.Fatalf("unexpected request counts a=%d b=%d", d.Requests("a"), d.Requests("b"))
+	}
+
+	resp, err := http.Get(d.URL + "/jobs/unknown/status")
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 for a job with nothing queued, got %d", resp.StatusCode)
+	}
+}
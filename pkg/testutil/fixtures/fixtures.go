@@ -0,0 +1,43 @@
+// Package fixtures has ready-made server configs for common test scenarios,
+// so tests can say which kind of server they need rather than repeating
+// numbers whose intent is easy to miss.
+//
+// The fixtures are shared; copy one before changing it.
+package fixtures
+
+import (
+	"fmt"
+	"net/http/httptest"
+
+	"Video-Translation-Simulator/pkg/server"
+)
+
+var (
+	// FixtureFastServer completes every job after 1s.
+	FixtureFastServer = &server.Config{DelaySeconds: 1, ErrorRate: 0}
+
+	// FixtureSlowServer completes every job after 30s, for tests that give up
+	// or time out long before that.
+	FixtureSlowServer = &server.Config{DelaySeconds: 30, ErrorRate: 0}
+
+	// FixtureFlakeyServer finishes jobs after 5s, half of them with "error".
+	FixtureFlakeyServer = &server.Config{DelaySeconds: 5, ErrorRate: 50}
+
+	// FixtureAlwaysError fails every job after 1s.
+	FixtureAlwaysError = &server.Config{DelaySeconds: 1, ErrorRate: 100}
+
+	// FixtureInstantComplete completes every job on its first poll.
+	FixtureInstantComplete = &server.Config{DelaySeconds: 0, ErrorRate: 0}
+)
+
+// NewFixtureServer creates a server using the whole of f, including any
+// progressive delay, error windows and max pending age, and serves it with
+// httptest. Close the httptest.Server when done. It panics if f is invalid,
+// which is a mistake in the test.
+func NewFixtureServer(f *server.Config) (*server.Server, *httptest.Server) {
+	s, err := server.NewServer(server.WithConfig(f))
+	if err != nil {
+		panic(fmt.Sprintf("fixtures: invalid config %+v: %v", *f, err))
+	}
+	return s, httptest.NewServer(s.Handler())
+}
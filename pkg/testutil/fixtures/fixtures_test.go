@@ -0,0 +1,65 @@
+package fixtures
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"Video-Translation-Simulator/pkg/server"
+)
+
+// getStatus polls the fixture server once.
+func getStatus(t *testing.T, url string) string {
+	t.Helper()
+	resp, err := http.Get(url + "/status")
+	if err != nil {
+		t.Fatalf("GET /status: %v", err)
+	}
+	defer resp.Body.Close()
+	var r server.Response
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	return r.Result
+}
+
+func TestFixtures(t *testing.T) {
+	tests := []struct {
+		name    string
+		fixture *server.Config
+		first   string
+	}{
+		{"fast", FixtureFastServer, "pending"},
+		{"slow", FixtureSlowServer, "pending"},
+		{"flakey", FixtureFlakeyServer, "pending"},
+		{"always error", FixtureAlwaysError, "pending"},
+		{"instant complete", FixtureInstantComplete, "completed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, ts := NewFixtureServer(tt.fixture)
+			defer ts.Close()
+			if got := getStatus(t, ts.URL); got != tt.first {
+				t.Fatalf("expected %q on the first poll, got %q", tt.first, got)
+			}
+		})
+	}
+}
+
+func TestNewFixtureServerRejectsInvalidConfig(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected a panic for an error rate over 100")
+		}
+	}()
+	NewFixtureServer(&server.Config{DelaySeconds: 1, ErrorRate: 101})
+}
+
+func TestNewFixtureServerAppliesWholeConfig(t *testing.T) {
+	_, ts := NewFixtureServer(&server.Config{DelaySeconds: 30, MaxPendingAge: time.Nanosecond})
+	defer ts.Close()
+	if got := getStatus(t, ts.URL); got != "error" {
+		t.Fatalf("expected max pending age to fail the job, got %q", got)
+	}
+}
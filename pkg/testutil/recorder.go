@@ -0,0 +1,90 @@
+package testutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+)
+
+/*
+	The testutil package holds helpers for testing code built on the client and
+	server without depending on a live server or on timing.
+
+	Recorder and the replay transport make golden-file tests possible: record a
+	real client/server run once with a Recorder, commit the JSON lines file, and
+	replay it with NewReplayTransport in CI.
+*/
+
+// Interaction is one recorded request/response pair, stored as a JSON line.
+type Interaction struct {
+	Method     string      `json:"method"`
+	URL        string      `json:"url"`
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header"`
+	Body       string      `json:"body"`
+}
+
+// Recorder is an http.RoundTripper which passes requests on to Transport and
+// appends every request/response pair to a file as JSON lines.
+type Recorder struct {
+	Transport http.RoundTripper // Defaults to http.DefaultTransport when nil.
+
+	mu   sync.Mutex
+	file *os.File
+	enc  *json.Encoder
+}
+
+// NewRecorder creates (or truncates) file and records the traffic going
+// through base into it. Close the recorder to flush the file.
+func NewRecorder(file string, base http.RoundTripper) (*Recorder, error) {
+	f, err := os.Create(file)
+	if err != nil {
+		return nil, fmt.Errorf("creating recording: %w", err)
+	}
+	return &Recorder{Transport: base, file: f, enc: json.NewEncoder(f)}, nil
+}
+
+// RoundTrip implements http.RoundTripper. The response body is read fully so
+// it can be recorded, and handed back to the caller unchanged.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	transport := r.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("reading response body for recording: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	err = r.enc.Encode(Interaction{
+		Method:     req.Method,
+		URL:        req.URL.String(),
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       string(body),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("writing recording: %w", err)
+	}
+	return resp, nil
+}
+
+// Close closes the recording file.
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.file.Close()
+}
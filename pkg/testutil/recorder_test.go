@@ -0,0 +1,84 @@
+package testutil
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecordThenReplay(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"result":"pending","call":%d}`, calls)
+	}))
+
+	file := filepath.Join(t.TempDir(), "recording.jsonl")
+	rec, err := NewRecorder(file, nil)
+	if err != nil {
+		t.Fatalf("NewRecorder: %v", err)
+	}
+	live := &http.Client{Transport: rec}
+	var recorded []string
+	for i := 0; i < 3; i++ {
+		recorded = append(recorded, get(t, live, srv.URL+"/status"))
+	}
+	rec.Close()
+	srv.Close()
+
+	// Replay against a different host: no server is listening anymore.
+	replay := &http.Client{Transport: NewReplayTransport(file)}
+	for i, want := range recorded {
+		if got := get(t, replay, "http://replayed.invalid/status"); got != want {
+			t.Fatalf("response %d: expected %q, got %q", i+1, want, got)
+		}
+	}
+
+	if _, err := replay.Get("http://replayed.invalid/status"); err == nil || !strings.Contains(err.Error(), "all 3 recorded interactions used") {
+		t.Fatalf("expected an error once the recording is exhausted, got %v", err)
+	}
+}
+
+func TestReplayRejectsMismatchedURL(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	file := filepath.Join(t.TempDir(), "recording.jsonl")
+	rec, err := NewRecorder(file, nil)
+	if err != nil {
+		t.Fatalf("NewRecorder: %v", err)
+	}
+	get(t, &http.Client{Transport: rec}, srv.URL+"/status")
+	rec.Close()
+
+	replay := &http.Client{Transport: NewReplayTransport(file)}
+	if _, err := replay.Get(srv.URL + "/healthz"); err == nil || !strings.Contains(err.Error(), "expected GET /status") {
+		t.Fatalf("expected a mismatch error, got %v", err)
+	}
+}
+
+func TestReplayMissingFile(t *testing.T) {
+	replay := &http.Client{Transport: NewReplayTransport(filepath.Join(t.TempDir(), "missing.jsonl"))}
+	if _, err := replay.Get("http://replayed.invalid/status"); err == nil {
+		t.Fatal("expected an error for a missing recording")
+	}
+}
+
+func get(t *testing.T, c *http.Client, url string) string {
+	t.Helper()
+	resp, err := c.Get(url)
+	if err != nil {
+		t.Fatalf("GET %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("reading body: %v", err)
+	}
+	return string(body)
+}
@@ -0,0 +1,98 @@
+package testutil
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+)
+
+// replayTransport serves recorded responses in order without touching the network.
+type replayTransport struct {
+	mu           sync.Mutex
+	interactions []Interaction
+	next         int
+	loadErr      error
+}
+
+// NewReplayTransport returns an http.RoundTripper which answers requests with
+// the responses recorded in file by a Recorder, in the order they were
+// recorded. Requests are matched on method, path and query, so a recording
+// made against one host (e.g. a random httptest port) replays against any
+// other. A mismatching request, running out of recorded responses or failing
+// to read file makes RoundTrip return an error.
+func NewReplayTransport(file string) http.RoundTripper {
+	interactions, err := loadInteractions(file)
+	return &replayTransport{interactions: interactions, loadErr: err}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *replayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	if t.loadErr != nil {
+		return nil, t.loadErr
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.next >= len(t.interactions) {
+		return nil, fmt.Errorf("replay: unexpected request %s %s, all %d recorded interactions used",
+			req.Method, req.URL, len(t.interactions))
+	}
+	recorded := t.interactions[t.next]
+
+	want, err := url.Parse(recorded.URL)
+	if err != nil {
+		return nil, fmt.Errorf("replay: bad recorded URL %q: %w", recorded.URL, err)
+	}
+	if recorded.Method != req.Method || want.RequestURI() != req.URL.RequestURI() {
+		return nil, fmt.Errorf("replay: interaction %d expected %s %s, got %s %s",
+			t.next+1, recorded.Method, want.RequestURI(), req.Method, req.URL.RequestURI())
+	}
+	t.next++
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recorded.StatusCode, http.StatusText(recorded.StatusCode)),
+		StatusCode:    recorded.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        recorded.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader([]byte(recorded.Body))),
+		ContentLength: int64(len(recorded.Body)),
+		Request:       req,
+	}, nil
+}
+
+func loadInteractions(file string) ([]Interaction, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, fmt.Errorf("replay: opening recording: %w", err)
+	}
+	defer f.Close()
+
+	var interactions []Interaction
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 10*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var i Interaction
+		if err := json.Unmarshal(scanner.Bytes(), &i); err != nil {
+			return nil, fmt.Errorf("replay: %s line %d: %w", file, line, err)
+		}
+		interactions = append(interactions, i)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("replay: reading recording: %w", err)
+	}
+	return interactions, nil
+}
@@ -0,0 +1,215 @@
+// Package trace is a small, dependency free tracing context: a trace per
+// request made up of spans with parent-child links, kept in memory so they
+// can be inspected without an external collector.
+package trace
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrNotFound is returned by TraceStore.Get for unknown or evicted traces.
+var ErrNotFound = errors.New("trace not found")
+
+// Trace is the root span of a request. Child spans started from it share its
+// TraceID and are recorded in Spans once added to a TraceStore.
+type Trace struct {
+	TraceID    string
+	SpanID     string
+	StartTime  time.Time
+	Attributes map[string]string
+	Spans      []*Span
+
+	// DroppedSpans counts spans not recorded because the trace already had
+	// MaxSpansPerTrace of them.
+	DroppedSpans int
+}
+
+// MaxSpansPerTrace caps the spans a TraceStore keeps for one trace. Trace IDs
+// can come from the client, so without it a caller reusing one ID could grow
+// a single trace without bound.
+const MaxSpansPerTrace = 256
+
+// Span is a timed piece of work within a trace.
+type Span struct {
+	TraceID      string
+	SpanID       string
+	ParentSpanID string
+	Name         string
+	StartTime    time.Time
+	Duration     time.Duration // Zero until End is called.
+	Attributes   map[string]string
+}
+
+// NewTrace starts a trace with a random, UUID formatted TraceID.
+func NewTrace() *Trace {
+	return NewTraceWithID(newTraceID())
+}
+
+// NewTraceWithID starts a trace continuing traceID, e.g. one received from
+// an upstream service.
+func NewTraceWithID(traceID string) *Trace {
+	return &Trace{
+		TraceID:    traceID,
+		SpanID:     newSpanID(),
+		StartTime:  time.Now(),
+		Attributes: make(map[string]string),
+	}
+}
+
+// StartSpan starts a child span of the trace's root span.
+func (t *Trace) StartSpan(name string) *Span {
+	return &Span{
+		TraceID:      t.TraceID,
+		SpanID:       newSpanID(),
+		ParentSpanID: t.SpanID,
+		Name:         name,
+		StartTime:    time.Now(),
+		Attributes:   make(map[string]string),
+	}
+}
+
+// StartChild starts a span nested under s.
+func (s *Span) StartChild(name string) *Span {
+	return &Span{
+		TraceID:      s.TraceID,
+		SpanID:       newSpanID(),
+		ParentSpanID: s.SpanID,
+		Name:         name,
+		StartTime:    time.Now(),
+		Attributes:   make(map[string]string),
+	}
+}
+
+// End records how long the span took.
+func (s *Span) End() {
+	s.Duration = time.Since(s.StartTime)
+}
+
+type contextKey struct{}
+
+// ContextWithTrace returns a copy of ctx carrying t.
+func ContextWithTrace(ctx context.Context, t *Trace) context.Context {
+	return context.WithValue(ctx, contextKey{}, t)
+}
+
+// TraceFromContext returns the trace stored in ctx by ContextWithTrace.
+func TraceFromContext(ctx context.Context) (*Trace, bool) {
+	t, ok := ctx.Value(contextKey{}).(*Trace)
+	return t, ok
+}
+
+// TraceStore keeps the most recent traces in memory. It is safe for
+// concurrent use.
+type TraceStore struct {
+	max int
+
+	mu     sync.Mutex
+	traces map[string]*Trace
+	order  []string // Trace IDs, oldest first, for eviction.
+}
+
+// NewTraceStore returns a store keeping up to max traces, evicting the oldest
+// beyond that.
+func NewTraceStore(max int) *TraceStore {
+	if max < 1 {
+		max = 1
+	}
+	return &TraceStore{max: max, traces: make(map[string]*Trace)}
+}
+
+// Add stores t. If a trace with the same ID is already stored, t is recorded
+// as a span under its root instead, so several requests in the same trace
+// end up together, up to MaxSpansPerTrace.
+func (s *TraceStore) Add(t *Trace) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if existing, ok := s.traces[t.TraceID]; ok {
+		existing.appendSpan(&Span{
+			TraceID:      t.TraceID,
+			SpanID:       t.SpanID,
+			ParentSpanID: existing.SpanID,
+			Name:         "request",
+			StartTime:    t.StartTime,
+			Attributes:   copyAttributes(t.Attributes),
+		})
+		return
+	}
+	if len(s.order) >= s.max {
+		delete(s.traces, s.order[0])
+		s.order = s.order[1:]
+	}
+	s.traces[t.TraceID] = t
+	s.order = append(s.order, t.TraceID)
+}
+
+// AddSpan appends span to its trace's span list. Spans of unknown traces, and
+// those beyond MaxSpansPerTrace, are dropped.
+func (s *TraceStore) AddSpan(span *Span) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if t, ok := s.traces[span.TraceID]; ok {
+		t.appendSpan(span)
+	}
+}
+
+// appendSpan records span unless t is already full.
+func (t *Trace) appendSpan(span *Span) {
+	if len(t.Spans) >= MaxSpansPerTrace {
+		t.DroppedSpans++
+		return
+	}
+	t.Spans = append(t.Spans, span)
+}
+
+// Get returns a copy of the trace with the given ID.
+func (s *TraceStore) Get(traceID string) (*Trace, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.traces[traceID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, traceID)
+	}
+	c := *t
+	c.Attributes = copyAttributes(t.Attributes)
+	c.Spans = make([]*Span, len(t.Spans))
+	for i, span := range t.Spans {
+		sc := *span
+		sc.Attributes = copyAttributes(span.Attributes)
+		c.Spans[i] = &sc
+	}
+	return &c, nil
+}
+
+func copyAttributes(m map[string]string) map[string]string {
+	c := make(map[string]string, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
+// newTraceID returns 16 random bytes formatted like a UUID.
+func newTraceID() string {
+	b := randomBytes(16)
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// newSpanID returns 8 random bytes in hex.
+func newSpanID() string {
+	return hex.EncodeToString(randomBytes(8))
+}
+
+func randomBytes(n int) []byte {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		// crypto/rand only fails if the OS has no entropy source at all.
+		panic(fmt.Sprintf("trace: generating ID: %v", err))
+	}
+	return b
+}
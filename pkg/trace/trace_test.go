@@ -0,0 +1,96 @@
+package trace
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+)
+
+func TestNewTraceIDs(t *testing.T) {
+	uuid := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+	a, b := NewTrace(), NewTrace()
+	if !uuid.MatchString(a.TraceID) {
+		t.Fatalf("expected a UUID formatted trace ID, got %q", a.TraceID)
+	}
+	if a.TraceID == b.TraceID || a.SpanID == b.SpanID {
+		t.Fatal("expected every trace to get new IDs")
+	}
+}
+
+func TestSpanParents(t *testing.T) {
+	root := NewTrace()
+	child := root.StartSpan("child")
+	grandchild := child.StartChild("grandchild")
+
+	if child.TraceID != root.TraceID || grandchild.TraceID != root.TraceID {
+		t.Fatal("expected spans to share the trace ID")
+	}
+	if child.ParentSpanID != root.SpanID {
+		t.Fatalf("expected child's parent %s, got %s", root.SpanID, child.ParentSpanID)
+	}
+	if grandchild.ParentSpanID != child.SpanID {
+		t.Fatalf("expected grandchild's parent %s, got %s", child.SpanID, grandchild.ParentSpanID)
+	}
+}
+
+func TestContext(t *testing.T) {
+	if _, ok := TraceFromContext(context.Background()); ok {
+		t.Fatal("expected no trace in an empty context")
+	}
+	tr := NewTrace()
+	if got, ok := TraceFromContext(ContextWithTrace(context.Background(), tr)); !ok || got != tr {
+		t.Fatal("expected the stored trace back")
+	}
+}
+
+func TestTraceStore(t *testing.T) {
+	s := NewTraceStore(2)
+	first := NewTrace()
+	s.Add(first)
+	span := first.StartSpan("work")
+	span.End()
+	s.AddSpan(span)
+
+	got, err := s.Get(first.TraceID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if len(got.Spans) != 1 || got.Spans[0].ParentSpanID != first.SpanID {
+		t.Fatalf("expected one child of the root, got %+v", got.Spans)
+	}
+
+	// A second request in the same trace is linked under the root.
+	again := NewTraceWithID(first.TraceID)
+	s.Add(again)
+	s.AddSpan(again.StartSpan("more work"))
+	got, _ = s.Get(first.TraceID)
+	if len(got.Spans) != 3 || got.Spans[1].SpanID != again.SpanID || got.Spans[1].ParentSpanID != first.SpanID || got.Spans[2].ParentSpanID != again.SpanID {
+		t.Fatalf("expected the second request nested under the root, got %+v", got.Spans)
+	}
+
+	// The oldest trace is evicted past the limit.
+	s.Add(NewTrace())
+	s.Add(NewTrace())
+	if _, err := s.Get(first.TraceID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound after eviction, got %v", err)
+	}
+}
+
+func TestTraceStoreCapsSpansPerTrace(t *testing.T) {
+	s := NewTraceStore(1)
+	root := NewTrace()
+	s.Add(root)
+	for i := 0; i < MaxSpansPerTrace+5; i++ {
+		s.Add(NewTraceWithID(root.TraceID))
+	}
+	s.AddSpan(root.StartSpan("late"))
+
+	got, err := s.Get(root.TraceID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if len(got.Spans) != MaxSpansPerTrace || got.DroppedSpans != 6 {
+		t.Fatalf("expected %d spans and 6 dropped, got %d and %d", MaxSpansPerTrace, len(got.Spans), got.DroppedSpans)
+	}
+}
@@ -0,0 +1,52 @@
+// Package types holds definitions shared between the client and server.
+package types
+
+import "errors"
+
+// Status is the state of a translation job as reported by the server.
+type Status string
+
+const (
+	StatusNone      Status = ""          // Before the first response for a job.
+	StatusScheduled Status = "scheduled" // Waiting for its scheduled time before it starts.
+	StatusPending   Status = "pending"
+	StatusCompleted Status = "completed"
+	StatusError     Status = "error"
+	StatusCancelled Status = "cancelled"
+)
+
+// IsTerminal reports whether s is a final status, after which the job won't change.
+func (s Status) IsTerminal() bool {
+	return s == StatusCompleted || s == StatusError || s == StatusCancelled
+}
+
+// ErrInvalidTransition is returned when a job moves between statuses in a way
+// the StatusMachine doesn't allow, e.g. from "error" to "completed".
+var ErrInvalidTransition = errors.New("invalid status transition")
+
+// StatusMachine describes which status changes a job can go through.
+type StatusMachine struct {
+	ValidTransitions map[Status][]Status
+}
+
+// NewStatusMachine returns the machine for the server's jobs: they start out
+// pending, or scheduled until they start, stay pending for any number of
+// polls and then end as completed, error or cancelled. A scheduled job may
+// have started and finished between two polls. Nothing follows a final status.
+func NewStatusMachine() *StatusMachine {
+	return &StatusMachine{ValidTransitions: map[Status][]Status{
+		StatusNone:      {StatusScheduled, StatusPending},
+		StatusScheduled: {StatusScheduled, StatusPending, StatusCompleted, StatusError, StatusCancelled},
+		StatusPending:   {StatusPending, StatusCompleted, StatusError, StatusCancelled},
+	}}
+}
+
+// IsValid reports whether a job may go from status from to status to.
+func (m *StatusMachine) IsValid(from, to Status) bool {
+	for _, s := range m.ValidTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
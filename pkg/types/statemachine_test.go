@@ -0,0 +1,51 @@
+package types
+
+import "testing"
+
+func TestStatusMachine(t *testing.T) {
+	m := NewStatusMachine()
+	tests := []struct {
+		from, to Status
+		valid    bool
+	}{
+		{StatusNone, StatusPending, true},
+		{StatusPending, StatusPending, true},
+		{StatusPending, StatusCompleted, true},
+		{StatusPending, StatusError, true},
+		{StatusPending, StatusCancelled, true},
+		{StatusNone, StatusScheduled, true},
+		{StatusScheduled, StatusScheduled, true},
+		{StatusScheduled, StatusPending, true},
+		{StatusScheduled, StatusCompleted, true},
+
+		{StatusNone, "unknown", false},
+		{StatusNone, StatusCompleted, false},
+		{StatusNone, StatusError, false},
+		{StatusCompleted, StatusCompleted, false},
+		{StatusError, StatusCompleted, false},
+		{StatusCompleted, StatusPending, false},
+		{StatusCompleted, StatusError, false},
+		{StatusCancelled, StatusPending, false},
+		{StatusPending, "unknown", false},
+		{StatusPending, StatusScheduled, false},
+		{StatusCompleted, StatusScheduled, false},
+	}
+	for _, tt := range tests {
+		if got := m.IsValid(tt.from, tt.to); got != tt.valid {
+			t.Errorf("IsValid(%q, %q) = %v, expected %v", tt.from, tt.to, got, tt.valid)
+		}
+	}
+}
+
+func TestIsTerminal(t *testing.T) {
+	for _, s := range []Status{StatusCompleted, StatusError, StatusCancelled} {
+		if !s.IsTerminal() {
+			t.Errorf("expected %q to be terminal", s)
+		}
+	}
+	for _, s := range []Status{StatusNone, StatusScheduled, StatusPending} {
+		if s.IsTerminal() {
+			t.Errorf("expected %q not to be terminal", s)
+		}
+	}
+}